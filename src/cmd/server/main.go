@@ -40,16 +40,30 @@ func main() {
 	// Serve static files (CSS, JS, images)
 	app.Static("/static", "./src/web/static")
 
+	// Load the roots C2PA signing certificates are trusted against
+	if path := strings.TrimSpace(os.Getenv("C2PA_TRUST_LIST")); path != "" {
+		n, err := services.LoadC2PATrustList(path)
+		if err != nil {
+			log.Fatalf("Failed to load C2PA trust list: %v", err)
+		}
+		log.Printf("🔏 Loaded %d C2PA trust anchors from %s", n, path)
+	}
+
 	// Initialize services
 	imageService := services.NewImageService()
 	blobStore := services.NewBlobStore(time.Hour)
 
 	// Initialize handlers
 	webHandler := handlers.NewWebHandler(imageService, blobStore)
-	apiHandler := handlers.NewAPIHandler(imageService)
+	apiHandler := handlers.NewAPIHandler(imageService, blobStore)
 
 	// API routes
 	api := app.Group("/api")
+	api.Get("/thumbnail/:id/:index", apiHandler.HandleThumbnail)
+	api.Get("/privacy/*", apiHandler.HandleGetPrivacy)
+	api.Post("/privacy", apiHandler.HandlePostPrivacy)
+	api.Post("/strip", apiHandler.HandleStrip)
+	api.Post("/edit", apiHandler.HandleEdit)
 	api.Get("/*", apiHandler.HandleGetMetadata)
 	api.Post("/", apiHandler.HandlePostMetadata)
 
@@ -6,6 +6,8 @@ import (
 	"mime/multipart"
 	"net/http"
 	"net/url"
+	"path/filepath"
+	"strconv"
 	"strings"
 
 	"github.com/ahrdadan/image-metadata-viewer/src/internal/models"
@@ -17,17 +19,36 @@ import (
 // APIHandler handles REST API requests
 type APIHandler struct {
 	imageService *services.ImageService
+	blobStore    *services.BlobStore
 }
 
 // NewAPIHandler creates a new APIHandler
-func NewAPIHandler(imageService *services.ImageService) *APIHandler {
+func NewAPIHandler(imageService *services.ImageService, blobStore *services.BlobStore) *APIHandler {
 	return &APIHandler{
 		imageService: imageService,
+		blobStore:    blobStore,
 	}
 }
 
 // HandleGetMetadata handles GET /api/* for URL metadata retrieval
 func (h *APIHandler) HandleGetMetadata(c *fiber.Ctx) error {
+	if msg := queryError(c); msg != "" {
+		return c.Status(http.StatusBadRequest).JSON(models.APIErrorResponse{
+			Success: false,
+			Error:   msg,
+		})
+	}
+	return h.handleURLPath(c, h.respond)
+}
+
+// HandleGetPrivacy handles GET /api/privacy/*, auditing the metadata of
+// the image at a URL for identifying information
+func (h *APIHandler) HandleGetPrivacy(c *fiber.Ctx) error {
+	return h.handleURLPath(c, h.respondPrivacy)
+}
+
+// handleURLPath processes the image whose URL is the wildcard path
+func (h *APIHandler) handleURLPath(c *fiber.Ctx, respond responder) error {
 	rawPath := c.Params("*")
 	if rawPath == "" {
 		return c.Status(http.StatusBadRequest).JSON(models.APIErrorResponse{
@@ -65,24 +86,196 @@ func (h *APIHandler) HandleGetMetadata(c *fiber.Ctx) error {
 		})
 	}
 
-	return c.JSON(models.APIResponse{
-		Success: true,
-		Data:    []models.ImageMetadata{*meta},
-	})
+	return respond(c, true, []models.ImageMetadata{*meta}, nil)
+}
+
+// HandleThumbnail handles GET /api/thumbnail/:id/:index, serving an
+// embedded thumbnail of an uploaded image
+func (h *APIHandler) HandleThumbnail(c *fiber.Ctx) error {
+	var data []byte
+	ok := false
+	if h.blobStore != nil {
+		data, _, ok = h.blobStore.Get(c.Params("id"))
+	}
+	if !ok {
+		return c.Status(http.StatusNotFound).JSON(models.APIErrorResponse{
+			Success: false,
+			Error:   "Image not found or expired",
+		})
+	}
+
+	index, err := strconv.Atoi(c.Params("index"))
+	if err != nil || index < 0 {
+		return c.Status(http.StatusBadRequest).JSON(models.APIErrorResponse{
+			Success: false,
+			Error:   "Invalid thumbnail index",
+		})
+	}
+
+	thumb, contentType, err := h.imageService.ExtractThumbnail(data, index)
+	if err != nil {
+		return c.Status(http.StatusNotFound).JSON(models.APIErrorResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+	}
+
+	c.Set("Content-Type", contentType)
+	// Preview bytes come from the upload; never let them run on this origin
+	c.Set("Content-Security-Policy", "default-src 'none'; sandbox")
+	c.Set("X-Content-Type-Options", "nosniff")
+	c.Set("Cache-Control", "private, max-age=3600")
+	return c.Send(thumb)
+}
+
+// HandleStrip handles POST /api/strip, returning an uploaded file or an
+// image uploaded through the web form with its metadata removed
+func (h *APIHandler) HandleStrip(c *fiber.Ctx) error {
+	data, fileName, err := h.requestImage(c)
+	if data == nil {
+		return err
+	}
+
+	cleaned, contentType, err := h.imageService.StripMetadata(data, formValues(c, "keep"))
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(models.APIErrorResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+	}
+
+	c.Attachment(outputName(fileName, "-clean", contentType))
+	c.Set("Content-Type", contentType)
+	return c.Send(cleaned)
+}
+
+// HandleEdit handles POST /api/edit, writing the JSON tag patch of the
+// "patch" form field into an uploaded file or an image uploaded through
+// the web form and returning the edited file
+func (h *APIHandler) HandleEdit(c *fiber.Ctx) error {
+	data, fileName, err := h.requestImage(c)
+	if data == nil {
+		return err
+	}
+
+	edited, contentType, err := h.imageService.EditMetadata(data, c.FormValue("patch"))
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(models.APIErrorResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+	}
+
+	c.Attachment(outputName(fileName, "-edited", contentType))
+	c.Set("Content-Type", contentType)
+	return c.Send(edited)
+}
+
+// requestImage reads the image a request names: the multipart field
+// "file", or "blob" holding the ID of an image uploaded through the web
+// form. When there is no image to read it answers with an error response
+// and returns nil data.
+func (h *APIHandler) requestImage(c *fiber.Ctx) ([]byte, string, error) {
+	if fileHeader, err := c.FormFile("file"); err == nil {
+		data, err := readUpload(fileHeader)
+		if err != nil {
+			return nil, "", c.Status(http.StatusBadRequest).JSON(models.APIErrorResponse{
+				Success: false,
+				Error:   fmt.Sprintf("%s: %s", fileHeader.Filename, err.Error()),
+			})
+		}
+		return data, fileHeader.Filename, nil
+	}
+
+	id := c.FormValue("blob")
+	if id == "" {
+		return nil, "", c.Status(http.StatusBadRequest).JSON(models.APIErrorResponse{
+			Success: false,
+			Error:   "A file or blob ID is required",
+		})
+	}
+	var data []byte
+	ok := false
+	if h.blobStore != nil {
+		data, _, ok = h.blobStore.Get(id)
+	}
+	if !ok {
+		return nil, "", c.Status(http.StatusNotFound).JSON(models.APIErrorResponse{
+			Success: false,
+			Error:   "Image not found or expired",
+		})
+	}
+	return data, "image", nil
+}
+
+// formValues returns every value of a form or query field, whether the
+// form is multipart or URL-encoded
+func formValues(c *fiber.Ctx, name string) []string {
+	var values []string
+	if form, err := c.MultipartForm(); err == nil {
+		values = append(values, form.Value[name]...)
+	} else {
+		for _, v := range c.Request().PostArgs().PeekMulti(name) {
+			values = append(values, string(v))
+		}
+	}
+	for _, v := range c.Request().URI().QueryArgs().PeekMulti(name) {
+		values = append(values, string(v))
+	}
+	return values
+}
+
+// outputExtensions names files of the formats that can be rewritten
+var outputExtensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/webp": ".webp",
+}
+
+// outputName derives the download name of a rewritten file, e.g.
+// photo.jpg becomes photo-clean.jpg
+func outputName(fileName, suffix, contentType string) string {
+	base := filepath.Base(fileName)
+	ext := filepath.Ext(base)
+	if ext == "" {
+		ext = outputExtensions[contentType]
+	}
+	return strings.TrimSuffix(base, ext) + suffix + ext
 }
 
 // HandlePostMetadata handles POST /api for multiple URLs or file uploads
 func (h *APIHandler) HandlePostMetadata(c *fiber.Ctx) error {
+	if msg := queryError(c); msg != "" {
+		return c.Status(http.StatusBadRequest).JSON(models.APIErrorResponse{
+			Success: false,
+			Error:   msg,
+		})
+	}
+
+	return h.handlePost(c, h.respond)
+}
+
+// HandlePostPrivacy handles POST /api/privacy, auditing uploaded files or
+// a list of URLs like POST /api
+func (h *APIHandler) HandlePostPrivacy(c *fiber.Ctx) error {
+	return h.handlePost(c, h.respondPrivacy)
+}
+
+// responder writes the processed images in the form an endpoint returns
+type responder func(c *fiber.Ctx, success bool, results []models.ImageMetadata, errors []string) error
+
+// handlePost processes a file upload or a JSON list of URLs
+func (h *APIHandler) handlePost(c *fiber.Ctx, respond responder) error {
 	contentType := c.Get("Content-Type")
 
 	// Check if it's a multipart form (file upload)
 	if strings.Contains(contentType, "multipart/form-data") {
-		return h.handleFileUpload(c)
+		return h.handleFileUpload(c, respond)
 	}
 
 	// Check if it's JSON (URL list)
 	if strings.Contains(contentType, "application/json") {
-		return h.handleJSONURLs(c)
+		return h.handleJSONURLs(c, respond)
 	}
 
 	return c.Status(http.StatusBadRequest).JSON(models.APIErrorResponse{
@@ -92,7 +285,7 @@ func (h *APIHandler) HandlePostMetadata(c *fiber.Ctx) error {
 }
 
 // handleJSONURLs processes JSON payload with URLs
-func (h *APIHandler) handleJSONURLs(c *fiber.Ctx) error {
+func (h *APIHandler) handleJSONURLs(c *fiber.Ctx, respond responder) error {
 	var payload struct {
 		URLs []string `json:"urls"`
 	}
@@ -132,20 +325,11 @@ func (h *APIHandler) handleJSONURLs(c *fiber.Ctx) error {
 		results = append(results, *meta)
 	}
 
-	response := models.APIResponse{
-		Success: len(results) > 0,
-		Data:    results,
-	}
-
-	if len(errors) > 0 {
-		response.Errors = errors
-	}
-
-	return c.JSON(response)
+	return respond(c, len(results) > 0, results, errors)
 }
 
 // handleFileUpload processes multipart file uploads
-func (h *APIHandler) handleFileUpload(c *fiber.Ctx) error {
+func (h *APIHandler) handleFileUpload(c *fiber.Ctx, respond responder) error {
 	form, err := c.MultipartForm()
 	if err != nil {
 		return c.Status(http.StatusBadRequest).JSON(models.APIErrorResponse{
@@ -181,20 +365,93 @@ func (h *APIHandler) handleFileUpload(c *fiber.Ctx) error {
 		})
 	}
 
-	response := models.APIResponse{
-		Success: true,
-		Data:    results,
+	return respond(c, true, results, errors)
+}
+
+// queryError checks the view and ai query parameters, returning what is
+// wrong with them or ""
+func queryError(c *fiber.Ctx) string {
+	switch c.Query("view") {
+	case "", "summary", "sources", "composite":
+	default:
+		return "view must be summary, sources or composite"
+	}
+	switch c.Query("ai") {
+	case "", "true", "false":
+	default:
+		return "ai must be true or false"
+	}
+	return ""
+}
+
+// respond writes metadata in the view chosen by the view query parameter:
+// the flat summary (the default), grouped by source, or composite values
+// only. ai=true keeps only the images with markers of generative AI and
+// ai=false the others.
+func (h *APIHandler) respond(c *fiber.Ctx, success bool, results []models.ImageMetadata, errors []string) error {
+	if len(errors) == 0 {
+		errors = nil
+	}
+
+	if ai := c.Query("ai"); ai != "" {
+		kept := make([]models.ImageMetadata, 0, len(results))
+		for _, r := range results {
+			if (r.AIProvenance != nil && r.AIProvenance.Detected) == (ai == "true") {
+				kept = append(kept, r)
+			}
+		}
+		results = kept
 	}
 
-	if len(errors) > 0 {
-		response.Errors = errors
+	view := c.Query("view")
+	if view == "" || view == "summary" {
+		return c.JSON(models.APIResponse{
+			Success: success,
+			Data:    results,
+			Errors:  errors,
+		})
 	}
 
-	return c.JSON(response)
+	data := make([]models.SourcedMetadata, len(results))
+	for i := range results {
+		sourced := h.imageService.GroupBySource(&results[i])
+		if view == "composite" {
+			sourced = &models.SourcedMetadata{
+				FileName:     sourced.FileName,
+				Composite:    sourced.Composite,
+				Consistency:  sourced.Consistency,
+				AIProvenance: sourced.AIProvenance,
+				Errors:       sourced.Errors,
+			}
+		}
+		data[i] = *sourced
+	}
+
+	return c.JSON(models.APISourcedResponse{
+		Success: success,
+		Data:    data,
+		Errors:  errors,
+	})
 }
 
-// processAPIUpload processes a single uploaded file for API
-func (h *APIHandler) processAPIUpload(fileHeader *multipart.FileHeader) (*models.ImageMetadata, error) {
+// respondPrivacy writes a privacy report for each image
+func (h *APIHandler) respondPrivacy(c *fiber.Ctx, success bool, results []models.ImageMetadata, errors []string) error {
+	if len(errors) == 0 {
+		errors = nil
+	}
+	reports := make([]models.PrivacyReport, len(results))
+	for i := range results {
+		reports[i] = *h.imageService.AuditPrivacy(&results[i])
+	}
+	return c.JSON(models.APIPrivacyResponse{
+		Success: success,
+		Data:    reports,
+		Errors:  errors,
+	})
+}
+
+// readUpload reads an uploaded file, rejecting empty and oversized ones
+func readUpload(fileHeader *multipart.FileHeader) ([]byte, error) {
 	if fileHeader.Size > services.MaxUploadBytes {
 		return nil, fmt.Errorf("file exceeds size limit")
 	}
@@ -213,6 +470,15 @@ func (h *APIHandler) processAPIUpload(fileHeader *multipart.FileHeader) (*models
 	if len(data) == 0 {
 		return nil, fmt.Errorf("file is empty")
 	}
+	return data, nil
+}
+
+// processAPIUpload processes a single uploaded file for API
+func (h *APIHandler) processAPIUpload(fileHeader *multipart.FileHeader) (*models.ImageMetadata, error) {
+	data, err := readUpload(fileHeader)
+	if err != nil {
+		return nil, err
+	}
 
 	contentType := fileHeader.Header.Get("Content-Type")
 	if contentType == "" {
@@ -175,6 +175,9 @@ func (h *WebHandler) HandleBlob(c *fiber.Ctx) error {
 	if contentType != "" {
 		c.Set("Content-Type", contentType)
 	}
+	// Uploads such as SVG may carry scripts; never let them run on this origin
+	c.Set("Content-Security-Policy", "default-src 'none'; sandbox")
+	c.Set("X-Content-Type-Options", "nosniff")
 	c.Set("Cache-Control", "private, max-age=3600")
 	return c.Send(data)
 }
@@ -222,6 +225,7 @@ func (h *WebHandler) processUploadedFile(fileHeader *multipart.FileHeader) model
 	if h.blobStore != nil {
 		blobID := h.blobStore.Put(data, contentType)
 		result.EmbedURL = "/blob/" + blobID
+		result.BlobID = blobID
 		result.IsBlob = true
 	}
 
@@ -0,0 +1,28 @@
+package models
+
+// AIProvenance gathers the markers of generative AI found in the image's
+// metadata. The summary fields come from the most confident finding that
+// records them.
+type AIProvenance struct {
+	Detected       bool        `json:"detected"`
+	Generator      string      `json:"generator,omitempty"` // e.g. Midjourney, Stable Diffusion web UI v1.6.0
+	Prompt         string      `json:"prompt,omitempty"`
+	NegativePrompt string      `json:"negativePrompt,omitempty"`
+	Seed           string      `json:"seed,omitempty"`
+	Model          string      `json:"model,omitempty"`
+	ModelHash      string      `json:"modelHash,omitempty"`
+	Findings       []AIFinding `json:"findings"` // most confident first
+}
+
+// AIFinding is one marker of AI generation and the values it was read from
+type AIFinding struct {
+	Marker         string         `json:"marker"`     // sd-parameters, comfyui-workflow, midjourney-job, digital-source-type, c2pa-action or generator-name
+	Confidence     string         `json:"confidence"` // high, medium or low
+	Generator      string         `json:"generator,omitempty"`
+	Prompt         string         `json:"prompt,omitempty"`
+	NegativePrompt string         `json:"negativePrompt,omitempty"`
+	Seed           string         `json:"seed,omitempty"`
+	Model          string         `json:"model,omitempty"`
+	ModelHash      string         `json:"modelHash,omitempty"`
+	Evidence       []SourcedValue `json:"evidence"`
+}
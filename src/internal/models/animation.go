@@ -0,0 +1,32 @@
+package models
+
+// Animation describes the frames of an animated GIF, WebP or PNG
+type Animation struct {
+	Format             string           `json:"format"` // GIF, WebP or APNG
+	FrameCount         int              `json:"frameCount"`
+	Plays              int              `json:"plays"` // 0 loops forever
+	Loop               string           `json:"loop"`
+	TotalDuration      int              `json:"totalDuration"` // milliseconds
+	TotalDurationHuman string           `json:"totalDurationHuman"`
+	CanvasWidth        int              `json:"canvasWidth"`
+	CanvasHeight       int              `json:"canvasHeight"`
+	GlobalPaletteSize  int              `json:"globalPaletteSize,omitempty"` // GIF only
+	BackgroundColor    string           `json:"backgroundColor,omitempty"`
+	Frames             []AnimationFrame `json:"frames"`
+}
+
+// AnimationFrame describes a single frame and how it is composited
+type AnimationFrame struct {
+	Index            int    `json:"index"`
+	X                int    `json:"x"`
+	Y                int    `json:"y"`
+	Width            int    `json:"width"`
+	Height           int    `json:"height"`
+	Delay            int    `json:"delay"` // milliseconds
+	Disposal         string `json:"disposal"`
+	Blend            string `json:"blend,omitempty"`
+	LocalPaletteSize int    `json:"localPaletteSize,omitempty"` // GIF only
+	Transparent      bool   `json:"transparent,omitempty"`
+	Interlaced       bool   `json:"interlaced,omitempty"`
+	Codec            string `json:"codec,omitempty"` // WebP only
+}
@@ -0,0 +1,102 @@
+package models
+
+// C2PAInfo is the C2PA (Content Credentials) manifest store embedded in the
+// image and the result of validating its active manifest
+type C2PAInfo struct {
+	Carrier         string         `json:"carrier"` // JPEG APP11, PNG caBX, ISOBMFF uuid, JPEG XL jumb or WebP C2PA
+	ActiveManifest  string         `json:"activeManifest"`
+	ValidationState string         `json:"validationState"` // invalid, well-formed, valid or trusted
+	Manifests       []C2PAManifest `json:"manifests"`       // in store order; the active manifest is last
+}
+
+// C2PAManifest is one manifest of the store: a signed claim and the
+// assertions it covers
+type C2PAManifest struct {
+	Label          string `json:"label"`
+	ClaimGenerator string `json:"claimGenerator,omitempty"`
+	Title          string `json:"title,omitempty"`
+	Format         string `json:"format,omitempty"`
+	InstanceID     string `json:"instanceId,omitempty"`
+
+	Actions        []C2PAAction         `json:"actions,omitempty"`
+	Ingredients    []C2PAIngredient     `json:"ingredients,omitempty"`
+	TrainingMining []C2PATrainingMining `json:"trainingMining,omitempty"`
+	CreativeWork   *C2PACreativeWork    `json:"creativeWork,omitempty"`
+
+	Assertions  []C2PAAssertion  `json:"assertions"`
+	HashBinding *C2PAHashBinding `json:"hashBinding,omitempty"`
+	Signature   *C2PASignature   `json:"signature,omitempty"`
+	Status      []C2PAStatus     `json:"status"`
+}
+
+// C2PAAction is an entry of a c2pa.actions assertion
+type C2PAAction struct {
+	Action            string `json:"action"` // e.g. c2pa.created, c2pa.edited
+	When              string `json:"when,omitempty"`
+	SoftwareAgent     string `json:"softwareAgent,omitempty"`
+	DigitalSourceType string `json:"digitalSourceType,omitempty"` // IPTC term, e.g. trainedAlgorithmicMedia
+	Description       string `json:"description,omitempty"`
+}
+
+// C2PAIngredient is an asset the image was made from
+type C2PAIngredient struct {
+	Title        string `json:"title,omitempty"`
+	Format       string `json:"format,omitempty"`
+	InstanceID   string `json:"instanceId,omitempty"`
+	Relationship string `json:"relationship,omitempty"` // parentOf, componentOf or inputTo
+	Manifest     string `json:"manifest,omitempty"`     // label of the ingredient's own manifest
+}
+
+// C2PATrainingMining is a permission for AI training, inference or data mining
+type C2PATrainingMining struct {
+	Use            string `json:"use"` // e.g. c2pa.ai_training
+	Permission     string `json:"permission"`
+	ConstraintInfo string `json:"constraintInfo,omitempty"`
+	Assertion      string `json:"assertion"`
+}
+
+// C2PACreativeWork is the schema.org CreativeWork assertion
+type C2PACreativeWork struct {
+	Authors   []string `json:"authors,omitempty"`
+	Copyright string   `json:"copyright,omitempty"`
+	URL       string   `json:"url,omitempty"`
+}
+
+// C2PAAssertion is an assertion the claim refers to and whether its hash matched
+type C2PAAssertion struct {
+	Label string `json:"label"`
+	Hash  string `json:"hash"` // match, mismatch or missing
+}
+
+// C2PAHashBinding is how the claim is bound to the image bytes
+type C2PAHashBinding struct {
+	Kind   string `json:"kind"` // data, bmff or boxes
+	Alg    string `json:"alg,omitempty"`
+	Status string `json:"status"` // match, mismatch or unsupported
+}
+
+// C2PASignature is the COSE signature over the claim
+type C2PASignature struct {
+	Algorithm    string            `json:"algorithm"`
+	Certificates []C2PACertificate `json:"certificates"` // signer first
+	Valid        bool              `json:"valid"`
+	Trusted      bool              `json:"trusted"`
+}
+
+// C2PACertificate is a certificate of the signing chain
+type C2PACertificate struct {
+	Subject   string `json:"subject"`
+	Issuer    string `json:"issuer"`
+	Serial    string `json:"serial"`
+	NotBefore string `json:"notBefore"`
+	NotAfter  string `json:"notAfter"`
+}
+
+// C2PAStatus is a validation result, using the status codes of the C2PA
+// specification
+type C2PAStatus struct {
+	Code        string `json:"code"` // e.g. claimSignature.validated, assertion.dataHash.mismatch
+	Kind        string `json:"kind"` // success, informational or failure
+	URL         string `json:"url,omitempty"`
+	Explanation string `json:"explanation"`
+}
@@ -0,0 +1,10 @@
+package models
+
+// ConsistencyFinding is a place where two metadata sources, or the
+// metadata and the image itself, disagree
+type ConsistencyFinding struct {
+	Check    string         `json:"check"`    // e.g. capture-date, pixel-dimensions, thumbnail-aspect
+	Severity string         `json:"severity"` // high, medium or low
+	Message  string         `json:"message"`
+	Evidence []SourcedValue `json:"evidence,omitempty"` // the values that were compared
+}
@@ -0,0 +1,49 @@
+package models
+
+// EXRInfo describes the header of an OpenEXR file. Multi-part files report
+// the first part's header.
+type EXRInfo struct {
+	Version   int  `json:"version"`
+	Tiled     bool `json:"tiled"`
+	Deep      bool `json:"deep"`
+	MultiPart bool `json:"multiPart"`
+	LongNames bool `json:"longNames"`
+	Parts     int  `json:"parts"`
+
+	Compression      string     `json:"compression"`
+	DataWindow       *EXRWindow `json:"dataWindow"`
+	DisplayWindow    *EXRWindow `json:"displayWindow,omitempty"`
+	LineOrder        string     `json:"lineOrder,omitempty"`
+	PixelAspectRatio float64    `json:"pixelAspectRatio,omitempty"`
+	TileWidth        int        `json:"tileWidth,omitempty"`
+	TileHeight       int        `json:"tileHeight,omitempty"`
+	TileMode         string     `json:"tileMode,omitempty"`
+
+	Channels   []EXRChannel   `json:"channels"`
+	Attributes []EXRAttribute `json:"attributes"`
+}
+
+// EXRWindow is an inclusive pixel rectangle (box2i)
+type EXRWindow struct {
+	XMin int `json:"xMin"`
+	YMin int `json:"yMin"`
+	XMax int `json:"xMax"`
+	YMax int `json:"yMax"`
+}
+
+// EXRChannel is an entry of the channels attribute
+type EXRChannel struct {
+	Name      string `json:"name"`
+	PixelType string `json:"pixelType"` // UINT, HALF or FLOAT
+	Linear    bool   `json:"linear"`
+	XSampling int    `json:"xSampling"`
+	YSampling int    `json:"ySampling"`
+}
+
+// EXRAttribute is a header attribute with its value rendered as text
+type EXRAttribute struct {
+	Name  string `json:"name"`
+	Type  string `json:"type"`
+	Size  int    `json:"size"`
+	Value string `json:"value,omitempty"`
+}
@@ -0,0 +1,45 @@
+package models
+
+// HEIFInfo describes the ISOBMFF structure of a HEIC or AVIF file
+type HEIFInfo struct {
+	MajorBrand       string   `json:"majorBrand"`
+	MinorVersion     int      `json:"minorVersion"`
+	CompatibleBrands []string `json:"compatibleBrands"`
+
+	// Primary image and its properties
+	PrimaryItemID   int    `json:"primaryItemID"`
+	PrimaryItemType string `json:"primaryItemType,omitempty"`
+	Codec           string `json:"codec,omitempty"`
+	Width           int    `json:"width"`  // ispe
+	Height          int    `json:"height"` // ispe
+	BitsPerChannel  []int  `json:"bitsPerChannel,omitempty"`
+	Chroma          string `json:"chroma,omitempty"`
+	Rotation        int    `json:"rotation"`         // irot, degrees anti-clockwise
+	Mirror          string `json:"mirror,omitempty"` // imir
+
+	// colr property
+	ColorType               string `json:"colorType,omitempty"` // nclx, prof or rICC
+	ColorPrimaries          string `json:"colorPrimaries,omitempty"`
+	TransferCharacteristics string `json:"transferCharacteristics,omitempty"`
+	MatrixCoefficients      string `json:"matrixCoefficients,omitempty"`
+	FullRange               bool   `json:"fullRange,omitempty"`
+
+	// Auxiliary images (auxC) and thumbnails linked to the primary image
+	HasAlpha        bool     `json:"hasAlpha"`
+	HasDepth        bool     `json:"hasDepth"`
+	AuxiliaryImages []string `json:"auxiliaryImages,omitempty"`
+	Thumbnails      int      `json:"thumbnails"`
+
+	ItemTypes []string   `json:"itemTypes"` // e.g. "hvc1 ×48"
+	Items     []HEIFItem `json:"items"`
+}
+
+// HEIFItem is an entry from the item info and location boxes
+type HEIFItem struct {
+	ID          int    `json:"id"`
+	Type        string `json:"type"`
+	Name        string `json:"name,omitempty"`
+	ContentType string `json:"contentType,omitempty"`
+	Hidden      bool   `json:"hidden,omitempty"`
+	Size        int    `json:"size"`
+}
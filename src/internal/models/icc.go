@@ -0,0 +1,32 @@
+package models
+
+// ICCProfile describes an embedded ICC color profile
+type ICCProfile struct {
+	Description        string   `json:"description,omitempty"`
+	Copyright          string   `json:"copyright,omitempty"`
+	Version            string   `json:"version"`
+	DeviceClass        string   `json:"deviceClass"`
+	ColorSpace         string   `json:"colorSpace"`
+	PCS                string   `json:"pcs"` // profile connection space
+	RenderingIntent    string   `json:"renderingIntent"`
+	WhitePoint         string   `json:"whitePoint,omitempty"` // X, Y, Z
+	CMM                string   `json:"cmm,omitempty"`
+	Platform           string   `json:"platform,omitempty"`
+	Manufacturer       string   `json:"manufacturer,omitempty"`
+	Model              string   `json:"model,omitempty"`
+	DeviceManufacturer string   `json:"deviceManufacturer,omitempty"`
+	DeviceModel        string   `json:"deviceModel,omitempty"`
+	Creator            string   `json:"creator,omitempty"`
+	Created            string   `json:"created,omitempty"`
+	ProfileID          string   `json:"profileID,omitempty"`
+	Size               int      `json:"size"`
+	Tags               []ICCTag `json:"tags"`
+}
+
+// ICCTag is an entry from the profile's tag table
+type ICCTag struct {
+	Signature string `json:"signature"`
+	Type      string `json:"type,omitempty"`
+	Offset    int    `json:"offset"`
+	Size      int    `json:"size"`
+}
@@ -0,0 +1,28 @@
+package models
+
+// IconInfo lists the images stored in an ICO or CUR file
+type IconInfo struct {
+	Type   string      `json:"type"` // ICO or CUR
+	Count  int         `json:"count"`
+	Images []IconImage `json:"images"`
+}
+
+// IconImage is one entry of the icon directory
+type IconImage struct {
+	Index      int          `json:"index"`
+	Width      int          `json:"width"`
+	Height     int          `json:"height"`
+	BitDepth   int          `json:"bitDepth"`             // bits per pixel
+	ColorCount int          `json:"colorCount,omitempty"` // palette size from the directory
+	Format     string       `json:"format"`               // PNG or BMP
+	ColorType  string       `json:"colorType,omitempty"`  // PNG images only
+	Hotspot    *IconHotspot `json:"hotspot,omitempty"`    // CUR only
+	Offset     int          `json:"offset"`
+	Size       int          `json:"size"`
+}
+
+// IconHotspot is the click point of a cursor image
+type IconHotspot struct {
+	X int `json:"x"`
+	Y int `json:"y"`
+}
@@ -11,15 +11,16 @@ type ImageMetadata struct {
 	FileType          string    `json:"fileType"`
 	FileTypeExtension string    `json:"fileTypeExtension"`
 	MIMEType          string    `json:"mimeType"`
-	Source            string    `json:"source"` // "upload" or "remote"
+	DeclaredMIMEType  string    `json:"declaredMimeType,omitempty"` // Content-Type sent with the file
+	Source            string    `json:"source"`                     // "upload" or "remote"
 	UploadedAt        time.Time `json:"uploadedAt,omitempty"`
 
 	// Image dimensions
-	Width                int     `json:"width"`
-	Height               int     `json:"height"`
-	AspectRatio          string  `json:"aspectRatio"`
-	AspectRatioFraction  string  `json:"aspectRatioFraction,omitempty"`
-	Megapixels           float64 `json:"megapixels"`
+	Width               int     `json:"width"`
+	Height              int     `json:"height"`
+	AspectRatio         string  `json:"aspectRatio"`
+	AspectRatioFraction string  `json:"aspectRatioFraction,omitempty"`
+	Megapixels          float64 `json:"megapixels"`
 
 	// Color information
 	ColorSpace      string `json:"colorSpace,omitempty"`
@@ -28,9 +29,42 @@ type ImageMetadata struct {
 	ColorComponents int    `json:"colorComponents,omitempty"`
 	SamplesPerPixel int    `json:"samplesPerPixel,omitempty"`
 
+	// Embedded ICC color profile
+	ICCProfile *ICCProfile `json:"iccProfile,omitempty"`
+
 	// JPEG specific
-	EncodingProcess  string `json:"encodingProcess,omitempty"`
-	PhotoshopQuality int    `json:"photoshopQuality,omitempty"`
+	EncodingProcess  string    `json:"encodingProcess,omitempty"`
+	PhotoshopQuality int       `json:"photoshopQuality,omitempty"`
+	JPEG             *JPEGInfo `json:"jpeg,omitempty"`
+
+	// PNG specific
+	PNG *PNGInfo `json:"png,omitempty"`
+
+	// Animated GIF, WebP and PNG frames
+	Animation *Animation `json:"animation,omitempty"`
+
+	// HEIC/AVIF container structure
+	HEIF *HEIFInfo `json:"heif,omitempty"`
+
+	// Camera raw layout
+	RAW *RAWInfo `json:"raw,omitempty"`
+
+	// ICO/CUR image directory
+	Icon *IconInfo `json:"icon,omitempty"`
+
+	// SVG document structure and active content
+	SVG *SVGInfo `json:"svg,omitempty"`
+
+	// JPEG XL, JPEG 2000 and OpenEXR headers
+	JXL *JXLInfo `json:"jxl,omitempty"`
+	JP2 *JP2Info `json:"jp2,omitempty"`
+	EXR *EXRInfo `json:"exr,omitempty"`
+
+	// Photoshop document header, resources and layers
+	PSD *PSDInfo `json:"psd,omitempty"`
+
+	// Embedded thumbnails and previews
+	Thumbnails []Thumbnail `json:"thumbnails,omitempty"`
 
 	// EXIF data
 	Orientation    string `json:"orientation,omitempty"`
@@ -41,10 +75,43 @@ type ImageMetadata struct {
 	ModifyDate     string `json:"modifyDate,omitempty"`
 	CreateDate     string `json:"createDate,omitempty"`
 
+	// Every date in EXIF, XMP and IPTC normalized to RFC 3339, and the one
+	// that best gives the capture time
+	CaptureTime *Timestamp  `json:"captureTime,omitempty"`
+	Timestamps  []Timestamp `json:"timestamps,omitempty"`
+
+	// Camera, lens and capture settings
+	Camera *Camera `json:"camera,omitempty"`
+
+	// Manufacturer MakerNote
+	MakerNote *MakerNote `json:"makerNote,omitempty"`
+
+	// GPS location
+	Location *Location `json:"location,omitempty"`
+
+	// Complete EXIF tag dump
+	EXIF *EXIFData `json:"exif,omitempty"`
+
 	// XMP metadata
-	CreatorTool  string `json:"creatorTool,omitempty"`
-	MetadataDate string `json:"metadataDate,omitempty"`
-	Format       string `json:"format"`
+	CreatorTool  string   `json:"creatorTool,omitempty"`
+	MetadataDate string   `json:"metadataDate,omitempty"`
+	XMP          *XMPData `json:"xmp,omitempty"`
+	Format       string   `json:"format"`
+
+	// IPTC-IIM (Photoshop APP13)
+	IPTC *IPTCData `json:"iptc,omitempty"`
+
+	// C2PA Content Credentials
+	C2PA *C2PAInfo `json:"c2pa,omitempty"`
+
+	// Disagreements between sources, found once extraction is complete
+	Consistency []ConsistencyFinding `json:"consistency,omitempty"`
+
+	// Markers of generative AI gathered from every source
+	AIProvenance *AIProvenance `json:"aiProvenance,omitempty"`
+
+	// Values contributed by registered extractors, keyed by the name they set
+	Extensions map[string]any `json:"extensions,omitempty"`
 
 	// HTTP metadata (for remote images)
 	Status          string `json:"status,omitempty"`
@@ -56,8 +123,85 @@ type ImageMetadata struct {
 	Duration        string `json:"duration,omitempty"`
 
 	// Error information
-	FetchError  string `json:"fetchError,omitempty"`
-	DecodeError string `json:"decodeError,omitempty"`
+	FetchError      string           `json:"fetchError,omitempty"`
+	DecodeError     string           `json:"decodeError,omitempty"`
+	ExtractorErrors []ExtractorError `json:"extractorErrors,omitempty"`
+}
+
+// ExtractorError is a failure reported by one metadata extractor
+type ExtractorError struct {
+	Extractor string `json:"extractor"`
+	Error     string `json:"error"`
+}
+
+// EXIFData contains every tag found in the image's EXIF block
+type EXIFData struct {
+	ByteOrder string    `json:"byteOrder"`
+	Tags      []EXIFTag `json:"tags"`
+}
+
+// EXIFTag represents a single tag from an EXIF image file directory
+type EXIFTag struct {
+	IFD      string `json:"ifd"`      // IFD0, ExifIFD, GPS, Interop, IFD1 or a MakerNote IFD
+	ID       uint16 `json:"id"`       // numeric tag ID
+	Name     string `json:"name"`     // canonical EXIF/TIFF tag name
+	Type     string `json:"type"`     // TIFF field type, e.g. RATIONAL
+	Count    uint32 `json:"count"`    // number of values
+	RawValue string `json:"rawValue"` // value as stored in the file
+	Value    string `json:"value"`    // human-readable interpretation
+}
+
+// Camera describes the capture device, lens and exposure settings
+type Camera struct {
+	Make              string  `json:"make,omitempty"`
+	Model             string  `json:"model,omitempty"`
+	SerialNumber      string  `json:"serialNumber,omitempty"`
+	LensMake          string  `json:"lensMake,omitempty"`
+	LensModel         string  `json:"lensModel,omitempty"`
+	LensSerialNumber  string  `json:"lensSerialNumber,omitempty"`
+	LensSpecification string  `json:"lensSpecification,omitempty"` // e.g. "24-70mm f/2.8"
+	ExposureTime      string  `json:"exposureTime,omitempty"`      // e.g. "1/250 s"
+	FNumber           float64 `json:"fNumber,omitempty"`
+	Aperture          string  `json:"aperture,omitempty"` // e.g. "f/2.8"
+	ISO               int     `json:"iso,omitempty"`
+	ExposureBias      string  `json:"exposureBias,omitempty"` // e.g. "+0.33 EV"
+	FocalLength       float64 `json:"focalLength,omitempty"`  // millimeters
+	FocalLengthHuman  string  `json:"focalLengthHuman,omitempty"`
+	FocalLengthIn35mm int     `json:"focalLengthIn35mm,omitempty"` // millimeters
+	ExposureProgram   string  `json:"exposureProgram,omitempty"`
+	MeteringMode      string  `json:"meteringMode,omitempty"`
+	WhiteBalance      string  `json:"whiteBalance,omitempty"`
+	Flash             *Flash  `json:"flash,omitempty"`
+}
+
+// Flash is the decoded EXIF Flash bit field
+type Flash struct {
+	Raw             int    `json:"raw"`
+	Fired           bool   `json:"fired"`
+	Mode            string `json:"mode,omitempty"`   // compulsory firing/suppression or auto
+	Return          string `json:"return,omitempty"` // strobe return light detection
+	FunctionPresent bool   `json:"functionPresent"`
+	RedEyeReduction bool   `json:"redEyeReduction"`
+	Description     string `json:"description"`
+}
+
+// Location contains the GPS position recorded in the image's EXIF block
+type Location struct {
+	Latitude          float64  `json:"latitude"`           // decimal degrees, negative = south
+	Longitude         float64  `json:"longitude"`          // decimal degrees, negative = west
+	LatitudeDMS       string   `json:"latitudeDMS"`        // e.g. 37° 46′ 29.64″ N
+	LongitudeDMS      string   `json:"longitudeDMS"`       // e.g. 122° 25′ 9.84″ W
+	Coordinates       string   `json:"coordinates"`        // "lat, lon" for copy & paste
+	Altitude          *float64 `json:"altitude,omitempty"` // meters, negative = below sea level
+	AltitudeHuman     string   `json:"altitudeHuman,omitempty"`
+	Timestamp         string   `json:"timestamp,omitempty"`    // GPS UTC time, RFC 3339
+	ImgDirection      *float64 `json:"imgDirection,omitempty"` // degrees the camera was facing
+	ImgDirectionRef   string   `json:"imgDirectionRef,omitempty"`
+	ImgDirectionHuman string   `json:"imgDirectionHuman,omitempty"`
+	Speed             *float64 `json:"speed,omitempty"`
+	SpeedUnit         string   `json:"speedUnit,omitempty"`
+	SpeedHuman        string   `json:"speedHuman,omitempty"`
+	MapDatum          string   `json:"mapDatum,omitempty"`
 }
 
 // ViewData represents the data passed to view templates
@@ -80,6 +224,7 @@ type ImageResult struct {
 	Metadata   *ImageMetadata
 	Error      string
 	IsBlob     bool
+	BlobID     string
 }
 
 // HomeData represents the data passed to home template
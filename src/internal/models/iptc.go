@@ -0,0 +1,43 @@
+package models
+
+// IPTCData contains IPTC-IIM datasets from Photoshop image resources
+type IPTCData struct {
+	CodedCharacterSet string `json:"codedCharacterSet,omitempty"`
+
+	// Record 2 (application record)
+	ObjectName             string   `json:"objectName,omitempty"`
+	Headline               string   `json:"headline,omitempty"`
+	Caption                string   `json:"caption,omitempty"`
+	CaptionWriter          string   `json:"captionWriter,omitempty"`
+	Keywords               []string `json:"keywords,omitempty"`
+	Category               string   `json:"category,omitempty"`
+	SupplementalCategories []string `json:"supplementalCategories,omitempty"`
+	Urgency                string   `json:"urgency,omitempty"`
+	SpecialInstructions    string   `json:"specialInstructions,omitempty"`
+	DateCreated            string   `json:"dateCreated,omitempty"` // YYYY-MM-DD
+	TimeCreated            string   `json:"timeCreated,omitempty"` // HH:MM:SS±HH:MM
+	OriginatingProgram     string   `json:"originatingProgram,omitempty"`
+	Byline                 []string `json:"byline,omitempty"`
+	BylineTitle            string   `json:"bylineTitle,omitempty"`
+	Credit                 string   `json:"credit,omitempty"`
+	Source                 string   `json:"source,omitempty"`
+	CopyrightNotice        string   `json:"copyrightNotice,omitempty"`
+	Contact                []string `json:"contact,omitempty"`
+	City                   string   `json:"city,omitempty"`
+	SubLocation            string   `json:"subLocation,omitempty"`
+	ProvinceState          string   `json:"provinceState,omitempty"`
+	Country                string   `json:"country,omitempty"`
+	CountryCode            string   `json:"countryCode,omitempty"`
+	TransmissionReference  string   `json:"transmissionReference,omitempty"`
+
+	// Every dataset in stream order
+	Datasets []IPTCDataset `json:"datasets"`
+}
+
+// IPTCDataset is a single record:dataset entry
+type IPTCDataset struct {
+	Record  int    `json:"record"`
+	Dataset int    `json:"dataset"`
+	Name    string `json:"name"`
+	Value   string `json:"value"`
+}
@@ -0,0 +1,37 @@
+package models
+
+// JP2Info describes a JPEG 2000 file: the JP2/JPX box structure when present
+// and the SIZ and COD markers of the codestream
+type JP2Info struct {
+	Container        bool     `json:"container"` // false for a bare J2K codestream
+	Brand            string   `json:"brand,omitempty"`
+	CompatibleBrands []string `json:"compatibleBrands,omitempty"`
+
+	// Image header (ihdr, bpcc) or SIZ marker
+	Width            int   `json:"width"`
+	Height           int   `json:"height"`
+	Components       int   `json:"components"`
+	BitsPerComponent []int `json:"bitsPerComponent"`
+	Signed           bool  `json:"signed"`
+
+	// Color specification (colr), channel definitions (cdef) and palette (pclr)
+	ColorMethod    string   `json:"colorMethod,omitempty"` // Enumerated, Restricted ICC or Any ICC
+	ColorSpace     string   `json:"colorSpace,omitempty"`
+	Channels       []string `json:"channels,omitempty"` // e.g. "Color 1", "Opacity"
+	PaletteEntries int      `json:"paletteEntries,omitempty"`
+
+	// Resolution boxes, in pixels per inch
+	CaptureResolution string `json:"captureResolution,omitempty"`
+	DisplayResolution string `json:"displayResolution,omitempty"`
+
+	// Coding style (COD marker)
+	TileWidth           int    `json:"tileWidth,omitempty"`
+	TileHeight          int    `json:"tileHeight,omitempty"`
+	Tiles               int    `json:"tiles,omitempty"`
+	DecompositionLevels int    `json:"decompositionLevels"`
+	Wavelet             string `json:"wavelet,omitempty"` // 5/3 reversible (lossless) or 9/7 irreversible
+	Progression         string `json:"progression,omitempty"`
+	QualityLayers       int    `json:"qualityLayers,omitempty"`
+
+	Boxes []ContainerBox `json:"boxes,omitempty"`
+}
@@ -0,0 +1,36 @@
+package models
+
+// JPEGInfo describes the frame and table structure of a JPEG stream
+type JPEGInfo struct {
+	Marker           string           `json:"marker"` // SOF0, SOF2...
+	EncodingProcess  string           `json:"encodingProcess"`
+	Progressive      bool             `json:"progressive"`
+	Lossless         bool             `json:"lossless"`
+	Arithmetic       bool             `json:"arithmetic"`
+	Differential     bool             `json:"differential"`
+	BitsPerSample    int              `json:"bitsPerSample"`
+	Width            int              `json:"width"`
+	Height           int              `json:"height"`
+	Components       []JPEGComponent  `json:"components"`
+	Subsampling      string           `json:"subsampling,omitempty"`
+	RestartInterval  int              `json:"restartInterval"`
+	QuantTables      []JPEGQuantTable `json:"quantTables"`
+	EstimatedQuality int              `json:"estimatedQuality,omitempty"`
+	QualityExact     bool             `json:"qualityExact"`
+}
+
+// JPEGComponent is a color component from the SOF header
+type JPEGComponent struct {
+	ID         int    `json:"id"`
+	Name       string `json:"name"`
+	Horizontal int    `json:"horizontal"` // sampling factor
+	Vertical   int    `json:"vertical"`   // sampling factor
+	QuantTable int    `json:"quantTable"`
+}
+
+// JPEGQuantTable is a DQT table with values in natural (row-major) order
+type JPEGQuantTable struct {
+	ID        int   `json:"id"`
+	Precision int   `json:"precision"` // 8 or 16 bits
+	Values    []int `json:"values"`
+}
@@ -0,0 +1,38 @@
+package models
+
+// JXLInfo describes a JPEG XL codestream header and its optional container
+type JXLInfo struct {
+	Container bool `json:"container"`       // ISOBMFF boxes wrap the codestream
+	Level     int  `json:"level,omitempty"` // jxll box
+
+	Width         int  `json:"width"`
+	Height        int  `json:"height"`
+	BitsPerSample int  `json:"bitsPerSample"`
+	ExponentBits  int  `json:"exponentBits,omitempty"` // floating-point samples only
+	FloatSamples  bool `json:"floatSamples"`
+	Orientation   int  `json:"orientation"` // EXIF orientation value, 1-8
+
+	// Color encoding signalled in the header
+	XYBEncoded       bool   `json:"xybEncoded"` // lossy VarDCT-style color transform
+	ColorSpace       string `json:"colorSpace"` // RGB, Grayscale, XYB or Unknown
+	EmbeddedICC      bool   `json:"embeddedICC"`
+	WhitePoint       string `json:"whitePoint,omitempty"`
+	Primaries        string `json:"primaries,omitempty"`
+	TransferFunction string `json:"transferFunction,omitempty"`
+	RenderingIntent  string `json:"renderingIntent,omitempty"`
+
+	ExtraChannels      []string `json:"extraChannels,omitempty"` // e.g. "Alpha (8-bit)"
+	HasPreview         bool     `json:"hasPreview"`
+	Animated           bool     `json:"animated"`
+	JPEGReconstruction bool     `json:"jpegReconstruction"` // jbrd box: the original JPEG can be rebuilt
+
+	Boxes []ContainerBox `json:"boxes,omitempty"`
+}
+
+// ContainerBox is a top-level box of a JPEG XL or JPEG 2000 file
+type ContainerBox struct {
+	Type        string `json:"type"`
+	Offset      int    `json:"offset"`
+	Length      int    `json:"length"`
+	Description string `json:"description,omitempty"`
+}
@@ -0,0 +1,23 @@
+package models
+
+// MakerNote holds the decoded manufacturer-specific EXIF MakerNote
+type MakerNote struct {
+	Vendor    string `json:"vendor"`    // Canon, Nikon, Sony, Fujifilm, Olympus or Apple
+	Format    string `json:"format"`    // header variant, e.g. "Nikon type 3"
+	ByteOrder string `json:"byteOrder"` // byte order of the MakerNote IFD
+	Offset    int    `json:"offset"`    // position within the TIFF block holding it
+	Size      int    `json:"size"`      // bytes
+
+	// Fields decoded across vendors
+	SerialNumber         string `json:"serialNumber,omitempty"`
+	InternalSerialNumber string `json:"internalSerialNumber,omitempty"`
+	FirmwareVersion      string `json:"firmwareVersion,omitempty"`
+	ShutterCount         int    `json:"shutterCount,omitempty"`
+	LensModel            string `json:"lensModel,omitempty"`
+	LensSerialNumber     string `json:"lensSerialNumber,omitempty"`
+	FocusMode            string `json:"focusMode,omitempty"`
+	ContentIdentifier    string `json:"contentIdentifier,omitempty"` // Apple Live Photo pairing ID
+
+	// Every MakerNote tag, named from the vendor's tag table
+	Tags []EXIFTag `json:"tags"`
+}
@@ -0,0 +1,62 @@
+package models
+
+// PNGInfo describes the chunk structure of a PNG file
+type PNGInfo struct {
+	BitDepth  int    `json:"bitDepth"`
+	ColorType string `json:"colorType"`
+	Interlace string `json:"interlace"`
+
+	// Physical pixel dimensions (pHYs)
+	PixelsPerUnitX int    `json:"pixelsPerUnitX,omitempty"`
+	PixelsPerUnitY int    `json:"pixelsPerUnitY,omitempty"`
+	PixelUnit      string `json:"pixelUnit,omitempty"` // "meter" or "unknown"
+
+	// Color chunks
+	Gamma          float64            `json:"gamma,omitempty"`
+	Chromaticities *PNGChromaticities `json:"chromaticities,omitempty"`
+	SRGBIntent     string             `json:"srgbIntent,omitempty"`
+	ICCProfileName string             `json:"iccProfileName,omitempty"`
+
+	LastModified string        `json:"lastModified,omitempty"` // tIME
+	Animation    *PNGAnimation `json:"animation,omitempty"`
+	Text         []PNGText     `json:"text,omitempty"`
+	Chunks       []PNGChunk    `json:"chunks"`
+}
+
+// PNGChunk is an entry in the PNG chunk listing
+type PNGChunk struct {
+	Type     string `json:"type"`
+	Offset   int    `json:"offset"`
+	Length   int    `json:"length"`
+	CRC      string `json:"crc"`
+	CRCValid bool   `json:"crcValid"`
+}
+
+// PNGText is a keyword/value pair from a tEXt, zTXt or iTXt chunk
+type PNGText struct {
+	Chunk             string `json:"chunk"`
+	Keyword           string `json:"keyword"`
+	Value             string `json:"value"`
+	Language          string `json:"language,omitempty"`
+	TranslatedKeyword string `json:"translatedKeyword,omitempty"`
+}
+
+// PNGChromaticities holds the cHRM white point and primaries
+type PNGChromaticities struct {
+	WhiteX float64 `json:"whiteX"`
+	WhiteY float64 `json:"whiteY"`
+	RedX   float64 `json:"redX"`
+	RedY   float64 `json:"redY"`
+	GreenX float64 `json:"greenX"`
+	GreenY float64 `json:"greenY"`
+	BlueX  float64 `json:"blueX"`
+	BlueY  float64 `json:"blueY"`
+}
+
+// PNGAnimation describes an animated PNG (APNG)
+type PNGAnimation struct {
+	Frames              int  `json:"frames"`
+	Plays               int  `json:"plays"` // 0 loops forever
+	FrameControls       int  `json:"frameControls"`
+	DefaultImageIsFrame bool `json:"defaultImageIsFrame"`
+}
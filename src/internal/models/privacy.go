@@ -0,0 +1,26 @@
+package models
+
+// PrivacyReport lists the metadata that could identify who made an image,
+// who is in it, where it was taken or the device that took it
+type PrivacyReport struct {
+	FileName string           `json:"fileName"`
+	Score    int              `json:"score"`    // 0 when nothing was found, up to 100
+	Risk     string           `json:"risk"`     // none, low, medium or high
+	Findings []PrivacyFinding `json:"findings"` // most severe first
+}
+
+// PrivacyFinding is one kind of identifying metadata and the values that
+// carry it
+type PrivacyFinding struct {
+	Category string         `json:"category"` // gps, place-name, serial-number, person, windows-author, device-id, file-path, document-id, face-region or thumbnail
+	Severity string         `json:"severity"` // high, medium or low
+	Message  string         `json:"message"`
+	Evidence []SourcedValue `json:"evidence"`
+}
+
+// APIPrivacyResponse is the response of the privacy endpoints
+type APIPrivacyResponse struct {
+	Success bool            `json:"success"`
+	Data    []PrivacyReport `json:"data,omitempty"`
+	Errors  []string        `json:"errors,omitempty"`
+}
@@ -0,0 +1,41 @@
+package models
+
+// PSDInfo describes a Photoshop document's header, image resources and layers
+type PSDInfo struct {
+	Version   string `json:"version"` // PSD or PSB (large document format)
+	Width     int    `json:"width"`
+	Height    int    `json:"height"`
+	Channels  int    `json:"channels"`
+	Depth     int    `json:"depth"` // bits per channel
+	ColorMode string `json:"colorMode"`
+
+	Resources []PSDResource `json:"resources"`
+
+	LayerCount  int        `json:"layerCount"`            // every record, including group markers
+	MergedAlpha bool       `json:"mergedAlpha,omitempty"` // first alpha channel holds the merged transparency
+	Layers      []PSDLayer `json:"layers"`                // top of the stack first
+}
+
+// PSDResource is one image resource block
+type PSDResource struct {
+	ID          int    `json:"id"`
+	Name        string `json:"name,omitempty"`
+	Description string `json:"description,omitempty"`
+	Size        int    `json:"size"`
+}
+
+// PSDLayer is a layer record; groups hold their layers in Children
+type PSDLayer struct {
+	Name      string     `json:"name"`
+	Kind      string     `json:"kind"` // pixel, group, text, smart object, adjustment or fill
+	Top       int        `json:"top"`
+	Left      int        `json:"left"`
+	Bottom    int        `json:"bottom"`
+	Right     int        `json:"right"`
+	BlendMode string     `json:"blendMode"`
+	Opacity   int        `json:"opacity"` // percent
+	Visible   bool       `json:"visible"`
+	Clipped   bool       `json:"clipped,omitempty"` // clipped to the layer below
+	Open      bool       `json:"open,omitempty"`    // groups only: expanded in the Layers panel
+	Children  []PSDLayer `json:"children,omitempty"`
+}
@@ -0,0 +1,36 @@
+package models
+
+// RAWInfo describes the layout of a camera raw file
+type RAWInfo struct {
+	Format    string `json:"format"`    // DNG, CR2, CR3, NEF, ARW, ORF or RAF
+	Container string `json:"container"` // TIFF, ISOBMFF or RAF
+	Camera    string `json:"camera,omitempty"`
+
+	// DNG only
+	DNGVersion         string `json:"dngVersion,omitempty"`
+	DNGBackwardVersion string `json:"dngBackwardVersion,omitempty"`
+	UniqueCameraModel  string `json:"uniqueCameraModel,omitempty"`
+
+	// Sensor image, as stored before demosaicing
+	SensorWidth   int    `json:"sensorWidth"`
+	SensorHeight  int    `json:"sensorHeight"`
+	CropWidth     int    `json:"cropWidth,omitempty"` // DNG DefaultCropSize or RAF cropped size
+	CropHeight    int    `json:"cropHeight,omitempty"`
+	BitsPerSample int    `json:"bitsPerSample,omitempty"`
+	Compression   string `json:"compression,omitempty"`
+	Photometric   string `json:"photometric,omitempty"`
+	CFAPattern    string `json:"cfaPattern,omitempty"` // e.g. "RGGB"; larger patterns list rows separated by "/"
+	SensorSource  string `json:"sensorSource,omitempty"`
+
+	Previews []RAWPreview `json:"previews"`
+}
+
+// RAWPreview is an embedded preview or thumbnail image
+type RAWPreview struct {
+	Source string `json:"source"` // IFD, box or header the image was found in
+	Format string `json:"format"` // JPEG or the TIFF compression name
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+	Offset int    `json:"offset"` // byte offset within the file
+	Length int    `json:"length"`
+}
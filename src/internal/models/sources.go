@@ -0,0 +1,49 @@
+package models
+
+// SourcedMetadata groups every value by the part of the file or transfer
+// it was read from, so values that disagree are all kept
+type SourcedMetadata struct {
+	FileName string `json:"fileName"`
+
+	File      ValueGroup `json:"file,omitempty"`      // name, size and declared type
+	HTTP      ValueGroup `json:"http,omitempty"`      // response of a remote fetch
+	EXIF      ValueGroup `json:"exif,omitempty"`      // TIFF/EXIF tags, keyed by tag name
+	XMP       ValueGroup `json:"xmp,omitempty"`       // XMP properties, keyed by path
+	IPTC      ValueGroup `json:"iptc,omitempty"`      // IPTC-IIM datasets, keyed by name
+	ICC       ValueGroup `json:"icc,omitempty"`       // ICC profile header and text tags
+	Container ValueGroup `json:"container,omitempty"` // image header of the file format
+
+	// One value per field, chosen by the precedence documented in docs/API.md
+	Composite map[string]CompositeValue `json:"composite"`
+
+	Consistency  []ConsistencyFinding `json:"consistency,omitempty"`
+	AIProvenance *AIProvenance        `json:"aiProvenance,omitempty"`
+	Errors       []ExtractorError     `json:"errors,omitempty"`
+}
+
+// ValueGroup holds the values read from one source, keyed by field name
+type ValueGroup map[string]SourcedValue
+
+// SourcedValue is a value with the structure it was read from
+type SourcedValue struct {
+	Source  string `json:"source"`  // e.g. "EXIF IFD0", "XMP dc", "IPTC 2:80"
+	Raw     string `json:"raw"`     // as stored
+	Display string `json:"display"` // human-readable form
+}
+
+// CompositeValue is the value chosen for a field, together with the
+// differing values of lower-precedence sources
+type CompositeValue struct {
+	SourcedValue
+	Group     string         `json:"group"` // file, http, exif, xmp, iptc, icc or container
+	Key       string         `json:"key"`   // field within the group
+	Conflicts []SourcedValue `json:"conflicts,omitempty"`
+}
+
+// APISourcedResponse is the API response for the sources and composite
+// views, with values grouped by source
+type APISourcedResponse struct {
+	Success bool              `json:"success"`
+	Data    []SourcedMetadata `json:"data,omitempty"`
+	Errors  []string          `json:"errors,omitempty"`
+}
@@ -0,0 +1,28 @@
+package models
+
+// SVGInfo describes an SVG document. The document is tokenized only, never
+// rendered, so the findings list what a browser would execute or fetch.
+type SVGInfo struct {
+	Version     string `json:"version,omitempty"`
+	Width       string `json:"width,omitempty"`  // as written, e.g. "24", "10mm" or "100%"
+	Height      string `json:"height,omitempty"` // as written
+	ViewBox     string `json:"viewBox,omitempty"`
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	Metadata    string `json:"metadata,omitempty"` // raw content of the <metadata> element
+
+	Elements   int          `json:"elements"`
+	Namespaces []string     `json:"namespaces,omitempty"`
+	Safe       bool         `json:"safe"` // no findings
+	Findings   []SVGFinding `json:"findings,omitempty"`
+}
+
+// SVGFinding is active or external content found in an SVG document
+type SVGFinding struct {
+	Severity  string `json:"severity"` // high, medium or low
+	Kind      string `json:"kind"`     // script, event-handler, javascript-url, external-reference, foreign-object, embedded-content or entity-declaration
+	Element   string `json:"element"`
+	Attribute string `json:"attribute,omitempty"`
+	Value     string `json:"value,omitempty"` // truncated
+	Line      int    `json:"line"`
+}
@@ -0,0 +1,14 @@
+package models
+
+// Thumbnail is an embedded thumbnail or preview image
+type Thumbnail struct {
+	Index     int    `json:"index"`
+	Source    string `json:"source"` // e.g. "EXIF IFD1", "Photoshop", "SubIFD0", "HEIF thmb"
+	Format    string `json:"format"`
+	MIMEType  string `json:"mimeType,omitempty"` // set when a browser can display it
+	Width     int    `json:"width"`
+	Height    int    `json:"height"`
+	Size      int    `json:"size"`      // bytes
+	SizeHuman string `json:"sizeHuman"` // e.g. "12.3 KiB"
+	Offset    int    `json:"offset"`    // byte offset within the file, -1 if not stored contiguously
+}
@@ -0,0 +1,12 @@
+package models
+
+// Timestamp is a date from the image's metadata, normalized to RFC 3339
+type Timestamp struct {
+	Source       string `json:"source"`           // e.g. "EXIF DateTimeOriginal", "XMP xmp:CreateDate", "IPTC DateCreated"
+	Raw          string `json:"raw"`              // as stored
+	Value        string `json:"value"`            // RFC 3339, or local time without an offset when none is known
+	UTC          string `json:"utc,omitempty"`    // the same instant in UTC, when the offset is known
+	Offset       string `json:"offset,omitempty"` // e.g. "+02:00"
+	OffsetSource string `json:"offsetSource"`     // recorded, an EXIF OffsetTime tag, gps or unknown
+	DateOnly     bool   `json:"dateOnly,omitempty"`
+}
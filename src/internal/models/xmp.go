@@ -0,0 +1,73 @@
+package models
+
+// XMPData contains the parsed XMP packet of an image
+type XMPData struct {
+	// xmp namespace
+	CreatorTool  string `json:"creatorTool,omitempty"`
+	CreateDate   string `json:"createDate,omitempty"`
+	ModifyDate   string `json:"modifyDate,omitempty"`
+	MetadataDate string `json:"metadataDate,omitempty"`
+	Rating       string `json:"rating,omitempty"`
+	Label        string `json:"label,omitempty"`
+
+	// dc namespace
+	Title       string   `json:"title,omitempty"`
+	Description string   `json:"description,omitempty"`
+	Creator     []string `json:"creator,omitempty"`
+	Subject     []string `json:"subject,omitempty"` // keywords
+	Rights      string   `json:"rights,omitempty"`
+
+	// xmpMM namespace
+	DocumentID         string            `json:"documentID,omitempty"`
+	InstanceID         string            `json:"instanceID,omitempty"`
+	OriginalDocumentID string            `json:"originalDocumentID,omitempty"`
+	History            []XMPHistoryEvent `json:"history,omitempty"`
+
+	// photoshop namespace
+	Headline    string `json:"headline,omitempty"`
+	Credit      string `json:"credit,omitempty"`
+	Source      string `json:"source,omitempty"`
+	City        string `json:"city,omitempty"`
+	State       string `json:"state,omitempty"`
+	Country     string `json:"country,omitempty"`
+	DateCreated string `json:"dateCreated,omitempty"`
+
+	// Iptc4xmpCore namespace
+	Location    string `json:"location,omitempty"`
+	CountryCode string `json:"countryCode,omitempty"`
+
+	// crs namespace
+	CameraRawVersion string `json:"cameraRawVersion,omitempty"`
+
+	// Every property in the packet, flattened
+	Namespaces []XMPNamespace `json:"namespaces"`
+	Properties []XMPProperty  `json:"properties"`
+	Raw        string         `json:"raw"`
+}
+
+// XMPProperty is a single flattened XMP property.
+// Path uses prefix:name, [n] for array items, [lang] for language
+// alternatives and / for struct fields, e.g. xmpMM:History[2]/stEvt:action.
+type XMPProperty struct {
+	Namespace string `json:"namespace"`
+	Prefix    string `json:"prefix"`
+	Path      string `json:"path"`
+	Value     string `json:"value"`
+}
+
+// XMPNamespace summarizes how many properties a namespace contributes
+type XMPNamespace struct {
+	Prefix     string `json:"prefix"`
+	URI        string `json:"uri"`
+	Properties int    `json:"properties"`
+}
+
+// XMPHistoryEvent is one entry of xmpMM:History
+type XMPHistoryEvent struct {
+	Action        string `json:"action,omitempty"`
+	When          string `json:"when,omitempty"`
+	SoftwareAgent string `json:"softwareAgent,omitempty"`
+	InstanceID    string `json:"instanceID,omitempty"`
+	Changed       string `json:"changed,omitempty"`
+	Parameters    string `json:"parameters,omitempty"`
+}
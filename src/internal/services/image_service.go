@@ -39,6 +39,8 @@ func NewImageService() *ImageService {
 func (s *ImageService) ProcessUpload(data []byte, contentType, fileName string) *models.ImageMetadata {
 	meta := metadata.ExtractMetadata(data, contentType, fileName)
 	meta.Source = "upload"
+	metadata.CheckConsistency(meta)
+	metadata.DetectAIProvenance(meta)
 	return meta
 }
 
@@ -86,6 +88,7 @@ func (s *ImageService) ProcessRemoteURL(ctx context.Context, imageURL string) *m
 
 	meta.Status = resp.Status
 	meta.MIMEType = resp.Header.Get("Content-Type")
+	meta.DeclaredMIMEType = meta.MIMEType
 	meta.ContentLength = resp.ContentLength
 	meta.LastModified = resp.Header.Get("Last-Modified")
 
@@ -124,9 +127,57 @@ func (s *ImageService) ProcessRemoteURL(ctx context.Context, imageURL string) *m
 	extracted.Duration = meta.Duration
 	extracted.LastModified = meta.LastModified
 
+	metadata.CheckConsistency(extracted)
+	metadata.DetectAIProvenance(extracted)
 	return extracted
 }
 
+// ExtractThumbnail returns the embedded thumbnail at index in data
+func (s *ImageService) ExtractThumbnail(data []byte, index int) ([]byte, string, error) {
+	return metadata.ExtractThumbnail(data, index)
+}
+
+// GroupBySource regroups extracted metadata by the source of each value
+func (s *ImageService) GroupBySource(meta *models.ImageMetadata) *models.SourcedMetadata {
+	return metadata.GroupBySource(meta)
+}
+
+// AuditPrivacy lists the metadata that could identify a person, place or
+// device
+func (s *ImageService) AuditPrivacy(meta *models.ImageMetadata) *models.PrivacyReport {
+	return metadata.AuditPrivacy(meta)
+}
+
+// StripMetadata removes the metadata of a JPEG, PNG or WebP file except
+// what the keep-list names, returning the cleaned file and its MIME type
+func (s *ImageService) StripMetadata(data []byte, keep []string) ([]byte, string, error) {
+	k, err := metadata.ParseStripKeep(keep)
+	if err != nil {
+		return nil, "", err
+	}
+	return metadata.Strip(data, k)
+}
+
+// EditMetadata writes a JSON patch of EXIF, XMP and IPTC tags into a JPEG,
+// PNG or WebP file, returning the edited file and its MIME type
+func (s *ImageService) EditMetadata(data []byte, patch string) ([]byte, string, error) {
+	p, err := metadata.ParseEditPatch(patch)
+	if err != nil {
+		return nil, "", err
+	}
+	return metadata.Edit(data, p)
+}
+
+// LoadC2PATrustList adds the PEM certificates in the file at path to the
+// trust anchors C2PA signatures are checked against
+func LoadC2PATrustList(path string) (int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	return metadata.LoadC2PATrustList(data)
+}
+
 // ProcessMultipleURLs processes multiple URLs concurrently
 func (s *ImageService) ProcessMultipleURLs(ctx context.Context, urls []string) []*models.ImageMetadata {
 	results := make([]*models.ImageMetadata, len(urls))
@@ -0,0 +1,342 @@
+package metadata
+
+import (
+	"encoding/json"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/ahrdadan/image-metadata-viewer/src/internal/models"
+)
+
+// Confidence of an AI finding, from a generator's own settings record down
+// to a name that may only mean the tool was involved
+const (
+	confidenceHigh   = "high"
+	confidenceMedium = "medium"
+	confidenceLow    = "low"
+)
+
+// maxAIEvidenceDisplay shortens prompts and workflows quoted as evidence
+const maxAIEvidenceDisplay = 200
+
+// digitalSourceTypes are the IPTC digital source types that mark
+// generative AI or algorithmic content, with how strongly they do
+var digitalSourceTypes = map[string]string{
+	"trainedAlgorithmicMedia":              confidenceHigh,
+	"compositeWithTrainedAlgorithmicMedia": confidenceMedium,
+	"algorithmicMedia":                     confidenceLow,
+	"algorithmicallyEnhanced":              confidenceLow,
+	"compositeSynthetic":                   confidenceLow,
+}
+
+// aiGenerators recognizes generators by name in software and creator fields
+var aiGenerators = []struct {
+	pattern *regexp.Regexp
+	name    string
+}{
+	{regexp.MustCompile(`(?i)midjourney`), "Midjourney"},
+	{regexp.MustCompile(`(?i)dall[-·・ ]?e`), "DALL·E"},
+	{regexp.MustCompile(`(?i)\bopenai\b|chatgpt|gpt-4o`), "OpenAI"},
+	{regexp.MustCompile(`(?i)stable[- ]?diffusion|\bsdxl\b`), "Stable Diffusion"},
+	{regexp.MustCompile(`(?i)comfyui`), "ComfyUI"},
+	{regexp.MustCompile(`(?i)novelai`), "NovelAI"},
+	{regexp.MustCompile(`(?i)firefly`), "Adobe Firefly"},
+	{regexp.MustCompile(`(?i)\bimagen\b`), "Google Imagen"},
+	{regexp.MustCompile(`(?i)leonardo[. ]ai`), "Leonardo.Ai"},
+	{regexp.MustCompile(`(?i)ideogram`), "Ideogram"},
+	{regexp.MustCompile(`(?i)\bflux\.1\b|black forest labs`), "FLUX"},
+	{regexp.MustCompile(`(?i)bing image creator|microsoft designer`), "Microsoft Designer"},
+	{regexp.MustCompile(`(?i)invokeai`), "InvokeAI"},
+	{regexp.MustCompile(`(?i)fooocus`), "Fooocus"},
+}
+
+// sdParameterPattern matches a "Key: value" pair of the settings line,
+// where values containing commas are quoted
+var sdParameterPattern = regexp.MustCompile(`\s*([\w ./-]+):\s*("(?:\\.|[^\\"])+"|[^,]*)(?:,|$)`)
+
+// midjourneyJobPattern finds the job ID Midjourney appends to the prompt
+var midjourneyJobPattern = regexp.MustCompile(`\s*Job ID:\s*([0-9a-f-]{36})`)
+
+// DetectAIProvenance gathers the markers of generative AI from the PNG
+// text chunks, EXIF, XMP and C2PA manifests into meta.AIProvenance. Like
+// CheckConsistency it runs once extraction is complete.
+func DetectAIProvenance(meta *models.ImageMetadata) {
+	if meta.DecodeError != "" || meta.FetchError != "" {
+		return
+	}
+
+	var findings []models.AIFinding
+	add := func(f models.AIFinding) { findings = append(findings, f) }
+
+	// Text the generators write their settings to
+	type text struct{ source, value string }
+	var texts []text
+	if meta.PNG != nil {
+		for _, t := range meta.PNG.Text {
+			texts = append(texts, text{"PNG " + t.Chunk + " " + t.Keyword, t.Value})
+		}
+	}
+	var names []text
+	if meta.EXIF != nil {
+		for _, tag := range meta.EXIF.Tags {
+			switch tag.Name {
+			case "UserComment", "ImageDescription":
+				texts = append(texts, text{"EXIF " + tag.Name, tag.Value})
+			case "Software", "Artist":
+				names = append(names, text{"EXIF " + tag.Name, tag.Value})
+			}
+		}
+	}
+	if meta.XMP != nil {
+		for _, p := range meta.XMP.Properties {
+			switch name, _, _ := strings.Cut(p.Path, "["); name {
+			case "dc:description":
+				texts = append(texts, text{"XMP " + p.Path, p.Value})
+			case "Iptc4xmpExt:DigitalSourceType":
+				if f, ok := digitalSourceTypeFinding("XMP "+p.Path, p.Value); ok {
+					add(f)
+				}
+			case "xmp:CreatorTool", "tiff:Software", "photoshop:Credit":
+				names = append(names, text{"XMP " + p.Path, p.Value})
+			}
+		}
+	}
+
+	for _, t := range texts {
+		keyword := t.source[strings.LastIndex(t.source, " ")+1:]
+		switch {
+		case strings.HasPrefix(t.source, "PNG ") && (keyword == "prompt" || keyword == "workflow"):
+			if f, ok := comfyUIFinding(t.source, t.value); ok {
+				add(f)
+			}
+		case strings.HasPrefix(t.source, "PNG ") && (keyword == "Software" || keyword == "Source"):
+			names = append(names, t)
+		default:
+			if f, ok := sdParametersFinding(t.source, t.value); ok {
+				add(f)
+			} else if f, ok := midjourneyFinding(t.source, t.value); ok {
+				add(f)
+			}
+		}
+	}
+
+	if meta.C2PA != nil {
+		for _, m := range meta.C2PA.Manifests {
+			for _, a := range m.Actions {
+				f, ok := digitalSourceTypeFinding("C2PA "+m.Label+" "+a.Action, a.DigitalSourceType)
+				if !ok {
+					continue
+				}
+				f.Marker = "c2pa-action"
+				f.Generator = a.SoftwareAgent
+				if f.Generator == "" {
+					f.Generator = m.ClaimGenerator
+				}
+				add(f)
+			}
+			names = append(names, text{"C2PA " + m.Label + " claim_generator", m.ClaimGenerator})
+		}
+	}
+
+	for _, n := range names {
+		for _, g := range aiGenerators {
+			if n.value != "" && g.pattern.MatchString(n.value) {
+				add(models.AIFinding{
+					Marker:     "generator-name",
+					Confidence: confidenceMedium,
+					Generator:  g.name,
+					Evidence:   []models.SourcedValue{aiEvidence(n.source, n.value)},
+				})
+				break
+			}
+		}
+	}
+
+	meta.AIProvenance = summarizeAIFindings(findings)
+}
+
+// summarizeAIFindings orders findings by confidence and fills the summary
+// fields from the first finding that records each
+func summarizeAIFindings(findings []models.AIFinding) *models.AIProvenance {
+	rank := map[string]int{confidenceHigh: 0, confidenceMedium: 1, confidenceLow: 2}
+	sort.SliceStable(findings, func(i, j int) bool {
+		return rank[findings[i].Confidence] < rank[findings[j].Confidence]
+	})
+
+	p := &models.AIProvenance{Detected: len(findings) > 0, Findings: findings}
+	if p.Findings == nil {
+		p.Findings = []models.AIFinding{}
+	}
+	first := func(field *string, value string) {
+		if *field == "" {
+			*field = value
+		}
+	}
+	for _, f := range findings {
+		first(&p.Generator, f.Generator)
+		first(&p.Prompt, f.Prompt)
+		first(&p.NegativePrompt, f.NegativePrompt)
+		first(&p.Seed, f.Seed)
+		first(&p.Model, f.Model)
+		first(&p.ModelHash, f.ModelHash)
+	}
+	return p
+}
+
+// sdParametersFinding reads the generation settings the Stable Diffusion
+// web UI (AUTOMATIC1111) and its forks write to a PNG "parameters" chunk
+// or EXIF UserComment: the prompt, an optional "Negative prompt:" line and
+// a settings line such as "Steps: 20, Sampler: Euler a, Seed: 1234,
+// Model hash: 6ce0161689, Model: v1-5-pruned-emaonly, Version: v1.6.0"
+func sdParametersFinding(source, value string) (models.AIFinding, bool) {
+	value = strings.TrimSpace(value)
+	i := strings.LastIndex("\n"+value, "\nSteps: ")
+	if i < 0 {
+		return models.AIFinding{}, false
+	}
+	settings := make(map[string]string)
+	for _, m := range sdParameterPattern.FindAllStringSubmatch(value[i:], -1) {
+		key, v := strings.TrimSpace(m[1]), strings.TrimSpace(m[2])
+		if strings.HasPrefix(v, `"`) {
+			json.Unmarshal([]byte(v), &v)
+		}
+		settings[key] = v
+	}
+	if settings["Seed"] == "" && settings["Sampler"] == "" {
+		return models.AIFinding{}, false
+	}
+
+	prompt, negative, _ := strings.Cut(strings.TrimSpace(value[:i]), "Negative prompt:")
+	f := models.AIFinding{
+		Marker:         "sd-parameters",
+		Confidence:     confidenceHigh,
+		Generator:      strings.TrimSpace("Stable Diffusion web UI " + settings["Version"]),
+		Prompt:         strings.TrimSpace(prompt),
+		NegativePrompt: strings.TrimSpace(negative),
+		Seed:           settings["Seed"],
+		Model:          settings["Model"],
+		ModelHash:      settings["Model hash"],
+		Evidence:       []models.SourcedValue{aiEvidence(source, value)},
+	}
+	return f, true
+}
+
+// comfyUIFinding reads the node graph ComfyUI stores in the PNG "prompt"
+// chunk, in its API format, or the editor's "workflow" chunk. The seed and
+// prompts are taken from the sampler nodes and the text encoders wired to
+// their positive and negative inputs.
+func comfyUIFinding(source, value string) (models.AIFinding, bool) {
+	type node struct {
+		ClassType string         `json:"class_type"`
+		Inputs    map[string]any `json:"inputs"`
+	}
+	f := models.AIFinding{
+		Marker:     "comfyui-workflow",
+		Confidence: confidenceHigh,
+		Generator:  "ComfyUI",
+		Evidence:   []models.SourcedValue{aiEvidence(source, value)},
+	}
+	if strings.HasSuffix(source, " workflow") {
+		var workflow struct {
+			Nodes []json.RawMessage `json:"nodes"`
+		}
+		if json.Unmarshal([]byte(value), &workflow) != nil || len(workflow.Nodes) == 0 {
+			return models.AIFinding{}, false
+		}
+		return f, true
+	}
+
+	var graph map[string]node
+	d := json.NewDecoder(strings.NewReader(value))
+	d.UseNumber()
+	if d.Decode(&graph) != nil || len(graph) == 0 {
+		return models.AIFinding{}, false
+	}
+	ids := make([]string, 0, len(graph))
+	for id := range graph {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	// text follows a [node ID, output] link to a text encoder
+	text := func(link any) string {
+		l, _ := link.([]any)
+		if len(l) == 0 {
+			return ""
+		}
+		id, _ := l[0].(string)
+		inputs := graph[id].Inputs
+		for _, key := range []string{"text", "text_g", "prompt"} {
+			if s, ok := inputs[key].(string); ok {
+				return s
+			}
+		}
+		return ""
+	}
+
+	for _, id := range ids {
+		n := graph[id]
+		for _, key := range []string{"seed", "noise_seed"} {
+			if seed, ok := n.Inputs[key].(json.Number); ok && f.Seed == "" {
+				f.Seed = seed.String()
+			}
+		}
+		if _, ok := n.Inputs["positive"]; ok && f.Prompt == "" {
+			f.Prompt = text(n.Inputs["positive"])
+			f.NegativePrompt = text(n.Inputs["negative"])
+		}
+		if strings.HasPrefix(n.ClassType, "CheckpointLoader") || n.ClassType == "UNETLoader" {
+			for _, key := range []string{"ckpt_name", "unet_name"} {
+				if name, ok := n.Inputs[key].(string); ok && f.Model == "" {
+					f.Model = name
+				}
+			}
+		}
+	}
+	return f, true
+}
+
+// midjourneyFinding recognizes the prompt and job ID Midjourney writes to
+// the image description
+func midjourneyFinding(source, value string) (models.AIFinding, bool) {
+	loc := midjourneyJobPattern.FindStringIndex(value)
+	if loc == nil {
+		return models.AIFinding{}, false
+	}
+	return models.AIFinding{
+		Marker:     "midjourney-job",
+		Confidence: confidenceHigh,
+		Generator:  "Midjourney",
+		Prompt:     strings.TrimSpace(value[:loc[0]]),
+		Evidence:   []models.SourcedValue{aiEvidence(source, value)},
+	}, true
+}
+
+// digitalSourceTypeFinding reports an IPTC digital source type, given as a
+// term or its URI, that marks generated content
+func digitalSourceTypeFinding(source, value string) (models.AIFinding, bool) {
+	term := value[strings.LastIndex(value, "/")+1:]
+	confidence, ok := digitalSourceTypes[term]
+	if !ok {
+		return models.AIFinding{}, false
+	}
+	ev := aiEvidence(source, value)
+	ev.Display = term
+	return models.AIFinding{
+		Marker:     "digital-source-type",
+		Confidence: confidence,
+		Evidence:   []models.SourcedValue{ev},
+	}, true
+}
+
+// aiEvidence quotes a value, shortening the display of long prompts and
+// node graphs
+func aiEvidence(source, value string) models.SourcedValue {
+	display := strings.Join(strings.Fields(value), " ")
+	if utf8.RuneCountInString(display) > maxAIEvidenceDisplay {
+		display = string([]rune(display)[:maxAIEvidenceDisplay]) + "…"
+	}
+	return models.SourcedValue{Source: source, Raw: value, Display: display}
+}
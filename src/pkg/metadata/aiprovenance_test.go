@@ -0,0 +1,125 @@
+package metadata
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ahrdadan/image-metadata-viewer/src/internal/models"
+)
+
+func TestDetectAIProvenanceStableDiffusionPNG(t *testing.T) {
+	parameters := "a castle on a hill, golden hour\n" +
+		"Negative prompt: blurry, lowres\n" +
+		`Steps: 30, Sampler: DPM++ 2M Karras, CFG scale: 7, Seed: 3141592653, Size: 512x768, ` +
+		`Model hash: 6ce0161689, Model: v1-5-pruned-emaonly, Lora hashes: "detail: 0f4e, style: 9a1b", Version: v1.6.0`
+	data := buildPNGWithChunks(t, buildChunk("tEXt", []byte("parameters\x00"+parameters)))
+	meta := ExtractMetadata(data, "image/png", "castle.png")
+	DetectAIProvenance(meta)
+
+	p := meta.AIProvenance
+	if p == nil || !p.Detected || len(p.Findings) != 1 {
+		t.Fatalf("AIProvenance = %+v", p)
+	}
+	if p.Generator != "Stable Diffusion web UI v1.6.0" || p.Prompt != "a castle on a hill, golden hour" ||
+		p.NegativePrompt != "blurry, lowres" || p.Seed != "3141592653" ||
+		p.Model != "v1-5-pruned-emaonly" || p.ModelHash != "6ce0161689" {
+		t.Errorf("summary = %+v", p)
+	}
+	if ev := p.Findings[0].Evidence; len(ev) != 1 || ev[0].Source != "PNG tEXt parameters" || ev[0].Raw != parameters {
+		t.Errorf("Evidence = %+v", ev)
+	}
+}
+
+func TestDetectAIProvenance(t *testing.T) {
+	xmpProp := func(path, value string) models.XMPProperty {
+		return models.XMPProperty{Path: path, Value: value}
+	}
+	comfy := `{
+		"3": {"class_type": "KSampler", "inputs": {"seed": 156680208700286, "positive": ["6", 0], "negative": ["7", 0], "model": ["4", 0]}},
+		"4": {"class_type": "CheckpointLoaderSimple", "inputs": {"ckpt_name": "sd_xl_base_1.0.safetensors"}},
+		"6": {"class_type": "CLIPTextEncode", "inputs": {"text": "a red fox in snow"}},
+		"7": {"class_type": "CLIPTextEncode", "inputs": {"text": "watermark"}}
+	}`
+
+	tests := []struct {
+		name      string
+		meta      models.ImageMetadata
+		want      []string // marker:confidence
+		generator string
+		prompt    string
+		seed      string
+	}{
+		{
+			name: "camera photo",
+			meta: models.ImageMetadata{
+				EXIF: &models.EXIFData{Tags: []models.EXIFTag{{Name: "Software", Value: "Adobe Lightroom 7.0"}}},
+			},
+		},
+		{
+			name: "comfyui",
+			meta: models.ImageMetadata{PNG: &models.PNGInfo{Text: []models.PNGText{
+				{Chunk: "tEXt", Keyword: "prompt", Value: comfy},
+				{Chunk: "tEXt", Keyword: "workflow", Value: `{"nodes": [{"id": 3, "type": "KSampler"}]}`},
+			}}},
+			want:      []string{"comfyui-workflow:high", "comfyui-workflow:high"},
+			generator: "ComfyUI",
+			prompt:    "a red fox in snow",
+			seed:      "156680208700286",
+		},
+		{
+			name: "midjourney",
+			meta: models.ImageMetadata{
+				PNG: &models.PNGInfo{Text: []models.PNGText{
+					{Chunk: "tEXt", Keyword: "Description", Value: "lighthouse in a storm --ar 16:9 --v 6.1 Job ID: 1c5a2d4e-7b8f-4e21-9a3c-0d9e8f7a6b5c"},
+				}},
+				XMP: &models.XMPData{Properties: []models.XMPProperty{
+					xmpProp("Iptc4xmpExt:DigitalSourceType", "http://cv.iptc.org/newscodes/digitalsourcetype/trainedAlgorithmicMedia"),
+				}},
+			},
+			want:      []string{"digital-source-type:high", "midjourney-job:high"},
+			generator: "Midjourney",
+			prompt:    "lighthouse in a storm --ar 16:9 --v 6.1",
+		},
+		{
+			name: "c2pa generative fill",
+			meta: models.ImageMetadata{
+				C2PA: &models.C2PAInfo{Manifests: []models.C2PAManifest{{
+					Label:          "urn:c2pa:edit",
+					ClaimGenerator: "Adobe Photoshop 25.9",
+					Actions: []models.C2PAAction{
+						{Action: "c2pa.opened"},
+						{Action: "c2pa.edited", DigitalSourceType: "http://cv.iptc.org/newscodes/digitalsourcetype/compositeWithTrainedAlgorithmicMedia"},
+					},
+				}}},
+			},
+			want:      []string{"c2pa-action:medium"},
+			generator: "Adobe Photoshop 25.9",
+		},
+		{
+			name: "generator name",
+			meta: models.ImageMetadata{
+				XMP: &models.XMPData{Properties: []models.XMPProperty{xmpProp("xmp:CreatorTool", "DALL·E 3")}},
+			},
+			want:      []string{"generator-name:medium"},
+			generator: "DALL·E",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			meta := tt.meta
+			DetectAIProvenance(&meta)
+			p := meta.AIProvenance
+			var got []string
+			for _, f := range p.Findings {
+				got = append(got, f.Marker+":"+f.Confidence)
+			}
+			if strings.Join(got, " ") != strings.Join(tt.want, " ") {
+				t.Errorf("findings = %v, want %v", got, tt.want)
+			}
+			if p.Detected != (len(tt.want) > 0) || p.Generator != tt.generator || p.Prompt != tt.prompt || p.Seed != tt.seed {
+				t.Errorf("summary = %+v", p)
+			}
+		})
+	}
+}
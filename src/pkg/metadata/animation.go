@@ -0,0 +1,254 @@
+package metadata
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/ahrdadan/image-metadata-viewer/src/internal/models"
+)
+
+// gifNetscapeAppID identifies the NETSCAPE2.0 looping extension
+const gifNetscapeAppID = "NETSCAPE2.0"
+
+// gifDisposals maps GIF disposal methods to names
+var gifDisposals = map[int]string{
+	0: "Unspecified",
+	1: "None",
+	2: "Restore to background",
+	3: "Restore to previous",
+}
+
+// apngDisposals maps APNG dispose_op values to names
+var apngDisposals = map[int]string{
+	0: "None",
+	1: "Restore to background",
+	2: "Restore to previous",
+}
+
+// apngBlends maps APNG blend_op values to names
+var apngBlends = map[int]string{
+	0: "Source",
+	1: "Over",
+}
+
+// gifAnimation walks GIF blocks and pairs each image with its graphic
+// control extension. Pixel data is skipped, not decoded.
+func gifAnimation(data []byte) *models.Animation {
+	screen, blocks := readGIFBlocks(data)
+
+	anim := &models.Animation{
+		Format:            "GIF",
+		Plays:             1,
+		CanvasWidth:       screen.width,
+		CanvasHeight:      screen.height,
+		GlobalPaletteSize: screen.globalColorSize,
+	}
+
+	var gce []byte
+	for _, block := range blocks {
+		switch {
+		case block.label == gifAppLabel && block.appID == gifNetscapeAppID:
+			// Sub-block: 0x01, loop count (LE). The count is the number of
+			// repeats after the first play; 0 repeats forever.
+			if len(block.raw) >= 4 && block.raw[0] >= 3 && block.raw[1] == 1 {
+				if repeats := int(binary.LittleEndian.Uint16(block.raw[2:])); repeats == 0 {
+					anim.Plays = 0
+				} else {
+					anim.Plays = repeats + 1
+				}
+			}
+
+		case block.label == gifGCELabel:
+			gce = block.header
+
+		case block.introducer == gifImage:
+			desc := block.header
+			frame := models.AnimationFrame{
+				Index:      len(anim.Frames),
+				X:          int(binary.LittleEndian.Uint16(desc[0:])),
+				Y:          int(binary.LittleEndian.Uint16(desc[2:])),
+				Width:      int(binary.LittleEndian.Uint16(desc[4:])),
+				Height:     int(binary.LittleEndian.Uint16(desc[6:])),
+				Disposal:   gifDisposals[0],
+				Interlaced: desc[8]&0x40 != 0,
+			}
+			if desc[8]&0x80 != 0 {
+				frame.LocalPaletteSize = 1 << ((desc[8] & 0x07) + 1)
+			}
+			if len(gce) >= 4 {
+				frame.Disposal = lookupName(gifDisposals, int(gce[0]>>2&0x07))
+				frame.Transparent = gce[0]&0x01 != 0
+				// Delay is stored in hundredths of a second
+				frame.Delay = int(binary.LittleEndian.Uint16(gce[1:])) * 10
+			}
+			gce = nil
+			anim.Frames = append(anim.Frames, frame)
+		}
+	}
+
+	return finishAnimation(anim)
+}
+
+// webpAnimation reads the VP8X canvas, ANIM loop settings and ANMF frame
+// headers. Frame bitstreams are identified but not decoded.
+func webpAnimation(data []byte) *models.Animation {
+	anim := &models.Animation{Format: "WebP"}
+
+	for _, chunk := range readWebPChunks(data) {
+		body := chunk.data
+		switch chunk.fourCC {
+		case "VP8X":
+			if len(body) >= 10 {
+				anim.CanvasWidth = int(uint24LE(body[4:])) + 1
+				anim.CanvasHeight = int(uint24LE(body[7:])) + 1
+			}
+
+		case "ANIM":
+			if len(body) >= 6 {
+				// Stored as BGRA
+				anim.BackgroundColor = fmt.Sprintf("#%02X%02X%02X%02X", body[2], body[1], body[0], body[3])
+				anim.Plays = int(binary.LittleEndian.Uint16(body[4:]))
+			}
+
+		case "ANMF":
+			if len(body) < 16 {
+				continue
+			}
+			frame := models.AnimationFrame{
+				Index:    len(anim.Frames),
+				X:        int(uint24LE(body[0:])) * 2,
+				Y:        int(uint24LE(body[3:])) * 2,
+				Width:    int(uint24LE(body[6:])) + 1,
+				Height:   int(uint24LE(body[9:])) + 1,
+				Delay:    int(uint24LE(body[12:])),
+				Disposal: "None",
+				Blend:    "Alpha blend",
+			}
+			if body[15]&0x01 != 0 {
+				frame.Disposal = "Restore to background"
+			}
+			if body[15]&0x02 != 0 {
+				frame.Blend = "No blend"
+			}
+			frame.Codec = webpFrameCodec(body[16:])
+			anim.Frames = append(anim.Frames, frame)
+		}
+	}
+
+	return finishAnimation(anim)
+}
+
+// webpFrameCodec names the bitstream inside an ANMF frame
+func webpFrameCodec(data []byte) string {
+	hasAlpha := false
+	pos := 0
+	for pos+8 <= len(data) {
+		size := int(binary.LittleEndian.Uint32(data[pos+4:]))
+		switch string(data[pos : pos+4]) {
+		case "ALPH":
+			hasAlpha = true
+		case "VP8 ":
+			if hasAlpha {
+				return "VP8 (lossy) + alpha"
+			}
+			return "VP8 (lossy)"
+		case "VP8L":
+			return "VP8L (lossless)"
+		}
+		pos += 8 + size + size%2
+	}
+	return ""
+}
+
+// apngAnimation reads the acTL and fcTL chunks of an animated PNG
+func apngAnimation(data []byte) *models.Animation {
+	chunks := readPNGChunks(data)
+	if len(chunks) == 0 || chunks[0].typ != "IHDR" || len(chunks[0].data) < 8 {
+		return nil
+	}
+
+	anim := &models.Animation{
+		Format:       "APNG",
+		CanvasWidth:  int(binary.BigEndian.Uint32(chunks[0].data)),
+		CanvasHeight: int(binary.BigEndian.Uint32(chunks[0].data[4:])),
+	}
+	animated := false
+
+	for _, chunk := range chunks {
+		body := chunk.data
+		switch chunk.typ {
+		case "acTL":
+			if len(body) >= 8 {
+				animated = true
+				anim.Plays = int(binary.BigEndian.Uint32(body[4:]))
+			}
+
+		case "fcTL":
+			if len(body) < 26 {
+				continue
+			}
+			frame := models.AnimationFrame{
+				Index:    len(anim.Frames),
+				Width:    int(binary.BigEndian.Uint32(body[4:])),
+				Height:   int(binary.BigEndian.Uint32(body[8:])),
+				X:        int(binary.BigEndian.Uint32(body[12:])),
+				Y:        int(binary.BigEndian.Uint32(body[16:])),
+				Disposal: lookupName(apngDisposals, int(body[24])),
+				Blend:    lookupName(apngBlends, int(body[25])),
+			}
+			// Delay is a fraction of a second; a zero denominator means 1/100
+			num, den := int(binary.BigEndian.Uint16(body[20:])), int(binary.BigEndian.Uint16(body[22:]))
+			if den == 0 {
+				den = 100
+			}
+			frame.Delay = num * 1000 / den
+			anim.Frames = append(anim.Frames, frame)
+		}
+	}
+
+	if !animated {
+		return nil
+	}
+	return finishAnimation(anim)
+}
+
+// finishAnimation fills the totals and drops single-frame images
+func finishAnimation(anim *models.Animation) *models.Animation {
+	anim.FrameCount = len(anim.Frames)
+	if anim.FrameCount < 2 {
+		return nil
+	}
+
+	for _, frame := range anim.Frames {
+		anim.TotalDuration += frame.Delay
+	}
+	anim.TotalDurationHuman = fmt.Sprintf("%.2f s", float64(anim.TotalDuration)/1000)
+
+	switch anim.Plays {
+	case 0:
+		anim.Loop = "Loops forever"
+	case 1:
+		anim.Loop = "Plays once"
+	default:
+		anim.Loop = fmt.Sprintf("Plays %d times", anim.Plays)
+	}
+
+	return anim
+}
+
+// uint24LE reads a 3-byte little-endian integer
+func uint24LE(b []byte) uint32 {
+	return uint32(b[0]) | uint32(b[1])<<8 | uint32(b[2])<<16
+}
+
+// extractAnimation fills frame details for animated GIF, WebP and PNG
+func extractAnimation(data []byte, meta *models.ImageMetadata) {
+	switch sniffFormat(data) {
+	case formatGIF:
+		meta.Animation = gifAnimation(data)
+	case formatWebP:
+		meta.Animation = webpAnimation(data)
+	case formatPNG:
+		meta.Animation = apngAnimation(data)
+	}
+}
@@ -0,0 +1,92 @@
+package metadata
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/gif"
+	"testing"
+)
+
+func TestExtractMetadataGIFAnimation(t *testing.T) {
+	palette := color.Palette{color.Black, color.White}
+	anim := &gif.GIF{
+		LoopCount: 2,
+		Config:    image.Config{Width: 16, Height: 16, ColorModel: palette},
+	}
+	for i := 0; i < 3; i++ {
+		frame := image.NewPaletted(image.Rect(i, i, 8+i, 8+i), palette)
+		anim.Image = append(anim.Image, frame)
+		anim.Delay = append(anim.Delay, 10*(i+1))
+		anim.Disposal = append(anim.Disposal, gif.DisposalBackground)
+	}
+
+	var buf bytes.Buffer
+	if err := gif.EncodeAll(&buf, anim); err != nil {
+		t.Fatalf("gif.EncodeAll: %v", err)
+	}
+	meta := ExtractMetadata(buf.Bytes(), "image/gif", "anim.gif")
+
+	a := meta.Animation
+	if a == nil {
+		t.Fatal("expected Animation section")
+	}
+	if a.Format != "GIF" || a.FrameCount != 3 || a.Plays != 3 || a.Loop != "Plays 3 times" {
+		t.Errorf("Animation = %+v", a)
+	}
+	if a.TotalDuration != 600 || a.TotalDurationHuman != "0.60 s" {
+		t.Errorf("TotalDuration = %d (%s)", a.TotalDuration, a.TotalDurationHuman)
+	}
+	f := a.Frames[2]
+	if f.X != 2 || f.Y != 2 || f.Width != 8 || f.Delay != 300 || f.Disposal != "Restore to background" {
+		t.Errorf("Frames[2] = %+v", f)
+	}
+}
+
+func TestExtractMetadataWebPAnimation(t *testing.T) {
+	chunk := func(fourCC string, body []byte) []byte {
+		out := append([]byte(fourCC), 0, 0, 0, 0)
+		binary.LittleEndian.PutUint32(out[4:], uint32(len(body)))
+		out = append(out, body...)
+		if len(body)%2 == 1 {
+			out = append(out, 0)
+		}
+		return out
+	}
+	uint24 := func(v int) []byte { return []byte{byte(v), byte(v >> 8), byte(v >> 16)} }
+	frame := func(x, y, w, h, delay int, flags byte, bitstream string) []byte {
+		var body []byte
+		for _, v := range []int{x / 2, y / 2, w - 1, h - 1, delay} {
+			body = append(body, uint24(v)...)
+		}
+		body = append(body, flags)
+		return chunk("ANMF", append(body, chunk(bitstream, make([]byte, 5))...))
+	}
+
+	vp8x := append([]byte{0x02, 0, 0, 0}, append(uint24(31), uint24(31)...)...)
+	anim := []byte{0x00, 0x00, 0xFF, 0xFF, 0x00, 0x00} // opaque red, loop forever
+
+	var body []byte
+	body = append(body, "WEBP"...)
+	body = append(body, chunk("VP8X", vp8x)...)
+	body = append(body, chunk("ANIM", anim)...)
+	body = append(body, frame(0, 0, 32, 32, 100, 0, "VP8L")...)
+	body = append(body, frame(4, 8, 16, 16, 250, 0x03, "VP8 ")...)
+	data := chunk("RIFF", body)
+
+	meta := ExtractMetadata(data, "image/webp", "anim.webp")
+	a := meta.Animation
+	if a == nil {
+		t.Fatalf("expected Animation section (decode error %q)", meta.DecodeError)
+	}
+	if a.FrameCount != 2 || a.Loop != "Loops forever" || a.TotalDuration != 350 ||
+		a.CanvasWidth != 32 || a.BackgroundColor != "#FF0000FF" {
+		t.Errorf("Animation = %+v", a)
+	}
+	f := a.Frames[1]
+	if f.X != 4 || f.Y != 8 || f.Width != 16 || f.Disposal != "Restore to background" ||
+		f.Blend != "No blend" || f.Codec != "VP8 (lossy)" {
+		t.Errorf("Frames[1] = %+v", f)
+	}
+}
@@ -0,0 +1,428 @@
+package metadata
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ahrdadan/image-metadata-viewer/src/internal/models"
+)
+
+// C2PA validation states of the active manifest
+const (
+	c2paInvalid    = "invalid"
+	c2paWellFormed = "well-formed" // nothing failed, but the hash binding could not be checked
+	c2paValid      = "valid"
+	c2paTrusted    = "trusted"
+)
+
+// Kinds of validation status, as in the C2PA validation results
+const (
+	statusSuccess       = "success"
+	statusInformational = "informational"
+	statusFailure       = "failure"
+)
+
+// c2paLabelVersion matches the instance ("__2") and version (".v3") suffixes
+// of assertion labels
+var c2paLabelVersion = regexp.MustCompile(`(\.v\d+)?(__\d+)?$`)
+
+// extractC2PA reads the C2PA manifest store and validates its manifests
+// against the file bytes
+func extractC2PA(data []byte, meta *models.ImageMetadata) error {
+	carrier, candidates := jumbfCandidates(data)
+	var store *jumbfBox
+	for _, candidate := range candidates {
+		boxes := readBoxes(candidate)
+		if len(boxes) == 0 || boxes[0].typ != "jumb" {
+			return fmt.Errorf("c2pa: %s does not hold a JUMBF superbox", carrier)
+		}
+		if box := parseJUMBF(boxes[0].data, 0); box != nil && box.typ == "c2pa" {
+			store = box
+			break
+		}
+	}
+	if store == nil {
+		return nil
+	}
+
+	info := &models.C2PAInfo{Carrier: carrier}
+	var manifests []*jumbfBox
+	for _, m := range store.children {
+		if m.typ == "c2ma" || m.typ == "c2um" {
+			manifests = append(manifests, m)
+		}
+	}
+	if len(manifests) == 0 {
+		return errors.New("c2pa: manifest store holds no manifests")
+	}
+
+	v := c2paValidator{data: data, store: store, now: time.Now()}
+	for i, m := range manifests {
+		info.Manifests = append(info.Manifests, v.manifest(m, i == len(manifests)-1))
+	}
+	active := &info.Manifests[len(info.Manifests)-1]
+	info.ActiveManifest = active.Label
+	info.ValidationState = validationState(active)
+
+	meta.C2PA = info
+	return nil
+}
+
+// validationState summarizes the status of the active manifest
+func validationState(m *models.C2PAManifest) string {
+	for _, s := range m.Status {
+		if s.Kind == statusFailure {
+			return c2paInvalid
+		}
+	}
+	switch {
+	case m.HashBinding == nil || m.HashBinding.Status != "match":
+		return c2paWellFormed
+	case m.Signature != nil && m.Signature.Trusted:
+		return c2paTrusted
+	}
+	return c2paValid
+}
+
+// c2paValidator decodes and validates the manifests of a store
+type c2paValidator struct {
+	data  []byte
+	store *jumbfBox
+	now   time.Time
+}
+
+// manifest decodes a manifest and validates its claim. Only the active
+// manifest describes this file, so only its hash binding is checked.
+func (v *c2paValidator) manifest(box *jumbfBox, active bool) models.C2PAManifest {
+	m := models.C2PAManifest{Label: box.label, Assertions: []models.C2PAAssertion{}}
+	status := func(code, kind, url, format string, args ...any) {
+		m.Status = append(m.Status, models.C2PAStatus{Code: code, Kind: kind, URL: url, Explanation: fmt.Sprintf(format, args...)})
+	}
+
+	claimBox := box.child("c2pa.claim.v2")
+	if claimBox == nil {
+		claimBox = box.child("c2pa.claim")
+	}
+	if claimBox == nil {
+		status("claim.missing", statusFailure, "", "manifest has no claim")
+		return m
+	}
+	claimBytes := claimBox.contentBox("cbor")
+	decoded, err := decodeCBOR(claimBytes)
+	claim := cborMap(decoded)
+	if err != nil || claim == nil {
+		status("claim.malformed", statusFailure, "", "claim is not a CBOR map")
+		return m
+	}
+
+	m.ClaimGenerator = claimGenerator(claim)
+	m.Title = cborString(claim, "dc:title")
+	m.Format = cborString(claim, "dc:format")
+	m.InstanceID = cborString(claim, "instanceID")
+	claimAlg := cborString(claim, "alg")
+	if claimAlg == "" {
+		claimAlg = "sha256"
+	}
+
+	refs := cborList(claim, "assertions")
+	refs = append(refs, cborList(claim, "created_assertions")...)
+	refs = append(refs, cborList(claim, "gathered_assertions")...)
+	hardBinding := false
+	for _, ref := range refs {
+		ref := cborMap(ref)
+		url := cborString(ref, "url")
+		label := url[strings.LastIndex(url, "/")+1:]
+		if i := strings.Index(label, "="); i >= 0 {
+			label = label[i+1:]
+		}
+
+		assertion := v.resolve(box, url)
+		if assertion == nil {
+			m.Assertions = append(m.Assertions, models.C2PAAssertion{Label: label, Hash: "missing"})
+			status("assertion.missing", statusFailure, url, "assertion %s is not in the manifest", label)
+			continue
+		}
+
+		alg := cborString(ref, "alg")
+		if alg == "" {
+			alg = claimAlg
+		}
+		sum, ok := c2paHash(alg, assertion.payload)
+		if !ok {
+			m.Assertions = append(m.Assertions, models.C2PAAssertion{Label: label, Hash: "mismatch"})
+			status("algorithm.unsupported", statusFailure, url, "hash algorithm %q is not supported", alg)
+			continue
+		}
+		if !bytes.Equal(sum, cborBytes(ref, "hash")) {
+			m.Assertions = append(m.Assertions, models.C2PAAssertion{Label: label, Hash: "mismatch"})
+			status("assertion.hashedURI.mismatch", statusFailure, url, "hash of assertion %s does not match the claim", label)
+			continue
+		}
+		m.Assertions = append(m.Assertions, models.C2PAAssertion{Label: label, Hash: "match"})
+		status("assertion.hashedURI.match", statusSuccess, url, "hash of assertion %s matches", label)
+
+		switch c2paBaseLabel(label) {
+		case "c2pa.hash.data":
+			hardBinding = true
+			if active {
+				v.dataHash(&m, assertion, claimAlg, url, status)
+			}
+		case "c2pa.hash.bmff", "c2pa.hash.boxes", "c2pa.hash.collection.data":
+			hardBinding = true
+			if active {
+				kind := strings.TrimPrefix(c2paBaseLabel(label), "c2pa.hash.")
+				m.HashBinding = &models.C2PAHashBinding{Kind: kind, Status: "unsupported"}
+				status("hashBinding.unsupported", statusFailure, url, "%s hash bindings are not checked, so the binding to the asset is not validated", kind)
+			}
+		default:
+			decodeC2PAAssertion(&m, label, assertion)
+		}
+	}
+	if !hardBinding {
+		status("claim.hardBindings.missing", statusFailure, "", "claim has no hash binding to the asset")
+	}
+
+	sigURL := cborString(claim, "signature")
+	sigBox := v.resolve(box, sigURL)
+	if sigBox == nil {
+		sigBox = box.child("c2pa.signature")
+	}
+	if sigBox == nil {
+		status("claimSignature.missing", statusFailure, sigURL, "manifest has no claim signature")
+		return m
+	}
+	sig, statuses := verifyClaimSignature(sigBox.contentBox("cbor"), claimBytes, v.now)
+	m.Signature = sig
+	m.Status = append(m.Status, statuses...)
+	return m
+}
+
+// resolve finds the superbox a JUMBF URI points to. URIs are either
+// absolute, "self#jumbf=/c2pa/<manifest>/c2pa.assertions/c2pa.actions", or
+// relative to the manifest, "self#jumbf=c2pa.assertions/c2pa.actions".
+func (v *c2paValidator) resolve(manifest *jumbfBox, uri string) *jumbfBox {
+	path, ok := strings.CutPrefix(uri, "self#jumbf=")
+	if !ok || path == "" {
+		return nil
+	}
+	box := manifest
+	if strings.HasPrefix(path, "/") {
+		parts := strings.SplitN(path[1:], "/", 2)
+		if parts[0] != v.store.label || len(parts) < 2 {
+			return nil
+		}
+		box, path = v.store, parts[1]
+	}
+	for _, label := range strings.Split(path, "/") {
+		if box = box.child(label); box == nil {
+			return nil
+		}
+	}
+	return box
+}
+
+// dataHash checks a c2pa.hash.data assertion: a hash of the file with the
+// byte ranges holding the manifest store excluded
+func (v *c2paValidator) dataHash(m *models.C2PAManifest, assertion *jumbfBox, claimAlg, url string, status func(code, kind, url, format string, args ...any)) {
+	decoded, _ := decodeCBOR(assertion.contentBox("cbor"))
+	a := cborMap(decoded)
+	alg := cborString(a, "alg")
+	if alg == "" {
+		alg = claimAlg
+	}
+	m.HashBinding = &models.C2PAHashBinding{Kind: "data", Alg: alg, Status: "mismatch"}
+
+	h := newC2PAHash(alg)
+	if h == nil {
+		status("algorithm.unsupported", statusFailure, url, "hash algorithm %q is not supported", alg)
+		return
+	}
+	type span struct{ start, length int64 }
+	var exclusions []span
+	for _, e := range cborList(a, "exclusions") {
+		start, okStart := cborInt(cborMap(e), "start")
+		length, okLength := cborInt(cborMap(e), "length")
+		if !okStart || !okLength || start < 0 || length < 0 {
+			status("assertion.dataHash.malformed", statusFailure, url, "exclusion range is malformed")
+			return
+		}
+		exclusions = append(exclusions, span{start, length})
+	}
+	sort.Slice(exclusions, func(i, j int) bool { return exclusions[i].start < exclusions[j].start })
+
+	pos, size := int64(0), int64(len(v.data))
+	for _, e := range exclusions {
+		if e.start < pos || e.start > size || e.length > size-e.start {
+			status("assertion.dataHash.mismatch", statusFailure, url, "exclusion %d+%d lies outside the %d-byte file", e.start, e.length, size)
+			return
+		}
+		h.Write(v.data[pos:e.start])
+		pos = e.start + e.length
+	}
+	h.Write(v.data[pos:])
+
+	if !bytes.Equal(h.Sum(nil), cborBytes(a, "hash")) {
+		status("assertion.dataHash.mismatch", statusFailure, url, "file bytes do not match the data hash")
+		return
+	}
+	m.HashBinding.Status = "match"
+	status("assertion.dataHash.match", statusSuccess, url, "file bytes match the data hash")
+}
+
+// decodeC2PAAssertion fills the manifest fields an assertion describes
+func decodeC2PAAssertion(m *models.C2PAManifest, label string, box *jumbfBox) {
+	base := c2paBaseLabel(label)
+	if base == "stds.schema-org.CreativeWork" {
+		var work map[string]any
+		if json.Unmarshal(box.contentBox("json"), &work) == nil {
+			m.CreativeWork = creativeWork(work)
+		}
+		return
+	}
+
+	decoded, err := decodeCBOR(box.contentBox("cbor"))
+	a := cborMap(decoded)
+	if err != nil || a == nil {
+		return
+	}
+	switch base {
+	case "c2pa.actions":
+		for _, item := range cborList(a, "actions") {
+			action := cborMap(item)
+			params := cborMap(action["parameters"])
+			entry := models.C2PAAction{
+				Action:            cborString(action, "action"),
+				When:              cborString(action, "when"),
+				SoftwareAgent:     softwareAgent(action["softwareAgent"]),
+				DigitalSourceType: cborString(action, "digitalSourceType"),
+				Description:       cborString(action, "description"),
+			}
+			if entry.Description == "" {
+				entry.Description = cborString(params, "description")
+			}
+			m.Actions = append(m.Actions, entry)
+		}
+	case "c2pa.ingredient":
+		ingredient := models.C2PAIngredient{
+			Title:        cborString(a, "dc:title"),
+			Format:       cborString(a, "dc:format"),
+			InstanceID:   cborString(a, "instanceID"),
+			Relationship: cborString(a, "relationship"),
+		}
+		manifest := cborMap(a["activeManifest"])
+		if manifest == nil {
+			manifest = cborMap(a["c2pa_manifest"])
+		}
+		if url := cborString(manifest, "url"); strings.HasPrefix(url, "self#jumbf=/") {
+			if parts := strings.Split(url, "/"); len(parts) > 2 {
+				ingredient.Manifest = parts[2]
+			}
+		}
+		m.Ingredients = append(m.Ingredients, ingredient)
+	case "c2pa.training-mining", "cawg.training-mining":
+		entries := cborMap(a["entries"])
+		uses := make([]string, 0, len(entries))
+		for use := range entries {
+			if s, ok := use.(string); ok {
+				uses = append(uses, s)
+			}
+		}
+		sort.Strings(uses)
+		for _, use := range uses {
+			entry := cborMap(entries[use])
+			m.TrainingMining = append(m.TrainingMining, models.C2PATrainingMining{
+				Use:            use,
+				Permission:     cborString(entry, "use"),
+				ConstraintInfo: cborString(entry, "constraint_info"),
+				Assertion:      base,
+			})
+		}
+	}
+}
+
+// c2paBaseLabel strips the version and instance suffixes of an assertion
+// label, so "c2pa.ingredient.v3__2" becomes "c2pa.ingredient"
+func c2paBaseLabel(label string) string {
+	return c2paLabelVersion.ReplaceAllString(label, "")
+}
+
+// claimGenerator reads claim_generator, or the name and version of the
+// first claim_generator_info entry
+func claimGenerator(claim map[any]any) string {
+	if g := cborString(claim, "claim_generator"); g != "" {
+		return g
+	}
+	info := cborMap(claim["claim_generator_info"])
+	if list := cborList(claim, "claim_generator_info"); len(list) > 0 {
+		info = cborMap(list[0])
+	}
+	return strings.TrimSpace(cborString(info, "name") + " " + cborString(info, "version"))
+}
+
+// softwareAgent reads an action's softwareAgent, a string in version 1
+// actions and a generator info map in version 2
+func softwareAgent(v any) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	info := cborMap(v)
+	return strings.TrimSpace(cborString(info, "name") + " " + cborString(info, "version"))
+}
+
+// creativeWork reads the authors, copyright and URL of a schema.org
+// CreativeWork in JSON-LD
+func creativeWork(work map[string]any) *models.C2PACreativeWork {
+	out := &models.C2PACreativeWork{}
+	authors, ok := work["author"].([]any)
+	if !ok && work["author"] != nil {
+		authors = []any{work["author"]}
+	}
+	for _, a := range authors {
+		switch a := a.(type) {
+		case string:
+			out.Authors = append(out.Authors, a)
+		case map[string]any:
+			if name, _ := a["name"].(string); name != "" {
+				out.Authors = append(out.Authors, name)
+			}
+		}
+	}
+	out.Copyright, _ = work["copyrightNotice"].(string)
+	out.URL, _ = work["url"].(string)
+	if len(out.Authors) == 0 && out.Copyright == "" && out.URL == "" {
+		return nil
+	}
+	return out
+}
+
+// newC2PAHash returns a hash for a C2PA algorithm name, or nil
+func newC2PAHash(alg string) hash.Hash {
+	switch alg {
+	case "sha256":
+		return sha256.New()
+	case "sha384":
+		return sha512.New384()
+	case "sha512":
+		return sha512.New()
+	}
+	return nil
+}
+
+// c2paHash hashes data with a C2PA algorithm
+func c2paHash(alg string, data []byte) ([]byte, bool) {
+	h := newC2PAHash(alg)
+	if h == nil {
+		return nil, false
+	}
+	h.Write(data)
+	return h.Sum(nil), true
+}
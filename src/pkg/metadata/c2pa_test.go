@@ -0,0 +1,308 @@
+package metadata
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ahrdadan/image-metadata-viewer/src/internal/models"
+)
+
+// jumbfSuperbox builds a labelled JUMBF superbox whose content type UUID
+// starts with typ
+func jumbfSuperbox(typ, label string, children ...[]byte) []byte {
+	desc := append([]byte(typ), 0x00, 0x11, 0x00, 0x10, 0x80, 0x00, 0x00, 0xAA, 0x00, 0x38, 0x9B, 0x71)
+	desc = append(append(desc, 0x03), label...)
+	return bmff("jumb", append([][]byte{bmff("jumd", append(desc, 0))}, children...)...)
+}
+
+// c2paTestSigner is a self-signed ES256 signing credential
+type c2paTestSigner struct {
+	key  *ecdsa.PrivateKey
+	cert []byte
+}
+
+func newC2PATestSigner(t *testing.T) c2paTestSigner {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(42),
+		Subject:               pkix.Name{CommonName: "Test Signer", Organization: []string{"Example"}},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+	}
+	cert, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c2paTestSigner{key: key, cert: cert}
+}
+
+// buildC2PAPNG embeds a signed manifest store in a caBX chunk after IHDR,
+// with a data hash over the rest of the file
+func buildC2PAPNG(t *testing.T, signer c2paTestSigner) []byte {
+	t.Helper()
+	base := buildPNGWithChunks(t)
+	fileHash := sha256.Sum256(base)
+
+	assertion := func(label string, content map[string]any) []byte {
+		return jumbfSuperbox("cbor", label, bmff("cbor", encodeCBOR(content)))
+	}
+	build := func(length int) []byte {
+		assertions := [][]byte{
+			assertion("c2pa.actions.v2", map[string]any{"actions": []any{
+				map[string]any{
+					"action":            "c2pa.created",
+					"digitalSourceType": "http://cv.iptc.org/newscodes/digitalsourcetype/trainedAlgorithmicMedia",
+					"softwareAgent":     map[string]any{"name": "Diffuser", "version": "2.1"},
+				},
+			}}),
+			assertion("c2pa.ingredient.v3", map[string]any{
+				"dc:title":       "sketch.jpg",
+				"dc:format":      "image/jpeg",
+				"relationship":   "inputTo",
+				"activeManifest": map[string]any{"url": "self#jumbf=/c2pa/urn:c2pa:sketch/c2pa.claim"},
+			}),
+			assertion("c2pa.training-mining", map[string]any{"entries": map[string]any{
+				"c2pa.ai_training":  map[string]any{"use": "notAllowed"},
+				"c2pa.ai_inference": map[string]any{"use": "constrained", "constraint_info": "ask first"},
+			}}),
+			jumbfSuperbox("json", "stds.schema-org.CreativeWork", bmff("json",
+				[]byte(`{"@type":"CreativeWork","author":[{"@type":"Person","name":"Ada Lovelace"}]}`))),
+			assertion("c2pa.hash.data", map[string]any{
+				"exclusions": []any{map[string]any{"start": 33, "length": length}},
+				"alg":        "sha256",
+				"hash":       fileHash[:],
+			}),
+		}
+
+		var refs []any
+		for _, a := range assertions {
+			box := parseJUMBF(a[8:], 0)
+			sum := sha256.Sum256(a[8:])
+			refs = append(refs, map[string]any{"url": "self#jumbf=c2pa.assertions/" + box.label, "hash": sum[:]})
+		}
+		claim := encodeCBOR(map[string]any{
+			"instanceID":           "xmp:iid:0001",
+			"dc:title":             "generated.png",
+			"claim_generator_info": map[string]any{"name": "Diffuser", "version": "2.1"},
+			"signature":            "self#jumbf=c2pa.signature",
+			"created_assertions":   refs,
+			"alg":                  "sha256",
+		})
+
+		protected := encodeCBOR(map[any]any{int64(1): int64(-7), int64(33): signer.cert})
+		digest := sha256.Sum256(encodeCBOR([]any{"Signature1", protected, []byte{}, claim}))
+		r, s, err := ecdsa.Sign(rand.Reader, signer.key, digest[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		signature := append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+		sign1 := encodeCBOR(cborTag{number: 18, value: []any{protected, map[any]any{}, nil, signature}})
+
+		manifest := jumbfSuperbox("c2ma", "urn:c2pa:generated",
+			jumbfSuperbox("c2as", "c2pa.assertions", assertions...),
+			jumbfSuperbox("c2cl", "c2pa.claim.v2", bmff("cbor", claim)),
+			jumbfSuperbox("c2cs", "c2pa.signature", bmff("cbor", sign1)),
+		)
+		return buildChunk("caBX", jumbfSuperbox("c2pa", "c2pa", manifest))
+	}
+
+	// The exclusion covers the chunk itself, whose length depends on the
+	// width of the encoded exclusion
+	chunk := build(0)
+	for length := 0; length != len(chunk); {
+		length = len(chunk)
+		chunk = build(length)
+	}
+	return buildPNGWithChunks(t, chunk)
+}
+
+func TestExtractMetadataC2PA(t *testing.T) {
+	signer := newC2PATestSigner(t)
+	data := buildC2PAPNG(t, signer)
+
+	meta := ExtractMetadata(data, "image/png", "generated.png")
+	info := meta.C2PA
+	if info == nil {
+		t.Fatalf("expected C2PA section, errors = %+v", meta.ExtractorErrors)
+	}
+	if info.Carrier != "PNG caBX" || info.ActiveManifest != "urn:c2pa:generated" || info.ValidationState != c2paValid {
+		t.Errorf("store = %q %q %q, status = %+v", info.Carrier, info.ActiveManifest, info.ValidationState, info.Manifests[0].Status)
+	}
+
+	m := info.Manifests[0]
+	if m.ClaimGenerator != "Diffuser 2.1" || m.Title != "generated.png" || m.InstanceID != "xmp:iid:0001" {
+		t.Errorf("claim = %q %q %q", m.ClaimGenerator, m.Title, m.InstanceID)
+	}
+	if len(m.Actions) != 1 || m.Actions[0].Action != "c2pa.created" || m.Actions[0].SoftwareAgent != "Diffuser 2.1" ||
+		m.Actions[0].DigitalSourceType != "http://cv.iptc.org/newscodes/digitalsourcetype/trainedAlgorithmicMedia" {
+		t.Errorf("Actions = %+v", m.Actions)
+	}
+	if len(m.Ingredients) != 1 || m.Ingredients[0].Title != "sketch.jpg" || m.Ingredients[0].Manifest != "urn:c2pa:sketch" {
+		t.Errorf("Ingredients = %+v", m.Ingredients)
+	}
+	if len(m.TrainingMining) != 2 || m.TrainingMining[0].Use != "c2pa.ai_inference" ||
+		m.TrainingMining[0].ConstraintInfo != "ask first" || m.TrainingMining[1].Permission != "notAllowed" {
+		t.Errorf("TrainingMining = %+v", m.TrainingMining)
+	}
+	if m.CreativeWork == nil || len(m.CreativeWork.Authors) != 1 || m.CreativeWork.Authors[0] != "Ada Lovelace" {
+		t.Errorf("CreativeWork = %+v", m.CreativeWork)
+	}
+	for _, a := range m.Assertions {
+		if a.Hash != "match" {
+			t.Errorf("assertion %s hash = %s", a.Label, a.Hash)
+		}
+	}
+	if m.HashBinding == nil || m.HashBinding.Kind != "data" || m.HashBinding.Status != "match" {
+		t.Errorf("HashBinding = %+v", m.HashBinding)
+	}
+	if s := m.Signature; s == nil || s.Algorithm != "ES256" || !s.Valid || s.Trusted ||
+		len(s.Certificates) != 1 || s.Certificates[0].Subject != "CN=Test Signer,O=Example" {
+		t.Errorf("Signature = %+v", m.Signature)
+	}
+
+	// Trusting the signer's certificate makes the manifest trusted
+	t.Cleanup(func() { c2paTrust.roots = nil })
+	if n, err := LoadC2PATrustList(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: signer.cert})); n != 1 || err != nil {
+		t.Fatalf("LoadC2PATrustList = %d, %v", n, err)
+	}
+	if meta := ExtractMetadata(data, "image/png", "generated.png"); meta.C2PA.ValidationState != c2paTrusted {
+		t.Errorf("trusted ValidationState = %q", meta.C2PA.ValidationState)
+	}
+
+	// Changing a byte outside the manifest breaks the data hash
+	tampered := append([]byte{}, data...)
+	tampered[len(tampered)-20] ^= 0xFF
+	meta = ExtractMetadata(tampered, "image/png", "generated.png")
+	if meta.C2PA == nil || meta.C2PA.ValidationState != c2paInvalid || meta.C2PA.Manifests[0].HashBinding.Status != "mismatch" {
+		t.Fatalf("tampered C2PA = %+v", meta.C2PA)
+	}
+	var sawMismatch bool
+	for _, s := range meta.C2PA.Manifests[0].Status {
+		sawMismatch = sawMismatch || s.Code == "assertion.dataHash.mismatch" && s.Kind == statusFailure
+	}
+	if !sawMismatch {
+		t.Errorf("tampered Status = %+v", meta.C2PA.Manifests[0].Status)
+	}
+}
+
+func TestDecodeCBOR(t *testing.T) {
+	// Examples from RFC 8949 Appendix A
+	tests := []struct {
+		hex  []byte
+		want any
+	}{
+		{[]byte{0x19, 0x03, 0xE8}, uint64(1000)},
+		{[]byte{0x38, 0x63}, int64(-100)},
+		{[]byte{0xF9, 0x3C, 0x00}, 1.0},
+		{[]byte{0xF9, 0xC4, 0x00}, -4.0},
+		{[]byte{0xF9, 0x7C, 0x00}, math.Inf(1)},
+		{[]byte{0x5F, 0x42, 0x01, 0x02, 0x43, 0x03, 0x04, 0x05, 0xFF}, []byte{1, 2, 3, 4, 5}},
+		{[]byte{0x7F, 0x65, 's', 't', 'r', 'e', 'a', 0x64, 'm', 'i', 'n', 'g', 0xFF}, "streaming"},
+		{[]byte{0xC1, 0x1A, 0x51, 0x4B, 0x67, 0xB0}, cborTag{number: 1, value: uint64(1363896240)}},
+	}
+	for _, tt := range tests {
+		got, err := decodeCBOR(tt.hex)
+		if b, ok := got.([]byte); ok && bytes.Equal(b, tt.want.([]byte)) {
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("decodeCBOR(% X) = %v, %v; want %v", tt.hex, got, err, tt.want)
+		}
+	}
+
+	got, err := decodeCBOR([]byte{0xBF, 0x61, 'a', 0x01, 0x61, 'b', 0x9F, 0x02, 0x03, 0xFF, 0xFF})
+	m := cborMap(got)
+	if n, _ := cborInt(m, "a"); err != nil || n != 1 || len(cborList(m, "b")) != 2 {
+		t.Errorf("indefinite map = %v, %v", got, err)
+	}
+	if _, err := decodeCBOR([]byte{0x5A, 0xFF, 0xFF, 0xFF, 0xFF}); err == nil {
+		t.Error("expected truncated byte string to fail")
+	}
+}
+
+func TestC2PADataHashExclusionOutOfRange(t *testing.T) {
+	for _, e := range [][2]int64{{math.MaxInt64, 1}, {4, math.MaxInt64}, {100, 0}} {
+		assertion := jumbfSuperbox("cbor", "c2pa.hash.data", bmff("cbor", encodeCBOR(map[string]any{
+			"exclusions": []any{map[string]any{"start": e[0], "length": e[1]}},
+			"alg":        "sha256",
+			"hash":       make([]byte, 32),
+		})))
+		v := &c2paValidator{data: make([]byte, 64)}
+		m := &models.C2PAManifest{}
+		var codes []string
+		v.dataHash(m, parseJUMBF(assertion[8:], 0), "sha256", "self#jumbf=c2pa.assertions/c2pa.hash.data",
+			func(code, kind, url, format string, args ...any) { codes = append(codes, code) })
+		if len(codes) != 1 || codes[0] != "assertion.dataHash.mismatch" || m.HashBinding.Status != "mismatch" {
+			t.Errorf("exclusion %d+%d: status %v, binding %+v", e[0], e[1], codes, m.HashBinding)
+		}
+	}
+}
+
+func TestC2PABMFFHashNotValidated(t *testing.T) {
+	assertion := jumbfSuperbox("cbor", "c2pa.hash.bmff.v2", bmff("cbor", encodeCBOR(map[string]any{
+		"exclusions": []any{map[string]any{"xpath": "/uuid"}},
+		"alg":        "sha256",
+		"hash":       make([]byte, 32),
+	})))
+	sum := sha256.Sum256(assertion[8:])
+	claim := encodeCBOR(map[string]any{
+		"created_assertions": []any{map[string]any{"url": "self#jumbf=c2pa.assertions/c2pa.hash.bmff.v2", "hash": sum[:]}},
+		"alg":                "sha256",
+	})
+	manifest := jumbfSuperbox("c2ma", "urn:c2pa:bmff",
+		jumbfSuperbox("c2as", "c2pa.assertions", assertion),
+		jumbfSuperbox("c2cl", "c2pa.claim.v2", bmff("cbor", claim)),
+	)
+
+	v := &c2paValidator{data: make([]byte, 64)}
+	m := v.manifest(parseJUMBF(manifest[8:], 0), true)
+	if m.HashBinding == nil || m.HashBinding.Kind != "bmff" || m.HashBinding.Status != "unsupported" {
+		t.Fatalf("HashBinding = %+v", m.HashBinding)
+	}
+	var sawFailure bool
+	for _, s := range m.Status {
+		sawFailure = sawFailure || s.Code == "hashBinding.unsupported" && s.Kind == statusFailure
+	}
+	if !sawFailure || validationState(&m) != c2paInvalid {
+		t.Errorf("status = %+v", m.Status)
+	}
+}
+
+func TestC2PAUntrustedSigner(t *testing.T) {
+	data := buildC2PAPNG(t, newC2PATestSigner(t))
+
+	// A trust list that does not hold the signer's certificate
+	t.Cleanup(func() { c2paTrust.roots = nil })
+	other := newC2PATestSigner(t)
+	if _, err := LoadC2PATrustList(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: other.cert})); err != nil {
+		t.Fatal(err)
+	}
+	meta := ExtractMetadata(data, "image/png", "generated.png")
+	if meta.C2PA == nil || meta.C2PA.ValidationState != c2paInvalid {
+		t.Fatalf("C2PA = %+v", meta.C2PA)
+	}
+	var sawUntrusted bool
+	for _, s := range meta.C2PA.Manifests[0].Status {
+		sawUntrusted = sawUntrusted || s.Code == "signingCredential.untrusted" && s.Kind == statusFailure
+	}
+	if !sawUntrusted {
+		t.Errorf("status = %+v", meta.C2PA.Manifests[0].Status)
+	}
+}
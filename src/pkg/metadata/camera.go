@@ -0,0 +1,135 @@
+package metadata
+
+import (
+	"strings"
+
+	"github.com/ahrdadan/image-metadata-viewer/src/internal/models"
+	"github.com/rwcarlsen/goexif/tiff"
+)
+
+// Camera-related tag IDs
+const (
+	tagMake                  uint16 = 0x010F
+	tagModel                 uint16 = 0x0110
+	tagCameraSerialNumber    uint16 = 0xC62F
+	tagExposureTime          uint16 = 0x829A
+	tagFNumber               uint16 = 0x829D
+	tagExposureProgram       uint16 = 0x8822
+	tagISO                   uint16 = 0x8827
+	tagExposureBias          uint16 = 0x9204
+	tagMeteringMode          uint16 = 0x9207
+	tagFlash                 uint16 = 0x9209
+	tagFocalLength           uint16 = 0x920A
+	tagWhiteBalance          uint16 = 0xA403
+	tagFocalLengthIn35mmFilm uint16 = 0xA405
+	tagBodySerialNumber      uint16 = 0xA431
+	tagLensSpecification     uint16 = 0xA432
+	tagLensMake              uint16 = 0xA433
+	tagLensModel             uint16 = 0xA434
+	tagLensSerialNumber      uint16 = 0xA435
+)
+
+// extractCamera collects body, lens and exposure settings from the EXIF
+// directories. It returns nil when none of them are present.
+func extractCamera(dirs *exifDirs) *models.Camera {
+	if dirs == nil {
+		return nil
+	}
+
+	cam := &models.Camera{
+		Make:             dirString(dirs.ifd0, tagMake),
+		Model:            dirString(dirs.ifd0, tagModel),
+		SerialNumber:     dirString(dirs.exif, tagBodySerialNumber),
+		LensMake:         dirString(dirs.exif, tagLensMake),
+		LensModel:        dirString(dirs.exif, tagLensModel),
+		LensSerialNumber: dirString(dirs.exif, tagLensSerialNumber),
+	}
+	if cam.SerialNumber == "" {
+		cam.SerialNumber = dirString(dirs.ifd0, tagCameraSerialNumber)
+	}
+	if tag := findTag(dirs.exif, tagLensSpecification); tag != nil {
+		cam.LensSpecification = describeLensSpecification(tag, dirs.order)
+	}
+
+	// Exposure triangle
+	if v, ok := dirRational(dirs.exif, tagExposureTime); ok {
+		cam.ExposureTime = formatExposureTime(v)
+	}
+	if v, ok := dirRational(dirs.exif, tagFNumber); ok && v > 0 {
+		cam.FNumber = v
+		cam.Aperture = formatFNumber(v)
+	}
+	if v, ok := dirInt(dirs.exif, tagISO); ok {
+		cam.ISO = v
+	}
+	if v, ok := dirRational(dirs.exif, tagExposureBias); ok {
+		cam.ExposureBias = formatExposureBias(v)
+	}
+
+	// Focal length
+	if v, ok := dirRational(dirs.exif, tagFocalLength); ok && v > 0 {
+		cam.FocalLength = v
+		cam.FocalLengthHuman = formatFocalLength(v)
+	}
+	if v, ok := dirInt(dirs.exif, tagFocalLengthIn35mmFilm); ok && v > 0 {
+		cam.FocalLengthIn35mm = v
+	}
+
+	// Modes
+	if v, ok := dirInt(dirs.exif, tagExposureProgram); ok {
+		cam.ExposureProgram = lookupName(exposureProgramNames, v)
+	}
+	if v, ok := dirInt(dirs.exif, tagMeteringMode); ok {
+		cam.MeteringMode = lookupName(meteringModeNames, v)
+	}
+	if v, ok := dirInt(dirs.exif, tagWhiteBalance); ok {
+		cam.WhiteBalance = lookupName(map[int]string{0: "Auto", 1: "Manual"}, v)
+	}
+	if v, ok := dirInt(dirs.exif, tagFlash); ok {
+		flash := decodeFlash(v)
+		cam.Flash = &flash
+	}
+
+	if *cam == (models.Camera{}) {
+		return nil
+	}
+	return cam
+}
+
+// dirString returns the trimmed ASCII value of tag id in dir
+func dirString(dir *tiff.Dir, id uint16) string {
+	tag := findTag(dir, id)
+	if tag == nil {
+		return ""
+	}
+	val, err := tag.StringVal()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(val)
+}
+
+// dirInt returns the first integer value of tag id in dir
+func dirInt(dir *tiff.Dir, id uint16) (int, bool) {
+	tag := findTag(dir, id)
+	if tag == nil || tag.Count == 0 {
+		return 0, false
+	}
+	v, err := tag.Int(0)
+	if err != nil {
+		return 0, false
+	}
+	return v, true
+}
+
+// dirRational returns the first rational value of tag id in dir
+func dirRational(dir *tiff.Dir, id uint16) (float64, bool) {
+	tag := findTag(dir, id)
+	if tag == nil || tag.Format() != tiff.RatVal || tag.Count == 0 {
+		return 0, false
+	}
+	if _, d, _ := tag.Rat2(0); d == 0 {
+		return 0, false
+	}
+	return ratValue(tag, 0), true
+}
@@ -0,0 +1,346 @@
+package metadata
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// CBOR (RFC 8949) is how C2PA stores claims, assertions and COSE
+// signatures. Decoded items become Go values: uint64 or int64 for
+// integers, []byte, string, []any, map[any]any keyed by int64 or string,
+// float64, bool and nil. Tags are unwrapped into cborTag.
+
+// maxCBORDepth bounds nesting so hostile input cannot exhaust the stack
+const maxCBORDepth = 64
+
+var errCBORTruncated = errors.New("cbor: truncated item")
+
+// cborTag is a tagged item, such as tag 18 around a COSE_Sign1 message
+type cborTag struct {
+	number uint64
+	value  any
+}
+
+// decodeCBOR decodes the single item that fills data
+func decodeCBOR(data []byte) (any, error) {
+	d := cborDecoder{data: data}
+	v, err := d.item(0)
+	if err != nil {
+		return nil, err
+	}
+	if d.pos != len(data) {
+		return nil, fmt.Errorf("cbor: %d trailing bytes", len(data)-d.pos)
+	}
+	return v, nil
+}
+
+// cborDecoder reads items from a byte slice
+type cborDecoder struct {
+	data []byte
+	pos  int
+}
+
+// head reads an initial byte and its argument. Indefinite lengths are
+// reported with indefinite set.
+func (d *cborDecoder) head() (major byte, arg uint64, indefinite bool, err error) {
+	if d.pos >= len(d.data) {
+		return 0, 0, false, errCBORTruncated
+	}
+	b := d.data[d.pos]
+	d.pos++
+	major, info := b>>5, b&0x1F
+
+	switch {
+	case info < 24:
+		return major, uint64(info), false, nil
+	case info == 31:
+		return major, 0, true, nil
+	case info > 27:
+		return 0, 0, false, fmt.Errorf("cbor: reserved additional info %d", info)
+	}
+	n := 1 << (info - 24)
+	if d.pos+n > len(d.data) {
+		return 0, 0, false, errCBORTruncated
+	}
+	for _, c := range d.data[d.pos : d.pos+n] {
+		arg = arg<<8 | uint64(c)
+	}
+	d.pos += n
+	return major, arg, false, nil
+}
+
+// item decodes the next item
+func (d *cborDecoder) item(depth int) (any, error) {
+	if depth > maxCBORDepth {
+		return nil, errors.New("cbor: nested too deeply")
+	}
+	start := d.pos
+	major, arg, indefinite, err := d.head()
+	if err != nil {
+		return nil, err
+	}
+
+	switch major {
+	case 0:
+		return arg, nil
+	case 1:
+		if arg > math.MaxInt64 {
+			return nil, errors.New("cbor: negative integer overflows int64")
+		}
+		return -1 - int64(arg), nil
+	case 2, 3:
+		b, err := d.bytes(major, arg, indefinite)
+		if err != nil {
+			return nil, err
+		}
+		if major == 3 {
+			return string(b), nil
+		}
+		return b, nil
+	case 4:
+		var list []any
+		for i := uint64(0); indefinite || i < arg; i++ {
+			if indefinite && d.atBreak() {
+				break
+			}
+			v, err := d.item(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+		}
+		return list, nil
+	case 5:
+		m := make(map[any]any)
+		for i := uint64(0); indefinite || i < arg; i++ {
+			if indefinite && d.atBreak() {
+				break
+			}
+			k, err := d.item(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			v, err := d.item(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			switch k := k.(type) {
+			case uint64:
+				m[int64(k)] = v
+			case int64, string:
+				m[k] = v
+			default:
+				return nil, fmt.Errorf("cbor: unsupported map key %T", k)
+			}
+		}
+		return m, nil
+	case 6:
+		v, err := d.item(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		return cborTag{number: arg, value: v}, nil
+	}
+
+	// Major type 7: simple values and floats
+	switch d.data[start] & 0x1F {
+	case 20:
+		return false, nil
+	case 21:
+		return true, nil
+	case 22, 23:
+		return nil, nil
+	case 25:
+		return halfFloat(uint16(arg)), nil
+	case 26:
+		return float64(math.Float32frombits(uint32(arg))), nil
+	case 27:
+		return math.Float64frombits(arg), nil
+	}
+	return nil, fmt.Errorf("cbor: unsupported simple value %d", arg)
+}
+
+// bytes reads a byte or text string, joining indefinite-length chunks
+func (d *cborDecoder) bytes(major byte, n uint64, indefinite bool) ([]byte, error) {
+	if !indefinite {
+		if n > uint64(len(d.data)-d.pos) {
+			return nil, errCBORTruncated
+		}
+		b := d.data[d.pos : d.pos+int(n)]
+		d.pos += int(n)
+		return b, nil
+	}
+	var out []byte
+	for !d.atBreak() {
+		m, n, ind, err := d.head()
+		if err != nil {
+			return nil, err
+		}
+		if m != major || ind {
+			return nil, errors.New("cbor: malformed indefinite-length string")
+		}
+		chunk, err := d.bytes(major, n, false)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, chunk...)
+	}
+	return out, nil
+}
+
+// atBreak consumes the break code that ends an indefinite-length item
+func (d *cborDecoder) atBreak() bool {
+	if d.pos < len(d.data) && d.data[d.pos] == 0xFF {
+		d.pos++
+		return true
+	}
+	return d.pos >= len(d.data)
+}
+
+// halfFloat converts an IEEE 754 half-precision value
+func halfFloat(h uint16) float64 {
+	exp := int(h>>10) & 0x1F
+	mant := float64(h & 0x3FF)
+	var v float64
+	switch exp {
+	case 0:
+		v = math.Ldexp(mant, -24)
+	case 31:
+		if mant == 0 {
+			v = math.Inf(1)
+		} else {
+			v = math.NaN()
+		}
+	default:
+		v = math.Ldexp(mant+1024, exp-25)
+	}
+	if h&0x8000 != 0 {
+		v = -v
+	}
+	return v
+}
+
+// encodeCBOR encodes the subset of values C2PA signing structures use:
+// []any, maps, cborTag, string, []byte, int, int64, uint64, bool and nil.
+// Map keys are sorted bytewise by their encoding, the deterministic order.
+func encodeCBOR(v any) []byte {
+	var out []byte
+	head := func(major byte, n uint64) {
+		switch {
+		case n < 24:
+			out = append(out, major<<5|byte(n))
+		case n <= math.MaxUint8:
+			out = append(out, major<<5|24, byte(n))
+		case n <= math.MaxUint16:
+			out = binary.BigEndian.AppendUint16(append(out, major<<5|25), uint16(n))
+		case n <= math.MaxUint32:
+			out = binary.BigEndian.AppendUint32(append(out, major<<5|26), uint32(n))
+		default:
+			out = binary.BigEndian.AppendUint64(append(out, major<<5|27), n)
+		}
+	}
+
+	var enc func(v any)
+	enc = func(v any) {
+		switch v := v.(type) {
+		case nil:
+			out = append(out, 0xF6)
+		case bool:
+			if v {
+				out = append(out, 0xF5)
+			} else {
+				out = append(out, 0xF4)
+			}
+		case int:
+			enc(int64(v))
+		case int64:
+			if v < 0 {
+				head(1, uint64(-1-v))
+			} else {
+				head(0, uint64(v))
+			}
+		case uint64:
+			head(0, v)
+		case []byte:
+			head(2, uint64(len(v)))
+			out = append(out, v...)
+		case string:
+			head(3, uint64(len(v)))
+			out = append(out, v...)
+		case []any:
+			head(4, uint64(len(v)))
+			for _, item := range v {
+				enc(item)
+			}
+		case map[string]any:
+			m := make(map[any]any, len(v))
+			for k, item := range v {
+				m[k] = item
+			}
+			enc(m)
+		case map[any]any:
+			type pair struct {
+				key   []byte
+				value any
+			}
+			pairs := make([]pair, 0, len(v))
+			for k, item := range v {
+				pairs = append(pairs, pair{encodeCBOR(k), item})
+			}
+			sort.Slice(pairs, func(i, j int) bool { return bytes.Compare(pairs[i].key, pairs[j].key) < 0 })
+			head(5, uint64(len(v)))
+			for _, p := range pairs {
+				out = append(out, p.key...)
+				enc(p.value)
+			}
+		case cborTag:
+			head(6, v.number)
+			enc(v.value)
+		default:
+			panic(fmt.Sprintf("cbor: cannot encode %T", v))
+		}
+	}
+	enc(v)
+	return out
+}
+
+// cborMap returns v as a map, or nil
+func cborMap(v any) map[any]any {
+	m, _ := v.(map[any]any)
+	return m
+}
+
+// cborString returns the text string at key, or ""
+func cborString(m map[any]any, key any) string {
+	s, _ := m[key].(string)
+	return s
+}
+
+// cborBytes returns the byte string at key, or nil
+func cborBytes(m map[any]any, key any) []byte {
+	b, _ := m[key].([]byte)
+	return b
+}
+
+// cborList returns the array at key, or nil
+func cborList(m map[any]any, key any) []any {
+	l, _ := m[key].([]any)
+	return l
+}
+
+// cborInt returns the integer at key
+func cborInt(m map[any]any, key any) (int64, bool) {
+	switch v := m[key].(type) {
+	case uint64:
+		if v <= math.MaxInt64 {
+			return int64(v), true
+		}
+	case int64:
+		return v, true
+	}
+	return 0, false
+}
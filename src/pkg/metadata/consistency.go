@@ -0,0 +1,431 @@
+package metadata
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ahrdadan/image-metadata-viewer/src/internal/models"
+	"github.com/ahrdadan/image-metadata-viewer/src/internal/utils"
+)
+
+// Severities of consistency and privacy findings, on the scale of SVG
+// findings
+const (
+	severityHigh   = "high"
+	severityMedium = "medium"
+	severityLow    = "low"
+)
+
+// consistencyChecks run in order; each reports its findings
+var consistencyChecks = []func(*models.ImageMetadata, *models.SourcedMetadata) []models.ConsistencyFinding{
+	checkCaptureDates,
+	checkModifyDates,
+	checkLastModified,
+	checkPixelDimensions,
+	checkThumbnailAspect,
+	checkDeclaredType,
+	checkFieldConflicts,
+}
+
+// CheckConsistency cross-checks the values that several sources record
+// and lists where they disagree in meta.Consistency. It runs once the
+// metadata is complete, including the HTTP fields of a remote fetch.
+func CheckConsistency(meta *models.ImageMetadata) {
+	if meta.DecodeError != "" || meta.FetchError != "" {
+		return
+	}
+	s := GroupBySource(meta)
+	var findings []models.ConsistencyFinding
+	for _, check := range consistencyChecks {
+		findings = append(findings, check(meta, s)...)
+	}
+	meta.Consistency = findings
+}
+
+// datedValue is a date together with the value it was read from
+type datedValue struct {
+	name  string // e.g. "EXIF DateTimeOriginal"
+	value models.SourcedValue
+	ts    timestamp
+}
+
+// dated parses the value of key in g, applying an EXIF offset tag
+func dated(g models.ValueGroup, group, key, offsetKey string) (datedValue, bool) {
+	v, ok := g[key]
+	if !ok {
+		return datedValue{}, false
+	}
+	ts, ok := parseTimestamp(v.Raw)
+	if !ok {
+		return datedValue{}, false
+	}
+	if offset, ok := g[offsetKey]; ok {
+		ts = ts.withOffset(offset.Raw)
+	}
+	return datedValue{name: group + " " + key, value: v, ts: ts}, true
+}
+
+// iptcDated joins the IPTC date and time datasets, stored as CCYYMMDD and
+// HHMMSS±HHMM
+func iptcDated(g models.ValueGroup, dateKey, timeKey string) (datedValue, bool) {
+	d, ok := g[dateKey]
+	if !ok {
+		return datedValue{}, false
+	}
+	raw := d.Raw
+	if t, ok := g[timeKey]; ok {
+		raw += "T" + t.Raw
+	}
+	ts, ok := parseTimestamp(raw)
+	if !ok {
+		return datedValue{}, false
+	}
+	return datedValue{name: "IPTC " + dateKey, value: d, ts: ts}, true
+}
+
+// captureDates lists the recorded capture dates, highest precedence first
+func captureDates(s *models.SourcedMetadata) []datedValue {
+	var dates []datedValue
+	if d, ok := dated(s.EXIF, "EXIF", "DateTimeOriginal", "OffsetTimeOriginal"); ok {
+		dates = append(dates, d)
+	}
+	if d, ok := dated(s.XMP, "XMP", "photoshop:DateCreated", ""); ok {
+		dates = append(dates, d)
+	}
+	if d, ok := dated(s.XMP, "XMP", "xmp:CreateDate", ""); ok {
+		dates = append(dates, d)
+	}
+	if d, ok := iptcDated(s.IPTC, "DateCreated", "TimeCreated"); ok {
+		dates = append(dates, d)
+	}
+	return dates
+}
+
+// modifyDates lists the recorded dates of the last edit
+func modifyDates(s *models.SourcedMetadata) []datedValue {
+	var dates []datedValue
+	if d, ok := dated(s.EXIF, "EXIF", "DateTime", "OffsetTime"); ok {
+		dates = append(dates, d)
+	}
+	if d, ok := dated(s.XMP, "XMP", "xmp:ModifyDate", ""); ok {
+		dates = append(dates, d)
+	}
+	return dates
+}
+
+// maxZoneOffset is the widest gap a missing zone can explain
+const maxZoneOffset = 14 * time.Hour
+
+// zoneShift reports whether d could be the offset between two zones
+func zoneShift(d time.Duration) bool {
+	d = d.Abs()
+	return d <= maxZoneOffset && d%(15*time.Minute) == 0
+}
+
+// checkCaptureDates compares every capture date with the one of highest
+// precedence
+func checkCaptureDates(_ *models.ImageMetadata, s *models.SourcedMetadata) []models.ConsistencyFinding {
+	dates := captureDates(s)
+	if len(dates) < 2 {
+		return nil
+	}
+	var findings []models.ConsistencyFinding
+	ref := dates[0]
+	for _, other := range dates[1:] {
+		d, zoneUnknown := other.ts.sub(ref.ts)
+		if d.Abs() < time.Second {
+			continue
+		}
+		f := models.ConsistencyFinding{
+			Check:    "capture-date",
+			Severity: severityMedium,
+			Message: fmt.Sprintf("%s and %s are %s apart; one of them was changed or written by a different device",
+				ref.name, other.name, humanDuration(d)),
+			Evidence: []models.SourcedValue{ref.value, other.value},
+		}
+		if zoneUnknown && zoneShift(d) {
+			f.Severity = severityLow
+			f.Message = fmt.Sprintf("%s and %s are %s apart, which matches a time zone offset; one of them has no zone",
+				ref.name, other.name, humanDuration(d))
+		}
+		findings = append(findings, f)
+	}
+	return findings
+}
+
+// checkModifyDates flags edits dated before the capture and EXIF and XMP
+// edit dates that disagree
+func checkModifyDates(_ *models.ImageMetadata, s *models.SourcedMetadata) []models.ConsistencyFinding {
+	var findings []models.ConsistencyFinding
+	modified := modifyDates(s)
+	if captured := captureDates(s); len(captured) > 0 {
+		ref := captured[0]
+		for _, m := range modified {
+			d, zoneUnknown := m.ts.sub(ref.ts)
+			if d > -time.Second || (zoneUnknown && -d <= maxZoneOffset) {
+				continue
+			}
+			findings = append(findings, models.ConsistencyFinding{
+				Check:    "modify-date",
+				Severity: severityMedium,
+				Message: fmt.Sprintf("%s is %s before %s; the file cannot have been edited before it was created",
+					m.name, humanDuration(d), ref.name),
+				Evidence: []models.SourcedValue{m.value, ref.value},
+			})
+		}
+	}
+
+	if len(modified) == 2 {
+		d, zoneUnknown := modified[1].ts.sub(modified[0].ts)
+		if d.Abs() >= time.Second && !(zoneUnknown && zoneShift(d)) {
+			findings = append(findings, models.ConsistencyFinding{
+				Check:    "modify-date",
+				Severity: severityLow,
+				Message: fmt.Sprintf("%s and %s are %s apart; the last editor updated only one of them",
+					modified[0].name, modified[1].name, humanDuration(d)),
+				Evidence: []models.SourcedValue{modified[0].value, modified[1].value},
+			})
+		}
+	}
+	return findings
+}
+
+// checkLastModified flags a server copy that is older than a date
+// embedded in the file
+func checkLastModified(_ *models.ImageMetadata, s *models.SourcedMetadata) []models.ConsistencyFinding {
+	lm, ok := s.HTTP["Last-Modified"]
+	if !ok {
+		return nil
+	}
+	served, ok := parseTimestamp(lm.Raw)
+	if !ok {
+		return nil
+	}
+
+	// Report only the latest embedded date the server copy predates
+	var latest *datedValue
+	var gap time.Duration
+	for _, e := range append(captureDates(s), modifyDates(s)...) {
+		d, zoneUnknown := served.sub(e.ts)
+		if zoneUnknown {
+			d += maxZoneOffset
+		}
+		if d < 0 && d < gap {
+			latest, gap = &e, d
+		}
+	}
+	if latest == nil {
+		return nil
+	}
+	return []models.ConsistencyFinding{{
+		Check:    "last-modified",
+		Severity: severityMedium,
+		Message: fmt.Sprintf("The server's Last-Modified date is at least %s before %s; the embedded date is wrong or the server's clock was",
+			humanDuration(gap), latest.name),
+		Evidence: []models.SourcedValue{lm, latest.value},
+	}}
+}
+
+// checkPixelDimensions compares EXIF PixelXDimension and PixelYDimension
+// with the decoded image. Raw files are skipped: their EXIF describes
+// the developed image, not the sensor data.
+func checkPixelDimensions(meta *models.ImageMetadata, s *models.SourcedMetadata) []models.ConsistencyFinding {
+	if meta.RAW != nil || meta.Width == 0 || meta.Height == 0 {
+		return nil
+	}
+	xv, okX := s.EXIF["PixelXDimension"]
+	yv, okY := s.EXIF["PixelYDimension"]
+	if !okX || !okY {
+		return nil
+	}
+	x, errX := strconv.Atoi(strings.TrimSpace(xv.Raw))
+	y, errY := strconv.Atoi(strings.TrimSpace(yv.Raw))
+	if errX != nil || errY != nil || x == 0 || y == 0 {
+		return nil
+	}
+	if x == meta.Width && y == meta.Height {
+		return nil
+	}
+
+	actual := models.SourcedValue{
+		Source:  s.Container["width"].Source,
+		Raw:     fmt.Sprintf("%dx%d", meta.Width, meta.Height),
+		Display: fmt.Sprintf("%d × %d", meta.Width, meta.Height),
+	}
+	f := models.ConsistencyFinding{
+		Check:    "pixel-dimensions",
+		Severity: severityMedium,
+		Message: fmt.Sprintf("EXIF records %d × %d but the image is %d × %d; it was resized or cropped after the EXIF block was written",
+			x, y, meta.Width, meta.Height),
+		Evidence: []models.SourcedValue{xv, yv, actual},
+	}
+	if x == meta.Height && y == meta.Width {
+		f.Severity = severityLow
+		f.Message = fmt.Sprintf("EXIF records %d × %d, the image is %d × %d; it was rotated without updating EXIF",
+			x, y, meta.Width, meta.Height)
+	}
+	return []models.ConsistencyFinding{f}
+}
+
+// checkThumbnailAspect compares the shape of each embedded thumbnail with
+// the main image. A crop that leaves the old thumbnail in place is the
+// usual cause of a mismatch.
+func checkThumbnailAspect(meta *models.ImageMetadata, s *models.SourcedMetadata) []models.ConsistencyFinding {
+	if meta.RAW != nil || meta.Width == 0 || meta.Height == 0 {
+		return nil
+	}
+	var findings []models.ConsistencyFinding
+	for _, t := range meta.Thumbnails {
+		if t.Width == 0 || t.Height == 0 || sameAspect(t.Width, t.Height, meta.Width, meta.Height) {
+			continue
+		}
+		evidence := []models.SourcedValue{
+			{
+				Source:  t.Source + " thumbnail",
+				Raw:     fmt.Sprintf("%dx%d", t.Width, t.Height),
+				Display: fmt.Sprintf("%d × %d (%s)", t.Width, t.Height, utils.CalculateAspectRatio(t.Width, t.Height)),
+			},
+			{
+				Source:  s.Container["width"].Source,
+				Raw:     fmt.Sprintf("%dx%d", meta.Width, meta.Height),
+				Display: fmt.Sprintf("%d × %d (%s)", meta.Width, meta.Height, meta.AspectRatio),
+			},
+		}
+		f := models.ConsistencyFinding{
+			Check:    "thumbnail-aspect",
+			Severity: severityMedium,
+			Message: fmt.Sprintf("The %s thumbnail is %d × %d, a different shape from the %d × %d image; the image was cropped after the thumbnail was made",
+				t.Source, t.Width, t.Height, meta.Width, meta.Height),
+			Evidence: evidence,
+		}
+		switch {
+		case sameAspect(t.Width, t.Height, meta.Height, meta.Width):
+			f.Severity = severityLow
+			f.Message = fmt.Sprintf("The %s thumbnail is turned 90° from the image; the image was rotated without regenerating the thumbnail", t.Source)
+		case t.Width == 160 && t.Height == 120:
+			// DCF thumbnails are always 160 × 120, letterboxed if needed
+			f.Severity = severityLow
+			f.Message = fmt.Sprintf("The %s thumbnail has the fixed 160 × 120 DCF size, so its shape need not match the image", t.Source)
+		}
+		findings = append(findings, f)
+	}
+	return findings
+}
+
+// sameAspect reports whether w×h has the shape of W×H, allowing for the
+// rounding of a scaled-down copy
+func sameAspect(w, h, W, H int) bool {
+	expected := float64(w) * float64(H) / float64(W)
+	return math.Abs(float64(h)-expected) <= math.Max(1, 0.02*float64(h))
+}
+
+// checkDeclaredType compares the Content-Type the file was sent with to
+// the format of its content
+func checkDeclaredType(meta *models.ImageMetadata, s *models.SourcedMetadata) []models.ConsistencyFinding {
+	actual, ok := s.Container["mimeType"]
+	if !ok {
+		return nil
+	}
+	declared, ok := s.HTTP["Content-Type"]
+	if !ok {
+		declared, ok = s.File["declaredMimeType"]
+	}
+	if !ok {
+		return nil
+	}
+	dt := strings.ToLower(utils.ContentTypeBase(declared.Raw))
+	switch {
+	case dt == "" || dt == "application/octet-stream" || dt == "binary/octet-stream":
+		return nil
+	case canonicalMIMEType(dt) == canonicalMIMEType(actual.Raw):
+		return nil
+	case dt == "image/tiff" && meta.RAW != nil:
+		// Raw formats are TIFF-based and commonly served as such
+		return nil
+	}
+	return []models.ConsistencyFinding{{
+		Check:    "declared-type",
+		Severity: severityMedium,
+		Message:  fmt.Sprintf("The file was sent as %s but its content is %s", dt, actual.Raw),
+		Evidence: []models.SourcedValue{declared, actual},
+	}}
+}
+
+// mimeAliases maps alternative names of a type to the one formatMIMETypes
+// uses
+var mimeAliases = map[string]string{
+	"image/jpg":                "image/jpeg",
+	"image/pjpeg":              "image/jpeg",
+	"image/x-png":              "image/png",
+	"image/x-ms-bmp":           "image/bmp",
+	"image/heif":               "image/heic",
+	"image/vnd.microsoft.icon": "image/x-icon",
+	"image/jpx":                "image/jp2",
+}
+
+// canonicalMIMEType resolves an alias to its usual name
+func canonicalMIMEType(t string) string {
+	if canonical, ok := mimeAliases[t]; ok {
+		return canonical
+	}
+	return t
+}
+
+// conflictFields are the composite fields whose conflicts are reported as
+// they are; dates, dimensions and types have checks of their own, and a
+// profile description is not comparable with EXIF ColorSpace
+var conflictFields = map[string]bool{
+	"orientation": true,
+	"make":        true,
+	"model":       true,
+	"lens":        true,
+	"software":    true,
+	"title":       true,
+	"description": true,
+	"creator":     true,
+	"copyright":   true,
+	"keywords":    true,
+	"city":        true,
+	"country":     true,
+	"rating":      true,
+}
+
+// checkFieldConflicts reports descriptive fields that sources disagree on
+func checkFieldConflicts(_ *models.ImageMetadata, s *models.SourcedMetadata) []models.ConsistencyFinding {
+	var findings []models.ConsistencyFinding
+	for _, rule := range compositeRules {
+		c, ok := s.Composite[rule.field]
+		if !ok || len(c.Conflicts) == 0 || !conflictFields[rule.field] {
+			continue
+		}
+		sources := make([]string, len(c.Conflicts))
+		for i, v := range c.Conflicts {
+			sources[i] = v.Source
+		}
+		findings = append(findings, models.ConsistencyFinding{
+			Check:    "field-conflict",
+			Severity: severityLow,
+			Message: fmt.Sprintf("%s differs between %s and %s; the %s value takes precedence",
+				rule.field, c.Source, strings.Join(sources, ", "), c.Source),
+			Evidence: append([]models.SourcedValue{c.SourcedValue}, c.Conflicts...),
+		})
+	}
+	return findings
+}
+
+// humanDuration renders the size of a gap in its largest whole unit
+func humanDuration(d time.Duration) string {
+	d = d.Abs()
+	switch {
+	case d >= 48*time.Hour:
+		return fmt.Sprintf("%d days", d/(24*time.Hour))
+	case d >= 2*time.Hour:
+		return fmt.Sprintf("%d hours", d/time.Hour)
+	case d >= 2*time.Minute:
+		return fmt.Sprintf("%d minutes", d/time.Minute)
+	}
+	return fmt.Sprintf("%d seconds", d/time.Second)
+}
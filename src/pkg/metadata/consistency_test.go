@@ -0,0 +1,113 @@
+package metadata
+
+import (
+	"testing"
+
+	"github.com/ahrdadan/image-metadata-viewer/src/internal/models"
+)
+
+func TestCheckConsistency(t *testing.T) {
+	exifTag := func(ifd, name, value string) models.EXIFTag {
+		return models.EXIFTag{IFD: ifd, Name: name, RawValue: value, Value: value}
+	}
+	xmpProp := func(prefix, path, value string) models.XMPProperty {
+		return models.XMPProperty{Prefix: prefix, Path: path, Value: value}
+	}
+
+	tests := []struct {
+		name string
+		meta models.ImageMetadata
+		want []string // check:severity
+	}{
+		{
+			name: "consistent",
+			meta: models.ImageMetadata{
+				Format: "jpeg", Width: 640, Height: 480, DeclaredMIMEType: "image/jpg",
+				EXIF: &models.EXIFData{Tags: []models.EXIFTag{
+					exifTag("ExifIFD", "DateTimeOriginal", "2024:05:01 10:20:30"),
+					exifTag("ExifIFD", "OffsetTimeOriginal", "+02:00"),
+					exifTag("ExifIFD", "PixelXDimension", "640"),
+					exifTag("ExifIFD", "PixelYDimension", "480"),
+				}},
+				XMP: &models.XMPData{Properties: []models.XMPProperty{
+					xmpProp("xmp", "xmp:CreateDate", "2024-05-01T08:20:30.25Z"),
+				}},
+				IPTC: &models.IPTCData{Datasets: []models.IPTCDataset{
+					{Record: 2, Dataset: 55, Name: "DateCreated", Value: "20240501"},
+				}},
+				Thumbnails: []models.Thumbnail{{Source: "EXIF IFD1", Width: 160, Height: 120}},
+			},
+		},
+		{
+			name: "edited",
+			meta: models.ImageMetadata{
+				Format: "jpeg", Width: 800, Height: 600, DeclaredMIMEType: "image/png",
+				Source: "remote", LastModified: "Mon, 01 Jan 2024 00:00:00 GMT",
+				EXIF: &models.EXIFData{Tags: []models.EXIFTag{
+					exifTag("IFD0", "DateTime", "2024:04:01 09:00:00"),
+					exifTag("ExifIFD", "DateTimeOriginal", "2024:05:01 10:20:30"),
+					exifTag("ExifIFD", "PixelXDimension", "640"),
+					exifTag("ExifIFD", "PixelYDimension", "480"),
+				}},
+				IPTC: &models.IPTCData{Datasets: []models.IPTCDataset{
+					{Record: 2, Dataset: 55, Name: "DateCreated", Value: "20240612"},
+				}},
+				Thumbnails: []models.Thumbnail{{Source: "EXIF IFD1", Width: 160, Height: 90}},
+			},
+			want: []string{
+				"capture-date:medium",
+				"modify-date:medium",
+				"last-modified:medium",
+				"pixel-dimensions:medium",
+				"thumbnail-aspect:medium",
+				"declared-type:medium",
+			},
+		},
+		{
+			name: "rotated, no zone",
+			meta: models.ImageMetadata{
+				Format: "jpeg", Width: 480, Height: 640,
+				EXIF: &models.EXIFData{Tags: []models.EXIFTag{
+					exifTag("IFD0", "Software", "Camera 1.0"),
+					exifTag("ExifIFD", "DateTimeOriginal", "2024:05:01 10:20:30"),
+					exifTag("ExifIFD", "PixelXDimension", "640"),
+					exifTag("ExifIFD", "PixelYDimension", "480"),
+				}},
+				XMP: &models.XMPData{Properties: []models.XMPProperty{
+					xmpProp("photoshop", "photoshop:DateCreated", "2024-05-01T05:20:30-03:00"),
+					xmpProp("xmp", "xmp:CreatorTool", "Editor 2.0"),
+				}},
+				Thumbnails: []models.Thumbnail{{Source: "EXIF IFD1", Width: 160, Height: 120}},
+			},
+			want: []string{
+				"capture-date:low",
+				"pixel-dimensions:low",
+				"thumbnail-aspect:low",
+				"field-conflict:low",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			meta := tt.meta
+			CheckConsistency(&meta)
+
+			var got []string
+			for _, f := range meta.Consistency {
+				got = append(got, f.Check+":"+f.Severity)
+				if f.Message == "" || len(f.Evidence) < 2 {
+					t.Errorf("%s finding lacks a message or evidence: %+v", f.Check, f)
+				}
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("findings = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("finding %d = %s, want %s", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package metadata
+
+import (
+	"net/http"
+	"strings"
+	"time"
+)
+
+// timestamp is a date read from metadata. EXIF dates usually carry no
+// zone and IPTC dates may carry no time; the missing parts stay zero and
+// are flagged so comparisons can allow for them.
+type timestamp struct {
+	time    time.Time
+	hasTime bool
+	hasZone bool
+}
+
+// timestampLayouts are the date notations of EXIF, XMP (ISO 8601) and
+// IPTC-IIM. Fractional seconds are accepted after any seconds field.
+var timestampLayouts = []struct {
+	layout  string
+	hasTime bool
+	hasZone bool
+}{
+	{"2006:01:02 15:04:05Z07:00", true, true},
+	{"2006:01:02 15:04:05-0700", true, true},
+	{"2006:01:02 15:04:05", true, false},
+	{"2006:01:02", false, false},
+	{"2006-01-02T15:04:05Z07:00", true, true},
+	{"2006-01-02T15:04:05", true, false},
+	{"2006-01-02T15:04Z07:00", true, true},
+	{"2006-01-02T15:04", true, false},
+	{"2006-01-02", false, false},
+	{"20060102T150405-0700", true, true},
+	{"20060102T150405", true, false},
+	{"20060102", false, false},
+}
+
+// parseTimestamp reads a metadata or HTTP date. Blank EXIF dates such as
+// "0000:00:00 00:00:00" do not parse.
+func parseTimestamp(s string) (timestamp, bool) {
+	s = strings.TrimSpace(strings.TrimRight(s, "\x00"))
+	if s == "" {
+		return timestamp{}, false
+	}
+	for _, l := range timestampLayouts {
+		if t, err := time.Parse(l.layout, s); err == nil {
+			return timestamp{time: t, hasTime: l.hasTime, hasZone: l.hasZone}, true
+		}
+	}
+	if t, err := http.ParseTime(s); err == nil {
+		return timestamp{time: t, hasTime: true, hasZone: true}, true
+	}
+	return timestamp{}, false
+}
+
+// withOffset applies an EXIF OffsetTime value such as "+02:00" to a
+// timestamp that has no zone of its own
+func (ts timestamp) withOffset(offset string) timestamp {
+	zone, err := time.Parse("-07:00", strings.TrimSpace(offset))
+	if err != nil {
+		return ts
+	}
+	_, seconds := zone.Zone()
+	return ts.inZone(time.Duration(seconds) * time.Second)
+}
+
+// inZone places a timestamp that has no zone of its own at offset from UTC
+func (ts timestamp) inZone(offset time.Duration) timestamp {
+	if ts.hasZone || !ts.hasTime {
+		return ts
+	}
+	w := ts.wallClock()
+	zone := time.FixedZone("", int(offset/time.Second))
+	return timestamp{
+		time:    time.Date(w.Year(), w.Month(), w.Day(), w.Hour(), w.Minute(), w.Second(), w.Nanosecond(), zone),
+		hasTime: true,
+		hasZone: true,
+	}
+}
+
+// sub returns how far ts is after other. Without a zone on both sides the
+// wall clock times are compared, and the difference may be a zone offset;
+// without a time on either side only the calendar days are compared.
+func (ts timestamp) sub(other timestamp) (d time.Duration, zoneUnknown bool) {
+	if !ts.hasTime || !other.hasTime {
+		return ts.day().Sub(other.day()), false
+	}
+	if ts.hasZone && other.hasZone {
+		return ts.time.Sub(other.time), false
+	}
+	return ts.wallClock().Sub(other.wallClock()), true
+}
+
+// day is the calendar day of ts, as written
+func (ts timestamp) day() time.Time {
+	y, m, d := ts.time.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// wallClock is ts as written, with its zone dropped
+func (ts timestamp) wallClock() time.Time {
+	y, mo, d := ts.time.Date()
+	h, mi, s := ts.time.Clock()
+	return time.Date(y, mo, d, h, mi, s, ts.time.Nanosecond(), time.UTC)
+}
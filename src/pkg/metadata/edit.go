@@ -0,0 +1,563 @@
+package metadata
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/ahrdadan/image-metadata-viewer/src/internal/models"
+)
+
+// ErrEditUnsupported is returned by Edit for formats other than JPEG, PNG
+// and WebP
+var ErrEditUnsupported = errors.New("only JPEG, PNG and WebP files can be edited")
+
+// maxJPEGSegment is the largest payload of a JPEG marker segment
+const maxJPEGSegment = 0xFFFF - 2
+
+// vp8xAlpha is the VP8X flag of an image with an alpha channel
+const vp8xAlpha = 0x10
+
+// EditValue is the new value of a tag: one string, or several for XMP
+// arrays and repeatable IPTC datasets. An empty value removes the tag.
+type EditValue []string
+
+// UnmarshalJSON accepts a string, a number, an array of strings or null
+func (v *EditValue) UnmarshalJSON(b []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var value any
+	if err := dec.Decode(&value); err != nil {
+		return err
+	}
+
+	*v = nil
+	switch t := value.(type) {
+	case nil:
+	case string:
+		if t != "" {
+			*v = EditValue{t}
+		}
+	case json.Number:
+		*v = EditValue{t.String()}
+	case []any:
+		for _, item := range t {
+			switch s := item.(type) {
+			case string:
+				*v = append(*v, s)
+			case json.Number:
+				*v = append(*v, s.String())
+			default:
+				return errors.New("array items must be strings")
+			}
+		}
+	default:
+		return errors.New("a value must be a string, an array of strings or null")
+	}
+	return nil
+}
+
+// first returns the first value, or ""
+func (v EditValue) first() string {
+	if len(v) == 0 {
+		return ""
+	}
+	return v[0]
+}
+
+// EditPatch lists tag changes under the names the metadata output uses:
+// EXIF tag names, top-level XMP properties such as dc:title and IPTC
+// dataset names
+type EditPatch struct {
+	EXIF map[string]EditValue `json:"exif,omitempty"`
+	XMP  map[string]EditValue `json:"xmp,omitempty"`
+	IPTC map[string]EditValue `json:"iptc,omitempty"`
+}
+
+// ParseEditPatch reads a JSON patch
+func ParseEditPatch(raw string) (EditPatch, error) {
+	var patch EditPatch
+	dec := json.NewDecoder(strings.NewReader(raw))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&patch); err != nil {
+		return EditPatch{}, fmt.Errorf("patch is not valid JSON: %v", err)
+	}
+	if len(patch.EXIF)+len(patch.XMP)+len(patch.IPTC) == 0 {
+		return EditPatch{}, errors.New("patch lists no tags to change")
+	}
+	return patch, nil
+}
+
+// Edit writes a patch into a JPEG, PNG or WebP file and returns the
+// edited file and its MIME type. The EXIF, XMP and IPTC blocks are edited
+// in place: tags the patch does not name, MakerNotes, thumbnails and the
+// image data are kept as stored. Changes are mirrored between EXIF, XMP
+// and IPTC-IIM as the MWG guidelines describe; IPTC-IIM is only written
+// to JPEG files, and only when the file already has it or the patch
+// names IPTC datasets.
+func Edit(data []byte, patch EditPatch) ([]byte, string, error) {
+	format := sniffFormat(data)
+	if format != formatJPEG && format != formatPNG && format != formatWebP {
+		return nil, "", ErrEditUnsupported
+	}
+	if format != formatJPEG && len(patch.IPTC) > 0 {
+		return nil, "", errors.New("IPTC-IIM can only be written to JPEG files; edit the matching XMP properties instead")
+	}
+
+	meta := ExtractMetadata(data, "", "")
+	patch = syncMWG(patch, meta, format == formatJPEG && (meta.IPTC != nil || len(patch.IPTC) > 0))
+	splitJoinedLists(patch)
+	exifEdits, err := resolveEXIF(patch.EXIF, meta)
+	if err != nil {
+		return nil, "", err
+	}
+	xmpEdits, err := resolveXMP(patch.XMP, meta)
+	if err != nil {
+		return nil, "", err
+	}
+	iptcEdits, err := resolveIPTC(patch.IPTC)
+	if err != nil {
+		return nil, "", err
+	}
+
+	current := embeddedBlocks(data, format)
+	var m embedded
+	if len(exifEdits) > 0 {
+		if m.exif, err = patchTIFF(current.exif, exifEdits); err != nil {
+			return nil, "", err
+		}
+	}
+	if len(xmpEdits) > 0 {
+		if m.xmp, err = patchXMP(current.xmp, xmpEdits); err != nil {
+			return nil, "", err
+		}
+	}
+	if len(iptcEdits) > 0 {
+		var iim []byte
+		for _, res := range readPhotoshopResources(current.photoshop) {
+			if res.id == psResourceIPTC {
+				iim = res.data
+				break
+			}
+		}
+		iim, err = patchIIM(iim, iptcEdits)
+		if err != nil {
+			return nil, "", err
+		}
+		m.photoshop = setPhotoshopIPTC(current.photoshop, iim)
+	}
+
+	switch format {
+	case formatJPEG:
+		out, err := editJPEG(data, m)
+		return out, "image/jpeg", err
+	case formatPNG:
+		out, err := editPNG(data, m)
+		return out, "image/png", err
+	default:
+		out, err := editWebP(data, m)
+		return out, "image/webp", err
+	}
+}
+
+// splitJoinedLists splits a single value given for an XMP array or a
+// repeatable IPTC dataset at semicolons, the way the grouped metadata
+// view joins them
+func splitJoinedLists(patch EditPatch) {
+	for name, v := range patch.XMP {
+		if form := xmpForms[name]; len(v) == 1 && (form == xmpBag || form == xmpSeq) {
+			patch.XMP[name] = splitList(v[0])
+		}
+	}
+	for name, v := range patch.IPTC {
+		if key, ok := iptcKeyByName(name); ok && len(v) == 1 && iptcRepeatable[key] {
+			patch.IPTC[name] = splitList(v[0])
+		}
+	}
+}
+
+// resolveEXIF finds the IFD and ID of each EXIF tag of a patch. A tag the
+// file has is edited where it is; a new one goes to the IFD the EXIF
+// specification puts it in, with the type the specification gives it.
+func resolveEXIF(values map[string]EditValue, meta *models.ImageMetadata) ([]exifEdit, error) {
+	existing := make(map[string]models.EXIFTag)
+	if meta.EXIF != nil {
+		for _, tag := range meta.EXIF.Tags {
+			if _, seen := existing[tag.Name]; !seen && (tag.IFD == ifd0Name || tag.IFD == exifIFDName || tag.IFD == gpsIFDName) {
+				existing[tag.Name] = tag
+			}
+		}
+	}
+
+	var edits []exifEdit
+	for _, name := range sortedNames(values) {
+		if exifStructuralTags[name] {
+			return nil, fmt.Errorf("EXIF %s describes the file structure and cannot be edited", name)
+		}
+		ed := exifEdit{name: name, typ: exifTagTypes[name]}
+		if tag, ok := existing[name]; ok {
+			ed.ifd, ed.id = tag.IFD, tag.ID
+		} else if ed.ifd, ed.id, ok = exifTagByName(name); !ok {
+			return nil, fmt.Errorf("EXIF %s is not a known tag", name)
+		}
+		if v := values[name]; v != nil {
+			value := strings.Join(v, " ")
+			ed.value = &value
+			if _, ok := existing[name]; !ok && ed.typ == 0 {
+				return nil, fmt.Errorf("EXIF %s cannot be added, only changed or removed", name)
+			}
+		}
+		edits = append(edits, ed)
+	}
+	return edits, nil
+}
+
+// exifTagByName looks a tag up in the Exif, GPS and TIFF name tables
+func exifTagByName(name string) (string, uint16, bool) {
+	for _, table := range []struct {
+		ifd   string
+		names map[uint16]string
+	}{{exifIFDName, exifTagNames}, {gpsIFDName, gpsTagNames}, {ifd0Name, tiffTagNames}} {
+		for id, n := range table.names {
+			if n == name {
+				return table.ifd, id, true
+			}
+		}
+	}
+	return "", 0, false
+}
+
+// xmpForms are the array forms of the XMP properties that hold arrays
+var xmpForms = map[string]string{
+	"dc:title":                         xmpAlt,
+	"dc:description":                   xmpAlt,
+	"dc:rights":                        xmpAlt,
+	"xmpRights:UsageTerms":             xmpAlt,
+	"dc:creator":                       xmpSeq,
+	"dc:date":                          xmpSeq,
+	"dc:subject":                       xmpBag,
+	"dc:contributor":                   xmpBag,
+	"dc:publisher":                     xmpBag,
+	"dc:language":                      xmpBag,
+	"dc:type":                          xmpBag,
+	"xmp:Identifier":                   xmpBag,
+	"photoshop:SupplementalCategories": xmpBag,
+	"Iptc4xmpCore:Scene":               xmpBag,
+	"Iptc4xmpCore:SubjectCode":         xmpBag,
+	"Iptc4xmpExt:PersonInImage":        xmpBag,
+}
+
+// resolveXMP finds the namespace and form of each XMP property of a patch
+func resolveXMP(values map[string]EditValue, meta *models.ImageMetadata) ([]xmpField, error) {
+	namespaces := make(map[string]string)
+	for uri, prefix := range xmpPrefixes {
+		namespaces[prefix] = uri
+	}
+	if meta.XMP != nil {
+		for _, prop := range meta.XMP.Properties {
+			if _, ok := namespaces[prop.Prefix]; !ok {
+				namespaces[prop.Prefix] = prop.Namespace
+			}
+		}
+	}
+
+	var fields []xmpField
+	for _, name := range sortedNames(values) {
+		prefix, local, ok := strings.Cut(name, ":")
+		if !ok || local == "" || strings.ContainsAny(local, "/[") {
+			return nil, fmt.Errorf("XMP %s is not a top-level property such as dc:title", name)
+		}
+		namespace, ok := namespaces[prefix]
+		if !ok {
+			return nil, fmt.Errorf("XMP %s has an unknown namespace prefix", name)
+		}
+		f := xmpField{namespace: namespace, name: local, form: xmpForms[name], values: values[name]}
+		if f.form == xmpAlt && len(f.values) > 1 {
+			return nil, fmt.Errorf("XMP %s takes a single value", name)
+		}
+		fields = append(fields, f)
+	}
+	return fields, nil
+}
+
+// iptcKeyByName looks up a dataset by name
+func iptcKeyByName(name string) (iptcKey, bool) {
+	for key, n := range iptcDatasetNames {
+		if n == name {
+			return key, true
+		}
+	}
+	return iptcKey{}, false
+}
+
+// resolveIPTC finds the record and dataset of each IPTC name of a patch
+func resolveIPTC(values map[string]EditValue) ([]iptcEdit, error) {
+	var edits []iptcEdit
+	for _, name := range sortedNames(values) {
+		key, ok := iptcKeyByName(name)
+		switch {
+		case !ok:
+			return nil, fmt.Errorf("IPTC %s is not a known dataset", name)
+		case iptcBinaryDatasets[key] || key == (iptcKey{1, 90}):
+			return nil, fmt.Errorf("IPTC %s is written automatically and cannot be edited", name)
+		case len(values[name]) > 1 && !iptcRepeatable[key]:
+			return nil, fmt.Errorf("IPTC %s takes a single value", name)
+		}
+		edits = append(edits, iptcEdit{key: key, name: name, values: values[name]})
+	}
+	return edits, nil
+}
+
+// sortedNames returns the keys of a patch section in order
+func sortedNames(values map[string]EditValue) []string {
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// embeddedBlocks returns the EXIF, main XMP and Photoshop resource blocks
+// of a JPEG, PNG or WebP file
+func embeddedBlocks(data []byte, format string) embedded {
+	var m embedded
+	switch format {
+	case formatJPEG:
+		for _, seg := range readJPEGSegments(data) {
+			switch {
+			case m.exif == nil && isJPEGBlock(seg, exifJPEGSignature):
+				m.exif = seg.data[len(exifJPEGSignature):]
+			case m.xmp == nil && isJPEGBlock(seg, xmpJPEGSignature):
+				m.xmp = seg.data[len(xmpJPEGSignature):]
+			}
+		}
+		m.photoshop, _ = findPhotoshopResources(data)
+	case formatPNG:
+		m.exif = pngExifTIFF(data)
+		m.xmp = findXMPPacket(data, newInflateBudget())
+	case formatWebP:
+		m.exif = webpExifTIFF(data)
+		m.xmp = findXMPPacket(data, newInflateBudget())
+	}
+	return m
+}
+
+// isJPEGBlock reports whether seg is an APP1 or APP13 block starting
+// with signature
+func isJPEGBlock(seg markerSegment, signature []byte) bool {
+	marker := byte(markerAPP1)
+	if bytes.Equal(signature, photoshopJPEGSignature) {
+		marker = markerAPP13
+	}
+	return seg.marker == marker && bytes.HasPrefix(seg.data, signature)
+}
+
+// editJPEG replaces the EXIF, XMP and Photoshop segments of a JPEG with
+// those in m, or adds them after JFIF. Every other byte is copied.
+func editJPEG(data []byte, m embedded) ([]byte, error) {
+	segments := readJPEGSegments(data)
+	if len(segments) == 0 || segments[len(segments)-1].marker != markerSOS {
+		return nil, errors.New("JPEG has no image data")
+	}
+	if m.exif != nil && len(exifJPEGSignature)+len(m.exif) > maxJPEGSegment {
+		return nil, errors.New("EXIF block is larger than the 64 KB a JPEG segment holds")
+	}
+	if m.xmp != nil && len(xmpJPEGSignature)+len(m.xmp) > maxJPEGSegment {
+		return nil, errors.New("XMP packet is larger than the 64 KB a JPEG segment holds")
+	}
+
+	// A new EXIF block goes right after JFIF, other new blocks after the
+	// leading APP0 and APP1 segments
+	blocks := []struct {
+		signature []byte
+		body      []byte
+		follows   []byte // markers of the segments a new block goes after
+		present   bool
+		written   bool
+	}{
+		{signature: exifJPEGSignature, body: m.exif, follows: []byte{markerAPP0}},
+		{signature: xmpJPEGSignature, body: m.xmp, follows: []byte{markerAPP0, markerAPP1}},
+		{signature: photoshopJPEGSignature, body: m.photoshop, follows: []byte{markerAPP0, markerAPP1}},
+	}
+	segmentsOf := func(signature, body []byte) []byte {
+		marker := byte(markerAPP1)
+		if bytes.Equal(signature, photoshopJPEGSignature) {
+			marker = markerAPP13
+		}
+		// A large resource section is split over several APP13 segments
+		var out []byte
+		for chunk := maxJPEGSegment - len(signature); len(body) > 0; {
+			n := min(chunk, len(body))
+			out = append(out, jpegSegmentBytes(marker, append(append([]byte{}, signature...), body[:n]...))...)
+			body = body[n:]
+		}
+		return out
+	}
+
+	for _, seg := range segments {
+		for i := range blocks {
+			blocks[i].present = blocks[i].present || isJPEGBlock(seg, blocks[i].signature)
+		}
+	}
+
+	out := []byte{0xFF, markerSOI}
+	for _, seg := range segments {
+		for i := range blocks {
+			b := &blocks[i]
+			if b.body != nil && !b.present && !b.written && bytes.IndexByte(b.follows, seg.marker) < 0 {
+				out = append(out, segmentsOf(b.signature, b.body)...)
+				b.written = true
+			}
+		}
+		if seg.marker == markerSOS {
+			break
+		}
+
+		replaced := false
+		for i := range blocks {
+			b := &blocks[i]
+			if b.body == nil || !isJPEGBlock(seg, b.signature) {
+				continue
+			}
+			// Photoshop resources split over several segments are
+			// replaced as a whole; a second EXIF or XMP block is kept
+			if !b.written {
+				out = append(out, segmentsOf(b.signature, b.body)...)
+				b.written = true
+				replaced = true
+			} else if bytes.Equal(b.signature, photoshopJPEGSignature) {
+				replaced = true
+			}
+		}
+		if !replaced {
+			out = append(out, data[seg.offset:seg.offset+4+len(seg.data)]...)
+		}
+	}
+	return append(out, data[segments[len(segments)-1].offset:]...), nil
+}
+
+// editPNG replaces the eXIf and XMP iTXt chunks of a PNG with those in
+// m, or adds them after IHDR
+func editPNG(data []byte, m embedded) ([]byte, error) {
+	chunks := readPNGChunks(data)
+	if len(chunks) == 0 || chunks[0].typ != "IHDR" || chunks[len(chunks)-1].typ != "IEND" {
+		return nil, errors.New("PNG is truncated or does not start with IHDR")
+	}
+	isXMP := func(c pngChunk) bool {
+		return c.typ == "iTXt" && bytes.HasPrefix(c.data, []byte(xmpPNGKeyword+"\x00"))
+	}
+	hasEXIF, hasXMP := false, false
+	for _, c := range chunks {
+		hasEXIF = hasEXIF || c.typ == "eXIf"
+		hasXMP = hasXMP || isXMP(c)
+	}
+
+	out := append([]byte{}, pngSignature...)
+	exifDone, xmpDone := false, false
+	for i, c := range chunks {
+		switch {
+		case m.exif != nil && c.typ == "eXIf":
+			if !exifDone {
+				out = append(out, pngChunkBytes("eXIf", m.exif)...)
+				exifDone = true
+			}
+		case m.xmp != nil && isXMP(c):
+			if !xmpDone {
+				out = append(out, pngXMPChunk(m.xmp)...)
+				xmpDone = true
+			}
+		default:
+			out = append(out, data[c.offset:c.offset+12+len(c.data)]...)
+		}
+		if i == 0 {
+			if m.exif != nil && !hasEXIF {
+				out = append(out, pngChunkBytes("eXIf", m.exif)...)
+			}
+			if m.xmp != nil && !hasXMP {
+				out = append(out, pngXMPChunk(m.xmp)...)
+			}
+		}
+	}
+	return out, nil
+}
+
+// editWebP replaces the EXIF and XMP chunks of a WebP with those in m, or
+// appends them. A simple WebP is given the VP8X header that metadata
+// needs.
+func editWebP(data []byte, m embedded) ([]byte, error) {
+	chunks := readWebPChunks(data)
+	if len(chunks) == 0 {
+		return nil, errors.New("WebP has no chunks")
+	}
+
+	var body []byte
+	flagsAt := 8
+	if chunks[0].fourCC != "VP8X" {
+		vp8x, err := newVP8X(chunks[0])
+		if err != nil {
+			return nil, err
+		}
+		body = appendRIFFChunk(body, "VP8X", vp8x)
+	} else if len(chunks[0].data) < 10 {
+		return nil, errors.New("VP8X chunk is truncated")
+	}
+
+	exifDone, xmpDone := false, false
+	for _, c := range chunks {
+		switch {
+		case c.fourCC == "EXIF" && m.exif != nil:
+			if !exifDone {
+				body = appendRIFFChunk(body, "EXIF", m.exif)
+				exifDone = true
+			}
+		case c.fourCC == "XMP " && m.xmp != nil:
+			if !xmpDone {
+				body = appendRIFFChunk(body, "XMP ", m.xmp)
+				xmpDone = true
+			}
+		default:
+			body = appendRIFFChunk(body, c.fourCC, c.data)
+		}
+	}
+	if m.exif != nil && !exifDone {
+		body = appendRIFFChunk(body, "EXIF", m.exif)
+	}
+	if m.xmp != nil && !xmpDone {
+		body = appendRIFFChunk(body, "XMP ", m.xmp)
+	}
+	if m.exif != nil {
+		body[flagsAt] |= vp8xEXIF
+	}
+	if m.xmp != nil {
+		body[flagsAt] |= vp8xXMP
+	}
+
+	out := []byte("RIFF")
+	out = binary.LittleEndian.AppendUint32(out, uint32(4+len(body)))
+	out = append(out, "WEBP"...)
+	return append(out, body...), nil
+}
+
+// webpCanvas reads the size of a simple WebP from its VP8 or VP8L
+// bitstream header, and whether a lossless image uses alpha
+func webpCanvas(c riffChunk) (width, height int, alpha, ok bool) {
+	switch c.fourCC {
+	case "VP8 ":
+		if len(c.data) < 10 || !bytes.Equal(c.data[3:6], []byte{0x9D, 0x01, 0x2A}) {
+			return 0, 0, false, false
+		}
+		return int(binary.LittleEndian.Uint16(c.data[6:]) & 0x3FFF), int(binary.LittleEndian.Uint16(c.data[8:]) & 0x3FFF), false, true
+	case "VP8L":
+		if len(c.data) < 5 || c.data[0] != 0x2F {
+			return 0, 0, false, false
+		}
+		bits := binary.LittleEndian.Uint32(c.data[1:])
+		return int(bits&0x3FFF) + 1, int(bits>>14&0x3FFF) + 1, bits>>28&1 == 1, true
+	}
+	return 0, 0, false, false
+}
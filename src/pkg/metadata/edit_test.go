@@ -0,0 +1,198 @@
+package metadata
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image/jpeg"
+	"image/png"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestEditJPEG(t *testing.T) {
+	tiffData := buildTIFF([]testIFD{
+		{
+			tags:     []testTag{shortTag(0x0112, 1), asciiTag(0x013B, "Jane Doe")},
+			pointers: map[uint16]int{tagExifIFDPointer: 1},
+		},
+		{
+			tags: []testTag{asciiTag(0x9003, "2025:10:31 21:41:50")},
+		},
+	})
+	data := buildJPEGWithSegments(t,
+		exifSegment(tiffData),
+		jpegSegment(markerAPP1, append(append([]byte{}, xmpJPEGSignature...), testXMPPacket...)),
+		photoshopSegment(psResourceBlock(psResourceIPTC, append(iimDataset(2, 25, "harbour"), iimDataset(2, 25, "boats")...))),
+	)
+	segments := readJPEGSegments(data)
+	scans := data[segments[len(segments)-1].offset:]
+
+	patch, err := ParseEditPatch(`{
+		"exif": {"Artist": "John Roe", "DateTimeOriginal": "2024:05:01 10:00:00", "OffsetTimeOriginal": "+02:00"},
+		"xmp": {"dc:title": "Quay at night", "dc:subject": "quay; night", "photoshop:City": null}
+	}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, contentType, err := Edit(data, patch)
+	if err != nil || contentType != "image/jpeg" {
+		t.Fatalf("Edit = %q, %v", contentType, err)
+	}
+	if !bytes.HasSuffix(out, scans) {
+		t.Error("image data was not copied unchanged")
+	}
+	if _, err := jpeg.Decode(bytes.NewReader(out)); err != nil {
+		t.Fatalf("jpeg.Decode: %v", err)
+	}
+
+	meta := ExtractMetadata(out, "image/jpeg", "edited.jpg")
+	tags := make(map[string]string)
+	for _, tag := range meta.EXIF.Tags {
+		tags[tag.Name] = tag.RawValue
+	}
+	if tags["Artist"] != "John Roe" || tags["Orientation"] != "1" || tags["DateTimeOriginal"] != "2024:05:01 10:00:00" {
+		t.Errorf("EXIF tags = %v", tags)
+	}
+	x := meta.XMP
+	if x.Title != "Quay at night" || !reflect.DeepEqual(x.Subject, []string{"quay", "night"}) ||
+		!reflect.DeepEqual(x.Creator, []string{"John Roe"}) || x.City != "" || x.CreatorTool == "" {
+		t.Errorf("XMP = title %q, subject %v, creator %v, city %q, creator tool %q", x.Title, x.Subject, x.Creator, x.City, x.CreatorTool)
+	}
+	if x.DateCreated != "2024-05-01T10:00:00+02:00" {
+		t.Errorf("photoshop:DateCreated = %q", x.DateCreated)
+	}
+	i := meta.IPTC
+	if i.ObjectName != "Quay at night" || !reflect.DeepEqual(i.Keywords, []string{"quay", "night"}) ||
+		!reflect.DeepEqual(i.Byline, []string{"John Roe"}) || i.DateCreated != "2024-05-01" || i.TimeCreated != "10:00:00+02:00" {
+		t.Errorf("IPTC = %+v", i)
+	}
+
+	// Writing the values the file already has changes nothing
+	again, _, err := Edit(out, patch)
+	if err != nil || !bytes.Equal(again, out) {
+		t.Errorf("second Edit changed the file: %v", err)
+	}
+}
+
+func TestPatchTIFFKeepsLayout(t *testing.T) {
+	tiffData := buildTIFF([]testIFD{
+		{tags: []testTag{asciiTag(0x010F, "Canon"), asciiTag(0x013B, "Jane Doe")}},
+		{tags: []testTag{shortTag(0x0103, 6)}},
+	})
+	// Link the second IFD as IFD1: 2 entries, then 6 and 10 bytes of text
+	binary.LittleEndian.PutUint32(tiffData[8+2+2*12:], 8+2+2*12+4+6+10)
+	artist := "A much longer artist name than before"
+	out, err := patchTIFF(tiffData, []exifEdit{
+		{ifd: ifd0Name, id: 0x013B, name: "Artist", value: &artist},
+		{ifd: ifd0Name, id: 0x010F, name: "Make"},
+		{ifd: gpsIFDName, id: 0x0001, name: "GPSLatitudeRef", typ: exifTagTypes["GPSLatitudeRef"], value: new(string)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(out, tiffData[:8]) {
+		t.Error("TIFF header changed")
+	}
+	var tags []string
+	for _, tag := range ExtractMetadata(buildJPEGWithSegments(t, exifSegment(out)), "", "").EXIF.Tags {
+		tags = append(tags, tag.IFD+":"+tag.Name+"="+tag.RawValue)
+	}
+	want := []string{"IFD0:Artist=" + artist, "IFD0:GPSInfoIFDPointer", "GPS:GPSVersionID=2 3 0 0", "GPS:GPSLatitudeRef=", "IFD1:Compression=6"}
+	if len(tags) != len(want) {
+		t.Fatalf("tags = %v, want %v", tags, want)
+	}
+	for i := range want {
+		if !strings.HasPrefix(tags[i], want[i]) {
+			t.Errorf("tag %d = %q, want %q", i, tags[i], want[i])
+		}
+	}
+}
+
+func TestEditPNG(t *testing.T) {
+	data := buildPNGWithChunks(t, buildChunk("tEXt", []byte("Author\x00Jane Doe")))
+
+	patch := EditPatch{EXIF: map[string]EditValue{"Orientation": {"6"}}, XMP: map[string]EditValue{"dc:rights": {"CC BY 4.0"}}}
+	out, contentType, err := Edit(data, patch)
+	if err != nil || contentType != "image/png" {
+		t.Fatalf("Edit = %q, %v", contentType, err)
+	}
+	var types []string
+	for _, c := range readPNGChunks(out) {
+		types = append(types, c.typ)
+		if !c.crcValid {
+			t.Errorf("%s CRC is invalid", c.typ)
+		}
+	}
+	if want := []string{"IHDR", "eXIf", "iTXt", "tEXt", "IDAT", "IEND"}; !reflect.DeepEqual(types, want) {
+		t.Errorf("chunks = %v, want %v", types, want)
+	}
+	meta := ExtractMetadata(out, "image/png", "edited.png")
+	if meta.Orientation == "" || meta.XMP.Rights != "CC BY 4.0" || meta.XMP.Properties[0].Path != "dc:rights[x-default]" {
+		t.Errorf("Orientation = %q, XMP = %+v", meta.Orientation, meta.XMP)
+	}
+	if _, err := png.Decode(bytes.NewReader(out)); err != nil {
+		t.Errorf("png.Decode: %v", err)
+	}
+
+	if _, _, err := Edit(data, EditPatch{IPTC: map[string]EditValue{"Keywords": {"quay"}}}); err == nil {
+		t.Error("expected IPTC to be refused for PNG")
+	}
+}
+
+func TestEditWebPAddsVP8X(t *testing.T) {
+	// A 16x8 lossless image without alpha
+	body := appendRIFFChunk(nil, "VP8L", []byte{0x2F, 0x0F, 0xC0, 0x01, 0x00})
+	data := append(append(append([]byte("RIFF"), 0, 0, 0, 0), "WEBP"...), body...)
+	binary.LittleEndian.PutUint32(data[4:], uint32(len(data)-8))
+
+	out, contentType, err := Edit(data, EditPatch{XMP: map[string]EditValue{"xmp:Rating": {"4"}}})
+	if err != nil || contentType != "image/webp" {
+		t.Fatalf("Edit = %q, %v", contentType, err)
+	}
+	var fourCCs []string
+	for _, c := range readWebPChunks(out) {
+		fourCCs = append(fourCCs, c.fourCC)
+	}
+	if want := []string{"VP8X", "VP8L", "EXIF", "XMP "}; !reflect.DeepEqual(fourCCs, want) {
+		t.Errorf("chunks = %v, want %v", fourCCs, want)
+	}
+	if vp8x := out[20:30]; !bytes.Equal(vp8x, []byte{vp8xEXIF | vp8xXMP, 0, 0, 0, 15, 0, 0, 7, 0, 0}) {
+		t.Errorf("VP8X = % x", vp8x)
+	}
+	if int(binary.LittleEndian.Uint32(out[4:])) != len(out)-8 {
+		t.Errorf("RIFF size = %d for %d bytes", binary.LittleEndian.Uint32(out[4:]), len(out))
+	}
+	if meta := ExtractMetadata(out, "image/webp", "edited.webp"); meta.XMP.Rating != "4" || meta.EXIF.Tags[0].Name != "Rating" {
+		t.Errorf("XMP rating = %q, EXIF = %+v", meta.XMP.Rating, meta.EXIF)
+	}
+}
+
+func TestParseEditPatch(t *testing.T) {
+	patch, err := ParseEditPatch(`{"xmp": {"dc:subject": ["a", "b"], "xmp:Rating": 5, "dc:title": null}}`)
+	want := map[string]EditValue{"dc:subject": {"a", "b"}, "xmp:Rating": {"5"}, "dc:title": nil}
+	if err != nil || !reflect.DeepEqual(patch.XMP, want) {
+		t.Errorf("ParseEditPatch = %+v, %v", patch, err)
+	}
+	for _, raw := range []string{``, `{}`, `{"exif": {"Artist": {"a": 1}}}`, `{"icc": {}}`} {
+		if _, err := ParseEditPatch(raw); err == nil {
+			t.Errorf("ParseEditPatch(%q) succeeded", raw)
+		}
+	}
+
+	data := buildJPEGWithSegments(t)
+	for _, patch := range []EditPatch{
+		{EXIF: map[string]EditValue{"StripOffsets": {"0"}}},
+		{EXIF: map[string]EditValue{"NoSuchTag": {"1"}}},
+		{EXIF: map[string]EditValue{"Orientation": {"70000"}}},
+		{XMP: map[string]EditValue{"title": {"x"}}},
+		{IPTC: map[string]EditValue{"ObjectName": {"a", "b"}}},
+	} {
+		if _, _, err := Edit(data, patch); err == nil {
+			t.Errorf("Edit(%+v) succeeded", patch)
+		}
+	}
+	if _, _, err := Edit([]byte("GIF89a"), EditPatch{}); err != ErrEditUnsupported {
+		t.Errorf("Edit(GIF) error = %v", err)
+	}
+}
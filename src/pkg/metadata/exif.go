@@ -0,0 +1,374 @@
+package metadata
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/ahrdadan/image-metadata-viewer/src/internal/models"
+	"github.com/rwcarlsen/goexif/exif"
+	"github.com/rwcarlsen/goexif/tiff"
+)
+
+const (
+	// maxRawBytes limits how many bytes of binary tag data are shown
+	maxRawBytes = 32
+	// maxRawValues limits how many array elements are shown per tag
+	maxRawValues = 32
+)
+
+// exifDirs holds the decoded directories of an EXIF block
+type exifDirs struct {
+	order   binary.ByteOrder
+	raw     []byte // TIFF block the Exif IFD's value offsets refer to
+	ifd0    *tiff.Dir
+	exif    *tiff.Dir
+	gps     *tiff.Dir
+	interop *tiff.Dir
+	ifd1    *tiff.Dir
+}
+
+// exifPayload returns the bytes exif.Decode should read. JPEG and TIFF are
+// passed through; HEIF stores a bare TIFF structure in its Exif item, RAF
+// keeps EXIF in its embedded JPEG and CR3 stores IFD0 in the CMT1 box.
+// JPEG XL and JPEG 2000 carry the TIFF structure in an Exif or uuid box,
+// PSD in an image resource and PNG and WebP in an eXIf or EXIF chunk.
+func exifPayload(data []byte) []byte {
+	switch sniffFormat(data) {
+	case formatPNG:
+		if tiff := pngExifTIFF(data); tiff != nil {
+			return tiff
+		}
+	case formatWebP:
+		if tiff := webpExifTIFF(data); tiff != nil {
+			return tiff
+		}
+	case formatHEIF:
+		if file := parseHEIF(data); file != nil {
+			if tiff := file.exifTIFF(); tiff != nil {
+				return tiff
+			}
+		}
+	case formatRAF:
+		if jpeg := rafJPEG(data); jpeg != nil {
+			return jpeg
+		}
+	case formatCR3:
+		if cmt1 := findBox(cr3MetadataBoxes(data), "CMT1"); cmt1 != nil {
+			return cmt1.data
+		}
+	case formatTIFF:
+		return tiffPayload(data)
+	case formatJXL:
+		if tiff := jxlExifTIFF(data); tiff != nil {
+			return tiff
+		}
+	case formatJP2:
+		if tiff := jp2ExifTIFF(data); tiff != nil {
+			return tiff
+		}
+	case formatPSD:
+		if tiff := psdResource(data, psResourceEXIF); tiff != nil {
+			return tiff
+		}
+	}
+	return data
+}
+
+// exifBlock returns the TIFF structure of the block exifPayload locates,
+// without the JPEG APP1 and Exif headers
+func exifBlock(data []byte) []byte {
+	payload := bytes.TrimPrefix(exifPayload(data), []byte("Exif\x00\x00"))
+	if len(payload) >= 8 && tiffByteOrder(payload) != nil {
+		return payload
+	}
+	for _, seg := range readJPEGSegments(payload) {
+		if seg.marker == markerAPP1 && bytes.HasPrefix(seg.data, []byte("Exif\x00\x00")) {
+			return seg.data[6:]
+		}
+	}
+	return nil
+}
+
+// decodeEXIF decodes an EXIF TIFF structure with goexif once the IFD chain
+// and the Exif, GPS and Interop directories it points to have passed
+// ifdFits; exif.Decode follows those pointers without checking them
+func decodeEXIF(block []byte) (*exif.Exif, error) {
+	t, err := decodeTIFF(block)
+	if err != nil {
+		return nil, err
+	}
+	dirs := append([]*tiff.Dir(nil), t.Dirs...)
+	seen := make(map[int64]bool)
+	for i := 0; i < len(dirs); i++ {
+		for _, pointer := range []uint16{tagExifIFDPointer, tagGPSIFDPointer, tagInteropIFDPointer} {
+			tag := findTag(dirs[i], pointer)
+			if tag == nil {
+				continue
+			}
+			offset, err := tag.Int64(0)
+			if err != nil || offset <= 0 || offset >= int64(len(block)) || seen[offset] || len(seen) == maxTIFFChain {
+				continue
+			}
+			seen[offset] = true
+			if !ifdFits(block, t.Order, offset) {
+				return nil, errors.New("tiff: IFD data too large")
+			}
+			if sub, _ := decodeDirAt(block, t.Order, offset); sub != nil {
+				dirs = append(dirs, sub)
+			}
+		}
+	}
+	return exif.Decode(bytes.NewReader(block))
+}
+
+// loadEXIFDirs decodes IFD0/IFD1 and follows the Exif, GPS and Interop pointers
+func loadEXIFDirs(x *exif.Exif) *exifDirs {
+	if x == nil || x.Tiff == nil || len(x.Tiff.Dirs) == 0 {
+		return nil
+	}
+
+	dirs := &exifDirs{
+		order: x.Tiff.Order,
+		raw:   x.Raw,
+		ifd0:  x.Tiff.Dirs[0],
+	}
+	dirs.exif = loadPointedDir(x, dirs.ifd0, tagExifIFDPointer)
+	dirs.gps = loadPointedDir(x, dirs.ifd0, tagGPSIFDPointer)
+	dirs.interop = loadPointedDir(x, dirs.exif, tagInteropIFDPointer)
+	if len(x.Tiff.Dirs) > 1 {
+		dirs.ifd1 = x.Tiff.Dirs[1]
+	}
+	return dirs
+}
+
+// collectEXIFTags returns a complete tag dump of every decoded IFD,
+// ordered IFD0, ExifIFD, GPS, Interop, IFD1
+func collectEXIFTags(dirs *exifDirs) *models.EXIFData {
+	if dirs == nil {
+		return nil
+	}
+
+	data := &models.EXIFData{
+		ByteOrder: byteOrderName(dirs.order),
+	}
+	data.Tags = appendDirTags(data.Tags, dirs.ifd0, ifd0Name, dirs.order)
+	data.Tags = appendDirTags(data.Tags, dirs.exif, exifIFDName, dirs.order)
+	data.Tags = appendDirTags(data.Tags, dirs.gps, gpsIFDName, dirs.order)
+	data.Tags = appendDirTags(data.Tags, dirs.interop, interopName, dirs.order)
+	data.Tags = appendDirTags(data.Tags, dirs.ifd1, ifd1Name, dirs.order)
+	return data
+}
+
+// loadPointedDir decodes the sub-IFD referenced by a pointer tag in dir
+func loadPointedDir(x *exif.Exif, dir *tiff.Dir, pointer uint16) *tiff.Dir {
+	tag := findTag(dir, pointer)
+	if tag == nil {
+		return nil
+	}
+	offset, err := tag.Int64(0)
+	if err != nil {
+		return nil
+	}
+	sub, _ := decodeDirAt(x.Raw, x.Tiff.Order, offset)
+	return sub
+}
+
+// decodeDirAt decodes the IFD at offset in a TIFF structure and returns it
+// with the offset of the next IFD in the chain
+func decodeDirAt(data []byte, order binary.ByteOrder, offset int64) (*tiff.Dir, int64) {
+	if offset <= 0 || offset >= int64(len(data)) {
+		return nil, 0
+	}
+	if !ifdFits(data, order, offset) {
+		return nil, 0
+	}
+	r := bytes.NewReader(data)
+	if _, err := r.Seek(offset, 0); err != nil {
+		return nil, 0
+	}
+	dir, next, err := tiff.DecodeDir(r, order)
+	if err != nil {
+		return nil, 0
+	}
+	return dir, int64(next)
+}
+
+// maxTIFFChain bounds the IFD chain decodeTIFF follows
+const maxTIFFChain = 64
+
+// decodeTIFF decodes the IFD chain of a TIFF structure with goexif once
+// every IFD in it has been checked with ifdFits
+func decodeTIFF(data []byte) (*tiff.Tiff, error) {
+	order := tiffByteOrder(data)
+	if order == nil || len(data) < 8 {
+		return nil, errors.New("tiff: could not read tiff byte order")
+	}
+	seen := make(map[int64]bool)
+	for offset := int64(order.Uint32(data[4:])); offset != 0; {
+		if seen[offset] || len(seen) == maxTIFFChain {
+			return nil, errors.New("tiff: IFD chain loops or is too long")
+		}
+		seen[offset] = true
+		if !ifdFits(data, order, offset) {
+			return nil, errors.New("tiff: IFD data too large")
+		}
+		offset = int64(order.Uint32(data[offset+2+12*int64(order.Uint16(data[offset:])):]))
+	}
+	return tiff.Decode(bytes.NewReader(data))
+}
+
+// ifdFits reports whether the IFD at offset and the values of all its
+// entries lie within data. goexif sizes value slices by the entry count
+// alone, so a count whose byte length overflows would exhaust memory.
+func ifdFits(data []byte, order binary.ByteOrder, offset int64) bool {
+	if offset <= 0 || offset+2 > int64(len(data)) {
+		return false
+	}
+	count := int64(order.Uint16(data[offset:]))
+	if offset+2+12*count+4 > int64(len(data)) {
+		return false
+	}
+	for i := int64(0); i < count; i++ {
+		pos := offset + 2 + 12*i
+		if !entryFits(data[pos:pos+12], order, int64(len(data))) {
+			return false
+		}
+	}
+	return true
+}
+
+// entryFits reports whether the value of a 12-byte IFD entry lies within
+// the first size bytes it is resolved against. Entries of unknown types
+// are left to goexif, which rejects them.
+func entryFits(entry []byte, order binary.ByteOrder, size int64) bool {
+	typeSize, ok := tiffTypeSizes[tiff.DataType(order.Uint16(entry[2:]))]
+	if !ok {
+		return true
+	}
+	length := uint64(order.Uint32(entry[4:])) * uint64(typeSize)
+	return length <= 4 || uint64(order.Uint32(entry[8:]))+length <= uint64(size)
+}
+
+// findTag returns the tag with the given ID in dir, or nil
+func findTag(dir *tiff.Dir, id uint16) *tiff.Tag {
+	if dir == nil {
+		return nil
+	}
+	for _, tag := range dir.Tags {
+		if tag.Id == id {
+			return tag
+		}
+	}
+	return nil
+}
+
+// appendDirTags converts every tag in dir to a models.EXIFTag
+func appendDirTags(tags []models.EXIFTag, dir *tiff.Dir, ifd string, order binary.ByteOrder) []models.EXIFTag {
+	if dir == nil {
+		return tags
+	}
+	names := tagNamesForIFD(ifd)
+	for _, tag := range dir.Tags {
+		name, ok := names[tag.Id]
+		if !ok {
+			name = fmt.Sprintf("Unknown (0x%04X)", tag.Id)
+		}
+
+		tags = append(tags, models.EXIFTag{
+			IFD:      ifd,
+			ID:       tag.Id,
+			Name:     name,
+			Type:     dataTypeName(tag.Type),
+			Count:    tag.Count,
+			RawValue: rawTagValue(tag),
+			Value:    describeTag(name, tag, order),
+		})
+	}
+	return tags
+}
+
+// dataTypeName returns the TIFF field type name
+func dataTypeName(dt tiff.DataType) string {
+	if name, ok := dataTypeNames[dt]; ok {
+		return name
+	}
+	return fmt.Sprintf("TYPE%d", dt)
+}
+
+// byteOrderName returns the TIFF byte order marker name
+func byteOrderName(order binary.ByteOrder) string {
+	if order == binary.BigEndian {
+		return "Big-endian (Motorola, MM)"
+	}
+	return "Little-endian (Intel, II)"
+}
+
+// rawTagValue formats the stored value of a tag without interpretation
+func rawTagValue(tag *tiff.Tag) string {
+	switch tag.Format() {
+	case tiff.StringVal:
+		val, _ := tag.StringVal()
+		return val
+	case tiff.IntVal:
+		return joinValues(int(tag.Count), func(i int) string {
+			v, _ := tag.Int64(i)
+			return strconv.FormatInt(v, 10)
+		})
+	case tiff.RatVal:
+		return joinValues(int(tag.Count), func(i int) string {
+			n, d, _ := tag.Rat2(i)
+			return fmt.Sprintf("%d/%d", n, d)
+		})
+	case tiff.FloatVal:
+		return joinValues(int(tag.Count), func(i int) string {
+			v, _ := tag.Float(i)
+			return strconv.FormatFloat(v, 'g', -1, 64)
+		})
+	default:
+		return hexPreview(tag.Val)
+	}
+}
+
+// joinValues joins up to maxRawValues formatted array elements
+func joinValues(count int, format func(i int) string) string {
+	shown := count
+	if shown > maxRawValues {
+		shown = maxRawValues
+	}
+	parts := make([]string, 0, shown)
+	for i := 0; i < shown; i++ {
+		parts = append(parts, format(i))
+	}
+	out := strings.Join(parts, " ")
+	if count > shown {
+		out += fmt.Sprintf(" … (%d values)", count)
+	}
+	return out
+}
+
+// hexPreview formats the first maxRawBytes bytes of b as hex
+func hexPreview(b []byte) string {
+	shown := b
+	if len(shown) > maxRawBytes {
+		shown = shown[:maxRawBytes]
+	}
+	parts := make([]string, len(shown))
+	for i, c := range shown {
+		parts[i] = fmt.Sprintf("%02x", c)
+	}
+	out := strings.Join(parts, " ")
+	if len(b) > len(shown) {
+		out += fmt.Sprintf(" … (%d bytes)", len(b))
+	}
+	return out
+}
+
+// formatFloat prints a float with at most four decimals and no trailing zeros
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(math.Round(v*10000)/10000, 'f', -1, 64)
+}
@@ -0,0 +1,371 @@
+package metadata
+
+import "github.com/rwcarlsen/goexif/tiff"
+
+// IFD names used in the EXIF tag dump
+const (
+	ifd0Name    = "IFD0"
+	ifd1Name    = "IFD1"
+	exifIFDName = "ExifIFD"
+	gpsIFDName  = "GPS"
+	interopName = "Interop"
+)
+
+// Pointer tags that link IFD0/ExifIFD to their sub-IFDs
+const (
+	tagExifIFDPointer    uint16 = 0x8769
+	tagGPSIFDPointer     uint16 = 0x8825
+	tagInteropIFDPointer uint16 = 0xA005
+)
+
+// IFD0 tags that stripping can keep
+const (
+	tagOrientation uint16 = 0x0112
+	tagCopyright   uint16 = 0x8298
+)
+
+// tiffTagNames maps TIFF baseline and extension tags found in IFD0/IFD1
+var tiffTagNames = map[uint16]string{
+	0x00FE: "NewSubfileType",
+	0x00FF: "SubfileType",
+	0x0100: "ImageWidth",
+	0x0101: "ImageHeight",
+	0x0102: "BitsPerSample",
+	0x0103: "Compression",
+	0x0106: "PhotometricInterpretation",
+	0x0107: "Thresholding",
+	0x010A: "FillOrder",
+	0x010D: "DocumentName",
+	0x010E: "ImageDescription",
+	0x010F: "Make",
+	0x0110: "Model",
+	0x0111: "StripOffsets",
+	0x0112: "Orientation",
+	0x0115: "SamplesPerPixel",
+	0x0116: "RowsPerStrip",
+	0x0117: "StripByteCounts",
+	0x011A: "XResolution",
+	0x011B: "YResolution",
+	0x011C: "PlanarConfiguration",
+	0x011D: "PageName",
+	0x0128: "ResolutionUnit",
+	0x0129: "PageNumber",
+	0x012D: "TransferFunction",
+	0x0131: "Software",
+	0x0132: "DateTime",
+	0x013B: "Artist",
+	0x013C: "HostComputer",
+	0x013D: "Predictor",
+	0x013E: "WhitePoint",
+	0x013F: "PrimaryChromaticities",
+	0x0140: "ColorMap",
+	0x0142: "TileWidth",
+	0x0143: "TileLength",
+	0x0144: "TileOffsets",
+	0x0145: "TileByteCounts",
+	0x014A: "SubIFDs",
+	0x0152: "ExtraSamples",
+	0x0153: "SampleFormat",
+	0x0201: "JPEGInterchangeFormat",
+	0x0202: "JPEGInterchangeFormatLength",
+	0x0211: "YCbCrCoefficients",
+	0x0212: "YCbCrSubSampling",
+	0x0213: "YCbCrPositioning",
+	0x0214: "ReferenceBlackWhite",
+	0x02BC: "ApplicationNotes",
+	0x4746: "Rating",
+	0x4749: "RatingPercent",
+	0x828D: "CFARepeatPatternDim",
+	0x828E: "CFAPattern2",
+	0x8298: "Copyright",
+	0x83BB: "IPTC-NAA",
+	0x8649: "PhotoshopSettings",
+	0x8769: "ExifIFDPointer",
+	0x8773: "ICCProfile",
+	0x8825: "GPSInfoIFDPointer",
+	0x9216: "TIFF-EPStandardID",
+	0x9217: "SensingMethod",
+	0x9C9B: "XPTitle",
+	0x9C9C: "XPComment",
+	0x9C9D: "XPAuthor",
+	0x9C9E: "XPKeywords",
+	0x9C9F: "XPSubject",
+	0xC4A5: "PrintIM",
+	0xC612: "DNGVersion",
+	0xC613: "DNGBackwardVersion",
+	0xC614: "UniqueCameraModel",
+	0xC615: "LocalizedCameraModel",
+	0xC621: "ColorMatrix1",
+	0xC622: "ColorMatrix2",
+	0xC627: "AnalogBalance",
+	0xC628: "AsShotNeutral",
+	0xC62A: "BaselineExposure",
+	0xC62F: "CameraSerialNumber",
+	0xC630: "DNGLensInfo",
+	0xC634: "DNGPrivateData",
+	0xC65A: "CalibrationIlluminant1",
+	0xC65B: "CalibrationIlluminant2",
+	0xC68B: "OriginalRawFileName",
+	0xC71A: "PreviewColorSpace",
+	0xEA1C: "Padding",
+	0xEA1D: "OffsetSchema",
+}
+
+// exifTagNames maps tags found in the Exif sub-IFD
+var exifTagNames = map[uint16]string{
+	0x829A: "ExposureTime",
+	0x829D: "FNumber",
+	0x8822: "ExposureProgram",
+	0x8824: "SpectralSensitivity",
+	0x8827: "ISO",
+	0x8828: "OECF",
+	0x8830: "SensitivityType",
+	0x8831: "StandardOutputSensitivity",
+	0x8832: "RecommendedExposureIndex",
+	0x8833: "ISOSpeed",
+	0x9000: "ExifVersion",
+	0x9003: "DateTimeOriginal",
+	0x9004: "DateTimeDigitized",
+	0x9010: "OffsetTime",
+	0x9011: "OffsetTimeOriginal",
+	0x9012: "OffsetTimeDigitized",
+	0x9101: "ComponentsConfiguration",
+	0x9102: "CompressedBitsPerPixel",
+	0x9201: "ShutterSpeedValue",
+	0x9202: "ApertureValue",
+	0x9203: "BrightnessValue",
+	0x9204: "ExposureBiasValue",
+	0x9205: "MaxApertureValue",
+	0x9206: "SubjectDistance",
+	0x9207: "MeteringMode",
+	0x9208: "LightSource",
+	0x9209: "Flash",
+	0x920A: "FocalLength",
+	0x9214: "SubjectArea",
+	0x927C: "MakerNote",
+	0x9286: "UserComment",
+	0x9290: "SubSecTime",
+	0x9291: "SubSecTimeOriginal",
+	0x9292: "SubSecTimeDigitized",
+	0x9400: "AmbientTemperature",
+	0x9401: "Humidity",
+	0x9402: "Pressure",
+	0x9403: "WaterDepth",
+	0x9404: "Acceleration",
+	0x9405: "CameraElevationAngle",
+	0xA000: "FlashpixVersion",
+	0xA001: "ColorSpace",
+	0xA002: "PixelXDimension",
+	0xA003: "PixelYDimension",
+	0xA004: "RelatedSoundFile",
+	0xA005: "InteropIFDPointer",
+	0xA20B: "FlashEnergy",
+	0xA20C: "SpatialFrequencyResponse",
+	0xA20E: "FocalPlaneXResolution",
+	0xA20F: "FocalPlaneYResolution",
+	0xA210: "FocalPlaneResolutionUnit",
+	0xA214: "SubjectLocation",
+	0xA215: "ExposureIndex",
+	0xA217: "SensingMethod",
+	0xA300: "FileSource",
+	0xA301: "SceneType",
+	0xA302: "CFAPattern",
+	0xA401: "CustomRendered",
+	0xA402: "ExposureMode",
+	0xA403: "WhiteBalance",
+	0xA404: "DigitalZoomRatio",
+	0xA405: "FocalLengthIn35mmFilm",
+	0xA406: "SceneCaptureType",
+	0xA407: "GainControl",
+	0xA408: "Contrast",
+	0xA409: "Saturation",
+	0xA40A: "Sharpness",
+	0xA40B: "DeviceSettingDescription",
+	0xA40C: "SubjectDistanceRange",
+	0xA420: "ImageUniqueID",
+	0xA430: "CameraOwnerName",
+	0xA431: "BodySerialNumber",
+	0xA432: "LensSpecification",
+	0xA433: "LensMake",
+	0xA434: "LensModel",
+	0xA435: "LensSerialNumber",
+	0xA460: "CompositeImage",
+	0xA461: "SourceImageNumberOfCompositeImage",
+	0xA462: "SourceExposureTimesOfCompositeImage",
+	0xA500: "Gamma",
+	0xEA1C: "Padding",
+	0xEA1D: "OffsetSchema",
+}
+
+// gpsTagNames maps tags found in the GPS sub-IFD
+var gpsTagNames = map[uint16]string{
+	0x0000: "GPSVersionID",
+	0x0001: "GPSLatitudeRef",
+	0x0002: "GPSLatitude",
+	0x0003: "GPSLongitudeRef",
+	0x0004: "GPSLongitude",
+	0x0005: "GPSAltitudeRef",
+	0x0006: "GPSAltitude",
+	0x0007: "GPSTimeStamp",
+	0x0008: "GPSSatellites",
+	0x0009: "GPSStatus",
+	0x000A: "GPSMeasureMode",
+	0x000B: "GPSDOP",
+	0x000C: "GPSSpeedRef",
+	0x000D: "GPSSpeed",
+	0x000E: "GPSTrackRef",
+	0x000F: "GPSTrack",
+	0x0010: "GPSImgDirectionRef",
+	0x0011: "GPSImgDirection",
+	0x0012: "GPSMapDatum",
+	0x0013: "GPSDestLatitudeRef",
+	0x0014: "GPSDestLatitude",
+	0x0015: "GPSDestLongitudeRef",
+	0x0016: "GPSDestLongitude",
+	0x0017: "GPSDestBearingRef",
+	0x0018: "GPSDestBearing",
+	0x0019: "GPSDestDistanceRef",
+	0x001A: "GPSDestDistance",
+	0x001B: "GPSProcessingMethod",
+	0x001C: "GPSAreaInformation",
+	0x001D: "GPSDateStamp",
+	0x001E: "GPSDifferential",
+	0x001F: "GPSHPositioningError",
+}
+
+// interopTagNames maps tags found in the Interoperability sub-IFD
+var interopTagNames = map[uint16]string{
+	0x0001: "InteropIndex",
+	0x0002: "InteropVersion",
+	0x1000: "RelatedImageFileFormat",
+	0x1001: "RelatedImageWidth",
+	0x1002: "RelatedImageHeight",
+}
+
+// tagNamesForIFD returns the name table used for the given IFD
+func tagNamesForIFD(ifd string) map[uint16]string {
+	switch ifd {
+	case exifIFDName:
+		return exifTagNames
+	case gpsIFDName:
+		return gpsTagNames
+	case interopName:
+		return interopTagNames
+	default:
+		return tiffTagNames
+	}
+}
+
+// dataTypeNames maps TIFF field types to their specification names
+var dataTypeNames = map[tiff.DataType]string{
+	tiff.DTByte:      "BYTE",
+	tiff.DTAscii:     "ASCII",
+	tiff.DTShort:     "SHORT",
+	tiff.DTLong:      "LONG",
+	tiff.DTRational:  "RATIONAL",
+	tiff.DTSByte:     "SBYTE",
+	tiff.DTUndefined: "UNDEFINED",
+	tiff.DTSShort:    "SSHORT",
+	tiff.DTSLong:     "SLONG",
+	tiff.DTSRational: "SRATIONAL",
+	tiff.DTFloat:     "FLOAT",
+	tiff.DTDouble:    "DOUBLE",
+}
+
+// exifTagTypes gives the types of the tags an edit may add to a file
+var exifTagTypes = map[string]tiff.DataType{
+	"ImageDescription":      tiff.DTAscii,
+	"Make":                  tiff.DTAscii,
+	"Model":                 tiff.DTAscii,
+	"Orientation":           tiff.DTShort,
+	"XResolution":           tiff.DTRational,
+	"YResolution":           tiff.DTRational,
+	"ResolutionUnit":        tiff.DTShort,
+	"Software":              tiff.DTAscii,
+	"DateTime":              tiff.DTAscii,
+	"Artist":                tiff.DTAscii,
+	"HostComputer":          tiff.DTAscii,
+	"Rating":                tiff.DTShort,
+	"RatingPercent":         tiff.DTShort,
+	"Copyright":             tiff.DTAscii,
+	"ExposureTime":          tiff.DTRational,
+	"FNumber":               tiff.DTRational,
+	"ExposureProgram":       tiff.DTShort,
+	"ISO":                   tiff.DTShort,
+	"DateTimeOriginal":      tiff.DTAscii,
+	"DateTimeDigitized":     tiff.DTAscii,
+	"OffsetTime":            tiff.DTAscii,
+	"OffsetTimeOriginal":    tiff.DTAscii,
+	"OffsetTimeDigitized":   tiff.DTAscii,
+	"ShutterSpeedValue":     tiff.DTSRational,
+	"ApertureValue":         tiff.DTRational,
+	"ExposureBiasValue":     tiff.DTSRational,
+	"MaxApertureValue":      tiff.DTRational,
+	"SubjectDistance":       tiff.DTRational,
+	"MeteringMode":          tiff.DTShort,
+	"LightSource":           tiff.DTShort,
+	"Flash":                 tiff.DTShort,
+	"FocalLength":           tiff.DTRational,
+	"UserComment":           tiff.DTUndefined,
+	"SubSecTime":            tiff.DTAscii,
+	"SubSecTimeOriginal":    tiff.DTAscii,
+	"SubSecTimeDigitized":   tiff.DTAscii,
+	"ColorSpace":            tiff.DTShort,
+	"ExposureMode":          tiff.DTShort,
+	"WhiteBalance":          tiff.DTShort,
+	"DigitalZoomRatio":      tiff.DTRational,
+	"FocalLengthIn35mmFilm": tiff.DTShort,
+	"SceneCaptureType":      tiff.DTShort,
+	"ImageUniqueID":         tiff.DTAscii,
+	"CameraOwnerName":       tiff.DTAscii,
+	"BodySerialNumber":      tiff.DTAscii,
+	"LensSpecification":     tiff.DTRational,
+	"LensMake":              tiff.DTAscii,
+	"LensModel":             tiff.DTAscii,
+	"LensSerialNumber":      tiff.DTAscii,
+	"GPSVersionID":          tiff.DTByte,
+	"GPSLatitudeRef":        tiff.DTAscii,
+	"GPSLatitude":           tiff.DTRational,
+	"GPSLongitudeRef":       tiff.DTAscii,
+	"GPSLongitude":          tiff.DTRational,
+	"GPSAltitudeRef":        tiff.DTByte,
+	"GPSAltitude":           tiff.DTRational,
+	"GPSTimeStamp":          tiff.DTRational,
+	"GPSSatellites":         tiff.DTAscii,
+	"GPSStatus":             tiff.DTAscii,
+	"GPSMeasureMode":        tiff.DTAscii,
+	"GPSDOP":                tiff.DTRational,
+	"GPSSpeedRef":           tiff.DTAscii,
+	"GPSSpeed":              tiff.DTRational,
+	"GPSTrackRef":           tiff.DTAscii,
+	"GPSTrack":              tiff.DTRational,
+	"GPSImgDirectionRef":    tiff.DTAscii,
+	"GPSImgDirection":       tiff.DTRational,
+	"GPSMapDatum":           tiff.DTAscii,
+	"GPSDestBearingRef":     tiff.DTAscii,
+	"GPSDestBearing":        tiff.DTRational,
+	"GPSDateStamp":          tiff.DTAscii,
+	"GPSHPositioningError":  tiff.DTRational,
+}
+
+// exifStructuralTags describe the layout of the file rather than the
+// image, so edits may not touch them
+var exifStructuralTags = map[string]bool{
+	"StripOffsets":                true,
+	"StripByteCounts":             true,
+	"TileOffsets":                 true,
+	"TileByteCounts":              true,
+	"SubIFDs":                     true,
+	"JPEGInterchangeFormat":       true,
+	"JPEGInterchangeFormatLength": true,
+	"ExifIFDPointer":              true,
+	"GPSInfoIFDPointer":           true,
+	"InteropIFDPointer":           true,
+	"MakerNote":                   true,
+	"IPTC-NAA":                    true,
+	"PhotoshopSettings":           true,
+	"ICCProfile":                  true,
+	"DNGPrivateData":              true,
+	"Padding":                     true,
+	"OffsetSchema":                true,
+}
@@ -0,0 +1,261 @@
+package metadata
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"testing"
+)
+
+// testTag is a raw TIFF tag used to build EXIF fixtures
+type testTag struct {
+	id    uint16
+	typ   uint16
+	count uint32
+	value []byte
+}
+
+// testIFD is a directory of tags; pointers maps a pointer tag ID to the
+// index of the IFD it should reference
+type testIFD struct {
+	tags     []testTag
+	pointers map[uint16]int
+}
+
+func asciiTag(id uint16, s string) testTag {
+	return testTag{id: id, typ: 2, count: uint32(len(s) + 1), value: append([]byte(s), 0)}
+}
+
+func shortTag(id uint16, v uint16) testTag {
+	b := make([]byte, 2)
+	binary.LittleEndian.PutUint16(b, v)
+	return testTag{id: id, typ: 3, count: 1, value: b}
+}
+
+func rationalTag(id uint16, vals ...uint32) testTag {
+	b := make([]byte, 4*len(vals))
+	for i, v := range vals {
+		binary.LittleEndian.PutUint32(b[i*4:], v)
+	}
+	return testTag{id: id, typ: 5, count: uint32(len(vals) / 2), value: b}
+}
+
+func undefinedTag(id uint16, b []byte) testTag {
+	return testTag{id: id, typ: 7, count: uint32(len(b)), value: b}
+}
+
+// buildTIFF lays out little-endian IFDs one after another; only the first
+// IFD is linked from the header, the rest must be reached via pointers
+func buildTIFF(ifds []testIFD) []byte {
+	size := func(ifd testIFD) int {
+		n := 2 + 12*len(ifd.tags) + 4
+		for _, t := range ifd.tags {
+			if len(t.value) > 4 {
+				n += len(t.value) + len(t.value)%2
+			}
+		}
+		return n
+	}
+
+	offsets := make([]int, len(ifds))
+	pos := 8
+	for i, ifd := range ifds {
+		offsets[i] = pos
+		pos += size(ifd) + len(ifd.pointers)*12
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("II*\x00")
+	binary.Write(&buf, binary.LittleEndian, uint32(8))
+
+	for i, ifd := range ifds {
+		tags := append([]testTag{}, ifd.tags...)
+		for id, target := range ifd.pointers {
+			b := make([]byte, 4)
+			binary.LittleEndian.PutUint32(b, uint32(offsets[target]))
+			tags = append(tags, testTag{id: id, typ: 4, count: 1, value: b})
+		}
+
+		dataPos := offsets[i] + 2 + 12*len(tags) + 4
+		var data bytes.Buffer
+		binary.Write(&buf, binary.LittleEndian, uint16(len(tags)))
+		for _, t := range tags {
+			binary.Write(&buf, binary.LittleEndian, t.id)
+			binary.Write(&buf, binary.LittleEndian, t.typ)
+			binary.Write(&buf, binary.LittleEndian, t.count)
+			if len(t.value) > 4 {
+				binary.Write(&buf, binary.LittleEndian, uint32(dataPos+data.Len()))
+				data.Write(t.value)
+				if len(t.value)%2 == 1 {
+					data.WriteByte(0)
+				}
+			} else {
+				field := make([]byte, 4)
+				copy(field, t.value)
+				buf.Write(field)
+			}
+		}
+		binary.Write(&buf, binary.LittleEndian, uint32(0))
+		buf.Write(data.Bytes())
+	}
+
+	return buf.Bytes()
+}
+
+// buildJPEGWithSegments encodes a small JPEG and inserts raw marker
+// segments right after SOI
+func buildJPEGWithSegments(t *testing.T, segments ...[]byte) []byte {
+	t.Helper()
+
+	img := image.NewRGBA(image.Rect(0, 0, 16, 8))
+	for i := range img.Pix {
+		img.Pix[i] = 0x80
+	}
+	img.Set(0, 0, color.RGBA{R: 255, A: 255})
+
+	var enc bytes.Buffer
+	if err := jpeg.Encode(&enc, img, &jpeg.Options{Quality: 90}); err != nil {
+		t.Fatalf("jpeg.Encode: %v", err)
+	}
+
+	out := append([]byte{}, enc.Bytes()[:2]...)
+	for _, seg := range segments {
+		out = append(out, seg...)
+	}
+	return append(out, enc.Bytes()[2:]...)
+}
+
+// jpegSegment wraps payload in a JPEG marker segment
+func jpegSegment(marker byte, payload []byte) []byte {
+	seg := []byte{0xFF, marker, 0, 0}
+	binary.BigEndian.PutUint16(seg[2:], uint16(len(payload)+2))
+	return append(seg, payload...)
+}
+
+// exifSegment wraps a TIFF block in an APP1 Exif segment
+func exifSegment(tiffData []byte) []byte {
+	return jpegSegment(0xE1, append([]byte("Exif\x00\x00"), tiffData...))
+}
+
+func TestExtractMetadataEXIFDump(t *testing.T) {
+	tiffData := buildTIFF([]testIFD{
+		{
+			tags: []testTag{
+				asciiTag(0x010F, "TestMaker"),
+				shortTag(0x0112, 6),
+			},
+			pointers: map[uint16]int{tagExifIFDPointer: 1, tagGPSIFDPointer: 2},
+		},
+		{
+			tags: []testTag{
+				rationalTag(0x829A, 1, 250),
+				rationalTag(0x829D, 28, 10),
+				shortTag(0x8827, 200),
+				undefinedTag(0x9000, []byte("0232")),
+				shortTag(0xBEEF, 7),
+			},
+		},
+		{
+			tags: []testTag{
+				asciiTag(0x0001, "N"),
+				rationalTag(0x0002, 37, 1, 46, 1, 2964, 100),
+			},
+		},
+	})
+
+	data := buildJPEGWithSegments(t, exifSegment(tiffData))
+	meta := ExtractMetadata(data, "image/jpeg", "test.jpg")
+
+	if meta.DecodeError != "" {
+		t.Fatalf("unexpected decode error: %s", meta.DecodeError)
+	}
+	if meta.EXIF == nil {
+		t.Fatal("expected EXIF section")
+	}
+
+	want := map[string]struct{ ifd, value string }{
+		"Make":             {ifd0Name, "TestMaker"},
+		"Orientation":      {ifd0Name, "Rotate 90 CW"},
+		"ExposureTime":     {exifIFDName, "1/250 s"},
+		"FNumber":          {exifIFDName, "f/2.8"},
+		"ISO":              {exifIFDName, "200"},
+		"ExifVersion":      {exifIFDName, "2.32"},
+		"Unknown (0xBEEF)": {exifIFDName, "7"},
+		"GPSLatitudeRef":   {gpsIFDName, "North"},
+		"GPSLatitude":      {gpsIFDName, "37° 46′ 29.64″"},
+	}
+
+	found := make(map[string]bool)
+	for _, tag := range meta.EXIF.Tags {
+		w, ok := want[tag.Name]
+		if !ok {
+			continue
+		}
+		found[tag.Name] = true
+		if tag.IFD != w.ifd {
+			t.Errorf("%s: IFD = %s, want %s", tag.Name, tag.IFD, w.ifd)
+		}
+		if tag.Value != w.value {
+			t.Errorf("%s: Value = %q, want %q", tag.Name, tag.Value, w.value)
+		}
+	}
+	for name := range want {
+		if !found[name] {
+			t.Errorf("tag %s missing from dump", name)
+		}
+	}
+
+	cam := meta.Camera
+	if cam == nil {
+		t.Fatal("expected Camera section")
+	}
+	if cam.Make != "TestMaker" || cam.ExposureTime != "1/250 s" || cam.Aperture != "f/2.8" || cam.ISO != 200 {
+		t.Errorf("Camera = %+v", *cam)
+	}
+}
+
+func TestDecodeFlash(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    int
+		expected string
+	}{
+		{"no_flash", 0x00, "Did not fire"},
+		{"fired", 0x01, "Fired"},
+		{"fired_return", 0x0F, "Fired, Compulsory flash firing, Return detected"},
+		{"auto_red_eye", 0x59, "Fired, Auto mode, Red-eye reduction"},
+		{"no_function", 0x20, "No flash function"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := flashToString(tt.input)
+			if result != tt.expected {
+				t.Errorf("flashToString(0x%02X) = %q, want %q", tt.input, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestFormatExposureTime(t *testing.T) {
+	tests := []struct {
+		input    float64
+		expected string
+	}{
+		{0.004, "1/250 s"},
+		{1.0 / 3, "1/3 s"},
+		{0.5, "1/2 s"},
+		{0.8, "0.8 s"},
+		{30, "30 s"},
+		{0, ""},
+	}
+
+	for _, tt := range tests {
+		result := formatExposureTime(tt.input)
+		if result != tt.expected {
+			t.Errorf("formatExposureTime(%v) = %q, want %q", tt.input, result, tt.expected)
+		}
+	}
+}
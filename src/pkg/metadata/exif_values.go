@@ -0,0 +1,589 @@
+package metadata
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/ahrdadan/image-metadata-viewer/src/internal/models"
+	"github.com/rwcarlsen/goexif/tiff"
+)
+
+// tagDescriber turns a tag into a human-readable value
+type tagDescriber func(tag *tiff.Tag, order binary.ByteOrder) string
+
+// tagDescribers holds the per-tag interpretation rules, keyed by tag name
+var tagDescribers = map[string]tagDescriber{
+	"Orientation":               intDescriber(orientationToString),
+	"ResolutionUnit":            intDescriber(resolutionUnitToString),
+	"FocalPlaneResolutionUnit":  intDescriber(resolutionUnitToString),
+	"Compression":               enumDescriber(compressionNames),
+	"PhotometricInterpretation": enumDescriber(photometricNames),
+	"PlanarConfiguration":       enumDescriber(map[int]string{1: "Chunky", 2: "Planar"}),
+	"YCbCrPositioning":          enumDescriber(map[int]string{1: "Centered", 2: "Co-sited"}),
+	"YCbCrSubSampling":          describeYCbCrSubSampling,
+	"ExposureProgram":           enumDescriber(exposureProgramNames),
+	"MeteringMode":              enumDescriber(meteringModeNames),
+	"LightSource":               enumDescriber(lightSourceNames),
+	"CalibrationIlluminant1":    enumDescriber(lightSourceNames),
+	"CalibrationIlluminant2":    enumDescriber(lightSourceNames),
+	"Flash":                     intDescriber(flashToString),
+	"ColorSpace":                enumDescriber(colorSpaceNames),
+	"SensingMethod":             enumDescriber(sensingMethodNames),
+	"SensitivityType":           enumDescriber(sensitivityTypeNames),
+	"CustomRendered":            enumDescriber(map[int]string{0: "Normal", 1: "Custom"}),
+	"ExposureMode":              enumDescriber(map[int]string{0: "Auto", 1: "Manual", 2: "Auto bracket"}),
+	"WhiteBalance":              enumDescriber(map[int]string{0: "Auto", 1: "Manual"}),
+	"SceneCaptureType":          enumDescriber(map[int]string{0: "Standard", 1: "Landscape", 2: "Portrait", 3: "Night", 4: "Other"}),
+	"GainControl":               enumDescriber(map[int]string{0: "None", 1: "Low gain up", 2: "High gain up", 3: "Low gain down", 4: "High gain down"}),
+	"Contrast":                  enumDescriber(map[int]string{0: "Normal", 1: "Low", 2: "High"}),
+	"Saturation":                enumDescriber(map[int]string{0: "Normal", 1: "Low", 2: "High"}),
+	"Sharpness":                 enumDescriber(map[int]string{0: "Normal", 1: "Soft", 2: "Hard"}),
+	"SubjectDistanceRange":      enumDescriber(map[int]string{0: "Unknown", 1: "Macro", 2: "Close", 3: "Distant"}),
+	"CompositeImage":            enumDescriber(map[int]string{0: "Unknown", 1: "Not a composite image", 2: "General composite image", 3: "Composite image captured while shooting"}),
+	"FileSource":                enumDescriber(map[int]string{1: "Film scanner", 2: "Reflection print scanner", 3: "Digital camera"}),
+	"SceneType":                 enumDescriber(map[int]string{1: "Directly photographed"}),
+	"GPSAltitudeRef":            enumDescriber(map[int]string{0: "Above sea level", 1: "Below sea level"}),
+	"GPSDifferential":           enumDescriber(map[int]string{0: "No correction", 1: "Differential corrected"}),
+	"ExposureTime":              ratDescriber(formatExposureTime),
+	"FNumber":                   ratDescriber(formatFNumber),
+	"FocalLength":               ratDescriber(formatFocalLength),
+	"FocalLengthIn35mmFilm":     intDescriber(func(v int) string { return fmt.Sprintf("%d mm", v) }),
+	"ExposureBiasValue":         ratDescriber(formatExposureBias),
+	"ShutterSpeedValue":         ratDescriber(func(v float64) string { return formatExposureTime(math.Pow(2, -v)) }),
+	"ApertureValue":             ratDescriber(formatAPEXAperture),
+	"MaxApertureValue":          ratDescriber(formatAPEXAperture),
+	"SubjectDistance":           ratDescriber(func(v float64) string { return formatFloat(v) + " m" }),
+	"DigitalZoomRatio":          ratDescriber(func(v float64) string { return formatFloat(v) + "x" }),
+	"LensSpecification":         describeLensSpecification,
+	"DNGLensInfo":               describeLensSpecification,
+	"ExifVersion":               describeVersion,
+	"FlashpixVersion":           describeVersion,
+	"InteropVersion":            describeVersion,
+	"ComponentsConfiguration":   describeComponentsConfiguration,
+	"UserComment":               describeEncodedText,
+	"GPSProcessingMethod":       describeEncodedText,
+	"GPSAreaInformation":        describeEncodedText,
+	"XPTitle":                   describeXPString,
+	"XPComment":                 describeXPString,
+	"XPAuthor":                  describeXPString,
+	"XPKeywords":                describeXPString,
+	"XPSubject":                 describeXPString,
+	"GPSVersionID":              describeByteVersion,
+	"DNGVersion":                describeByteVersion,
+	"DNGBackwardVersion":        describeByteVersion,
+	"GPSLatitude":               describeDegrees,
+	"GPSLongitude":              describeDegrees,
+	"GPSDestLatitude":           describeDegrees,
+	"GPSDestLongitude":          describeDegrees,
+	"GPSTimeStamp":              describeGPSTime,
+	"GPSLatitudeRef":            stringEnumDescriber(map[string]string{"N": "North", "S": "South"}),
+	"GPSDestLatitudeRef":        stringEnumDescriber(map[string]string{"N": "North", "S": "South"}),
+	"GPSLongitudeRef":           stringEnumDescriber(map[string]string{"E": "East", "W": "West"}),
+	"GPSDestLongitudeRef":       stringEnumDescriber(map[string]string{"E": "East", "W": "West"}),
+	"GPSSpeedRef":               stringEnumDescriber(map[string]string{"K": "km/h", "M": "mph", "N": "knots"}),
+	"GPSDestDistanceRef":        stringEnumDescriber(map[string]string{"K": "Kilometers", "M": "Miles", "N": "Nautical miles"}),
+	"GPSTrackRef":               stringEnumDescriber(directionRefNames),
+	"GPSImgDirectionRef":        stringEnumDescriber(directionRefNames),
+	"GPSDestBearingRef":         stringEnumDescriber(directionRefNames),
+	"GPSStatus":                 stringEnumDescriber(map[string]string{"A": "Measurement active", "V": "Measurement void"}),
+	"GPSMeasureMode":            stringEnumDescriber(map[string]string{"2": "2-dimensional", "3": "3-dimensional"}),
+	"GPSAltitude":               ratDescriber(func(v float64) string { return formatFloat(v) + " m" }),
+	"MakerNote":                 describeBinary,
+	"PrintIM":                   describeBinary,
+	"DNGPrivateData":            describeBinary,
+	"ApplicationNotes":          describeBinary,
+	"IPTC-NAA":                  describeBinary,
+	"PhotoshopSettings":         describeBinary,
+	"ICCProfile":                describeBinary,
+	"Padding":                   describeBinary,
+}
+
+var compressionNames = map[int]string{
+	1:     "Uncompressed",
+	2:     "CCITT 1D",
+	3:     "T4/Group 3 Fax",
+	4:     "T6/Group 4 Fax",
+	5:     "LZW",
+	6:     "JPEG (old-style)",
+	7:     "JPEG",
+	8:     "Adobe Deflate",
+	32773: "PackBits",
+	32946: "Deflate",
+	34712: "JPEG 2000",
+	34713: "Nikon NEF Compressed",
+	34892: "Lossy JPEG",
+	65000: "Kodak DCR Compressed",
+}
+
+var photometricNames = map[int]string{
+	0:     "WhiteIsZero",
+	1:     "BlackIsZero",
+	2:     "RGB",
+	3:     "RGB Palette",
+	4:     "Transparency Mask",
+	5:     "CMYK",
+	6:     "YCbCr",
+	8:     "CIELab",
+	9:     "ICCLab",
+	10:    "ITULab",
+	32803: "Color Filter Array",
+	34892: "Linear Raw",
+}
+
+var exposureProgramNames = map[int]string{
+	0: "Not defined",
+	1: "Manual",
+	2: "Program AE",
+	3: "Aperture-priority AE",
+	4: "Shutter speed priority AE",
+	5: "Creative (slow speed)",
+	6: "Action (high speed)",
+	7: "Portrait",
+	8: "Landscape",
+	9: "Bulb",
+}
+
+var meteringModeNames = map[int]string{
+	0:   "Unknown",
+	1:   "Average",
+	2:   "Center-weighted average",
+	3:   "Spot",
+	4:   "Multi-spot",
+	5:   "Multi-segment",
+	6:   "Partial",
+	255: "Other",
+}
+
+var lightSourceNames = map[int]string{
+	0:   "Unknown",
+	1:   "Daylight",
+	2:   "Fluorescent",
+	3:   "Tungsten (incandescent)",
+	4:   "Flash",
+	9:   "Fine weather",
+	10:  "Cloudy",
+	11:  "Shade",
+	12:  "Daylight fluorescent",
+	13:  "Day white fluorescent",
+	14:  "Cool white fluorescent",
+	15:  "White fluorescent",
+	16:  "Warm white fluorescent",
+	17:  "Standard light A",
+	18:  "Standard light B",
+	19:  "Standard light C",
+	20:  "D55",
+	21:  "D65",
+	22:  "D75",
+	23:  "D50",
+	24:  "ISO studio tungsten",
+	255: "Other",
+}
+
+var colorSpaceNames = map[int]string{
+	1:      "sRGB",
+	2:      "Adobe RGB",
+	0xFFFF: "Uncalibrated",
+}
+
+var sensingMethodNames = map[int]string{
+	1: "Not defined",
+	2: "One-chip color area",
+	3: "Two-chip color area",
+	4: "Three-chip color area",
+	5: "Color sequential area",
+	7: "Trilinear",
+	8: "Color sequential linear",
+}
+
+var sensitivityTypeNames = map[int]string{
+	0: "Unknown",
+	1: "Standard output sensitivity",
+	2: "Recommended exposure index",
+	3: "ISO speed",
+	4: "SOS and REI",
+	5: "SOS and ISO speed",
+	6: "REI and ISO speed",
+	7: "SOS, REI and ISO speed",
+}
+
+var directionRefNames = map[string]string{
+	"T": "True direction",
+	"M": "Magnetic direction",
+}
+
+// describeTag returns the human-readable value of a tag
+func describeTag(name string, tag *tiff.Tag, order binary.ByteOrder) string {
+	if describe, ok := tagDescribers[name]; ok {
+		if val := describe(tag, order); val != "" {
+			return val
+		}
+	}
+	return defaultTagValue(tag)
+}
+
+// defaultTagValue formats a tag with no specific interpretation rule
+func defaultTagValue(tag *tiff.Tag) string {
+	switch tag.Format() {
+	case tiff.StringVal:
+		val, _ := tag.StringVal()
+		return strings.TrimSpace(val)
+	case tiff.IntVal:
+		return joinValues(int(tag.Count), func(i int) string {
+			v, _ := tag.Int64(i)
+			return fmt.Sprintf("%d", v)
+		})
+	case tiff.RatVal:
+		return joinValues(int(tag.Count), func(i int) string {
+			return formatFloat(ratValue(tag, i))
+		})
+	case tiff.FloatVal:
+		return joinValues(int(tag.Count), func(i int) string {
+			v, _ := tag.Float(i)
+			return formatFloat(v)
+		})
+	default:
+		if text, ok := printableText(tag.Val); ok {
+			return text
+		}
+		return describeBinary(tag, nil)
+	}
+}
+
+// intDescriber applies format to the first integer value of a tag
+func intDescriber(format func(int) string) tagDescriber {
+	return func(tag *tiff.Tag, _ binary.ByteOrder) string {
+		v, err := tag.Int(0)
+		if err != nil {
+			return ""
+		}
+		return format(v)
+	}
+}
+
+// enumDescriber maps the first integer value of a tag through names
+func enumDescriber(names map[int]string) tagDescriber {
+	return intDescriber(func(v int) string {
+		return lookupName(names, v)
+	})
+}
+
+// lookupName returns the name for v, or "Unknown (v)"
+func lookupName(names map[int]string, v int) string {
+	if name, ok := names[v]; ok {
+		return name
+	}
+	return fmt.Sprintf("Unknown (%d)", v)
+}
+
+// stringEnumDescriber maps an ASCII tag value through names
+func stringEnumDescriber(names map[string]string) tagDescriber {
+	return func(tag *tiff.Tag, _ binary.ByteOrder) string {
+		v, err := tag.StringVal()
+		if err != nil {
+			return ""
+		}
+		if name, ok := names[strings.TrimSpace(v)]; ok {
+			return name
+		}
+		return v
+	}
+}
+
+// ratDescriber applies format to the first rational value of a tag
+func ratDescriber(format func(float64) string) tagDescriber {
+	return func(tag *tiff.Tag, _ binary.ByteOrder) string {
+		if tag.Format() != tiff.RatVal || tag.Count == 0 {
+			return ""
+		}
+		n, d, _ := tag.Rat2(0)
+		if d == 0 {
+			return "undefined"
+		}
+		return format(float64(n) / float64(d))
+	}
+}
+
+// ratValue returns the i'th rational value of a tag as float, 0 for x/0
+func ratValue(tag *tiff.Tag, i int) float64 {
+	n, d, err := tag.Rat2(i)
+	if err != nil || d == 0 {
+		return 0
+	}
+	return float64(n) / float64(d)
+}
+
+// formatExposureTime formats seconds the way cameras display shutter speed
+func formatExposureTime(seconds float64) string {
+	if seconds <= 0 {
+		return ""
+	}
+	inv := 1 / seconds
+	if seconds < 0.25 || (seconds < 1 && math.Abs(inv-math.Round(inv)) < 0.01) {
+		return fmt.Sprintf("1/%d s", int(math.Round(inv)))
+	}
+	return formatFloat(seconds) + " s"
+}
+
+// formatFNumber formats an aperture value as f/N
+func formatFNumber(v float64) string {
+	if v <= 0 {
+		return ""
+	}
+	return fmt.Sprintf("f/%s", strings.TrimSuffix(fmt.Sprintf("%.1f", v), ".0"))
+}
+
+// formatAPEXAperture converts an APEX aperture value to f/N
+func formatAPEXAperture(v float64) string {
+	return formatFNumber(math.Pow(math.Sqrt2, v))
+}
+
+// formatFocalLength formats a focal length in millimeters
+func formatFocalLength(v float64) string {
+	if v <= 0 {
+		return ""
+	}
+	return fmt.Sprintf("%s mm", strings.TrimSuffix(fmt.Sprintf("%.1f", v), ".0"))
+}
+
+// formatExposureBias formats an exposure compensation in EV
+func formatExposureBias(v float64) string {
+	if v == 0 {
+		return "0 EV"
+	}
+	return fmt.Sprintf("%+.2g EV", v)
+}
+
+// flashToString decodes the EXIF Flash bit field into a readable string
+func flashToString(v int) string {
+	return decodeFlash(v).Description
+}
+
+// decodeFlash splits the EXIF Flash bit field into its component flags
+func decodeFlash(v int) models.Flash {
+	flash := models.Flash{
+		Raw:             v,
+		Fired:           v&0x01 != 0,
+		FunctionPresent: v&0x20 == 0,
+		RedEyeReduction: v&0x40 != 0,
+	}
+
+	switch (v >> 3) & 0x03 {
+	case 1:
+		flash.Mode = "Compulsory flash firing"
+	case 2:
+		flash.Mode = "Compulsory flash suppression"
+	case 3:
+		flash.Mode = "Auto mode"
+	}
+
+	switch (v >> 1) & 0x03 {
+	case 2:
+		flash.Return = "Return not detected"
+	case 3:
+		flash.Return = "Return detected"
+	}
+
+	if !flash.FunctionPresent {
+		flash.Description = "No flash function"
+		return flash
+	}
+
+	parts := []string{"Did not fire"}
+	if flash.Fired {
+		parts[0] = "Fired"
+	}
+	for _, part := range []string{flash.Mode, flash.Return} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	if flash.RedEyeReduction {
+		parts = append(parts, "Red-eye reduction")
+	}
+	flash.Description = strings.Join(parts, ", ")
+
+	return flash
+}
+
+// describeYCbCrSubSampling formats the horizontal/vertical chroma factors
+func describeYCbCrSubSampling(tag *tiff.Tag, _ binary.ByteOrder) string {
+	if tag.Count < 2 {
+		return ""
+	}
+	h, err1 := tag.Int(0)
+	v, err2 := tag.Int(1)
+	if err1 != nil || err2 != nil {
+		return ""
+	}
+	return chromaSubsampling(h, v)
+}
+
+// chromaSubsampling converts chroma subsampling factors to J:a:b notation
+func chromaSubsampling(h, v int) string {
+	switch {
+	case h == 1 && v == 1:
+		return "YCbCr 4:4:4"
+	case h == 2 && v == 1:
+		return "YCbCr 4:2:2"
+	case h == 2 && v == 2:
+		return "YCbCr 4:2:0"
+	case h == 4 && v == 1:
+		return "YCbCr 4:1:1"
+	case h == 1 && v == 2:
+		return "YCbCr 4:4:0"
+	default:
+		return fmt.Sprintf("YCbCr (%d×%d)", h, v)
+	}
+}
+
+// describeLensSpecification formats min/max focal length and aperture
+func describeLensSpecification(tag *tiff.Tag, _ binary.ByteOrder) string {
+	if tag.Format() != tiff.RatVal || tag.Count < 4 {
+		return ""
+	}
+	minFocal, maxFocal := ratValue(tag, 0), ratValue(tag, 1)
+	minAperture, maxAperture := ratValue(tag, 2), ratValue(tag, 3)
+
+	var parts []string
+	switch {
+	case minFocal > 0 && maxFocal > 0 && minFocal != maxFocal:
+		parts = append(parts, fmt.Sprintf("%s-%smm", formatFloat(minFocal), formatFloat(maxFocal)))
+	case minFocal > 0:
+		parts = append(parts, formatFloat(minFocal)+"mm")
+	}
+	switch {
+	case minAperture > 0 && maxAperture > 0 && minAperture != maxAperture:
+		parts = append(parts, fmt.Sprintf("%s-%s", formatFNumber(minAperture), strings.TrimPrefix(formatFNumber(maxAperture), "f/")))
+	case minAperture > 0:
+		parts = append(parts, formatFNumber(minAperture))
+	}
+	return strings.Join(parts, " ")
+}
+
+// describeVersion formats four-character version strings such as "0232"
+func describeVersion(tag *tiff.Tag, _ binary.ByteOrder) string {
+	if len(tag.Val) != 4 {
+		return ""
+	}
+	v := string(tag.Val)
+	major := strings.TrimLeft(v[:2], "0")
+	if major == "" {
+		major = "0"
+	}
+	return major + "." + v[2:]
+}
+
+// describeByteVersion formats byte-array versions such as GPSVersionID
+func describeByteVersion(tag *tiff.Tag, _ binary.ByteOrder) string {
+	parts := make([]string, 0, len(tag.Val))
+	for _, b := range tag.Val {
+		parts = append(parts, fmt.Sprintf("%d", b))
+	}
+	return strings.Join(parts, ".")
+}
+
+// describeComponentsConfiguration names the channels of compressed data
+func describeComponentsConfiguration(tag *tiff.Tag, _ binary.ByteOrder) string {
+	names := []string{"-", "Y", "Cb", "Cr", "R", "G", "B"}
+	parts := make([]string, 0, len(tag.Val))
+	for _, b := range tag.Val {
+		if int(b) < len(names) {
+			parts = append(parts, names[b])
+		} else {
+			parts = append(parts, "?")
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// describeEncodedText decodes text prefixed with an 8-byte character code
+func describeEncodedText(tag *tiff.Tag, order binary.ByteOrder) string {
+	if len(tag.Val) < 8 {
+		return ""
+	}
+	return decodeEncodedText(tag.Val, order)
+}
+
+// decodeEncodedText decodes the UserComment-style charset prefix
+func decodeEncodedText(val []byte, order binary.ByteOrder) string {
+	code, body := string(val[:8]), val[8:]
+	switch {
+	case strings.HasPrefix(code, "ASCII"):
+		return strings.TrimSpace(strings.TrimRight(string(body), "\x00"))
+	case strings.HasPrefix(code, "UNICODE"):
+		if order == nil {
+			order = binary.BigEndian
+		}
+		return strings.TrimSpace(decodeUTF16(body, order))
+	default:
+		text := strings.TrimSpace(strings.TrimRight(string(body), "\x00"))
+		if utf8.ValidString(text) {
+			return text
+		}
+		return ""
+	}
+}
+
+// describeXPString decodes Windows XP tags stored as UTF-16LE bytes
+func describeXPString(tag *tiff.Tag, _ binary.ByteOrder) string {
+	return decodeUTF16(tag.Val, binary.LittleEndian)
+}
+
+// decodeUTF16 decodes a NUL-terminated UTF-16 byte string
+func decodeUTF16(b []byte, order binary.ByteOrder) string {
+	units := make([]uint16, 0, len(b)/2)
+	for i := 0; i+1 < len(b); i += 2 {
+		u := order.Uint16(b[i:])
+		if u == 0 {
+			break
+		}
+		units = append(units, u)
+	}
+	return string(utf16.Decode(units))
+}
+
+// describeDegrees formats a degrees/minutes/seconds rational triple
+func describeDegrees(tag *tiff.Tag, _ binary.ByteOrder) string {
+	if tag.Format() != tiff.RatVal || tag.Count < 3 {
+		return ""
+	}
+	deg, mins, sec := ratValue(tag, 0), ratValue(tag, 1), ratValue(tag, 2)
+	return fmt.Sprintf("%s° %s′ %s″", formatFloat(deg), formatFloat(mins), formatFloat(sec))
+}
+
+// describeGPSTime formats the hour/minute/second rational triple as UTC
+func describeGPSTime(tag *tiff.Tag, _ binary.ByteOrder) string {
+	if tag.Format() != tiff.RatVal || tag.Count < 3 {
+		return ""
+	}
+	h, m, s := ratValue(tag, 0), ratValue(tag, 1), ratValue(tag, 2)
+	whole := math.Floor(s)
+	out := fmt.Sprintf("%02d:%02d:%02d", int(h), int(m), int(whole))
+	if frac := s - whole; frac > 0 {
+		out += strings.TrimPrefix(formatFloat(frac), "0")
+	}
+	return out + " UTC"
+}
+
+// describeBinary summarizes an opaque binary tag by its size
+func describeBinary(tag *tiff.Tag, _ binary.ByteOrder) string {
+	return fmt.Sprintf("(binary data, %d bytes)", len(tag.Val))
+}
+
+// printableText returns b as text when it is printable UTF-8
+func printableText(b []byte) (string, bool) {
+	text := strings.TrimRight(string(b), "\x00 ")
+	if text == "" || !utf8.ValidString(text) {
+		return "", false
+	}
+	for _, r := range text {
+		if r < 0x20 && r != '\n' && r != '\r' && r != '\t' {
+			return "", false
+		}
+	}
+	return text, true
+}
@@ -0,0 +1,449 @@
+package metadata
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/rwcarlsen/goexif/tiff"
+)
+
+// exifEntry is a tag to write into a TIFF block, with its value in big
+// endian order
+type exifEntry struct {
+	id    uint16
+	typ   tiff.DataType
+	count uint32
+	value []byte
+}
+
+// exifASCII is a NUL-terminated ASCII tag
+func exifASCII(id uint16, s string) exifEntry {
+	return exifEntry{id: id, typ: tiff.DTAscii, count: uint32(len(s) + 1), value: append([]byte(s), 0)}
+}
+
+// exifShort is a single SHORT tag
+func exifShort(id uint16, v uint16) exifEntry {
+	return exifEntry{id: id, typ: tiff.DTShort, count: 1, value: binary.BigEndian.AppendUint16(nil, v)}
+}
+
+// encodeTIFF writes a big-endian TIFF block whose IFD0 holds entries
+func encodeTIFF(ifd0 []exifEntry) []byte {
+	entries := append([]exifEntry{}, ifd0...)
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].id < entries[j].id })
+
+	out := []byte("MM\x00\x2A\x00\x00\x00\x08")
+	dataPos := 8 + 2 + 12*len(entries) + 4
+	var values []byte
+	out = binary.BigEndian.AppendUint16(out, uint16(len(entries)))
+	for _, e := range entries {
+		out = binary.BigEndian.AppendUint16(out, e.id)
+		out = binary.BigEndian.AppendUint16(out, uint16(e.typ))
+		out = binary.BigEndian.AppendUint32(out, e.count)
+		if len(e.value) <= 4 {
+			field := make([]byte, 4)
+			copy(field, e.value)
+			out = append(out, field...)
+			continue
+		}
+		out = binary.BigEndian.AppendUint32(out, uint32(dataPos+len(values)))
+		values = append(values, e.value...)
+		if len(e.value)%2 == 1 {
+			values = append(values, 0)
+		}
+	}
+	out = binary.BigEndian.AppendUint32(out, 0) // no IFD1
+	return append(out, values...)
+}
+
+// tiffTypeSizes is the size in bytes of one value of each TIFF field type
+var tiffTypeSizes = map[tiff.DataType]int{
+	tiff.DTByte:      1,
+	tiff.DTAscii:     1,
+	tiff.DTShort:     2,
+	tiff.DTLong:      4,
+	tiff.DTRational:  8,
+	tiff.DTSByte:     1,
+	tiff.DTUndefined: 1,
+	tiff.DTSShort:    2,
+	tiff.DTSLong:     4,
+	tiff.DTSRational: 8,
+	tiff.DTFloat:     4,
+	tiff.DTDouble:    8,
+}
+
+// exifEdit sets or, with a nil value, removes a tag of IFD0, the Exif
+// IFD or the GPS IFD
+type exifEdit struct {
+	ifd   string
+	id    uint16
+	name  string
+	typ   tiff.DataType // used when the tag is added; an existing tag keeps its type
+	value *string
+}
+
+// ifdField is an IFD entry as stored, with its value or value offset
+type ifdField struct {
+	id    uint16
+	typ   tiff.DataType
+	count uint32
+	field [4]byte
+}
+
+// editedIFD is an IFD being edited. Its table is rewritten in place when
+// it still fits and moved to the end of the block otherwise.
+type editedIFD struct {
+	offset   int // 0 for an IFD not yet written
+	capacity int // entries the table at offset has room for
+	fields   []ifdField
+	next     uint32
+}
+
+// tiffEditor edits a TIFF block without moving the data already in it,
+// so offsets that cannot be rewritten, such as those inside MakerNotes,
+// stay valid. New values are appended and replaced ones are zeroed.
+type tiffEditor struct {
+	order blockOrder
+	buf   []byte
+}
+
+// blockOrder reads and appends in the byte order of a TIFF block
+type blockOrder interface {
+	binary.ByteOrder
+	binary.AppendByteOrder
+}
+
+// emptyTIFF is a big-endian TIFF block with an empty IFD0
+var emptyTIFF = []byte("MM\x00\x2A\x00\x00\x00\x08\x00\x00\x00\x00\x00\x00")
+
+// newTIFFEditor starts editing a copy of block, or of an empty TIFF block
+// when block is nil
+func newTIFFEditor(block []byte) (*tiffEditor, error) {
+	if len(block) == 0 {
+		block = emptyTIFF
+	}
+	if len(block) < 8 {
+		return nil, errors.New("EXIF block is truncated")
+	}
+	order, ok := tiffByteOrder(block).(blockOrder)
+	if !ok || order.Uint16(block[2:]) != 42 {
+		return nil, errors.New("EXIF block is not a TIFF structure")
+	}
+	return &tiffEditor{order: order, buf: append([]byte{}, block...)}, nil
+}
+
+// readIFD reads the IFD table at offset
+func (e *tiffEditor) readIFD(offset int) (*editedIFD, error) {
+	if offset < 8 || offset+2 > len(e.buf) {
+		return nil, fmt.Errorf("IFD offset %d is outside the EXIF block", offset)
+	}
+	n := int(e.order.Uint16(e.buf[offset:]))
+	end := offset + 2 + 12*n
+	if end+4 > len(e.buf) {
+		return nil, fmt.Errorf("IFD at %d is truncated", offset)
+	}
+	ifd := &editedIFD{offset: offset, capacity: n, next: e.order.Uint32(e.buf[end:])}
+	for pos := offset + 2; pos < end; pos += 12 {
+		f := ifdField{
+			id:    e.order.Uint16(e.buf[pos:]),
+			typ:   tiff.DataType(e.order.Uint16(e.buf[pos+2:])),
+			count: e.order.Uint32(e.buf[pos+4:]),
+		}
+		copy(f.field[:], e.buf[pos+8:pos+12])
+		ifd.fields = append(ifd.fields, f)
+	}
+	return ifd, nil
+}
+
+// find returns the index of tag id in ifd, or -1
+func (ifd *editedIFD) find(id uint16) int {
+	for i, f := range ifd.fields {
+		if f.id == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// outOfLine returns where the value of f is stored when it does not fit
+// in the entry itself
+func (e *tiffEditor) outOfLine(f ifdField) (start, size int, ok bool) {
+	size = tiffTypeSizes[f.typ] * int(f.count)
+	if size <= 4 {
+		return 0, 0, false
+	}
+	start = int(e.order.Uint32(f.field[:]))
+	if start < 8 || start+size > len(e.buf) {
+		return 0, 0, false
+	}
+	return start, size, true
+}
+
+// zero clears size bytes at start
+func (e *tiffEditor) zero(start, size int) {
+	clear(e.buf[start : start+size])
+}
+
+// appendAligned adds b at the next word boundary and returns its offset
+func (e *tiffEditor) appendAligned(b []byte) int {
+	if len(e.buf)%2 == 1 {
+		e.buf = append(e.buf, 0)
+	}
+	offset := len(e.buf)
+	e.buf = append(e.buf, b...)
+	return offset
+}
+
+// set stores a value for tag id, reusing the space of the old value when
+// the new one fits in it
+func (e *tiffEditor) set(ifd *editedIFD, id uint16, typ tiff.DataType, count uint32, value []byte) {
+	f := ifdField{id: id, typ: typ, count: count}
+	i := ifd.find(id)
+	if i >= 0 {
+		if start, size, ok := e.outOfLine(ifd.fields[i]); ok {
+			if len(value) > 4 && len(value) <= size {
+				copy(e.buf[start:], value)
+				e.zero(start+len(value), size-len(value))
+				f.field = ifd.fields[i].field
+				ifd.fields[i] = f
+				return
+			}
+			e.zero(start, size)
+		}
+	}
+
+	if len(value) <= 4 {
+		copy(f.field[:], value)
+	} else {
+		e.order.PutUint32(f.field[:], uint32(e.appendAligned(value)))
+	}
+	if i >= 0 {
+		ifd.fields[i] = f
+		return
+	}
+	ifd.fields = append(ifd.fields, f)
+	sort.SliceStable(ifd.fields, func(a, b int) bool { return ifd.fields[a].id < ifd.fields[b].id })
+}
+
+// remove deletes tag id and zeroes its value
+func (e *tiffEditor) remove(ifd *editedIFD, id uint16) {
+	i := ifd.find(id)
+	if i < 0 {
+		return
+	}
+	if start, size, ok := e.outOfLine(ifd.fields[i]); ok {
+		e.zero(start, size)
+	}
+	ifd.fields = append(ifd.fields[:i], ifd.fields[i+1:]...)
+}
+
+// writeIFD stores the table of ifd and returns its offset
+func (e *tiffEditor) writeIFD(ifd *editedIFD) int {
+	table := e.order.AppendUint16(nil, uint16(len(ifd.fields)))
+	for _, f := range ifd.fields {
+		table = e.order.AppendUint16(table, f.id)
+		table = e.order.AppendUint16(table, uint16(f.typ))
+		table = e.order.AppendUint32(table, f.count)
+		table = append(table, f.field[:]...)
+	}
+	table = e.order.AppendUint32(table, ifd.next)
+
+	if ifd.offset > 0 {
+		old := 2 + 12*ifd.capacity + 4
+		if len(ifd.fields) <= ifd.capacity {
+			copy(e.buf[ifd.offset:], table)
+			e.zero(ifd.offset+len(table), old-len(table))
+			return ifd.offset
+		}
+		e.zero(ifd.offset, old)
+	}
+	ifd.offset = e.appendAligned(table)
+	ifd.capacity = len(ifd.fields)
+	return ifd.offset
+}
+
+// apply performs the edits addressed to one IFD
+func (e *tiffEditor) apply(ifd *editedIFD, edits []exifEdit) error {
+	for _, ed := range edits {
+		if ed.value == nil {
+			e.remove(ifd, ed.id)
+			continue
+		}
+		typ := ed.typ
+		if i := ifd.find(ed.id); i >= 0 {
+			typ = ifd.fields[i].typ
+		}
+		value, count, err := encodeTagValue(e.order, typ, *ed.value)
+		if err != nil {
+			return fmt.Errorf("EXIF %s: %v", ed.name, err)
+		}
+		e.set(ifd, ed.id, typ, count, value)
+	}
+	return nil
+}
+
+// exifSubIFDs are the sub-IFDs edits can address, with the tag IFD0
+// points to them with and the entry a new one starts with
+var exifSubIFDs = []struct {
+	name    string
+	pointer uint16
+	version exifEntry
+}{
+	{exifIFDName, tagExifIFDPointer, exifEntry{id: 0x9000, typ: tiff.DTUndefined, count: 4, value: []byte("0232")}},
+	{gpsIFDName, tagGPSIFDPointer, exifEntry{id: 0x0000, typ: tiff.DTByte, count: 4, value: []byte{2, 3, 0, 0}}},
+}
+
+// patchTIFF applies edits to a TIFF block, or to an empty one when block
+// is nil. IFD1 and any data outside the edited IFDs keep their offsets.
+func patchTIFF(block []byte, edits []exifEdit) ([]byte, error) {
+	e, err := newTIFFEditor(block)
+	if err != nil {
+		return nil, err
+	}
+	ifd0, err := e.readIFD(int(e.order.Uint32(e.buf[4:])))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, sub := range exifSubIFDs {
+		var subEdits []exifEdit
+		adds := false
+		for _, ed := range edits {
+			if ed.ifd == sub.name {
+				subEdits = append(subEdits, ed)
+				adds = adds || ed.value != nil
+			}
+		}
+		if len(subEdits) == 0 {
+			continue
+		}
+
+		var dir *editedIFD
+		if i := ifd0.find(sub.pointer); i >= 0 {
+			if dir, err = e.readIFD(int(e.order.Uint32(ifd0.fields[i].field[:]))); err != nil {
+				return nil, err
+			}
+		} else if adds {
+			dir = &editedIFD{}
+			e.set(dir, sub.version.id, sub.version.typ, sub.version.count, sub.version.value)
+		} else {
+			continue
+		}
+
+		if err := e.apply(dir, subEdits); err != nil {
+			return nil, err
+		}
+		if len(dir.fields) == 0 {
+			if dir.offset > 0 {
+				e.zero(dir.offset, 2+12*dir.capacity+4)
+			}
+			e.remove(ifd0, sub.pointer)
+			continue
+		}
+		e.set(ifd0, sub.pointer, tiff.DTLong, 1, e.order.AppendUint32(nil, uint32(e.writeIFD(dir))))
+	}
+
+	var ifd0Edits []exifEdit
+	for _, ed := range edits {
+		if ed.ifd == ifd0Name {
+			ifd0Edits = append(ifd0Edits, ed)
+		}
+	}
+	if err := e.apply(ifd0, ifd0Edits); err != nil {
+		return nil, err
+	}
+	offset := e.writeIFD(ifd0)
+	e.order.PutUint32(e.buf[4:], uint32(offset))
+	return e.buf, nil
+}
+
+// encodeTagValue converts a value written as in the rawValue of the tag
+// dump to the bytes of a TIFF field type. Numbers are separated by spaces
+// and rationals may be given as n/d or as decimals; undefined bytes are
+// written in hex.
+func encodeTagValue(order blockOrder, typ tiff.DataType, raw string) ([]byte, uint32, error) {
+	if typ == tiff.DTAscii {
+		return append([]byte(raw), 0), uint32(len(raw) + 1), nil
+	}
+	if strings.Contains(raw, "…") {
+		return nil, 0, errors.New("the value is a shortened preview")
+	}
+
+	fields := strings.Fields(strings.ReplaceAll(raw, ",", " "))
+	if len(fields) == 0 {
+		return nil, 0, errors.New("no value given")
+	}
+	var out []byte
+	for _, f := range fields {
+		switch typ {
+		case tiff.DTByte, tiff.DTShort, tiff.DTLong, tiff.DTSByte, tiff.DTSShort, tiff.DTSLong:
+			v, err := strconv.ParseInt(f, 10, 64)
+			if err != nil || !fitsTIFFInt(typ, v) {
+				return nil, 0, fmt.Errorf("%q is not a valid %s", f, dataTypeName(typ))
+			}
+			switch tiffTypeSizes[typ] {
+			case 1:
+				out = append(out, byte(v))
+			case 2:
+				out = order.AppendUint16(out, uint16(v))
+			default:
+				out = order.AppendUint32(out, uint32(v))
+			}
+		case tiff.DTRational, tiff.DTSRational:
+			r, ok := new(big.Rat).SetString(f)
+			if !ok || !r.Num().IsInt64() || !r.Denom().IsInt64() {
+				return nil, 0, fmt.Errorf("%q is not a valid %s", f, dataTypeName(typ))
+			}
+			num, den := r.Num().Int64(), r.Denom().Int64()
+			if typ == tiff.DTRational && (num < 0 || num > math.MaxUint32 || den > math.MaxUint32) ||
+				typ == tiff.DTSRational && (num < math.MinInt32 || num > math.MaxInt32 || den > math.MaxInt32) {
+				return nil, 0, fmt.Errorf("%q is out of range for %s", f, dataTypeName(typ))
+			}
+			out = order.AppendUint32(out, uint32(num))
+			out = order.AppendUint32(out, uint32(den))
+		case tiff.DTFloat, tiff.DTDouble:
+			v, err := strconv.ParseFloat(f, 64)
+			if err != nil {
+				return nil, 0, fmt.Errorf("%q is not a valid %s", f, dataTypeName(typ))
+			}
+			if typ == tiff.DTFloat {
+				out = order.AppendUint32(out, math.Float32bits(float32(v)))
+			} else {
+				out = order.AppendUint64(out, math.Float64bits(v))
+			}
+		case tiff.DTUndefined:
+			b, err := hex.DecodeString(f)
+			if err != nil {
+				return nil, 0, fmt.Errorf("%q is not hex", f)
+			}
+			out = append(out, b...)
+		default:
+			return nil, 0, fmt.Errorf("%s values cannot be written", dataTypeName(typ))
+		}
+	}
+	return out, uint32(len(out) / tiffTypeSizes[typ]), nil
+}
+
+// fitsTIFFInt reports whether v is in range for an integer field type
+func fitsTIFFInt(typ tiff.DataType, v int64) bool {
+	switch typ {
+	case tiff.DTByte:
+		return v >= 0 && v <= math.MaxUint8
+	case tiff.DTShort:
+		return v >= 0 && v <= math.MaxUint16
+	case tiff.DTLong:
+		return v >= 0 && v <= math.MaxUint32
+	case tiff.DTSByte:
+		return v >= math.MinInt8 && v <= math.MaxInt8
+	case tiff.DTSShort:
+		return v >= math.MinInt16 && v <= math.MaxInt16
+	default:
+		return v >= math.MinInt32 && v <= math.MaxInt32
+	}
+}
@@ -0,0 +1,325 @@
+package metadata
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"math"
+	"strings"
+
+	"github.com/ahrdadan/image-metadata-viewer/src/internal/models"
+)
+
+// exrMagic starts every OpenEXR file
+var exrMagic = []byte{0x76, 0x2F, 0x31, 0x01}
+
+// Version field flags
+const (
+	exrFlagTiled     = 0x200
+	exrFlagLongNames = 0x400
+	exrFlagDeep      = 0x800
+	exrFlagMultiPart = 0x1000
+)
+
+// Limits on header walking; real headers hold a few dozen attributes
+const (
+	maxEXRAttributes = 256
+	maxEXRParts      = 64
+	maxEXRTextValue  = 200
+)
+
+// errEXRPixels is returned when asked to decode OpenEXR pixel data
+var errEXRPixels = errors.New("exr: decoding pixel data is not supported")
+
+var exrCompressions = map[int]string{
+	0: "None",
+	1: "RLE",
+	2: "ZIPS",
+	3: "ZIP",
+	4: "PIZ",
+	5: "PXR24",
+	6: "B44",
+	7: "B44A",
+	8: "DWAA",
+	9: "DWAB",
+}
+
+var exrPixelTypes = map[int]string{0: "UINT", 1: "HALF", 2: "FLOAT"}
+
+// exrPixelBits is the sample size of each pixel type
+var exrPixelBits = map[string]int{"UINT": 32, "HALF": 16, "FLOAT": 32}
+
+var exrLineOrders = map[int]string{0: "Increasing Y", 1: "Decreasing Y", 2: "Random Y"}
+
+var exrTileModes = map[int]string{0: "One level", 1: "Mipmap levels", 2: "Ripmap levels"}
+
+func init() {
+	// Register OpenEXR so image.DecodeConfig reports the data window size;
+	// pixels are never decoded
+	image.RegisterFormat("exr", string(exrMagic), decodeEXR, decodeEXRConfig)
+}
+
+// decodeEXR satisfies image.RegisterFormat; pixel decoding is unsupported
+func decodeEXR(io.Reader) (image.Image, error) {
+	return nil, errEXRPixels
+}
+
+// decodeEXRConfig reads the data window from the header
+func decodeEXRConfig(r io.Reader) (image.Config, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return image.Config{}, err
+	}
+	info := parseEXR(data)
+	if info == nil || info.DataWindow == nil {
+		return image.Config{}, errors.New("exr: missing dataWindow attribute")
+	}
+	w := info.DataWindow
+	return image.Config{Width: w.XMax - w.XMin + 1, Height: w.YMax - w.YMin + 1}, nil
+}
+
+// exrReader reads the little-endian, null-terminated fields of a header
+type exrReader struct {
+	data []byte
+	pos  int
+	err  bool
+}
+
+// cstring reads a null-terminated name
+func (r *exrReader) cstring() string {
+	if r.err {
+		return ""
+	}
+	end := bytes.IndexByte(r.data[r.pos:], 0)
+	if end < 0 {
+		r.err = true
+		return ""
+	}
+	s := string(r.data[r.pos : r.pos+end])
+	r.pos += end + 1
+	return s
+}
+
+// bytes reads n bytes
+func (r *exrReader) bytes(n int) []byte {
+	if r.err || n < 0 || r.pos+n > len(r.data) {
+		r.err = true
+		return nil
+	}
+	b := r.data[r.pos : r.pos+n]
+	r.pos += n
+	return b
+}
+
+// parseEXR reads the version field and the first part's header attributes.
+// Later parts of a multi-part file are only counted.
+func parseEXR(data []byte) *models.EXRInfo {
+	if len(data) < 8 || !bytes.HasPrefix(data, exrMagic) {
+		return nil
+	}
+	version := binary.LittleEndian.Uint32(data[4:])
+	info := &models.EXRInfo{
+		Version:    int(version & 0xFF),
+		Tiled:      version&exrFlagTiled != 0,
+		LongNames:  version&exrFlagLongNames != 0,
+		Deep:       version&exrFlagDeep != 0,
+		MultiPart:  version&exrFlagMultiPart != 0,
+		Channels:   []models.EXRChannel{},
+		Attributes: []models.EXRAttribute{},
+	}
+
+	r := &exrReader{data: data, pos: 8}
+	for part := 0; part < maxEXRParts && !r.err; part++ {
+		for i := 0; i < maxEXRAttributes; i++ {
+			name := r.cstring()
+			if name == "" {
+				break
+			}
+			typ := r.cstring()
+			var size int
+			if b := r.bytes(4); b != nil {
+				size = int(int32(binary.LittleEndian.Uint32(b)))
+			}
+			value := r.bytes(size)
+			if r.err {
+				break
+			}
+			if part == 0 {
+				readEXRAttribute(info, name, typ, value)
+			}
+		}
+		if r.err {
+			break
+		}
+		info.Parts++
+		if !info.MultiPart || (r.pos < len(data) && data[r.pos] == 0) {
+			// A single-part header ends the header section; a multi-part
+			// file ends its list of headers with an empty one
+			break
+		}
+	}
+	return info
+}
+
+// readEXRAttribute stores one attribute and decodes the well-known ones
+func readEXRAttribute(info *models.EXRInfo, name, typ string, value []byte) {
+	attr := models.EXRAttribute{Name: name, Type: typ, Size: len(value), Value: exrValue(typ, value)}
+
+	switch {
+	case name == "channels" && typ == "chlist":
+		info.Channels = exrChannels(value)
+		names := make([]string, len(info.Channels))
+		for i, ch := range info.Channels {
+			names[i] = ch.Name
+		}
+		attr.Value = strings.Join(names, ", ")
+	case name == "compression" && len(value) >= 1:
+		info.Compression = lookupName(exrCompressions, int(value[0]))
+	case name == "dataWindow" && typ == "box2i":
+		info.DataWindow = exrWindow(value)
+	case name == "displayWindow" && typ == "box2i":
+		info.DisplayWindow = exrWindow(value)
+	case name == "lineOrder" && len(value) >= 1:
+		info.LineOrder = lookupName(exrLineOrders, int(value[0]))
+	case name == "pixelAspectRatio" && len(value) >= 4:
+		info.PixelAspectRatio = float64(math.Float32frombits(binary.LittleEndian.Uint32(value)))
+	case name == "tiles" && typ == "tiledesc" && len(value) >= 9:
+		info.TileWidth = int(binary.LittleEndian.Uint32(value))
+		info.TileHeight = int(binary.LittleEndian.Uint32(value[4:]))
+		info.TileMode = lookupName(exrTileModes, int(value[8]&0x0F))
+	}
+	info.Attributes = append(info.Attributes, attr)
+}
+
+// exrChannels decodes a chlist attribute
+func exrChannels(value []byte) []models.EXRChannel {
+	channels := []models.EXRChannel{}
+	r := &exrReader{data: value}
+	for !r.err && r.pos < len(value) {
+		name := r.cstring()
+		if name == "" {
+			break
+		}
+		b := r.bytes(16)
+		if b == nil {
+			break
+		}
+		pixelType := int(int32(binary.LittleEndian.Uint32(b)))
+		channels = append(channels, models.EXRChannel{
+			Name:      name,
+			PixelType: lookupName(exrPixelTypes, pixelType),
+			Linear:    b[4] != 0,
+			XSampling: int(int32(binary.LittleEndian.Uint32(b[8:]))),
+			YSampling: int(int32(binary.LittleEndian.Uint32(b[12:]))),
+		})
+	}
+	return channels
+}
+
+// exrWindow decodes a box2i attribute
+func exrWindow(value []byte) *models.EXRWindow {
+	if len(value) < 16 {
+		return nil
+	}
+	v := func(i int) int { return int(int32(binary.LittleEndian.Uint32(value[4*i:]))) }
+	return &models.EXRWindow{XMin: v(0), YMin: v(1), XMax: v(2), YMax: v(3)}
+}
+
+// exrValue renders attributes of the basic types as text. Matrices,
+// previews and opaque types are listed by size only.
+func exrValue(typ string, value []byte) string {
+	i32 := func(i int) int32 { return int32(binary.LittleEndian.Uint32(value[4*i:])) }
+	f32 := func(i int) float32 { return math.Float32frombits(binary.LittleEndian.Uint32(value[4*i:])) }
+
+	switch {
+	case typ == "string":
+		s := strings.TrimRight(string(value), "\x00")
+		if len(s) > maxEXRTextValue {
+			s = s[:maxEXRTextValue] + "…"
+		}
+		return s
+	case typ == "int" && len(value) >= 4:
+		return fmt.Sprintf("%d", i32(0))
+	case typ == "float" && len(value) >= 4:
+		return fmt.Sprintf("%g", f32(0))
+	case typ == "double" && len(value) >= 8:
+		return fmt.Sprintf("%g", math.Float64frombits(binary.LittleEndian.Uint64(value)))
+	case typ == "v2i" && len(value) >= 8:
+		return fmt.Sprintf("%d, %d", i32(0), i32(1))
+	case typ == "v2f" && len(value) >= 8:
+		return fmt.Sprintf("%g, %g", f32(0), f32(1))
+	case typ == "v3f" && len(value) >= 12:
+		return fmt.Sprintf("%g, %g, %g", f32(0), f32(1), f32(2))
+	case typ == "box2i" && len(value) >= 16:
+		return fmt.Sprintf("(%d, %d) – (%d, %d)", i32(0), i32(1), i32(2), i32(3))
+	case typ == "box2f" && len(value) >= 16:
+		return fmt.Sprintf("(%g, %g) – (%g, %g)", f32(0), f32(1), f32(2), f32(3))
+	case typ == "rational" && len(value) >= 8:
+		return fmt.Sprintf("%d/%d", i32(0), binary.LittleEndian.Uint32(value[4:]))
+	case typ == "compression" && len(value) >= 1:
+		return lookupName(exrCompressions, int(value[0]))
+	case typ == "lineOrder" && len(value) >= 1:
+		return lookupName(exrLineOrders, int(value[0]))
+	case typ == "tiledesc" && len(value) >= 9:
+		return fmt.Sprintf("%d×%d, %s", i32(0), i32(1), lookupName(exrTileModes, int(value[8]&0x0F)))
+	case typ == "chromaticities" && len(value) >= 32:
+		return fmt.Sprintf("red %g,%g green %g,%g blue %g,%g white %g,%g",
+			f32(0), f32(1), f32(2), f32(3), f32(4), f32(5), f32(6), f32(7))
+	case typ == "preview" && len(value) >= 8:
+		return fmt.Sprintf("%d×%d", i32(0), i32(1))
+	}
+	return ""
+}
+
+// exrColorMode describes the channel layout of the default layer
+func exrColorMode(channels []models.EXRChannel) (mode string, components int) {
+	has := make(map[string]bool)
+	for _, ch := range channels {
+		if !strings.Contains(ch.Name, ".") {
+			has[ch.Name] = true
+		}
+	}
+	switch {
+	case has["R"] && has["G"] && has["B"]:
+		mode, components = "RGB", 3
+	case has["Y"] && has["RY"] && has["BY"]:
+		mode, components = "Luminance/chroma", 3
+	case has["Y"]:
+		mode, components = "Grayscale", 1
+	default:
+		return "Multichannel", len(channels)
+	}
+	if has["A"] {
+		mode += " with alpha"
+	}
+	return mode, components
+}
+
+// extractEXR fills the OpenEXR section and the shared color fields
+func extractEXR(data []byte, meta *models.ImageMetadata) {
+	if sniffFormat(data) != formatEXR {
+		return
+	}
+	info := parseEXR(data)
+	if info == nil {
+		return
+	}
+	meta.EXR = info
+	if meta.MIMEType == "" || meta.MIMEType == "application/octet-stream" {
+		meta.MIMEType = "image/x-exr"
+	}
+	if len(info.Channels) == 0 {
+		return
+	}
+
+	bits := make([]string, len(info.Channels))
+	for i, ch := range info.Channels {
+		bits[i] = fmt.Sprintf("%d", exrPixelBits[ch.PixelType])
+	}
+	meta.BitsPerSample = strings.Join(bits, " ")
+	meta.ColorMode, meta.ColorComponents = exrColorMode(info.Channels)
+	meta.SamplesPerPixel = len(info.Channels)
+}
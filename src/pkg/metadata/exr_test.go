@@ -0,0 +1,63 @@
+package metadata
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+// exrAttribute encodes one header attribute
+func exrAttribute(name, typ string, value []byte) []byte {
+	out := append([]byte(name+"\x00"), typ+"\x00"...)
+	out = binary.LittleEndian.AppendUint32(out, uint32(len(value)))
+	return append(out, value...)
+}
+
+// exrInts encodes little-endian 32-bit integers
+func exrInts(values ...int32) []byte {
+	var out []byte
+	for _, v := range values {
+		out = binary.LittleEndian.AppendUint32(out, uint32(v))
+	}
+	return out
+}
+
+func TestExtractMetadataEXR(t *testing.T) {
+	var chlist []byte
+	for _, name := range []string{"A", "B", "G", "R"} {
+		chlist = append(append(chlist, name+"\x00"...), exrInts(1, 0, 1, 1)...)
+	}
+	chlist = append(chlist, 0)
+
+	data := append([]byte{}, exrMagic...)
+	data = append(data, exrInts(2|exrFlagTiled)...)
+	data = append(data, exrAttribute("channels", "chlist", chlist)...)
+	data = append(data, exrAttribute("compression", "compression", []byte{4})...)
+	data = append(data, exrAttribute("dataWindow", "box2i", exrInts(0, 0, 1919, 1079))...)
+	data = append(data, exrAttribute("displayWindow", "box2i", exrInts(0, 0, 1919, 1079))...)
+	data = append(data, exrAttribute("lineOrder", "lineOrder", []byte{0})...)
+	data = append(data, exrAttribute("tiles", "tiledesc", append(exrInts(64, 64), 1))...)
+	data = append(data, exrAttribute("owner", "string", []byte("Studio"))...)
+	data = append(data, 0)
+
+	meta := ExtractMetadata(data, "application/octet-stream", "render.exr")
+	if meta.DecodeError != "" {
+		t.Fatalf("DecodeError = %q", meta.DecodeError)
+	}
+	if meta.Format != "exr" || meta.Width != 1920 || meta.Height != 1080 || meta.MIMEType != "image/x-exr" {
+		t.Errorf("Format = %q, size = %dx%d, MIMEType = %q", meta.Format, meta.Width, meta.Height, meta.MIMEType)
+	}
+	if meta.BitsPerSample != "16 16 16 16" || meta.ColorMode != "RGB with alpha" || meta.SamplesPerPixel != 4 {
+		t.Errorf("BitsPerSample = %q, ColorMode = %q, SamplesPerPixel = %d", meta.BitsPerSample, meta.ColorMode, meta.SamplesPerPixel)
+	}
+
+	info := meta.EXR
+	if info == nil || info.Version != 2 || !info.Tiled || info.Parts != 1 {
+		t.Fatalf("EXR = %+v", info)
+	}
+	if info.Compression != "PIZ" || info.LineOrder != "Increasing Y" || info.TileWidth != 64 || info.TileMode != "Mipmap levels" {
+		t.Errorf("EXR = %+v", info)
+	}
+	if len(info.Attributes) != 7 || info.Attributes[6].Value != "Studio" || info.Attributes[0].Value != "A, B, G, R" {
+		t.Errorf("attributes = %+v", info.Attributes)
+	}
+}
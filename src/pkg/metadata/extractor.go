@@ -1,14 +1,13 @@
 package metadata
 
 import (
-	"bytes"
 	"fmt"
 	"image"
+	"image/color"
 	_ "image/gif"
 	_ "image/jpeg"
 	_ "image/png"
 	"strings"
-	"time"
 
 	"github.com/ahrdadan/image-metadata-viewer/src/internal/models"
 	"github.com/ahrdadan/image-metadata-viewer/src/internal/utils"
@@ -18,24 +17,72 @@ import (
 	_ "golang.org/x/image/webp"
 )
 
-// ExtractMetadata extracts comprehensive metadata from image data
+// ExtractMetadata extracts comprehensive metadata from image data using
+// the extractors registered in DefaultRegistry
 func ExtractMetadata(data []byte, contentType, fileName string) *models.ImageMetadata {
-	meta := &models.ImageMetadata{
-		FileName:          fileName,
-		FileSize:          int64(len(data)),
-		FileSizeHuman:     utils.HumanBytes(int64(len(data))),
-		MIMEType:          contentType,
-		FileTypeExtension: utils.ExtensionFromName(fileName),
-		UploadedAt:        time.Now(),
-	}
+	return DefaultRegistry.Extract(data, contentType, fileName)
+}
+
+// BuiltinExtractors returns the extractors ExtractMetadata runs, in order
+func BuiltinExtractors() []Extractor {
+	return []Extractor{
+		// Read HEIC/AVIF item properties
+		NewExtractor("heif", formatIs(formatHEIF), infallible(extractHEIF)),
+
+		// Read camera raw sensor and preview layout
+		NewExtractor("raw", nil, extractRAW),
+
+		// List ICO/CUR images
+		NewExtractor("icon", formatIs(formatICO), infallible(extractIcon)),
+
+		// Inspect SVG structure and active content
+		NewExtractor("svg", formatIs(formatSVG), infallible(extractSVG)),
+
+		// Read JPEG XL, JPEG 2000 and OpenEXR headers
+		NewExtractor("jxl", formatIs(formatJXL), infallible(extractJXL)),
+		NewExtractor("jp2", formatIs(formatJP2), infallible(extractJP2)),
+		NewExtractor("exr", formatIs(formatEXR), infallible(extractEXR)),
+
+		// Read the Photoshop header, image resources and layer records
+		NewExtractor("psd", formatIs(formatPSD), infallible(extractPSD)),
+
+		// Analyze JPEG frame and quantization tables
+		NewExtractor("jpeg", formatIs(formatJPEG), infallible(extractJPEG)),
+
+		// Inspect PNG chunks
+		NewExtractor("png", formatIs(formatPNG), extractPNG),
+
+		// Analyze animation frames
+		NewExtractor("animation", formatIs(formatGIF, formatWebP, formatPNG), infallible(extractAnimation)),
+
+		// Extract EXIF data
+		NewExtractor("exif", nil, fallible(extractEXIF)),
+
+		// Extract XMP packet
+		NewExtractor("xmp", nil, extractXMP),
+
+		// Extract IPTC and Photoshop resources
+		NewExtractor("photoshop", formatIs(formatJPEG, formatTIFF, formatPSD), infallible(extractPhotoshop)),
+
+		// Read and validate C2PA Content Credentials
+		NewExtractor("c2pa", formatIs(formatJPEG, formatPNG, formatWebP, formatJXL, formatHEIF, formatCR3), fallible(extractC2PA)),
+
+		// Normalize the dates read so far to RFC 3339
+		NewExtractor("timestamps", nil, infallible(extractTimestamps)),
 
-	// Decode image config for basic dimensions
-	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
-	if err != nil {
-		meta.DecodeError = err.Error()
-		return meta
+		// List embedded thumbnails and previews
+		NewExtractor("thumbnails", nil, extractThumbnails),
+
+		// Set color space information from the decoded header
+		NewExtractor("color-model", nil, extractColorModel),
+
+		// Extract ICC color profile
+		NewExtractor("icc", nil, extractICC),
 	}
+}
 
+// setDimensions fills the basic fields from the decoded image header
+func setDimensions(meta *models.ImageMetadata, cfg image.Config, format string) {
 	meta.Format = format
 	meta.Width = cfg.Width
 	meta.Height = cfg.Height
@@ -47,27 +94,49 @@ func ExtractMetadata(data []byte, contentType, fileName string) *models.ImageMet
 	if meta.FileTypeExtension == "" {
 		meta.FileTypeExtension = utils.FormatToExtension(format)
 	}
+}
 
-	// Extract EXIF data
-	extractEXIF(data, meta)
-
-	// Set color space information
-	if cfg.ColorModel != nil {
-		meta.ColorComponents = 3 // Most images have RGB
-		meta.SamplesPerPixel = 3
+// extractColorModel applies the color model of the decoded header, which
+// takes precedence over the format-specific guesses
+func extractColorModel(_ []byte, result *Result) error {
+	meta := result.Metadata
+	if mode, components, samples := colorModelInfo(result.Config.ColorModel); components > 0 {
+		meta.ColorMode = mode
+		meta.ColorComponents = components
+		meta.SamplesPerPixel = samples
 	}
-
-	return meta
+	return nil
 }
 
-// extractEXIF extracts EXIF metadata from image data
-func extractEXIF(data []byte, meta *models.ImageMetadata) {
-	x, err := exif.Decode(bytes.NewReader(data))
-	if err != nil {
-		// EXIF not available or couldn't decode
-		return
+// extractEXIF extracts EXIF metadata from image data. Files without an
+// EXIF block are not an error; a block that cannot be decoded is.
+func extractEXIF(data []byte, meta *models.ImageMetadata) error {
+	block := exifBlock(data)
+	if block == nil {
+		return nil
+	}
+	x, err := decodeEXIF(block)
+	if x == nil || (err != nil && exif.IsCriticalError(err)) {
+		if err != nil && exifTIFFBlock(data) != nil {
+			return fmt.Errorf("exif: %w", err)
+		}
+		return nil
 	}
 
+	// Full tag dump across all IFDs
+	dirs := loadEXIFDirs(x)
+	loadCR3Dirs(data, x, dirs)
+	meta.EXIF = collectEXIFTags(dirs)
+
+	// Camera, lens and capture settings
+	meta.Camera = extractCamera(dirs)
+
+	// Manufacturer MakerNote
+	meta.MakerNote = extractMakerNote(data, dirs)
+
+	// GPS position
+	meta.Location = extractLocation(x)
+
 	// Orientation
 	if tag, err := x.Get(exif.Orientation); err == nil {
 		if val, err := tag.Int(0); err == nil {
@@ -79,7 +148,6 @@ func extractEXIF(data []byte, meta *models.ImageMetadata) {
 	if tag, err := x.Get(exif.Software); err == nil {
 		if val, err := tag.StringVal(); err == nil {
 			meta.Software = val
-			meta.CreatorTool = val
 		}
 	}
 
@@ -123,12 +191,39 @@ func extractEXIF(data []byte, meta *models.ImageMetadata) {
 		if val, err := tag.Int(0); err == nil {
 			if val == 1 {
 				meta.ColorSpace = "sRGB"
-				meta.ColorMode = "RGB"
 			} else {
 				meta.ColorSpace = "Uncalibrated"
 			}
 		}
 	}
+	return nil
+}
+
+// colorModelInfo reports the color mode, color component count and stored
+// samples per pixel for a decoded color model
+func colorModelInfo(model color.Model) (string, int, int) {
+	switch model {
+	case nil:
+		return "", 0, 0
+	case color.GrayModel, color.Gray16Model:
+		return "Grayscale", 1, 1
+	case color.AlphaModel, color.Alpha16Model:
+		return "Alpha", 1, 1
+	case color.CMYKModel:
+		return "CMYK", 4, 4
+	case color.YCbCrModel:
+		return "YCbCr", 3, 3
+	case color.NYCbCrAModel:
+		return "YCbCr with alpha", 3, 4
+	case color.RGBAModel, color.RGBA64Model:
+		return "RGB", 3, 3
+	case color.NRGBAModel, color.NRGBA64Model:
+		return "RGB with alpha", 3, 4
+	}
+	if _, ok := model.(color.Palette); ok {
+		return "Indexed", 3, 1
+	}
+	return "RGB", 3, 3
 }
 
 // orientationToString converts EXIF orientation value to string
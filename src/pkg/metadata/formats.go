@@ -0,0 +1,63 @@
+package metadata
+
+import "bytes"
+
+// Container formats recognised by sniffFormat
+const (
+	formatJPEG    = "jpeg"
+	formatPNG     = "png"
+	formatGIF     = "gif"
+	formatWebP    = "webp"
+	formatTIFF    = "tiff"
+	formatBMP     = "bmp"
+	formatHEIF    = "heif" // HEIC and AVIF share the ISOBMFF container
+	formatCR3     = "cr3"
+	formatRAF     = "raf"
+	formatICO     = "ico" // ICO and CUR share the icon directory
+	formatSVG     = "svg"
+	formatJXL     = "jxl"
+	formatJP2     = "jp2" // JP2/JPX boxes or a bare J2K codestream
+	formatEXR     = "exr"
+	formatPSD     = "psd" // PSD and PSB share the layout
+	formatUnknown = ""
+)
+
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// sniffFormat identifies the container format from its magic bytes
+func sniffFormat(data []byte) string {
+	switch {
+	case len(data) >= 3 && data[0] == 0xFF && data[1] == 0xD8 && data[2] == 0xFF:
+		return formatJPEG
+	case bytes.HasPrefix(data, pngSignature):
+		return formatPNG
+	case bytes.HasPrefix(data, []byte("GIF87a")), bytes.HasPrefix(data, []byte("GIF89a")):
+		return formatGIF
+	case len(data) >= 12 && string(data[:4]) == "RIFF" && string(data[8:12]) == "WEBP":
+		return formatWebP
+	case bytes.HasPrefix(data, []byte("II*\x00")), bytes.HasPrefix(data, []byte("MM\x00*")), isORF(data):
+		return formatTIFF
+	case isHEIFBrand(data):
+		return formatHEIF
+	case len(data) >= 12 && string(data[4:12]) == "ftypcrx ":
+		return formatCR3
+	case bytes.HasPrefix(data, rafSignature):
+		return formatRAF
+	case bytes.HasPrefix(data, []byte("BM")):
+		return formatBMP
+	case isJXL(data):
+		return formatJXL
+	case isJP2(data):
+		return formatJP2
+	case bytes.HasPrefix(data, exrMagic):
+		return formatEXR
+	case isPSD(data):
+		return formatPSD
+	case isIcon(data):
+		return formatICO
+	case isSVG(data):
+		return formatSVG
+	default:
+		return formatUnknown
+	}
+}
@@ -0,0 +1,126 @@
+package metadata
+
+import "encoding/binary"
+
+// GIF block introducers
+const (
+	gifExtension  = 0x21
+	gifImage      = 0x2C
+	gifTrailer    = 0x3B
+	gifAppLabel   = 0xFF
+	gifGCELabel   = 0xF9
+	gifCommentExt = 0xFE
+)
+
+// gifBlock is an extension or image block from a GIF stream
+type gifBlock struct {
+	introducer byte
+	label      byte   // extension label, 0 for image blocks
+	offset     int    // offset of the introducer byte
+	appID      string // application identifier + auth code for 0xFF extensions
+	header     []byte // first sub-block (extensions) or image descriptor
+	raw        []byte // data sub-blocks including their length bytes
+}
+
+// gifScreen is the logical screen descriptor of a GIF stream
+type gifScreen struct {
+	width, height   int
+	globalColorSize int // entries in the global color table, 0 if absent
+}
+
+// readGIFBlocks walks the blocks of a GIF stream up to the trailer
+func readGIFBlocks(data []byte) (gifScreen, []gifBlock) {
+	var screen gifScreen
+	if len(data) < 13 || sniffFormat(data) != formatGIF {
+		return screen, nil
+	}
+
+	screen.width = int(binary.LittleEndian.Uint16(data[6:]))
+	screen.height = int(binary.LittleEndian.Uint16(data[8:]))
+	pos := 13
+	if flags := data[10]; flags&0x80 != 0 {
+		screen.globalColorSize = 1 << ((flags & 0x07) + 1)
+		pos += 3 * screen.globalColorSize
+	}
+
+	var blocks []gifBlock
+	for pos < len(data) {
+		switch data[pos] {
+		case gifExtension:
+			if pos+2 > len(data) {
+				return screen, blocks
+			}
+			block := gifBlock{introducer: gifExtension, label: data[pos+1], offset: pos}
+			next, first, ok := readGIFSubBlock(data, pos+2)
+			if !ok {
+				return screen, blocks
+			}
+			block.header = first
+			if block.label == gifAppLabel && len(first) >= 11 {
+				block.appID = string(first[:11])
+			}
+			end, ok := skipGIFSubBlocks(data, next)
+			if !ok {
+				return screen, blocks
+			}
+			block.raw = data[next:end]
+			blocks = append(blocks, block)
+			pos = end
+
+		case gifImage:
+			if pos+10 > len(data) {
+				return screen, blocks
+			}
+			block := gifBlock{introducer: gifImage, offset: pos, header: data[pos+1 : pos+10]}
+			next := pos + 10
+			if flags := data[pos+9]; flags&0x80 != 0 {
+				next += 3 * (1 << ((flags & 0x07) + 1))
+			}
+			// LZW minimum code size precedes the image data sub-blocks
+			next++
+			end, ok := skipGIFSubBlocks(data, next)
+			if !ok {
+				return screen, blocks
+			}
+			block.raw = data[next:end]
+			blocks = append(blocks, block)
+			pos = end
+
+		default:
+			// Trailer or garbage
+			return screen, blocks
+		}
+	}
+
+	return screen, blocks
+}
+
+// readGIFSubBlock reads one sub-block at pos and returns the position after it
+func readGIFSubBlock(data []byte, pos int) (int, []byte, bool) {
+	if pos >= len(data) {
+		return pos, nil, false
+	}
+	size := int(data[pos])
+	if size == 0 {
+		return pos, nil, true
+	}
+	end := pos + 1 + size
+	if end > len(data) {
+		return pos, nil, false
+	}
+	return end, data[pos+1 : end], true
+}
+
+// skipGIFSubBlocks skips a chain of sub-blocks and returns the position
+// after the block terminator
+func skipGIFSubBlocks(data []byte, pos int) (int, bool) {
+	for pos < len(data) {
+		size := int(data[pos])
+		pos++
+		if size == 0 {
+			return pos, true
+		}
+		pos += size
+	}
+	return pos, false
+}
@@ -0,0 +1,186 @@
+package metadata
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ahrdadan/image-metadata-viewer/src/internal/models"
+	"github.com/rwcarlsen/goexif/exif"
+	"github.com/rwcarlsen/goexif/tiff"
+)
+
+// extractLocation decodes the GPS IFD into a models.Location.
+// It returns nil when the image carries no usable coordinates.
+func extractLocation(x *exif.Exif) *models.Location {
+	lat, latOK := gpsCoordinate(x, exif.GPSLatitude, exif.GPSLatitudeRef, "S")
+	lon, lonOK := gpsCoordinate(x, exif.GPSLongitude, exif.GPSLongitudeRef, "W")
+	if !latOK || !lonOK {
+		return nil
+	}
+	if math.Abs(lat) > 90 || math.Abs(lon) > 180 {
+		return nil
+	}
+
+	loc := &models.Location{
+		Latitude:     roundCoordinate(lat),
+		Longitude:    roundCoordinate(lon),
+		LatitudeDMS:  formatDMS(lat, "N", "S"),
+		LongitudeDMS: formatDMS(lon, "E", "W"),
+	}
+	loc.Coordinates = fmt.Sprintf("%.6f, %.6f", loc.Latitude, loc.Longitude)
+
+	// Altitude, negative when the reference says below sea level
+	if alt, ok := gpsRational(x, exif.GPSAltitude); ok {
+		if ref, err := getInt(x, exif.GPSAltitudeRef); err == nil && ref == 1 {
+			alt = -alt
+		}
+		loc.Altitude = &alt
+		if alt < 0 {
+			loc.AltitudeHuman = fmt.Sprintf("%s m below sea level", formatFloat(math.Round(-alt*10)/10))
+		} else {
+			loc.AltitudeHuman = fmt.Sprintf("%s m above sea level", formatFloat(math.Round(alt*10)/10))
+		}
+	}
+
+	// UTC timestamp from GPSDateStamp + GPSTimeStamp
+	if tag, err := x.Get(exif.GPSTimeStamp); err == nil {
+		if ts, ok := gpsTimestamp(getString(x, exif.GPSDateStamp), rawTagValue(tag)); ok {
+			loc.Timestamp = ts.Format(time.RFC3339)
+		}
+	}
+
+	// Direction the camera was pointing
+	if dir, ok := gpsRational(x, exif.GPSImgDirection); ok {
+		loc.ImgDirection = &dir
+		loc.ImgDirectionRef = directionRefNames[getString(x, exif.GPSImgDirectionRef)]
+		loc.ImgDirectionHuman = strings.TrimSpace(fmt.Sprintf("%.1f° %s", dir, strings.ToLower(loc.ImgDirectionRef)))
+	}
+
+	// Speed of the receiver
+	if speed, ok := gpsRational(x, exif.GPSSpeed); ok {
+		loc.Speed = &speed
+		switch getString(x, exif.GPSSpeedRef) {
+		case "M":
+			loc.SpeedUnit = "mph"
+		case "N":
+			loc.SpeedUnit = "knots"
+		default:
+			loc.SpeedUnit = "km/h"
+		}
+		loc.SpeedHuman = fmt.Sprintf("%s %s", formatFloat(math.Round(speed*10)/10), loc.SpeedUnit)
+	}
+
+	loc.MapDatum = getString(x, exif.GPSMapDatum)
+
+	return loc
+}
+
+// gpsCoordinate reads a DMS coordinate and applies its hemisphere reference
+func gpsCoordinate(x *exif.Exif, name, refName exif.FieldName, negativeRef string) (float64, bool) {
+	tag, err := x.Get(name)
+	if err != nil {
+		return 0, false
+	}
+	deg, ok := dmsToDecimal(tag)
+	if !ok {
+		return 0, false
+	}
+	if strings.EqualFold(getString(x, refName), negativeRef) {
+		deg = -deg
+	}
+	return deg, true
+}
+
+// dmsToDecimal converts a degrees/minutes/seconds rational triple to degrees
+func dmsToDecimal(tag *tiff.Tag) (float64, bool) {
+	if tag.Format() != tiff.RatVal || tag.Count < 3 {
+		return 0, false
+	}
+	for i := 0; i < 3; i++ {
+		if _, d, _ := tag.Rat2(i); d == 0 {
+			return 0, false
+		}
+	}
+	return ratValue(tag, 0) + ratValue(tag, 1)/60 + ratValue(tag, 2)/3600, true
+}
+
+// gpsRational reads a single rational GPS value
+func gpsRational(x *exif.Exif, name exif.FieldName) (float64, bool) {
+	tag, err := x.Get(name)
+	if err != nil || tag.Format() != tiff.RatVal || tag.Count == 0 {
+		return 0, false
+	}
+	if _, d, _ := tag.Rat2(0); d == 0 {
+		return 0, false
+	}
+	return ratValue(tag, 0), true
+}
+
+// gpsTimestamp combines GPSDateStamp and GPSTimeStamp, as shown in the tag
+// dump ("2024:05:01" and "8/1 20/1 29/1"), into a UTC time. A date without
+// a time of day is rejected rather than read as midnight.
+func gpsTimestamp(date, clock string) (time.Time, bool) {
+	day, err := time.Parse("2006:01:02", date)
+	if err != nil {
+		return time.Time{}, false
+	}
+	parts := strings.Fields(clock)
+	if len(parts) != 3 {
+		return time.Time{}, false
+	}
+	var seconds float64
+	for i, unit := range []float64{3600, 60, 1} {
+		num, den, ok := strings.Cut(parts[i], "/")
+		n, errN := strconv.ParseFloat(num, 64)
+		d, errD := strconv.ParseFloat(den, 64)
+		if !ok || errN != nil || errD != nil || d == 0 {
+			return time.Time{}, false
+		}
+		seconds += n / d * unit
+	}
+	return day.Add(time.Duration(seconds * float64(time.Second))), true
+}
+
+// formatDMS formats decimal degrees as degrees, minutes, seconds and hemisphere
+func formatDMS(v float64, positive, negative string) string {
+	ref := positive
+	if v < 0 {
+		ref = negative
+		v = -v
+	}
+	deg := math.Floor(v)
+	minutes := (v - deg) * 60
+	whole := math.Floor(minutes)
+	sec := (minutes - whole) * 60
+	return fmt.Sprintf("%d° %d′ %.2f″ %s", int(deg), int(whole), sec, ref)
+}
+
+// roundCoordinate rounds degrees to six decimals (about 0.1 m)
+func roundCoordinate(v float64) float64 {
+	return math.Round(v*1e6) / 1e6
+}
+
+// getString returns the trimmed ASCII value of a tag, or ""
+func getString(x *exif.Exif, name exif.FieldName) string {
+	tag, err := x.Get(name)
+	if err != nil {
+		return ""
+	}
+	val, err := tag.StringVal()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(val)
+}
+
+// getInt returns the first integer value of a tag
+func getInt(x *exif.Exif, name exif.FieldName) (int, error) {
+	tag, err := x.Get(name)
+	if err != nil {
+		return 0, err
+	}
+	return tag.Int(0)
+}
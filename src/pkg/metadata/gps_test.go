@@ -0,0 +1,78 @@
+package metadata
+
+import (
+	"testing"
+	"time"
+)
+
+func TestExtractMetadataLocation(t *testing.T) {
+	tiffData := buildTIFF([]testIFD{
+		{
+			tags:     []testTag{asciiTag(0x010F, "TestMaker")},
+			pointers: map[uint16]int{tagGPSIFDPointer: 1},
+		},
+		{
+			tags: []testTag{
+				asciiTag(0x0001, "S"),
+				rationalTag(0x0002, 33, 1, 51, 1, 5400, 100),
+				asciiTag(0x0003, "W"),
+				rationalTag(0x0004, 70, 1, 30, 1, 0, 1),
+				{id: 0x0005, typ: 1, count: 1, value: []byte{1}},
+				rationalTag(0x0006, 125, 10),
+				rationalTag(0x0007, 14, 1, 3, 1, 22, 1),
+				asciiTag(0x001D, "2023:04:01"),
+			},
+		},
+	})
+
+	meta := ExtractMetadata(buildJPEGWithSegments(t, exifSegment(tiffData)), "image/jpeg", "gps.jpg")
+	loc := meta.Location
+	if loc == nil {
+		t.Fatal("expected Location")
+	}
+
+	if loc.Latitude != -33.865 {
+		t.Errorf("Latitude = %v, want -33.865", loc.Latitude)
+	}
+	if loc.Longitude != -70.5 {
+		t.Errorf("Longitude = %v, want -70.5", loc.Longitude)
+	}
+	if loc.Altitude == nil || *loc.Altitude != -12.5 {
+		t.Errorf("Altitude = %v, want -12.5", loc.Altitude)
+	}
+	if loc.Timestamp != "2023-04-01T14:03:22Z" {
+		t.Errorf("Timestamp = %q, want 2023-04-01T14:03:22Z", loc.Timestamp)
+	}
+	if loc.LatitudeDMS != "33° 51′ 54.00″ S" {
+		t.Errorf("LatitudeDMS = %q", loc.LatitudeDMS)
+	}
+}
+
+func TestGPSTimestamp(t *testing.T) {
+	if ts, ok := gpsTimestamp("2023:04:01", "14/1 3/1 2250/100"); !ok || ts.Format(time.RFC3339Nano) != "2023-04-01T14:03:22.5Z" {
+		t.Errorf("gpsTimestamp = %v, %v", ts, ok)
+	}
+	for _, clock := range []string{"", "14/1 3/1", "14/1 3/1 22/0"} {
+		if ts, ok := gpsTimestamp("2023:04:01", clock); ok {
+			t.Errorf("gpsTimestamp(%q) = %v, want no time", clock, ts)
+		}
+	}
+}
+
+func TestFormatDMS(t *testing.T) {
+	tests := []struct {
+		input    float64
+		expected string
+	}{
+		{37.7749, "37° 46′ 29.64″ N"},
+		{-122.4194, "122° 25′ 9.84″ S"},
+		{0, "0° 0′ 0.00″ N"},
+	}
+
+	for _, tt := range tests {
+		result := formatDMS(tt.input, "N", "S")
+		if result != tt.expected {
+			t.Errorf("formatDMS(%v) = %q, want %q", tt.input, result, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,685 @@
+package metadata
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/ahrdadan/image-metadata-viewer/src/internal/models"
+)
+
+// HEIF brands by the format name reported to image.DecodeConfig
+var heifBrands = map[string]string{
+	"heic": "heic",
+	"heix": "heic",
+	"hevc": "heic",
+	"hevx": "heic",
+	"heim": "heic",
+	"heis": "heic",
+	"mif1": "heic",
+	"msf1": "heic",
+	"avif": "avif",
+	"avis": "avif",
+}
+
+// errHEIFPixels is returned when asked to decode HEIF pixel data
+var errHEIFPixels = errors.New("heif: decoding pixel data is not supported")
+
+func init() {
+	// Register HEIC and AVIF so image.DecodeConfig reports their
+	// dimensions from the ispe property; pixels are never decoded
+	for brand, name := range heifBrands {
+		image.RegisterFormat(name, "????ftyp"+brand, decodeHEIF, decodeHEIFConfig)
+	}
+}
+
+// decodeHEIF satisfies image.RegisterFormat; pixel decoding is unsupported
+func decodeHEIF(io.Reader) (image.Image, error) {
+	return nil, errHEIFPixels
+}
+
+// decodeHEIFConfig reads the primary item's dimensions and color model
+func decodeHEIFConfig(r io.Reader) (image.Config, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return image.Config{}, err
+	}
+	file := parseHEIF(data)
+	if file == nil {
+		return image.Config{}, errors.New("heif: missing meta box")
+	}
+	info := file.info()
+	if info.Width == 0 || info.Height == 0 {
+		return image.Config{}, errors.New("heif: primary item has no ispe property")
+	}
+
+	model := color.Model(color.YCbCrModel)
+	switch {
+	case info.Chroma == "Monochrome":
+		model = color.GrayModel
+	case info.HasAlpha:
+		model = color.NYCbCrAModel
+	}
+	return image.Config{ColorModel: model, Width: info.Width, Height: info.Height}, nil
+}
+
+// HEIF codec names by item type
+var heifCodecs = map[string]string{
+	"hvc1": "HEVC",
+	"av01": "AV1",
+	"jpeg": "JPEG",
+	"unci": "Uncompressed",
+	"vvc1": "VVC",
+}
+
+// nclx color primaries, transfer characteristics and matrix coefficients
+// (ITU-T H.273)
+var (
+	nclxPrimaries = map[int]string{
+		1:  "BT.709",
+		2:  "Unspecified",
+		4:  "BT.470M",
+		5:  "BT.470BG",
+		6:  "BT.601",
+		9:  "BT.2020",
+		10: "XYZ",
+		11: "DCI-P3",
+		12: "Display P3",
+	}
+	nclxTransfers = map[int]string{
+		1:  "BT.709",
+		2:  "Unspecified",
+		4:  "Gamma 2.2",
+		5:  "Gamma 2.8",
+		6:  "BT.601",
+		8:  "Linear",
+		13: "sRGB",
+		14: "BT.2020 10-bit",
+		15: "BT.2020 12-bit",
+		16: "PQ (SMPTE ST 2084)",
+		18: "HLG",
+	}
+	nclxMatrices = map[int]string{
+		0:  "Identity (RGB)",
+		1:  "BT.709",
+		2:  "Unspecified",
+		5:  "BT.470BG",
+		6:  "BT.601",
+		9:  "BT.2020 non-constant",
+		10: "BT.2020 constant",
+	}
+)
+
+// heifAuxTypes names auxiliary image URNs
+var heifAuxTypes = map[string]string{
+	"urn:mpeg:hevc:2015:auxid:1":                        "Alpha",
+	"urn:mpeg:mpegB:cicp:systems:auxiliary:alpha":       "Alpha",
+	"urn:mpeg:hevc:2015:auxid:2":                        "Depth",
+	"urn:mpeg:mpegB:cicp:systems:auxiliary:depth":       "Depth",
+	"urn:com:apple:photo:2020:aux:hdrgainmap":           "HDR gain map",
+	"urn:com:apple:photo:2019:aux:semanticskinmatte":    "Skin matte",
+	"urn:com:apple:photo:2019:aux:semantichairmatte":    "Hair matte",
+	"urn:com:apple:photo:2019:aux:semanticteethmatte":   "Teeth matte",
+	"urn:com:apple:photo:2018:aux:portraiteffectsmatte": "Portrait matte",
+}
+
+// heifItem is an entry from the iinf box with its location
+type heifItem struct {
+	id          uint32
+	typ         string
+	name        string
+	contentType string
+	hidden      bool
+	extents     []heifExtent
+	method      int // iloc construction method: 0 file, 1 idat
+}
+
+// heifExtent is a byte range of an item
+type heifExtent struct {
+	offset, length uint64
+}
+
+// heifRef is a typed reference between items from the iref box
+type heifRef struct {
+	typ  string
+	from uint32
+	to   []uint32
+}
+
+// heifFile is the parsed meta box of a HEIF/AVIF file
+type heifFile struct {
+	data       []byte
+	majorBrand string
+	minor      uint32
+	brands     []string
+	primary    uint32
+	items      []*heifItem
+	byID       map[uint32]*heifItem
+	props      []bmffBox           // ipco children, 1-based in ipma
+	assoc      map[uint32][]uint16 // item ID to property indexes
+	refs       []heifRef
+	idat       []byte
+}
+
+// parseHEIF reads the ftyp and meta boxes of an ISOBMFF image file
+func parseHEIF(data []byte) *heifFile {
+	top := readBoxes(data)
+	ftyp := findBox(top, "ftyp")
+	meta := findBox(top, "meta")
+	if ftyp == nil || meta == nil || len(ftyp.data) < 8 {
+		return nil
+	}
+
+	f := &heifFile{
+		data:       data,
+		majorBrand: string(ftyp.data[:4]),
+		minor:      binary.BigEndian.Uint32(ftyp.data[4:]),
+		byID:       make(map[uint32]*heifItem),
+		assoc:      make(map[uint32][]uint16),
+	}
+	for i := 8; i+4 <= len(ftyp.data); i += 4 {
+		f.brands = append(f.brands, string(ftyp.data[i:i+4]))
+	}
+
+	_, _, body, ok := fullBoxHeader(meta.data)
+	if !ok {
+		return nil
+	}
+	children := readBoxes(body)
+
+	if pitm := findBox(children, "pitm"); pitm != nil {
+		if v, _, b, ok := fullBoxHeader(pitm.data); ok {
+			r := &bmffReader{data: b}
+			f.primary = uint32(r.uint(idSize(v, 1)))
+		}
+	}
+	if iinf := findBox(children, "iinf"); iinf != nil {
+		f.parseIINF(iinf.data)
+	}
+	if iloc := findBox(children, "iloc"); iloc != nil {
+		f.parseILOC(iloc.data)
+	}
+	if iprp := findBox(children, "iprp"); iprp != nil {
+		f.parseIPRP(iprp.data)
+	}
+	if iref := findBox(children, "iref"); iref != nil {
+		f.parseIREF(iref.data)
+	}
+	if idat := findBox(children, "idat"); idat != nil {
+		f.idat = idat.data
+	}
+
+	return f
+}
+
+// idSize returns the width of an item ID or count field: 2 bytes before
+// the given box version, 4 bytes from it onwards
+func idSize(version, wideFrom byte) int {
+	if version >= wideFrom {
+		return 4
+	}
+	return 2
+}
+
+// parseIINF reads item info entries
+func (f *heifFile) parseIINF(data []byte) {
+	version, _, body, ok := fullBoxHeader(data)
+	if !ok {
+		return
+	}
+	countSize := 2
+	if version > 0 {
+		countSize = 4
+	}
+	if len(body) < countSize {
+		return
+	}
+
+	for _, infe := range readBoxes(body[countSize:]) {
+		if infe.typ != "infe" {
+			continue
+		}
+		v, flags, b, ok := fullBoxHeader(infe.data)
+		if !ok || v < 2 {
+			// Version 0/1 entries predate item types and are not used
+			// by image files
+			continue
+		}
+		r := &bmffReader{data: b}
+		item := &heifItem{id: uint32(r.uint(idSize(v, 3)))}
+		r.uint(2) // protection index
+		item.typ = r.fourCC()
+		item.name = r.cstring()
+		if item.typ == "mime" {
+			item.contentType = r.cstring()
+		}
+		item.hidden = flags&1 != 0
+		if !r.err {
+			f.items = append(f.items, item)
+			if _, dup := f.byID[item.id]; !dup {
+				f.byID[item.id] = item
+			}
+		}
+	}
+}
+
+// parseILOC reads item locations and attaches them to items
+func (f *heifFile) parseILOC(data []byte) {
+	version, _, body, ok := fullBoxHeader(data)
+	if !ok || len(body) < 2 {
+		return
+	}
+	offsetSize := int(body[0] >> 4)
+	lengthSize := int(body[0] & 0x0F)
+	baseOffsetSize := int(body[1] >> 4)
+	indexSize := 0
+	if version == 1 || version == 2 {
+		indexSize = int(body[1] & 0x0F)
+	}
+
+	r := &bmffReader{data: body, pos: 2}
+	count := int(r.uint(idSize(version, 2)))
+	for i := 0; i < count && !r.err; i++ {
+		id := uint32(r.uint(idSize(version, 2)))
+		method := 0
+		if version == 1 || version == 2 {
+			method = int(r.uint(2) & 0x0F)
+		}
+		r.uint(2) // data reference index
+		base := r.uint(baseOffsetSize)
+
+		extentCount := int(r.uint(2))
+		var extents []heifExtent
+		for j := 0; j < extentCount && !r.err; j++ {
+			r.uint(indexSize)
+			offset := r.uint(offsetSize)
+			length := r.uint(lengthSize)
+			extents = append(extents, heifExtent{offset: base + offset, length: length})
+		}
+
+		if item := f.item(id); item != nil {
+			item.extents = extents
+			item.method = method
+		}
+	}
+}
+
+// parseIPRP reads the property container and item associations
+func (f *heifFile) parseIPRP(data []byte) {
+	children := readBoxes(data)
+	if ipco := findBox(children, "ipco"); ipco != nil {
+		f.props = readBoxes(ipco.data)
+	}
+
+	for _, ipma := range children {
+		if ipma.typ != "ipma" {
+			continue
+		}
+		version, flags, body, ok := fullBoxHeader(ipma.data)
+		if !ok {
+			continue
+		}
+		r := &bmffReader{data: body}
+		count := int(r.uint(4))
+		for i := 0; i < count && !r.err; i++ {
+			id := uint32(r.uint(idSize(version, 1)))
+			n := int(r.uint(1))
+			for j := 0; j < n && !r.err; j++ {
+				// The top bit marks the property as essential
+				var index uint16
+				if flags&1 != 0 {
+					index = uint16(r.uint(2)) & 0x7FFF
+				} else {
+					index = uint16(r.uint(1)) & 0x7F
+				}
+				f.assoc[id] = append(f.assoc[id], index)
+			}
+		}
+	}
+}
+
+// parseIREF reads typed item references
+func (f *heifFile) parseIREF(data []byte) {
+	version, _, body, ok := fullBoxHeader(data)
+	if !ok {
+		return
+	}
+	size := idSize(version, 1)
+	for _, box := range readBoxes(body) {
+		r := &bmffReader{data: box.data}
+		ref := heifRef{typ: box.typ, from: uint32(r.uint(size))}
+		n := int(r.uint(2))
+		for i := 0; i < n && !r.err; i++ {
+			ref.to = append(ref.to, uint32(r.uint(size)))
+		}
+		if !r.err {
+			f.refs = append(f.refs, ref)
+		}
+	}
+}
+
+// item returns the item with the given ID
+func (f *heifFile) item(id uint32) *heifItem {
+	return f.byID[id]
+}
+
+// itemData concatenates an item's extents from the file or idat
+func (f *heifFile) itemData(item *heifItem) []byte {
+	src := f.data
+	if item.method == 1 {
+		src = f.idat
+	} else if item.method != 0 {
+		return nil
+	}
+
+	var out []byte
+	for _, ext := range item.extents {
+		length := ext.length
+		if length == 0 {
+			// Zero length means the rest of the source
+			length = uint64(len(src)) - min(ext.offset, uint64(len(src)))
+		}
+		if ext.offset > uint64(len(src)) || length > uint64(len(src))-ext.offset ||
+			uint64(len(out))+length > maxInflatedBytes {
+			return nil
+		}
+		body := src[ext.offset : ext.offset+length]
+		if len(item.extents) == 1 {
+			// Single extents are returned in place so callers can locate them
+			return body
+		}
+		out = append(out, body...)
+	}
+	return out
+}
+
+// itemProps returns the properties associated with an item
+func (f *heifFile) itemProps(id uint32) []bmffBox {
+	var out []bmffBox
+	for _, index := range f.assoc[id] {
+		if index > 0 && int(index) <= len(f.props) {
+			out = append(out, f.props[index-1])
+		}
+	}
+	return out
+}
+
+// referencing returns items that reference id with the given type
+func (f *heifFile) referencing(typ string, id uint32) []*heifItem {
+	var out []*heifItem
+	for _, ref := range f.refs {
+		if ref.typ != typ {
+			continue
+		}
+		for _, to := range ref.to {
+			if to == id {
+				if item := f.item(ref.from); item != nil {
+					out = append(out, item)
+				}
+			}
+		}
+	}
+	return out
+}
+
+// exifTIFF returns the TIFF structure from the Exif item. The item begins
+// with a 4-byte offset to the TIFF header.
+func (f *heifFile) exifTIFF() []byte {
+	for _, item := range f.items {
+		if item.typ != "Exif" {
+			continue
+		}
+		payload := f.itemData(item)
+		if len(payload) < 4 {
+			return nil
+		}
+		start := 4 + int(binary.BigEndian.Uint32(payload))
+		if start < 4 || start > len(payload) {
+			return nil
+		}
+		return payload[start:]
+	}
+	return nil
+}
+
+// xmpPacket returns the XMP item's payload
+func (f *heifFile) xmpPacket() []byte {
+	for _, item := range f.items {
+		if item.typ == "mime" && item.contentType == "application/rdf+xml" {
+			return f.itemData(item)
+		}
+	}
+	return nil
+}
+
+// iccProfile returns the ICC profile from the primary item's colr property
+func (f *heifFile) iccProfile() []byte {
+	for _, prop := range f.itemProps(f.primary) {
+		if prop.typ == "colr" && len(prop.data) >= 4 {
+			if kind := string(prop.data[:4]); kind == "prof" || kind == "rICC" {
+				return prop.data[4:]
+			}
+		}
+	}
+	return nil
+}
+
+// info summarizes the primary image and its properties
+func (f *heifFile) info() *models.HEIFInfo {
+	info := &models.HEIFInfo{
+		MajorBrand:       f.majorBrand,
+		MinorVersion:     int(f.minor),
+		CompatibleBrands: f.brands,
+		PrimaryItemID:    int(f.primary),
+	}
+
+	primary := f.item(f.primary)
+	if primary != nil {
+		info.PrimaryItemType = primary.typ
+		info.Codec = heifCodecs[primary.typ]
+		if primary.typ == "grid" {
+			info.Codec = f.gridCodec(primary.id)
+		}
+	}
+
+	f.applyProps(info, f.primary)
+
+	for _, aux := range f.referencing("auxl", f.primary) {
+		for _, prop := range f.itemProps(aux.id) {
+			if prop.typ != "auxC" {
+				continue
+			}
+			_, _, body, ok := fullBoxHeader(prop.data)
+			if !ok {
+				continue
+			}
+			urn := (&bmffReader{data: body}).cstring()
+			name, known := heifAuxTypes[urn]
+			if !known {
+				name = urn
+			}
+			switch name {
+			case "Alpha":
+				info.HasAlpha = true
+			case "Depth":
+				info.HasDepth = true
+			}
+			info.AuxiliaryImages = append(info.AuxiliaryImages, name)
+		}
+	}
+	info.Thumbnails = len(f.referencing("thmb", f.primary))
+
+	counts := make(map[string]int)
+	for _, item := range f.items {
+		counts[item.typ]++
+		size := 0
+		for _, ext := range item.extents {
+			size += int(ext.length)
+		}
+		info.Items = append(info.Items, models.HEIFItem{
+			ID:          int(item.id),
+			Type:        strings.TrimSpace(item.typ),
+			Name:        item.name,
+			ContentType: item.contentType,
+			Hidden:      item.hidden,
+			Size:        size,
+		})
+	}
+	for typ, n := range counts {
+		info.ItemTypes = append(info.ItemTypes, fmt.Sprintf("%s ×%d", strings.TrimSpace(typ), n))
+	}
+	sort.Strings(info.ItemTypes)
+
+	return info
+}
+
+// gridCodec names the codec of the tiles that make up a grid image
+func (f *heifFile) gridCodec(id uint32) string {
+	for _, ref := range f.refs {
+		if ref.typ == "dimg" && ref.from == id && len(ref.to) > 0 {
+			if tile := f.item(ref.to[0]); tile != nil {
+				return heifCodecs[tile.typ] + " (grid)"
+			}
+		}
+	}
+	return "Grid"
+}
+
+// applyProps decodes the image properties associated with an item
+func (f *heifFile) applyProps(info *models.HEIFInfo, id uint32) {
+	for _, prop := range f.itemProps(id) {
+		body := prop.data
+		switch prop.typ {
+		case "ispe":
+			if _, _, b, ok := fullBoxHeader(body); ok && len(b) >= 8 {
+				info.Width = int(binary.BigEndian.Uint32(b))
+				info.Height = int(binary.BigEndian.Uint32(b[4:]))
+			}
+
+		case "pixi":
+			if _, _, b, ok := fullBoxHeader(body); ok && len(b) >= 1 {
+				n := int(b[0])
+				for i := 0; i < n && 1+i < len(b); i++ {
+					info.BitsPerChannel = append(info.BitsPerChannel, int(b[1+i]))
+				}
+			}
+
+		case "colr":
+			if len(body) < 4 {
+				continue
+			}
+			info.ColorType = string(body[:4])
+			if info.ColorType == "nclx" && len(body) >= 11 {
+				info.ColorPrimaries = lookupName(nclxPrimaries, int(binary.BigEndian.Uint16(body[4:])))
+				info.TransferCharacteristics = lookupName(nclxTransfers, int(binary.BigEndian.Uint16(body[6:])))
+				info.MatrixCoefficients = lookupName(nclxMatrices, int(binary.BigEndian.Uint16(body[8:])))
+				info.FullRange = body[10]&0x80 != 0
+			}
+
+		case "irot":
+			if len(body) >= 1 {
+				// Anti-clockwise, in units of 90 degrees
+				info.Rotation = int(body[0]&0x03) * 90
+			}
+
+		case "imir":
+			if len(body) >= 1 {
+				info.Mirror = "Vertical axis"
+				if body[0]&0x01 != 0 {
+					info.Mirror = "Horizontal axis"
+				}
+			}
+
+		case "hvcC":
+			if len(body) >= 19 {
+				info.Chroma = heifChroma(int(body[16] & 0x03))
+				if info.BitsPerChannel == nil {
+					info.BitsPerChannel = []int{int(body[17]&0x07) + 8}
+				}
+			}
+
+		case "av1C":
+			if len(body) >= 3 {
+				depth := 8
+				if body[2]&0x40 != 0 {
+					depth = 10
+					if body[2]&0x20 != 0 {
+						depth = 12
+					}
+				}
+				if info.BitsPerChannel == nil {
+					info.BitsPerChannel = []int{depth}
+				}
+				switch sx, sy := body[2]&0x08 != 0, body[2]&0x04 != 0; {
+				case body[2]&0x10 != 0:
+					info.Chroma = heifChroma(0)
+				case sx && sy:
+					info.Chroma = heifChroma(1)
+				case sx:
+					info.Chroma = heifChroma(2)
+				default:
+					info.Chroma = heifChroma(3)
+				}
+			}
+		}
+	}
+}
+
+// heifChroma names an HEVC chroma_format_idc
+func heifChroma(format int) string {
+	switch format {
+	case 0:
+		return "Monochrome"
+	case 1:
+		return chromaSubsampling(2, 2)
+	case 2:
+		return chromaSubsampling(2, 1)
+	default:
+		return chromaSubsampling(1, 1)
+	}
+}
+
+// extractHEIF fills the HEIF section and corrects the reported format for
+// AVIF files that use a generic major brand
+func extractHEIF(data []byte, meta *models.ImageMetadata) {
+	if sniffFormat(data) != formatHEIF {
+		return
+	}
+	file := parseHEIF(data)
+	if file == nil {
+		return
+	}
+
+	info := file.info()
+	meta.HEIF = info
+	if info.Codec == "AV1" || strings.HasPrefix(info.Codec, "AV1 ") {
+		meta.Format = "avif"
+	}
+	meta.FileType = strings.ToUpper(meta.Format)
+	if meta.MIMEType == "" || meta.MIMEType == "application/octet-stream" {
+		meta.MIMEType = "image/" + meta.Format
+	}
+
+	if len(info.BitsPerChannel) > 0 {
+		parts := make([]string, len(info.BitsPerChannel))
+		for i, bits := range info.BitsPerChannel {
+			parts[i] = fmt.Sprintf("%d", bits)
+		}
+		meta.BitsPerSample = strings.Join(parts, " ")
+	}
+}
+
+// isHEIFBrand reports whether the ftyp box declares a HEIF or AVIF brand
+func isHEIFBrand(data []byte) bool {
+	if len(data) < 12 || !bytes.Equal(data[4:8], []byte("ftyp")) {
+		return false
+	}
+	_, ok := heifBrands[string(data[8:12])]
+	return ok
+}
@@ -0,0 +1,110 @@
+package metadata
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+// bmff builds an ISOBMFF box from a type and payload parts
+func bmff(typ string, parts ...[]byte) []byte {
+	var body []byte
+	for _, p := range parts {
+		body = append(body, p...)
+	}
+	out := binary.BigEndian.AppendUint32(nil, uint32(8+len(body)))
+	return append(append(out, typ...), body...)
+}
+
+// fullBox builds a FullBox with the given version and flags
+func fullBox(typ string, version byte, flags uint32, parts ...[]byte) []byte {
+	header := binary.BigEndian.AppendUint32(nil, uint32(version)<<24|flags)
+	return bmff(typ, append([][]byte{header}, parts...)...)
+}
+
+func u16(v uint16) []byte { return binary.BigEndian.AppendUint16(nil, v) }
+func u32(v uint32) []byte { return binary.BigEndian.AppendUint32(nil, v) }
+
+// buildAVIF lays out an AV1 primary item with an alpha auxiliary image and
+// an Exif item stored in idat
+func buildAVIF(tiffData []byte) []byte {
+	infe := func(id uint16, typ string) []byte {
+		return fullBox("infe", 2, 0, u16(id), u16(0), []byte(typ), []byte{0})
+	}
+	exifItem := append(u32(0), tiffData...)
+
+	// iloc version 1: offset and length are 4 bytes, no base offset
+	iloc := fullBox("iloc", 1, 0,
+		[]byte{0x44, 0x00}, u16(2),
+		u16(1), u16(0), u16(0), u16(1), u32(0), u32(100),
+		u16(3), u16(1), u16(0), u16(1), u32(0), u32(uint32(len(exifItem))),
+	)
+
+	ipco := bmff("ipco",
+		fullBox("ispe", 0, 0, u32(640), u32(480)),
+		bmff("av1C", []byte{0x81, 0x00, 0x4C, 0x00}), // 10-bit 4:2:0
+		bmff("colr", []byte("nclx"), u16(9), u16(16), u16(9), []byte{0x80}),
+		bmff("irot", []byte{1}),
+		fullBox("auxC", 0, 0, []byte("urn:mpeg:mpegB:cicp:systems:auxiliary:alpha\x00")),
+	)
+	ipma := fullBox("ipma", 0, 0, u32(2),
+		u16(1), []byte{4, 0x81, 0x82, 3, 4},
+		u16(2), []byte{2, 0x81, 5},
+	)
+
+	meta := fullBox("meta", 0, 0,
+		fullBox("pitm", 0, 0, u16(1)),
+		fullBox("iinf", 0, 0, u16(3), infe(1, "av01"), infe(2, "av01"), infe(3, "Exif")),
+		iloc,
+		bmff("iprp", ipco, ipma),
+		fullBox("iref", 0, 0, bmff("auxl", u16(2), u16(1), u16(1))),
+		bmff("idat", exifItem),
+	)
+
+	ftyp := bmff("ftyp", []byte("mif1"), u32(0), []byte("mif1miafMA1B"))
+	return append(ftyp, meta...)
+}
+
+func TestExtractMetadataAVIF(t *testing.T) {
+	tiffData := buildTIFF([]testIFD{{tags: []testTag{asciiTag(0x010F, "Canon")}}})
+	meta := ExtractMetadata(buildAVIF(tiffData), "application/octet-stream", "photo.avif")
+
+	if meta.DecodeError != "" {
+		t.Fatalf("DecodeError = %q", meta.DecodeError)
+	}
+	if meta.Width != 640 || meta.Height != 480 {
+		t.Errorf("dimensions = %dx%d", meta.Width, meta.Height)
+	}
+	if meta.Format != "avif" || meta.FileType != "AVIF" || meta.MIMEType != "image/avif" {
+		t.Errorf("Format = %q, FileType = %q, MIMEType = %q", meta.Format, meta.FileType, meta.MIMEType)
+	}
+	if meta.Camera == nil || meta.Camera.Make != "Canon" {
+		t.Errorf("Camera = %+v, want EXIF from the Exif item", meta.Camera)
+	}
+
+	h := meta.HEIF
+	if h == nil {
+		t.Fatal("expected HEIF section")
+	}
+	if h.MajorBrand != "mif1" || len(h.CompatibleBrands) != 3 || h.PrimaryItemID != 1 || h.Codec != "AV1" {
+		t.Errorf("HEIF = %+v", h)
+	}
+	if h.Chroma != "YCbCr 4:2:0" || len(h.BitsPerChannel) != 1 || h.BitsPerChannel[0] != 10 {
+		t.Errorf("Chroma = %q, BitsPerChannel = %v", h.Chroma, h.BitsPerChannel)
+	}
+	if h.ColorPrimaries != "BT.2020" || h.TransferCharacteristics != "PQ (SMPTE ST 2084)" || !h.FullRange {
+		t.Errorf("colr = %q / %q / full %v", h.ColorPrimaries, h.TransferCharacteristics, h.FullRange)
+	}
+	if h.Rotation != 90 || !h.HasAlpha || len(h.Items) != 3 {
+		t.Errorf("Rotation = %d, HasAlpha = %v, Items = %v", h.Rotation, h.HasAlpha, h.Items)
+	}
+}
+
+func TestExtractMetadataAVIFOversizedEXIF(t *testing.T) {
+	meta := ExtractMetadata(buildAVIF(overflowingTIFF()), "application/octet-stream", "overflow.avif")
+	if meta.DecodeError != "" || meta.Width != 640 {
+		t.Fatalf("AVIF not decoded: %q", meta.DecodeError)
+	}
+	if meta.EXIF != nil {
+		t.Errorf("EXIF decoded from an oversized entry: %+v", meta.EXIF)
+	}
+}
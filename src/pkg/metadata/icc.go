@@ -0,0 +1,322 @@
+package metadata
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ahrdadan/image-metadata-viewer/src/internal/models"
+)
+
+// iccJPEGSignature prefixes each ICC profile chunk in APP2
+var iccJPEGSignature = []byte("ICC_PROFILE\x00")
+
+// tagICCProfile is the TIFF tag holding an embedded ICC profile
+const tagICCProfile = 0x8773
+
+// iccHeaderSize is the fixed size of the ICC profile header
+const iccHeaderSize = 128
+
+// iccDeviceClasses maps profile class signatures to names
+var iccDeviceClasses = map[string]string{
+	"scnr": "Input Device",
+	"mntr": "Display Device",
+	"prtr": "Output Device",
+	"link": "Device Link",
+	"spac": "Color Space Conversion",
+	"abst": "Abstract",
+	"nmcl": "Named Color",
+}
+
+// iccColorSpaces maps data color space signatures to names
+var iccColorSpaces = map[string]string{
+	"XYZ ": "XYZ",
+	"Lab ": "Lab",
+	"Luv ": "Luv",
+	"YCbr": "YCbCr",
+	"Yxy ": "Yxy",
+	"RGB ": "RGB",
+	"GRAY": "Gray",
+	"HSV ": "HSV",
+	"HLS ": "HLS",
+	"CMYK": "CMYK",
+	"CMY ": "CMY",
+}
+
+// iccPlatforms maps primary platform signatures to names
+var iccPlatforms = map[string]string{
+	"APPL": "Apple",
+	"MSFT": "Microsoft",
+	"SGI ": "Silicon Graphics",
+	"SUNW": "Sun Microsystems",
+	"TGNT": "Taligent",
+}
+
+// iccRenderingIntents maps header rendering intent values to names
+var iccRenderingIntents = map[int]string{
+	0: "Perceptual",
+	1: "Media-Relative Colorimetric",
+	2: "Saturation",
+	3: "ICC-Absolute Colorimetric",
+}
+
+// findICCProfile locates the embedded ICC profile in a supported container,
+// inflating a PNG profile within budget
+func findICCProfile(data []byte, budget *inflateBudget) []byte {
+	switch sniffFormat(data) {
+	case formatJPEG:
+		return findJPEGICC(data)
+	case formatPNG:
+		for _, chunk := range readPNGChunks(data) {
+			if chunk.typ != "iCCP" {
+				continue
+			}
+			// Profile name, null separator, compression method, zlib stream
+			_, rest, ok := bytes.Cut(chunk.data, []byte{0})
+			if !ok || len(rest) < 1 || rest[0] != 0 {
+				return nil
+			}
+			profile, err := budget.inflate(rest[1:])
+			if err != nil {
+				return nil
+			}
+			return profile
+		}
+	case formatWebP:
+		for _, chunk := range readWebPChunks(data) {
+			if chunk.fourCC == "ICCP" {
+				return chunk.data
+			}
+		}
+	case formatTIFF:
+		t, err := decodeTIFF(tiffPayload(data))
+		if err != nil || len(t.Dirs) == 0 {
+			return nil
+		}
+		if tag := findTag(t.Dirs[0], tagICCProfile); tag != nil {
+			return tag.Val
+		}
+	case formatHEIF:
+		if file := parseHEIF(data); file != nil {
+			return file.iccProfile()
+		}
+	case formatJP2:
+		return jp2ICCProfile(data)
+	case formatPSD:
+		return psdResource(data, psResourceICCProfile)
+	}
+	return nil
+}
+
+// findJPEGICC reassembles an ICC profile split across APP2 segments.
+// Each segment carries a 1-based sequence number and the total count.
+func findJPEGICC(data []byte) []byte {
+	chunks := make(map[int][]byte)
+	for _, seg := range readJPEGSegments(data) {
+		if seg.marker != markerAPP2 || !bytes.HasPrefix(seg.data, iccJPEGSignature) {
+			continue
+		}
+		body := seg.data[len(iccJPEGSignature):]
+		if len(body) < 2 {
+			continue
+		}
+		seq := int(body[0])
+		if _, dup := chunks[seq]; !dup {
+			chunks[seq] = body[2:]
+		}
+	}
+	if len(chunks) == 0 {
+		return nil
+	}
+
+	seqs := make([]int, 0, len(chunks))
+	for seq := range chunks {
+		seqs = append(seqs, seq)
+	}
+	sort.Ints(seqs)
+
+	var profile []byte
+	for _, seq := range seqs {
+		profile = append(profile, chunks[seq]...)
+	}
+	return profile
+}
+
+// parseICCProfile decodes the header and descriptive tags of a profile
+func parseICCProfile(data []byte) *models.ICCProfile {
+	if len(data) < iccHeaderSize+4 || string(data[36:40]) != "acsp" {
+		return nil
+	}
+
+	p := &models.ICCProfile{
+		Size:            int(binary.BigEndian.Uint32(data[0:])),
+		CMM:             iccSignature(data[4:8]),
+		Version:         fmt.Sprintf("%d.%d.%d", data[8], data[9]>>4, data[9]&0x0F),
+		DeviceClass:     lookupSignature(iccDeviceClasses, data[12:16]),
+		ColorSpace:      lookupSignature(iccColorSpaces, data[16:20]),
+		PCS:             lookupSignature(iccColorSpaces, data[20:24]),
+		Created:         iccDateTime(data[24:36]),
+		Platform:        lookupSignature(iccPlatforms, data[40:44]),
+		Manufacturer:    iccSignature(data[48:52]),
+		Model:           iccSignature(data[52:56]),
+		RenderingIntent: lookupName(iccRenderingIntents, int(binary.BigEndian.Uint32(data[64:]))),
+		Creator:         iccSignature(data[80:84]),
+	}
+	if id := data[84:100]; !bytes.Equal(id, make([]byte, 16)) {
+		p.ProfileID = fmt.Sprintf("%x", id)
+	}
+
+	count := int(binary.BigEndian.Uint32(data[iccHeaderSize:]))
+	pos := iccHeaderSize + 4
+	for i := 0; i < count && pos+12 <= len(data); i++ {
+		sig := string(data[pos : pos+4])
+		offset := int(binary.BigEndian.Uint32(data[pos+4:]))
+		size := int(binary.BigEndian.Uint32(data[pos+8:]))
+		pos += 12
+
+		tag := models.ICCTag{Signature: strings.TrimSpace(sig), Offset: offset, Size: size}
+		if offset < 0 || size < 0 || offset+size > len(data) {
+			p.Tags = append(p.Tags, tag)
+			continue
+		}
+		body := data[offset : offset+size]
+		if len(body) >= 4 {
+			tag.Type = strings.TrimSpace(string(body[:4]))
+		}
+		p.Tags = append(p.Tags, tag)
+
+		switch sig {
+		case "desc":
+			p.Description = iccText(body)
+		case "cprt":
+			p.Copyright = iccText(body)
+		case "dmnd":
+			p.DeviceManufacturer = iccText(body)
+		case "dmdd":
+			p.DeviceModel = iccText(body)
+		case "wtpt":
+			p.WhitePoint = iccXYZ(body)
+		}
+	}
+
+	return p
+}
+
+// iccText decodes a textDescriptionType, textType or multiLocalizedUnicodeType
+func iccText(body []byte) string {
+	if len(body) < 12 {
+		return ""
+	}
+	switch string(body[:4]) {
+	case "desc":
+		// ASCII count includes the terminating null
+		n := int(binary.BigEndian.Uint32(body[8:]))
+		if n <= 0 || 12+n > len(body) {
+			return ""
+		}
+		return iccASCII(body[12 : 12+n])
+	case "text":
+		return iccASCII(body[8:])
+	case "mluc":
+		records := int(binary.BigEndian.Uint32(body[8:]))
+		recordSize := int(binary.BigEndian.Uint32(body[12:]))
+		if records <= 0 || recordSize < 12 {
+			return ""
+		}
+		// Prefer English, otherwise the first record
+		var chosen []byte
+		for i := 0; i < records; i++ {
+			rec := 16 + i*recordSize
+			if rec+12 > len(body) {
+				break
+			}
+			length := int(binary.BigEndian.Uint32(body[rec+4:]))
+			offset := int(binary.BigEndian.Uint32(body[rec+8:]))
+			if offset < 0 || length < 0 || offset+length > len(body) {
+				continue
+			}
+			text := body[offset : offset+length]
+			if chosen == nil || string(body[rec:rec+2]) == "en" {
+				chosen = text
+				if string(body[rec:rec+2]) == "en" {
+					break
+				}
+			}
+		}
+		return strings.TrimRight(decodeUTF16(chosen, binary.BigEndian), "\x00")
+	}
+	return ""
+}
+
+// iccXYZ formats the first value of an XYZType tag
+func iccXYZ(body []byte) string {
+	if len(body) < 20 || string(body[:4]) != "XYZ " {
+		return ""
+	}
+	xyz := make([]string, 3)
+	for i := range xyz {
+		v := float64(int32(binary.BigEndian.Uint32(body[8+4*i:]))) / 65536
+		xyz[i] = fmt.Sprintf("%.4f", v)
+	}
+	return strings.Join(xyz, ", ")
+}
+
+// iccDateTime formats the 12-byte dateTimeNumber from the header
+func iccDateTime(b []byte) string {
+	var v [6]int
+	for i := range v {
+		v[i] = int(binary.BigEndian.Uint16(b[2*i:]))
+	}
+	if v[0] == 0 {
+		return ""
+	}
+	return time.Date(v[0], time.Month(v[1]), v[2], v[3], v[4], v[5], 0, time.UTC).Format(time.RFC3339)
+}
+
+// iccSignature returns a 4-byte signature as text, or "" when unset
+func iccSignature(b []byte) string {
+	if bytes.Equal(b, []byte{0, 0, 0, 0}) {
+		return ""
+	}
+	return iccASCII(b)
+}
+
+// iccASCII returns null-terminated ASCII text with padding removed
+func iccASCII(b []byte) string {
+	if i := bytes.IndexByte(b, 0); i >= 0 {
+		b = b[:i]
+	}
+	return strings.TrimSpace(string(b))
+}
+
+// lookupSignature names a signature, falling back to the signature itself
+func lookupSignature(names map[string]string, b []byte) string {
+	if name, ok := names[string(b)]; ok {
+		return name
+	}
+	return iccSignature(b)
+}
+
+// extractICC finds and parses the embedded ICC profile
+func extractICC(data []byte, result *Result) error {
+	meta := result.Metadata
+	profile := findICCProfile(data, result.budget())
+	if len(profile) == 0 {
+		return nil
+	}
+
+	meta.ICCProfile = parseICCProfile(profile)
+	if meta.ICCProfile == nil {
+		return errors.New("icc: profile header is invalid")
+	}
+
+	// An embedded profile is more specific than EXIF's sRGB/Uncalibrated flag
+	if meta.ICCProfile.Description != "" && meta.ColorSpace != "sRGB" {
+		meta.ColorSpace = meta.ICCProfile.Description
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+package metadata
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+// buildICCProfile encodes a minimal v2 display profile with desc, cprt
+// and wtpt tags
+func buildICCProfile() []byte {
+	desc := []byte("desc\x00\x00\x00\x00")
+	desc = binary.BigEndian.AppendUint32(desc, 10)
+	desc = append(desc, "Test sRGB\x00"...)
+	cprt := append([]byte("text\x00\x00\x00\x00"), "Public Domain\x00"...)
+	wtpt := []byte("XYZ \x00\x00\x00\x00")
+	for _, v := range []float64{0.9505, 1.0, 1.0891} {
+		wtpt = binary.BigEndian.AppendUint32(wtpt, uint32(int32(v*65536+0.5)))
+	}
+
+	tags := []struct {
+		sig  string
+		body []byte
+	}{{"desc", desc}, {"cprt", cprt}, {"wtpt", wtpt}}
+
+	header := make([]byte, iccHeaderSize)
+	copy(header[4:], "lcms")
+	header[8], header[9] = 2, 0x10
+	copy(header[12:], "mntrRGB XYZ ")
+	for i, v := range []uint16{2025, 10, 31, 12, 0, 0} {
+		binary.BigEndian.PutUint16(header[24+2*i:], v)
+	}
+	copy(header[36:], "acspAPPL")
+	binary.BigEndian.PutUint32(header[64:], 1)
+
+	table := binary.BigEndian.AppendUint32(nil, uint32(len(tags)))
+	offset := iccHeaderSize + 4 + 12*len(tags)
+	var bodies []byte
+	for _, tag := range tags {
+		table = append(table, tag.sig...)
+		table = binary.BigEndian.AppendUint32(table, uint32(offset+len(bodies)))
+		table = binary.BigEndian.AppendUint32(table, uint32(len(tag.body)))
+		bodies = append(bodies, tag.body...)
+	}
+
+	profile := append(append(header, table...), bodies...)
+	binary.BigEndian.PutUint32(profile, uint32(len(profile)))
+	return profile
+}
+
+// iccSegment wraps one chunk of a profile in an APP2 segment
+func iccSegment(seq, count byte, chunk []byte) []byte {
+	payload := append(append([]byte{}, iccJPEGSignature...), seq, count)
+	return jpegSegment(markerAPP2, append(payload, chunk...))
+}
+
+func TestExtractMetadataICCProfile(t *testing.T) {
+	profile := buildICCProfile()
+	half := len(profile) / 2
+
+	// Segments deliberately out of order
+	data := buildJPEGWithSegments(t,
+		iccSegment(2, 2, profile[half:]),
+		iccSegment(1, 2, profile[:half]),
+	)
+	meta := ExtractMetadata(data, "image/jpeg", "icc.jpg")
+
+	p := meta.ICCProfile
+	if p == nil {
+		t.Fatal("expected ICC profile")
+	}
+	checks := map[string][2]string{
+		"Description":     {p.Description, "Test sRGB"},
+		"Copyright":       {p.Copyright, "Public Domain"},
+		"Version":         {p.Version, "2.1.0"},
+		"DeviceClass":     {p.DeviceClass, "Display Device"},
+		"ColorSpace":      {p.ColorSpace, "RGB"},
+		"PCS":             {p.PCS, "XYZ"},
+		"RenderingIntent": {p.RenderingIntent, "Media-Relative Colorimetric"},
+		"WhitePoint":      {p.WhitePoint, "0.9505, 1.0000, 1.0891"},
+		"Created":         {p.Created, "2025-10-31T12:00:00Z"},
+		"Platform":        {p.Platform, "Apple"},
+	}
+	for field, c := range checks {
+		if c[0] != c[1] {
+			t.Errorf("%s = %q, want %q", field, c[0], c[1])
+		}
+	}
+	if meta.ColorSpace != "Test sRGB" {
+		t.Errorf("ColorSpace = %q, want the profile description", meta.ColorSpace)
+	}
+}
+
+func TestExtractMetadataColorComponents(t *testing.T) {
+	tests := []struct {
+		name       string
+		encode     func(*bytes.Buffer) error
+		mode       string
+		components int
+		samples    int
+	}{
+		{"gray png", func(b *bytes.Buffer) error {
+			return png.Encode(b, image.NewGray(image.Rect(0, 0, 2, 2)))
+		}, "Grayscale", 1, 1},
+		{"rgba png", func(b *bytes.Buffer) error {
+			img := image.NewNRGBA(image.Rect(0, 0, 2, 2))
+			img.Set(0, 0, color.NRGBA{R: 255, A: 128})
+			return png.Encode(b, img)
+		}, "RGB with alpha", 3, 4},
+		{"gray jpeg", func(b *bytes.Buffer) error {
+			return jpeg.Encode(b, image.NewGray(image.Rect(0, 0, 8, 8)), nil)
+		}, "Grayscale", 1, 1},
+		{"color jpeg", func(b *bytes.Buffer) error {
+			return jpeg.Encode(b, image.NewRGBA(image.Rect(0, 0, 8, 8)), nil)
+		}, "YCbCr", 3, 3},
+	}
+
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		if err := tt.encode(&buf); err != nil {
+			t.Fatalf("%s: encode: %v", tt.name, err)
+		}
+		meta := ExtractMetadata(buf.Bytes(), "", tt.name)
+		if meta.ColorMode != tt.mode || meta.ColorComponents != tt.components || meta.SamplesPerPixel != tt.samples {
+			t.Errorf("%s: got %q/%d/%d, want %q/%d/%d", tt.name,
+				meta.ColorMode, meta.ColorComponents, meta.SamplesPerPixel,
+				tt.mode, tt.components, tt.samples)
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package metadata
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/color"
+	"io"
+
+	"github.com/ahrdadan/image-metadata-viewer/src/internal/models"
+)
+
+// ICO and CUR directory types
+const (
+	iconTypeICO = 1
+	iconTypeCUR = 2
+)
+
+// maxIconImages bounds the directory walk; real files hold a handful
+const maxIconImages = 256
+
+// errIconPixels is returned when asked to decode ICO or CUR pixel data
+var errIconPixels = errors.New("ico: decoding pixel data is not supported")
+
+// pngChannels is the sample count per pixel for each PNG color type
+var pngChannels = map[int]int{0: 1, 2: 3, 3: 1, 4: 2, 6: 4}
+
+func init() {
+	// Register ICO and CUR so image.DecodeConfig reports the largest
+	// embedded image; pixels are never decoded
+	image.RegisterFormat("ico", "\x00\x00\x01\x00", decodeIcon, decodeIconConfig)
+	image.RegisterFormat("cur", "\x00\x00\x02\x00", decodeIcon, decodeIconConfig)
+}
+
+// decodeIcon satisfies image.RegisterFormat; pixel decoding is unsupported
+func decodeIcon(io.Reader) (image.Image, error) {
+	return nil, errIconPixels
+}
+
+// decodeIconConfig reports the largest image in the directory, preferring
+// the deeper one when two share a size
+func decodeIconConfig(r io.Reader) (image.Config, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return image.Config{}, err
+	}
+	info := parseIcon(data)
+	if info == nil || len(info.Images) == 0 {
+		return image.Config{}, errors.New("ico: invalid image directory")
+	}
+
+	best := info.Images[0]
+	for _, img := range info.Images[1:] {
+		area, bestArea := img.Width*img.Height, best.Width*best.Height
+		if area > bestArea || (area == bestArea && img.BitDepth > best.BitDepth) {
+			best = img
+		}
+	}
+
+	model := color.Model(color.NRGBAModel)
+	if best.BitDepth > 0 && best.BitDepth <= 8 {
+		model = color.Palette{}
+	}
+	return image.Config{ColorModel: model, Width: best.Width, Height: best.Height}, nil
+}
+
+// isIcon reports whether data starts with an ICO or CUR directory header
+func isIcon(data []byte) bool {
+	if len(data) < 6 || data[0] != 0 || data[1] != 0 || data[3] != 0 {
+		return false
+	}
+	return (data[2] == iconTypeICO || data[2] == iconTypeCUR) && binary.LittleEndian.Uint16(data[4:]) > 0
+}
+
+// parseIcon reads the icon directory and the header of every image it
+// points to. Each image is either a complete PNG file or a BMP without its
+// file header, whose DIB height counts the XOR and AND masks together.
+func parseIcon(data []byte) *models.IconInfo {
+	if !isIcon(data) {
+		return nil
+	}
+
+	info := &models.IconInfo{Type: "ICO", Count: int(binary.LittleEndian.Uint16(data[4:]))}
+	cursor := data[2] == iconTypeCUR
+	if cursor {
+		info.Type = "CUR"
+	}
+
+	info.Images = []models.IconImage{}
+	for i := 0; i < info.Count && i < maxIconImages; i++ {
+		entry := 6 + 16*i
+		if entry+16 > len(data) {
+			break
+		}
+		e := data[entry : entry+16]
+		img := models.IconImage{
+			Index:      i,
+			Width:      iconDimension(e[0]),
+			Height:     iconDimension(e[1]),
+			ColorCount: int(e[2]),
+			Size:       int(binary.LittleEndian.Uint32(e[8:])),
+			Offset:     int(binary.LittleEndian.Uint32(e[12:])),
+		}
+		if cursor {
+			img.Hotspot = &models.IconHotspot{
+				X: int(binary.LittleEndian.Uint16(e[4:])),
+				Y: int(binary.LittleEndian.Uint16(e[6:])),
+			}
+		} else {
+			img.BitDepth = int(binary.LittleEndian.Uint16(e[6:]))
+		}
+
+		if img.Offset < len(data) {
+			readIconImage(data[img.Offset:], &img)
+		}
+		info.Images = append(info.Images, img)
+	}
+	return info
+}
+
+// readIconImage fills the format, size and depth from the stored image
+// header, which is more reliable than the directory entry
+func readIconImage(body []byte, img *models.IconImage) {
+	if bytes.HasPrefix(body, pngSignature) {
+		img.Format = "PNG"
+		if len(body) >= 26 && string(body[12:16]) == "IHDR" {
+			img.Width = int(binary.BigEndian.Uint32(body[16:]))
+			img.Height = int(binary.BigEndian.Uint32(body[20:]))
+			colorType := int(body[25])
+			img.ColorType = lookupName(pngColorTypes, colorType)
+			img.BitDepth = int(body[24]) * pngChannels[colorType]
+		}
+		return
+	}
+
+	img.Format = "BMP"
+	if len(body) < 16 || binary.LittleEndian.Uint32(body) < 12 {
+		return
+	}
+	if size := binary.LittleEndian.Uint32(body); size == 12 {
+		// BITMAPCOREHEADER
+		img.BitDepth = int(binary.LittleEndian.Uint16(body[10:]))
+		return
+	}
+	width := int(int32(binary.LittleEndian.Uint32(body[4:])))
+	height := int(int32(binary.LittleEndian.Uint32(body[8:]))) / 2
+	if height < 0 {
+		height = -height
+	}
+	if width > 0 && height > 0 {
+		img.Width, img.Height = width, height
+	}
+	if bits := int(binary.LittleEndian.Uint16(body[14:])); bits > 0 {
+		img.BitDepth = bits
+	}
+}
+
+// iconDimension decodes a directory width or height, where 0 means 256
+func iconDimension(b byte) int {
+	if b == 0 {
+		return 256
+	}
+	return int(b)
+}
+
+// extractIcon fills the icon section and MIME type for ICO and CUR files
+func extractIcon(data []byte, meta *models.ImageMetadata) {
+	if sniffFormat(data) != formatICO {
+		return
+	}
+	info := parseIcon(data)
+	if info == nil {
+		return
+	}
+	meta.Icon = info
+	if meta.MIMEType == "" || meta.MIMEType == "application/octet-stream" {
+		meta.MIMEType = "image/x-icon"
+		if info.Type == "CUR" {
+			meta.MIMEType = "image/x-win-cursor"
+		}
+	}
+}
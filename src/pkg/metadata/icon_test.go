@@ -0,0 +1,81 @@
+package metadata
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/png"
+	"testing"
+)
+
+// buildIcon writes an icon directory of the given type followed by the
+// image bodies. Directory sizes are left as 0 so the headers must be read.
+func buildIcon(typ uint16, hotspot [2]uint16, bodies ...[]byte) []byte {
+	out := binary.LittleEndian.AppendUint16(nil, 0)
+	out = binary.LittleEndian.AppendUint16(out, typ)
+	out = binary.LittleEndian.AppendUint16(out, uint16(len(bodies)))
+
+	offset := 6 + 16*len(bodies)
+	for _, body := range bodies {
+		out = append(out, 0, 0, 0, 0)
+		out = binary.LittleEndian.AppendUint16(out, hotspot[0])
+		out = binary.LittleEndian.AppendUint16(out, hotspot[1])
+		out = binary.LittleEndian.AppendUint32(out, uint32(len(body)))
+		out = binary.LittleEndian.AppendUint32(out, uint32(offset))
+		offset += len(body)
+	}
+	for _, body := range bodies {
+		out = append(out, body...)
+	}
+	return out
+}
+
+// iconBitmap returns a BITMAPINFOHEADER whose height covers both masks
+func iconBitmap(width, height int, bits uint16) []byte {
+	out := binary.LittleEndian.AppendUint32(nil, 40)
+	out = binary.LittleEndian.AppendUint32(out, uint32(width))
+	out = binary.LittleEndian.AppendUint32(out, uint32(2*height))
+	out = binary.LittleEndian.AppendUint16(out, 1)
+	out = binary.LittleEndian.AppendUint16(out, bits)
+	return append(out, make([]byte, 24)...)
+}
+
+func TestExtractMetadataICO(t *testing.T) {
+	var enc bytes.Buffer
+	if err := png.Encode(&enc, image.NewNRGBA(image.Rect(0, 0, 64, 64))); err != nil {
+		t.Fatalf("png.Encode: %v", err)
+	}
+
+	data := buildIcon(iconTypeICO, [2]uint16{1, 0}, iconBitmap(16, 16, 8), enc.Bytes())
+	meta := ExtractMetadata(data, "application/octet-stream", "favicon.ico")
+	if meta.DecodeError != "" {
+		t.Fatalf("DecodeError = %q", meta.DecodeError)
+	}
+	if meta.Format != "ico" || meta.Width != 64 || meta.Height != 64 || meta.MIMEType != "image/x-icon" {
+		t.Errorf("Format = %q, size = %dx%d, MIMEType = %q", meta.Format, meta.Width, meta.Height, meta.MIMEType)
+	}
+
+	icon := meta.Icon
+	if icon == nil || icon.Type != "ICO" || len(icon.Images) != 2 {
+		t.Fatalf("icon = %+v", icon)
+	}
+	bmp, pngImg := icon.Images[0], icon.Images[1]
+	if bmp.Format != "BMP" || bmp.Width != 16 || bmp.Height != 16 || bmp.BitDepth != 8 {
+		t.Errorf("BMP image = %+v", bmp)
+	}
+	if pngImg.Format != "PNG" || pngImg.Width != 64 || pngImg.BitDepth != 32 || pngImg.ColorType != "RGB with alpha" {
+		t.Errorf("PNG image = %+v", pngImg)
+	}
+}
+
+func TestExtractMetadataCUR(t *testing.T) {
+	data := buildIcon(iconTypeCUR, [2]uint16{5, 7}, iconBitmap(32, 32, 1))
+	meta := ExtractMetadata(data, "", "pointer.cur")
+	if meta.Format != "cur" || meta.Icon == nil || meta.Icon.Type != "CUR" {
+		t.Fatalf("Format = %q, icon = %+v", meta.Format, meta.Icon)
+	}
+	img := meta.Icon.Images[0]
+	if img.Hotspot == nil || img.Hotspot.X != 5 || img.Hotspot.Y != 7 || img.BitDepth != 1 {
+		t.Errorf("cursor image = %+v, hotspot = %+v", img, img.Hotspot)
+	}
+}
@@ -0,0 +1,313 @@
+package metadata
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/ahrdadan/image-metadata-viewer/src/internal/models"
+)
+
+// iptcTagMarker starts every IPTC-IIM dataset
+const iptcTagMarker = 0x1C
+
+// iptcKey identifies a dataset by record and dataset number
+type iptcKey struct {
+	record, dataset uint8
+}
+
+// iptcDatasetNames maps IIM record:dataset numbers to their names
+var iptcDatasetNames = map[iptcKey]string{
+	{1, 0}:   "EnvelopeRecordVersion",
+	{1, 5}:   "Destination",
+	{1, 20}:  "FileFormat",
+	{1, 22}:  "FileVersion",
+	{1, 30}:  "ServiceIdentifier",
+	{1, 40}:  "EnvelopeNumber",
+	{1, 50}:  "ProductID",
+	{1, 60}:  "EnvelopePriority",
+	{1, 70}:  "DateSent",
+	{1, 80}:  "TimeSent",
+	{1, 90}:  "CodedCharacterSet",
+	{1, 100}: "UniqueObjectName",
+	{2, 0}:   "ApplicationRecordVersion",
+	{2, 3}:   "ObjectTypeReference",
+	{2, 4}:   "ObjectAttributeReference",
+	{2, 5}:   "ObjectName",
+	{2, 7}:   "EditStatus",
+	{2, 10}:  "Urgency",
+	{2, 12}:  "SubjectReference",
+	{2, 15}:  "Category",
+	{2, 20}:  "SupplementalCategories",
+	{2, 22}:  "FixtureIdentifier",
+	{2, 25}:  "Keywords",
+	{2, 26}:  "ContentLocationCode",
+	{2, 27}:  "ContentLocationName",
+	{2, 30}:  "ReleaseDate",
+	{2, 35}:  "ReleaseTime",
+	{2, 37}:  "ExpirationDate",
+	{2, 38}:  "ExpirationTime",
+	{2, 40}:  "SpecialInstructions",
+	{2, 42}:  "ActionAdvised",
+	{2, 45}:  "ReferenceService",
+	{2, 47}:  "ReferenceDate",
+	{2, 50}:  "ReferenceNumber",
+	{2, 55}:  "DateCreated",
+	{2, 60}:  "TimeCreated",
+	{2, 62}:  "DigitalCreationDate",
+	{2, 63}:  "DigitalCreationTime",
+	{2, 65}:  "OriginatingProgram",
+	{2, 70}:  "ProgramVersion",
+	{2, 75}:  "ObjectCycle",
+	{2, 80}:  "By-line",
+	{2, 85}:  "By-lineTitle",
+	{2, 90}:  "City",
+	{2, 92}:  "Sub-location",
+	{2, 95}:  "Province-State",
+	{2, 100}: "Country-PrimaryLocationCode",
+	{2, 101}: "Country-PrimaryLocationName",
+	{2, 103}: "OriginalTransmissionReference",
+	{2, 105}: "Headline",
+	{2, 110}: "Credit",
+	{2, 115}: "Source",
+	{2, 116}: "CopyrightNotice",
+	{2, 118}: "Contact",
+	{2, 120}: "Caption-Abstract",
+	{2, 121}: "LocalCaption",
+	{2, 122}: "Writer-Editor",
+	{2, 125}: "RasterizedCaption",
+	{2, 130}: "ImageType",
+	{2, 131}: "ImageOrientation",
+	{2, 135}: "LanguageIdentifier",
+	{2, 150}: "AudioType",
+	{2, 200}: "ObjectPreviewFileFormat",
+	{2, 201}: "ObjectPreviewFileVersion",
+	{2, 202}: "ObjectPreviewData",
+	{2, 221}: "Prefs",
+	{2, 225}: "ClassifyState",
+	{2, 228}: "SimilarityIndex",
+	{2, 230}: "DocumentNotes",
+	{2, 231}: "DocumentHistory",
+	{2, 232}: "ExifCameraInfo",
+	{2, 255}: "CatalogSets",
+}
+
+// iptcBinaryDatasets hold integers or binary data rather than text
+var iptcBinaryDatasets = map[iptcKey]bool{
+	{1, 0}:   true,
+	{1, 20}:  true,
+	{1, 22}:  true,
+	{2, 0}:   true,
+	{2, 125}: true,
+	{2, 200}: true,
+	{2, 201}: true,
+	{2, 202}: true,
+}
+
+// iptcDataset is a raw dataset from an IIM stream
+type iptcDataset struct {
+	key  iptcKey
+	data []byte
+}
+
+// readIPTCDatasets walks the datasets of an IPTC-IIM stream
+func readIPTCDatasets(data []byte) []iptcDataset {
+	var datasets []iptcDataset
+	pos := 0
+	for pos+5 <= len(data) {
+		if data[pos] != iptcTagMarker {
+			// Photoshop pads the block with zeros
+			break
+		}
+		key := iptcKey{record: data[pos+1], dataset: data[pos+2]}
+		size := int(binary.BigEndian.Uint16(data[pos+3:]))
+		pos += 5
+
+		// Extended dataset: the low bits give the length of the size field
+		if size&0x8000 != 0 {
+			n := size & 0x7FFF
+			if n > 4 || pos+n > len(data) {
+				break
+			}
+			size = 0
+			for _, b := range data[pos : pos+n] {
+				size = size<<8 | int(b)
+			}
+			pos += n
+		}
+		if size < 0 || pos+size > len(data) {
+			break
+		}
+
+		datasets = append(datasets, iptcDataset{key: key, data: data[pos : pos+size]})
+		pos += size
+	}
+	return datasets
+}
+
+// iptcCharset describes how text datasets are encoded
+type iptcCharset int
+
+const (
+	iptcCharsetUnknown iptcCharset = iota
+	iptcCharsetUTF8
+	iptcCharsetLatin1
+)
+
+// codedCharacterSet interprets the ISO 2022 escape sequence in 1:90
+func codedCharacterSet(value []byte) (iptcCharset, string) {
+	switch {
+	case bytes.Equal(value, []byte("\x1b%G")):
+		return iptcCharsetUTF8, "UTF-8"
+	case bytes.Equal(value, []byte("\x1b.A")), bytes.Equal(value, []byte("\x1b-A")):
+		return iptcCharsetLatin1, "ISO-8859-1"
+	case len(value) == 0:
+		return iptcCharsetUnknown, ""
+	default:
+		return iptcCharsetUnknown, fmt.Sprintf("Unknown (%q)", value)
+	}
+}
+
+// decodeIPTCText decodes a text dataset using the declared character set.
+// Without a declaration the value is used as-is when it is valid UTF-8 and
+// read as Latin-1 otherwise.
+func decodeIPTCText(value []byte, charset iptcCharset) string {
+	value = bytes.TrimRight(value, "\x00")
+	if charset == iptcCharsetLatin1 || (charset != iptcCharsetUTF8 && !utf8.Valid(value)) {
+		return strings.TrimSpace(decodeLatin1(value))
+	}
+	return strings.TrimSpace(strings.ToValidUTF8(string(value), "�"))
+}
+
+// iptcBinaryValue formats an integer or binary dataset
+func iptcBinaryValue(value []byte) string {
+	switch len(value) {
+	case 1:
+		return fmt.Sprintf("%d", value[0])
+	case 2:
+		return fmt.Sprintf("%d", binary.BigEndian.Uint16(value))
+	case 4:
+		return fmt.Sprintf("%d", binary.BigEndian.Uint32(value))
+	default:
+		return fmt.Sprintf("(%d bytes of binary data)", len(value))
+	}
+}
+
+// formatIPTCDate converts CCYYMMDD to YYYY-MM-DD
+func formatIPTCDate(value string) string {
+	if len(value) != 8 {
+		return value
+	}
+	return value[:4] + "-" + value[4:6] + "-" + value[6:]
+}
+
+// formatIPTCTime converts HHMMSS±HHMM to HH:MM:SS±HH:MM
+func formatIPTCTime(value string) string {
+	switch len(value) {
+	case 6:
+		return value[:2] + ":" + value[2:4] + ":" + value[4:]
+	case 11:
+		return value[:2] + ":" + value[2:4] + ":" + value[4:6] + value[6:9] + ":" + value[9:]
+	default:
+		return value
+	}
+}
+
+// parseIPTC decodes an IPTC-IIM stream into curated fields and a full
+// dataset listing
+func parseIPTC(data []byte) *models.IPTCData {
+	datasets := readIPTCDatasets(data)
+	if len(datasets) == 0 {
+		return nil
+	}
+
+	out := &models.IPTCData{}
+	charset := iptcCharsetUnknown
+	for _, ds := range datasets {
+		if ds.key == (iptcKey{1, 90}) {
+			charset, out.CodedCharacterSet = codedCharacterSet(ds.data)
+		}
+	}
+
+	for _, ds := range datasets {
+		name, ok := iptcDatasetNames[ds.key]
+		if !ok {
+			name = fmt.Sprintf("Unknown (%d:%d)", ds.key.record, ds.key.dataset)
+		}
+
+		var value string
+		switch {
+		case ds.key == (iptcKey{1, 90}):
+			value = out.CodedCharacterSet
+		case iptcBinaryDatasets[ds.key]:
+			value = iptcBinaryValue(ds.data)
+		default:
+			value = decodeIPTCText(ds.data, charset)
+		}
+
+		out.Datasets = append(out.Datasets, models.IPTCDataset{
+			Record:  int(ds.key.record),
+			Dataset: int(ds.key.dataset),
+			Name:    name,
+			Value:   value,
+		})
+
+		if ds.key.record != 2 {
+			continue
+		}
+		switch ds.key.dataset {
+		case 5:
+			out.ObjectName = value
+		case 10:
+			out.Urgency = value
+		case 15:
+			out.Category = value
+		case 20:
+			out.SupplementalCategories = append(out.SupplementalCategories, value)
+		case 25:
+			out.Keywords = append(out.Keywords, value)
+		case 40:
+			out.SpecialInstructions = value
+		case 55:
+			out.DateCreated = formatIPTCDate(value)
+		case 60:
+			out.TimeCreated = formatIPTCTime(value)
+		case 65:
+			out.OriginatingProgram = value
+		case 80:
+			out.Byline = append(out.Byline, value)
+		case 85:
+			out.BylineTitle = value
+		case 90:
+			out.City = value
+		case 92:
+			out.SubLocation = value
+		case 95:
+			out.ProvinceState = value
+		case 100:
+			out.CountryCode = value
+		case 101:
+			out.Country = value
+		case 103:
+			out.TransmissionReference = value
+		case 105:
+			out.Headline = value
+		case 110:
+			out.Credit = value
+		case 115:
+			out.Source = value
+		case 116:
+			out.CopyrightNotice = value
+		case 118:
+			out.Contact = append(out.Contact, value)
+		case 120:
+			out.Caption = value
+		case 122:
+			out.CaptionWriter = value
+		}
+	}
+
+	return out
+}
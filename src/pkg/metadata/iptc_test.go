@@ -0,0 +1,101 @@
+package metadata
+
+import (
+	"encoding/binary"
+	"reflect"
+	"testing"
+)
+
+// iimDataset encodes a single IPTC-IIM dataset
+func iimDataset(record, dataset byte, value string) []byte {
+	out := []byte{iptcTagMarker, record, dataset, 0, 0}
+	binary.BigEndian.PutUint16(out[3:], uint16(len(value)))
+	return append(out, value...)
+}
+
+// psResourceBlock encodes an unnamed 8BIM image resource block
+func psResourceBlock(id uint16, payload []byte) []byte {
+	out := []byte("8BIM")
+	out = binary.BigEndian.AppendUint16(out, id)
+	out = append(out, 0, 0) // empty Pascal name, padded
+	out = binary.BigEndian.AppendUint32(out, uint32(len(payload)))
+	out = append(out, payload...)
+	if len(payload)%2 == 1 {
+		out = append(out, 0)
+	}
+	return out
+}
+
+// photoshopSegment wraps resource blocks in an APP13 segment
+func photoshopSegment(blocks ...[]byte) []byte {
+	payload := append([]byte{}, photoshopJPEGSignature...)
+	for _, block := range blocks {
+		payload = append(payload, block...)
+	}
+	return jpegSegment(markerAPP13, payload)
+}
+
+func TestExtractMetadataIPTC(t *testing.T) {
+	var iim []byte
+	iim = append(iim, iimDataset(1, 90, "\x1b%G")...)
+	iim = append(iim, iimDataset(2, 5, "Harbour")...)
+	iim = append(iim, iimDataset(2, 25, "boats")...)
+	iim = append(iim, iimDataset(2, 25, "Zürich")...)
+	iim = append(iim, iimDataset(2, 55, "20251031")...)
+	iim = append(iim, iimDataset(2, 60, "214150+0700")...)
+	iim = append(iim, iimDataset(2, 80, "Jane Doe")...)
+	iim = append(iim, iimDataset(2, 116, "© 2025 Example News")...)
+	iim = append(iim, iimDataset(2, 120, "Boats moored at dusk.")...)
+
+	// PhotoshopQuality 10 is stored as 6, followed by format and scans
+	quality := []byte{0x00, 0x06, 0x00, 0x01, 0x00, 0x01}
+
+	data := buildJPEGWithSegments(t, photoshopSegment(
+		psResourceBlock(psResourceIPTC, iim),
+		psResourceBlock(psResourceJPEGQuality, quality),
+	))
+	meta := ExtractMetadata(data, "image/jpeg", "iptc.jpg")
+
+	if meta.PhotoshopQuality != 10 {
+		t.Errorf("PhotoshopQuality = %d, want 10", meta.PhotoshopQuality)
+	}
+	iptc := meta.IPTC
+	if iptc == nil {
+		t.Fatal("expected IPTC section")
+	}
+	if iptc.CodedCharacterSet != "UTF-8" {
+		t.Errorf("CodedCharacterSet = %q", iptc.CodedCharacterSet)
+	}
+	if !reflect.DeepEqual(iptc.Keywords, []string{"boats", "Zürich"}) {
+		t.Errorf("Keywords = %v", iptc.Keywords)
+	}
+	if iptc.CopyrightNotice != "© 2025 Example News" {
+		t.Errorf("CopyrightNotice = %q", iptc.CopyrightNotice)
+	}
+	if iptc.DateCreated != "2025-10-31" || iptc.TimeCreated != "21:41:50+07:00" {
+		t.Errorf("DateCreated/TimeCreated = %q %q", iptc.DateCreated, iptc.TimeCreated)
+	}
+	if len(iptc.Datasets) != 9 || iptc.Datasets[1].Name != "ObjectName" {
+		t.Errorf("Datasets = %+v", iptc.Datasets)
+	}
+}
+
+func TestDecodeIPTCText(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		charset iptcCharset
+		want    string
+	}{
+		{"declared UTF-8", "Z\xc3\xbcrich", iptcCharsetUTF8, "Zürich"},
+		{"declared Latin-1", "Z\xfcrich", iptcCharsetLatin1, "Zürich"},
+		{"undeclared valid UTF-8", "Z\xc3\xbcrich", iptcCharsetUnknown, "Zürich"},
+		{"undeclared Latin-1", "Z\xfcrich", iptcCharsetUnknown, "Zürich"},
+	}
+
+	for _, tt := range tests {
+		if got := decodeIPTCText([]byte(tt.value), tt.charset); got != tt.want {
+			t.Errorf("%s: decodeIPTCText() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
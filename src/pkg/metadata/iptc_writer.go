@@ -0,0 +1,228 @@
+package metadata
+
+import (
+	"crypto/md5"
+	"encoding/binary"
+	"fmt"
+	"sort"
+	"unicode/utf8"
+)
+
+// iptcEdit sets or, without values, removes every dataset of one kind
+type iptcEdit struct {
+	key    iptcKey
+	name   string
+	values []string
+}
+
+// iptcRepeatable are the datasets IIM allows more than once
+var iptcRepeatable = map[iptcKey]bool{
+	{1, 5}:   true,
+	{2, 4}:   true,
+	{2, 12}:  true,
+	{2, 20}:  true,
+	{2, 25}:  true,
+	{2, 26}:  true,
+	{2, 27}:  true,
+	{2, 45}:  true,
+	{2, 47}:  true,
+	{2, 50}:  true,
+	{2, 80}:  true,
+	{2, 85}:  true,
+	{2, 118}: true,
+	{2, 122}: true,
+}
+
+// iptcUTF8 is the CodedCharacterSet (1:90) value declaring UTF-8
+var iptcUTF8 = []byte("\x1b%G")
+
+// less orders datasets by record, then dataset number
+func (k iptcKey) less(other iptcKey) bool {
+	if k.record != other.record {
+		return k.record < other.record
+	}
+	return k.dataset < other.dataset
+}
+
+// patchIIM applies edits to an IPTC-IIM stream, or to an empty one when
+// iim is nil. An edited dataset takes the place of its first occurrence
+// and new ones are inserted in dataset order. Text is encoded in the
+// declared character set, and UTF-8 is declared when new text needs it.
+func patchIIM(iim []byte, edits []iptcEdit) ([]byte, error) {
+	datasets := readIPTCDatasets(iim)
+	charset := iptcCharsetUnknown
+	validUTF8 := true
+	for _, ds := range datasets {
+		switch {
+		case ds.key == (iptcKey{1, 90}):
+			charset, _ = codedCharacterSet(ds.data)
+		case !iptcBinaryDatasets[ds.key] && !utf8.Valid(ds.data):
+			validUTF8 = false
+		}
+	}
+	latin1 := charset == iptcCharsetLatin1 || charset == iptcCharsetUnknown && !validUTF8
+
+	encoded := make(map[iptcKey][][]byte)
+	declareUTF8 := false
+	for _, ed := range edits {
+		var values [][]byte // nil removes the datasets
+		for _, v := range ed.values {
+			b := []byte(v)
+			if latin1 {
+				var ok bool
+				if b, ok = encodeLatin1(v); !ok {
+					return nil, fmt.Errorf("IPTC %s: %q cannot be written in the ISO-8859-1 the IPTC data uses", ed.name, v)
+				}
+			} else if charset != iptcCharsetUTF8 && !isASCII(v) {
+				declareUTF8 = true
+			}
+			if len(b) > 0x7FFF {
+				return nil, fmt.Errorf("IPTC %s is longer than 32767 bytes", ed.name)
+			}
+			values = append(values, b)
+		}
+		encoded[ed.key] = values
+	}
+	if declareUTF8 {
+		encoded[iptcKey{1, 90}] = [][]byte{iptcUTF8}
+	}
+
+	var out []iptcDataset
+	written := make(map[iptcKey]bool)
+	for _, ds := range datasets {
+		values, edited := encoded[ds.key]
+		if !edited {
+			out = append(out, ds)
+			continue
+		}
+		if !written[ds.key] {
+			written[ds.key] = true
+			for _, v := range values {
+				out = append(out, iptcDataset{key: ds.key, data: v})
+			}
+		}
+	}
+
+	var added []iptcKey
+	for key, values := range encoded {
+		if !written[key] && len(values) > 0 {
+			added = append(added, key)
+		}
+	}
+	// Record 2 starts with its version
+	hasVersion, hasRecord2 := false, false
+	for _, ds := range out {
+		hasVersion = hasVersion || ds.key == (iptcKey{2, 0})
+		hasRecord2 = hasRecord2 || ds.key.record == 2
+	}
+	for _, key := range added {
+		hasRecord2 = hasRecord2 || key.record == 2
+	}
+	if hasRecord2 && !hasVersion {
+		encoded[iptcKey{2, 0}] = [][]byte{{0, 4}}
+		added = append(added, iptcKey{2, 0})
+	}
+	sort.Slice(added, func(i, j int) bool { return added[i].less(added[j]) })
+	for _, key := range added {
+		at := len(out)
+		for i, ds := range out {
+			if key.less(ds.key) {
+				at = i
+				break
+			}
+		}
+		var insert []iptcDataset
+		for _, v := range encoded[key] {
+			insert = append(insert, iptcDataset{key: key, data: v})
+		}
+		out = append(out[:at], append(insert, out[at:]...)...)
+	}
+	return encodeIIM(out), nil
+}
+
+// encodeIIM writes datasets as an IPTC-IIM stream
+func encodeIIM(datasets []iptcDataset) []byte {
+	var out []byte
+	for _, ds := range datasets {
+		out = append(out, iptcTagMarker, ds.key.record, ds.key.dataset)
+		if len(ds.data) <= 0x7FFF {
+			out = binary.BigEndian.AppendUint16(out, uint16(len(ds.data)))
+		} else {
+			// Extended dataset with a four-byte size
+			out = binary.BigEndian.AppendUint16(out, 0x8004)
+			out = binary.BigEndian.AppendUint32(out, uint32(len(ds.data)))
+		}
+		out = append(out, ds.data...)
+	}
+	return out
+}
+
+// encodeLatin1 encodes s in ISO-8859-1, if every character has a code
+func encodeLatin1(s string) ([]byte, bool) {
+	out := make([]byte, 0, len(s))
+	for _, r := range s {
+		if r > 0xFF {
+			return nil, false
+		}
+		out = append(out, byte(r))
+	}
+	return out, true
+}
+
+// isASCII reports whether s is 7-bit ASCII
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= 0x80 {
+			return false
+		}
+	}
+	return true
+}
+
+// photoshopResourceBytes encodes an 8BIM image resource block
+func photoshopResourceBytes(id uint16, name string, data []byte) []byte {
+	out := []byte("8BIM")
+	out = binary.BigEndian.AppendUint16(out, id)
+	out = append(out, byte(len(name)))
+	out = append(out, name...)
+	if len(name)%2 == 0 {
+		out = append(out, 0)
+	}
+	out = binary.BigEndian.AppendUint32(out, uint32(len(data)))
+	out = append(out, data...)
+	if len(data)%2 == 1 {
+		out = append(out, 0)
+	}
+	return out
+}
+
+// setPhotoshopResource replaces the resource with the given ID in a
+// resource section, or appends it. Other resources are kept as stored.
+func setPhotoshopResource(section []byte, id uint16, data []byte) []byte {
+	var out []byte
+	read := 0
+	replaced := false
+	for _, res := range readPhotoshopResources(section) {
+		read += len(res.raw)
+		switch {
+		case res.id != id:
+			out = append(out, res.raw...)
+		case !replaced:
+			out = append(out, photoshopResourceBytes(id, res.name, data)...)
+			replaced = true
+		}
+	}
+	if !replaced {
+		out = append(out, photoshopResourceBytes(id, "", data)...)
+	}
+	return append(out, section[read:]...)
+}
+
+// setPhotoshopIPTC stores an IPTC-IIM stream in a resource section with
+// its digest, which tells MWG readers that the IPTC data and the XMP were
+// written together
+func setPhotoshopIPTC(section, iim []byte) []byte {
+	digest := md5.Sum(iim)
+	section = setPhotoshopResource(section, psResourceIPTC, iim)
+	return setPhotoshopResource(section, psResourceIPTCDigest, digest[:])
+}
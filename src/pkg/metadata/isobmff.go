@@ -0,0 +1,121 @@
+package metadata
+
+import "encoding/binary"
+
+// bmffBox is a box from an ISO base media file (ISOBMFF) or JPEG 2000
+// container
+type bmffBox struct {
+	typ    string
+	offset int    // offset of the size field
+	uuid   []byte // extended type of "uuid" boxes
+	data   []byte // payload, excluding the header
+}
+
+// readBoxes walks the boxes at one level of an ISOBMFF structure
+func readBoxes(data []byte) []bmffBox {
+	var boxes []bmffBox
+	pos := 0
+	for pos+8 <= len(data) {
+		size := uint64(binary.BigEndian.Uint32(data[pos:]))
+		typ := string(data[pos+4 : pos+8])
+		header := uint64(8)
+
+		switch size {
+		case 0:
+			// Box extends to the end of the enclosing container
+			size = uint64(len(data) - pos)
+		case 1:
+			if pos+16 > len(data) {
+				return boxes
+			}
+			size = binary.BigEndian.Uint64(data[pos+8:])
+			header = 16
+		}
+		if typ == "uuid" {
+			header += 16
+		}
+		if size < header || size > uint64(len(data)-pos) {
+			return boxes
+		}
+
+		box := bmffBox{
+			typ:    typ,
+			offset: pos,
+			data:   data[pos+int(header) : pos+int(size)],
+		}
+		if typ == "uuid" {
+			box.uuid = data[pos+int(header)-16 : pos+int(header)]
+		}
+		boxes = append(boxes, box)
+		pos += int(size)
+	}
+	return boxes
+}
+
+// findBox returns the first box of the given type
+func findBox(boxes []bmffBox, typ string) *bmffBox {
+	for i := range boxes {
+		if boxes[i].typ == typ {
+			return &boxes[i]
+		}
+	}
+	return nil
+}
+
+// fullBoxHeader splits the version and flags from a FullBox payload
+func fullBoxHeader(data []byte) (version byte, flags uint32, body []byte, ok bool) {
+	if len(data) < 4 {
+		return 0, 0, nil, false
+	}
+	return data[0], binary.BigEndian.Uint32(data) & 0x00FFFFFF, data[4:], true
+}
+
+// bmffReader reads big-endian fields of variable width from a box payload
+type bmffReader struct {
+	data []byte
+	pos  int
+	err  bool
+}
+
+// uint reads an n-byte big-endian integer; n may be 0, 1, 2, 4 or 8
+func (r *bmffReader) uint(n int) uint64 {
+	if r.err || r.pos+n > len(r.data) {
+		r.err = true
+		return 0
+	}
+	var v uint64
+	for _, b := range r.data[r.pos : r.pos+n] {
+		v = v<<8 | uint64(b)
+	}
+	r.pos += n
+	return v
+}
+
+// cstring reads a null-terminated string
+func (r *bmffReader) cstring() string {
+	if r.err {
+		return ""
+	}
+	for i := r.pos; i < len(r.data); i++ {
+		if r.data[i] == 0 {
+			s := string(r.data[r.pos:i])
+			r.pos = i + 1
+			return s
+		}
+	}
+	// Unterminated strings run to the end of the box
+	s := string(r.data[r.pos:])
+	r.pos = len(r.data)
+	return s
+}
+
+// fourCC reads a four-character code
+func (r *bmffReader) fourCC() string {
+	if r.err || r.pos+4 > len(r.data) {
+		r.err = true
+		return ""
+	}
+	s := string(r.data[r.pos : r.pos+4])
+	r.pos += 4
+	return s
+}
@@ -0,0 +1,440 @@
+package metadata
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"math"
+	"strings"
+
+	"github.com/ahrdadan/image-metadata-viewer/src/internal/models"
+)
+
+// JPEG 2000 signatures: the JP2 signature box, or a bare codestream's SOC
+// marker followed by SIZ
+var (
+	jp2Signature           = []byte("\x00\x00\x00\x0cjP  \r\n\x87\n")
+	j2kCodestreamSignature = []byte{0xFF, 0x4F, 0xFF, 0x51}
+)
+
+// Well-known uuid boxes carrying Exif and XMP
+var (
+	jp2ExifUUID = []byte("JpgTiffExif->JP2")
+	jp2XMPUUID  = []byte{0xBE, 0x7A, 0xCF, 0xCB, 0x97, 0xA9, 0x42, 0xE8, 0x9C, 0x71, 0x99, 0x94, 0x91, 0xE3, 0xAF, 0xAC}
+)
+
+// Codestream markers read from the main header
+const (
+	j2kMarkerSIZ = 0xFF51
+	j2kMarkerCOD = 0xFF52
+	j2kMarkerSOT = 0xFF90
+)
+
+// errJP2Pixels is returned when asked to decode JPEG 2000 pixel data
+var errJP2Pixels = errors.New("jp2: decoding pixel data is not supported")
+
+var jp2ColorMethods = map[int]string{1: "Enumerated", 2: "Restricted ICC", 3: "Any ICC", 4: "Vendor"}
+
+var jp2EnumeratedColorSpaces = map[int]string{
+	0:  "Bi-level",
+	1:  "YCbCr(1)",
+	3:  "YCbCr(2)",
+	4:  "YCbCr(3)",
+	9:  "PhotoYCC",
+	11: "CMY",
+	12: "CMYK",
+	13: "YCCK",
+	14: "CIELab",
+	15: "Bi-level(2)",
+	16: "sRGB",
+	17: "Grayscale",
+	18: "sYCC",
+	19: "CIEJab",
+	20: "e-sRGB",
+	21: "ROMM-RGB",
+	22: "YPbPr(1125/60)",
+	23: "YPbPr(1250/50)",
+	24: "e-sYCC",
+}
+
+var j2kProgressions = map[int]string{0: "LRCP", 1: "RLCP", 2: "RPCL", 3: "PCRL", 4: "CPRL"}
+
+// Descriptions of the JP2 and JPX boxes
+var jp2BoxNames = map[string]string{
+	"jP  ": "Signature",
+	"ftyp": "File type",
+	"rreq": "Reader requirements",
+	"jp2h": "JP2 header",
+	"jp2c": "Codestream",
+	"jp2i": "Intellectual property",
+	"xml ": "XML",
+	"uinf": "UUID info",
+	"asoc": "Association",
+	"jumb": "JUMBF",
+}
+
+func init() {
+	// Register JPEG 2000 so image.DecodeConfig reports the header
+	// dimensions; pixels are never decoded
+	image.RegisterFormat("jp2", string(jp2Signature), decodeJP2, decodeJP2Config)
+	image.RegisterFormat("j2k", string(j2kCodestreamSignature), decodeJP2, decodeJP2Config)
+}
+
+// decodeJP2 satisfies image.RegisterFormat; pixel decoding is unsupported
+func decodeJP2(io.Reader) (image.Image, error) {
+	return nil, errJP2Pixels
+}
+
+// decodeJP2Config reads the dimensions from ihdr or the SIZ marker
+func decodeJP2Config(r io.Reader) (image.Config, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return image.Config{}, err
+	}
+	info := parseJP2(data)
+	if info == nil || info.Width == 0 || info.Height == 0 {
+		return image.Config{}, errors.New("jp2: missing image header")
+	}
+	return image.Config{Width: info.Width, Height: info.Height}, nil
+}
+
+// isJP2 reports whether data is a JP2/JPX file or a bare J2K codestream
+func isJP2(data []byte) bool {
+	return bytes.HasPrefix(data, jp2Signature) || bytes.HasPrefix(data, j2kCodestreamSignature)
+}
+
+// parseJP2 walks the JP2 boxes, then the main header of the codestream
+func parseJP2(data []byte) *models.JP2Info {
+	if !isJP2(data) {
+		return nil
+	}
+	info := &models.JP2Info{}
+	if bytes.HasPrefix(data, j2kCodestreamSignature) {
+		parseJ2KHeader(data, info)
+		return info
+	}
+
+	info.Container = true
+	info.Boxes = []models.ContainerBox{}
+	var codestream []byte
+	for _, box := range readBoxes(data) {
+		description := jp2BoxNames[box.typ]
+		switch box.typ {
+		case "ftyp":
+			r := &bmffReader{data: box.data}
+			info.Brand = strings.TrimSpace(r.fourCC())
+			r.uint(4)
+			for !r.err && r.pos+4 <= len(box.data) {
+				info.CompatibleBrands = append(info.CompatibleBrands, strings.TrimSpace(r.fourCC()))
+			}
+		case "jp2h":
+			parseJP2Header(box.data, info)
+		case "jp2c":
+			if codestream == nil {
+				codestream = box.data
+			}
+		case "uuid":
+			switch {
+			case bytes.Equal(box.uuid, jp2ExifUUID):
+				description = "Exif"
+			case bytes.Equal(box.uuid, jp2XMPUUID):
+				description = "XMP"
+			default:
+				description = fmt.Sprintf("UUID %X", box.uuid)
+			}
+		}
+		info.Boxes = append(info.Boxes, models.ContainerBox{
+			Type:        box.typ,
+			Offset:      box.offset,
+			Length:      len(box.data),
+			Description: description,
+		})
+	}
+
+	// The codestream fills in what the JP2 header leaves out, such as the
+	// component depths of files written without ihdr
+	if codestream != nil {
+		parseJ2KHeader(codestream, info)
+	}
+	return info
+}
+
+// parseJP2Header reads the boxes inside the jp2h superbox
+func parseJP2Header(data []byte, info *models.JP2Info) {
+	for _, box := range readBoxes(data) {
+		b := box.data
+		switch box.typ {
+		case "ihdr":
+			if len(b) < 14 {
+				continue
+			}
+			info.Height = int(binary.BigEndian.Uint32(b))
+			info.Width = int(binary.BigEndian.Uint32(b[4:]))
+			info.Components = int(binary.BigEndian.Uint16(b[8:]))
+			if bpc := b[10]; bpc != 0xFF {
+				// 0xFF means the depths vary and are listed in bpcc
+				info.BitsPerComponent = make([]int, info.Components)
+				for i := range info.BitsPerComponent {
+					info.BitsPerComponent[i] = int(bpc&0x7F) + 1
+				}
+				info.Signed = bpc&0x80 != 0
+			}
+		case "bpcc":
+			info.BitsPerComponent = make([]int, len(b))
+			for i, bpc := range b {
+				info.BitsPerComponent[i] = int(bpc&0x7F) + 1
+				info.Signed = info.Signed || bpc&0x80 != 0
+			}
+		case "colr":
+			if len(b) < 3 || info.ColorMethod != "" {
+				// The first colr box is the one readers must use
+				continue
+			}
+			method := int(b[0])
+			info.ColorMethod = lookupName(jp2ColorMethods, method)
+			if method == 1 && len(b) >= 7 {
+				info.ColorSpace = lookupName(jp2EnumeratedColorSpaces, int(binary.BigEndian.Uint32(b[3:])))
+			} else if method == 2 || method == 3 {
+				info.ColorSpace = "ICC profile"
+			}
+		case "pclr":
+			if len(b) >= 2 {
+				info.PaletteEntries = int(binary.BigEndian.Uint16(b))
+			}
+		case "cdef":
+			info.Channels = jp2ChannelDefinitions(b)
+		case "res ":
+			for _, res := range readBoxes(b) {
+				switch res.typ {
+				case "resc":
+					info.CaptureResolution = jp2Resolution(res.data)
+				case "resd":
+					info.DisplayResolution = jp2Resolution(res.data)
+				}
+			}
+		}
+	}
+}
+
+// jp2ChannelDefinitions describes each channel of a cdef box
+func jp2ChannelDefinitions(b []byte) []string {
+	if len(b) < 2 {
+		return nil
+	}
+	n := int(binary.BigEndian.Uint16(b))
+	var channels []string
+	for i := 0; i < n && 2+6*i+6 <= len(b); i++ {
+		entry := b[2+6*i:]
+		typ := binary.BigEndian.Uint16(entry[2:])
+		assoc := binary.BigEndian.Uint16(entry[4:])
+		switch typ {
+		case 0:
+			channels = append(channels, fmt.Sprintf("Color %d", assoc))
+		case 1:
+			channels = append(channels, "Opacity")
+		case 2:
+			channels = append(channels, "Premultiplied opacity")
+		default:
+			channels = append(channels, "Unspecified")
+		}
+	}
+	return channels
+}
+
+// jp2Resolution converts a resc or resd box to pixels per inch. Each axis
+// is stored as a fraction of grid points per metre times a power of ten.
+func jp2Resolution(b []byte) string {
+	if len(b) < 10 {
+		return ""
+	}
+	axis := func(num, den uint16, exp int8) float64 {
+		if den == 0 {
+			return 0
+		}
+		return float64(num) / float64(den) * math.Pow10(int(exp)) * 0.0254
+	}
+	vertical := axis(binary.BigEndian.Uint16(b), binary.BigEndian.Uint16(b[2:]), int8(b[8]))
+	horizontal := axis(binary.BigEndian.Uint16(b[4:]), binary.BigEndian.Uint16(b[6:]), int8(b[9]))
+	if vertical == 0 || horizontal == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%.0f × %.0f dpi", horizontal, vertical)
+}
+
+// parseJ2KHeader reads the SIZ and COD markers of the codestream main
+// header, stopping at the first tile
+func parseJ2KHeader(data []byte, info *models.JP2Info) {
+	if !bytes.HasPrefix(data, j2kCodestreamSignature[:2]) {
+		return
+	}
+	pos := 2
+	for pos+4 <= len(data) {
+		marker := binary.BigEndian.Uint16(data[pos:])
+		length := int(binary.BigEndian.Uint16(data[pos+2:]))
+		if marker == j2kMarkerSOT || length < 2 || pos+2+length > len(data) {
+			return
+		}
+		segment := data[pos+4 : pos+2+length]
+
+		switch marker {
+		case j2kMarkerSIZ:
+			readJ2KSIZ(segment, info)
+		case j2kMarkerCOD:
+			if len(segment) >= 10 {
+				info.Progression = lookupName(j2kProgressions, int(segment[1]))
+				info.QualityLayers = int(binary.BigEndian.Uint16(segment[2:]))
+				info.DecompositionLevels = int(segment[5])
+				if segment[9] == 1 {
+					info.Wavelet = "5/3 reversible"
+				} else {
+					info.Wavelet = "9/7 irreversible"
+				}
+			}
+		}
+		pos += 2 + length
+	}
+}
+
+// readJ2KSIZ reads the image and tile sizes and the component depths
+func readJ2KSIZ(s []byte, info *models.JP2Info) {
+	if len(s) < 36 {
+		return
+	}
+	u32 := func(i int) int { return int(binary.BigEndian.Uint32(s[i:])) }
+	width, height := u32(2)-u32(10), u32(6)-u32(14)
+	tileWidth, tileHeight := u32(18), u32(22)
+	if info.Width == 0 {
+		info.Width, info.Height = width, height
+	}
+	info.TileWidth, info.TileHeight = tileWidth, tileHeight
+	if tileWidth > 0 && tileHeight > 0 {
+		across := (u32(2) - u32(26) + tileWidth - 1) / tileWidth
+		down := (u32(6) - u32(30) + tileHeight - 1) / tileHeight
+		info.Tiles = across * down
+	}
+
+	components := int(binary.BigEndian.Uint16(s[34:]))
+	if info.Components == 0 {
+		info.Components = components
+	}
+	if info.BitsPerComponent == nil {
+		for i := 0; i < components && 36+3*i < len(s); i++ {
+			ssiz := s[36+3*i]
+			info.BitsPerComponent = append(info.BitsPerComponent, int(ssiz&0x7F)+1)
+			info.Signed = info.Signed || ssiz&0x80 != 0
+		}
+	}
+}
+
+// jp2UUIDBox returns the payload of the first uuid box with the given id
+func jp2UUIDBox(data []byte, id []byte) []byte {
+	if !bytes.HasPrefix(data, jp2Signature) {
+		return nil
+	}
+	for _, box := range readBoxes(data) {
+		if box.typ == "uuid" && bytes.Equal(box.uuid, id) {
+			return box.data
+		}
+	}
+	return nil
+}
+
+// jp2ExifTIFF returns the TIFF structure from the Exif uuid box, which
+// some writers prefix with the JPEG APP1 signature
+func jp2ExifTIFF(data []byte) []byte {
+	return bytes.TrimPrefix(jp2UUIDBox(data, jp2ExifUUID), []byte("Exif\x00\x00"))
+}
+
+// jp2XMPPacket returns XMP from its uuid box or an XML box holding a packet
+func jp2XMPPacket(data []byte) []byte {
+	if packet := jp2UUIDBox(data, jp2XMPUUID); packet != nil {
+		return packet
+	}
+	if !bytes.HasPrefix(data, jp2Signature) {
+		return nil
+	}
+	for _, box := range readBoxes(data) {
+		if box.typ == "xml " && bytes.Contains(box.data, []byte("<x:xmpmeta")) {
+			return box.data
+		}
+	}
+	return nil
+}
+
+// jp2ICCProfile returns the profile of the first colr box when it uses an
+// ICC method
+func jp2ICCProfile(data []byte) []byte {
+	if !bytes.HasPrefix(data, jp2Signature) {
+		return nil
+	}
+	header := findBox(readBoxes(data), "jp2h")
+	if header == nil {
+		return nil
+	}
+	colr := findBox(readBoxes(header.data), "colr")
+	if colr == nil || len(colr.data) < 3 || (colr.data[0] != 2 && colr.data[0] != 3) {
+		return nil
+	}
+	return colr.data[3:]
+}
+
+// extractJP2 fills the JPEG 2000 section and the shared color fields
+func extractJP2(data []byte, meta *models.ImageMetadata) {
+	if sniffFormat(data) != formatJP2 {
+		return
+	}
+	info := parseJP2(data)
+	if info == nil {
+		return
+	}
+	meta.JP2 = info
+	if meta.MIMEType == "" || meta.MIMEType == "application/octet-stream" {
+		switch {
+		case !info.Container:
+			meta.MIMEType = "image/j2c"
+		case info.Brand == "jpx":
+			meta.MIMEType = "image/jpx"
+		default:
+			meta.MIMEType = "image/jp2"
+		}
+	}
+
+	if len(info.BitsPerComponent) > 0 {
+		parts := make([]string, len(info.BitsPerComponent))
+		for i, bits := range info.BitsPerComponent {
+			parts[i] = fmt.Sprintf("%d", bits)
+		}
+		meta.BitsPerSample = strings.Join(parts, " ")
+	}
+	meta.SamplesPerPixel = info.Components
+	if info.ColorSpace != "" && info.ColorSpace != "ICC profile" {
+		meta.ColorSpace = info.ColorSpace
+	}
+
+	alpha := false
+	for _, ch := range info.Channels {
+		alpha = alpha || strings.Contains(strings.ToLower(ch), "opacity")
+	}
+	meta.ColorComponents = info.Components
+	if alpha {
+		meta.ColorComponents--
+	}
+	switch {
+	case info.PaletteEntries > 0:
+		meta.ColorMode = "Indexed"
+	case meta.ColorComponents == 1:
+		meta.ColorMode = "Grayscale"
+	case strings.Contains(info.ColorSpace, "YC"):
+		meta.ColorMode = "YCbCr"
+	case meta.ColorComponents == 4:
+		meta.ColorMode = "CMYK"
+	default:
+		meta.ColorMode = "RGB"
+	}
+	if alpha {
+		meta.ColorMode += " with alpha"
+	}
+}
@@ -0,0 +1,89 @@
+package metadata
+
+import "testing"
+
+// buildJ2K writes a codestream main header for a 4-component 8-bit image
+// tiled 512x512, followed by the start of the first tile
+func buildJ2K(width, height uint32) []byte {
+	siz := append(u16(0), u32(width)...)
+	siz = append(siz, u32(height)...)
+	siz = append(siz, u32(0)...)
+	siz = append(siz, u32(0)...)
+	siz = append(siz, u32(512)...)
+	siz = append(siz, u32(512)...)
+	siz = append(siz, u32(0)...)
+	siz = append(siz, u32(0)...)
+	siz = append(siz, u16(4)...)
+	for i := 0; i < 4; i++ {
+		siz = append(siz, 7, 1, 1)
+	}
+	cod := []byte{0, 2, 0, 3, 1, 5, 4, 4, 0, 1} // RPCL, 3 layers, 5 levels, 5/3
+
+	out := []byte{0xFF, 0x4F}
+	out = append(append(append(out, 0xFF, 0x51), u16(uint16(2+len(siz)))...), siz...)
+	out = append(append(append(out, 0xFF, 0x52), u16(uint16(2+len(cod)))...), cod...)
+	return append(out, 0xFF, 0x90, 0x00, 0x0A)
+}
+
+func TestExtractMetadataJP2(t *testing.T) {
+	tiffData := buildTIFF([]testIFD{{tags: []testTag{asciiTag(0x010F, "Canon")}}})
+	ihdr := append(append(u32(1080), u32(1920)...), u16(4)...)
+	ihdr = append(ihdr, 7, 7, 0, 0)
+	cdef := u16(4)
+	for i, def := range [][2]uint16{{0, 1}, {0, 2}, {0, 3}, {1, 0}} {
+		cdef = append(append(append(cdef, u16(uint16(i))...), u16(def[0])...), u16(def[1])...)
+	}
+	// 11811 grid points per metre is 300 dpi
+	resc := []byte{0x2E, 0x23, 0, 1, 0x2E, 0x23, 0, 1, 0, 0}
+
+	data := append([]byte{}, jp2Signature...)
+	data = append(data, bmff("ftyp", []byte("jp2 "), u32(0), []byte("jp2 "))...)
+	data = append(data, bmff("jp2h",
+		bmff("ihdr", ihdr),
+		bmff("colr", []byte{1, 0, 0}, u32(16)),
+		bmff("cdef", cdef),
+		bmff("res ", bmff("resc", resc)),
+	)...)
+	data = append(data, bmff("uuid", jp2ExifUUID, tiffData)...)
+	data = append(data, bmff("uuid", jp2XMPUUID, []byte(testXMPPacket))...)
+	data = append(data, bmff("jp2c", buildJ2K(1920, 1080))...)
+
+	meta := ExtractMetadata(data, "application/octet-stream", "scan.jp2")
+	if meta.DecodeError != "" {
+		t.Fatalf("DecodeError = %q", meta.DecodeError)
+	}
+	if meta.Format != "jp2" || meta.Width != 1920 || meta.Height != 1080 || meta.MIMEType != "image/jp2" {
+		t.Errorf("Format = %q, size = %dx%d, MIMEType = %q", meta.Format, meta.Width, meta.Height, meta.MIMEType)
+	}
+	if meta.BitsPerSample != "8 8 8 8" || meta.ColorMode != "RGB with alpha" || meta.ColorSpace != "sRGB" {
+		t.Errorf("BitsPerSample = %q, ColorMode = %q, ColorSpace = %q", meta.BitsPerSample, meta.ColorMode, meta.ColorSpace)
+	}
+	if meta.Camera == nil || meta.Camera.Make != "Canon" {
+		t.Errorf("Camera = %+v, want EXIF from the uuid box", meta.Camera)
+	}
+	if meta.XMP == nil {
+		t.Error("expected XMP from the uuid box")
+	}
+
+	info := meta.JP2
+	if info == nil || !info.Container || info.Brand != "jp2" || len(info.Boxes) != 6 {
+		t.Fatalf("JP2 = %+v", info)
+	}
+	if info.Wavelet != "5/3 reversible" || info.Progression != "RPCL" || info.QualityLayers != 3 ||
+		info.DecompositionLevels != 5 || info.Tiles != 12 {
+		t.Errorf("coding = %+v", info)
+	}
+	if info.CaptureResolution != "300 × 300 dpi" || len(info.Channels) != 4 || info.Channels[3] != "Opacity" {
+		t.Errorf("resolution = %q, channels = %v", info.CaptureResolution, info.Channels)
+	}
+}
+
+func TestExtractMetadataJ2K(t *testing.T) {
+	meta := ExtractMetadata(buildJ2K(640, 480), "", "frame.j2k")
+	if meta.DecodeError != "" || meta.Width != 640 || meta.Height != 480 || meta.MIMEType != "image/j2c" {
+		t.Errorf("DecodeError = %q, size = %dx%d, MIMEType = %q", meta.DecodeError, meta.Width, meta.Height, meta.MIMEType)
+	}
+	if meta.JP2 == nil || meta.JP2.Container || meta.JP2.Components != 4 || meta.ColorMode != "CMYK" {
+		t.Errorf("JP2 = %+v, ColorMode = %q", meta.JP2, meta.ColorMode)
+	}
+}
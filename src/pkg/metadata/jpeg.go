@@ -0,0 +1,360 @@
+package metadata
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/ahrdadan/image-metadata-viewer/src/internal/models"
+)
+
+// JPEG marker codes
+const (
+	markerSOI   = 0xD8
+	markerEOI   = 0xD9
+	markerSOS   = 0xDA
+	markerAPP0  = 0xE0
+	markerAPP1  = 0xE1
+	markerAPP2  = 0xE2
+	markerAPP11 = 0xEB
+	markerAPP13 = 0xED
+	markerAPP14 = 0xEE
+	markerAPP15 = 0xEF
+	markerCOM   = 0xFE
+)
+
+// markerSegment is a marker segment from the header of a JPEG stream
+type markerSegment struct {
+	marker byte
+	offset int    // offset of the 0xFF byte that starts the marker
+	data   []byte // payload, excluding the marker and length bytes
+}
+
+// readJPEGSegments walks the marker segments from SOI up to and including
+// SOS. Entropy-coded data after SOS is not scanned.
+func readJPEGSegments(data []byte) []markerSegment {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != markerSOI {
+		return nil
+	}
+
+	var segments []markerSegment
+	pos := 2
+	for pos+4 <= len(data) {
+		if data[pos] != 0xFF {
+			break
+		}
+		// Skip fill bytes
+		for pos+1 < len(data) && data[pos+1] == 0xFF {
+			pos++
+		}
+		if pos+4 > len(data) {
+			break
+		}
+
+		marker := data[pos+1]
+		if marker == markerEOI {
+			break
+		}
+		// Standalone markers carry no length
+		if marker == 0x01 || (marker >= 0xD0 && marker <= 0xD7) {
+			pos += 2
+			continue
+		}
+
+		length := int(binary.BigEndian.Uint16(data[pos+2:]))
+		end := pos + 2 + length
+		if length < 2 || end > len(data) {
+			break
+		}
+
+		segments = append(segments, markerSegment{
+			marker: marker,
+			offset: pos,
+			data:   data[pos+4 : end],
+		})
+
+		if marker == markerSOS {
+			break
+		}
+		pos = end
+	}
+
+	return segments
+}
+
+// Frame, table and restart markers
+const (
+	markerSOF0 = 0xC0
+	markerDHT  = 0xC4
+	markerSOF5 = 0xC5
+	markerSOF7 = 0xC7
+	markerJPG  = 0xC8
+	markerSOF9 = 0xC9
+	markerDAC  = 0xCC
+	markerSOFF = 0xCF
+	markerDQT  = 0xDB
+	markerDRI  = 0xDD
+)
+
+// jpegProcesses describes the coding process selected by each SOF marker
+var jpegProcesses = map[byte]string{
+	0xC0: "Baseline DCT, Huffman coding",
+	0xC1: "Extended sequential DCT, Huffman coding",
+	0xC2: "Progressive DCT, Huffman coding",
+	0xC3: "Lossless, Huffman coding",
+	0xC5: "Sequential DCT, differential Huffman coding",
+	0xC6: "Progressive DCT, differential Huffman coding",
+	0xC7: "Lossless, differential Huffman coding",
+	0xC9: "Extended sequential DCT, arithmetic coding",
+	0xCA: "Progressive DCT, arithmetic coding",
+	0xCB: "Lossless, arithmetic coding",
+	0xCD: "Sequential DCT, differential arithmetic coding",
+	0xCE: "Progressive DCT, differential arithmetic coding",
+	0xCF: "Lossless, differential arithmetic coding",
+}
+
+// jpegZigzag maps zigzag positions to natural (row-major) order
+var jpegZigzag = [64]int{
+	0, 1, 8, 16, 9, 2, 3, 10,
+	17, 24, 32, 25, 18, 11, 4, 5,
+	12, 19, 26, 33, 40, 48, 41, 34,
+	27, 20, 13, 6, 7, 14, 21, 28,
+	35, 42, 49, 56, 57, 50, 43, 36,
+	29, 22, 15, 23, 30, 37, 44, 51,
+	58, 59, 52, 45, 38, 31, 39, 46,
+	53, 60, 61, 54, 47, 55, 62, 63,
+}
+
+// IJG (Annex K) base quantization tables in natural order
+var (
+	ijgLuminance = [64]int{
+		16, 11, 10, 16, 24, 40, 51, 61,
+		12, 12, 14, 19, 26, 58, 60, 55,
+		14, 13, 16, 24, 40, 57, 69, 56,
+		14, 17, 22, 29, 51, 87, 80, 62,
+		18, 22, 37, 56, 68, 109, 103, 77,
+		24, 35, 55, 64, 81, 104, 113, 92,
+		49, 64, 78, 87, 103, 121, 120, 101,
+		72, 92, 95, 98, 112, 100, 103, 99,
+	}
+	ijgChrominance = [64]int{
+		17, 18, 24, 47, 99, 99, 99, 99,
+		18, 21, 26, 66, 99, 99, 99, 99,
+		24, 26, 56, 99, 99, 99, 99, 99,
+		47, 66, 99, 99, 99, 99, 99, 99,
+		99, 99, 99, 99, 99, 99, 99, 99,
+		99, 99, 99, 99, 99, 99, 99, 99,
+		99, 99, 99, 99, 99, 99, 99, 99,
+		99, 99, 99, 99, 99, 99, 99, 99,
+	}
+)
+
+// isSOFMarker reports whether marker starts a frame
+func isSOFMarker(marker byte) bool {
+	return marker >= markerSOF0 && marker <= markerSOFF &&
+		marker != markerDHT && marker != markerJPG && marker != markerDAC
+}
+
+// analyzeJPEG reads the frame header, quantization tables and restart
+// interval from the segments before the first scan
+func analyzeJPEG(data []byte) *models.JPEGInfo {
+	segments := readJPEGSegments(data)
+	if segments == nil {
+		return nil
+	}
+
+	info := &models.JPEGInfo{}
+	tables := make(map[int]*models.JPEGQuantTable)
+	var tableOrder []int
+	sawFrame := false
+
+	for _, seg := range segments {
+		switch {
+		case isSOFMarker(seg.marker):
+			if sawFrame || len(seg.data) < 6 {
+				continue
+			}
+			sawFrame = true
+			info.Marker = fmt.Sprintf("SOF%d", seg.marker-markerSOF0)
+			info.EncodingProcess = jpegProcesses[seg.marker]
+			info.Progressive = seg.marker&0x03 == 0x02
+			info.Lossless = seg.marker&0x03 == 0x03
+			info.Arithmetic = seg.marker >= markerSOF9
+			info.Differential = (seg.marker >= markerSOF5 && seg.marker <= markerSOF7) || seg.marker >= 0xCD
+			info.BitsPerSample = int(seg.data[0])
+			info.Height = int(binary.BigEndian.Uint16(seg.data[1:]))
+			info.Width = int(binary.BigEndian.Uint16(seg.data[3:]))
+
+			count := int(seg.data[5])
+			for i := 0; i < count && 6+3*i+3 <= len(seg.data); i++ {
+				c := seg.data[6+3*i:]
+				info.Components = append(info.Components, models.JPEGComponent{
+					ID:         int(c[0]),
+					Horizontal: int(c[1] >> 4),
+					Vertical:   int(c[1] & 0x0F),
+					QuantTable: int(c[2]),
+				})
+			}
+			nameJPEGComponents(info.Components)
+			info.Subsampling = jpegSubsampling(info.Components)
+
+		case seg.marker == markerDQT:
+			body := seg.data
+			for len(body) > 0 {
+				precision, id := int(body[0]>>4), int(body[0]&0x0F)
+				size := 64
+				if precision == 1 {
+					size = 128
+				}
+				if len(body) < 1+size {
+					break
+				}
+				table := &models.JPEGQuantTable{ID: id, Precision: 8, Values: make([]int, 64)}
+				if precision == 1 {
+					table.Precision = 16
+				}
+				for k := 0; k < 64; k++ {
+					v := int(body[1+k])
+					if precision == 1 {
+						v = int(binary.BigEndian.Uint16(body[1+2*k:]))
+					}
+					table.Values[jpegZigzag[k]] = v
+				}
+				// A later definition replaces an earlier one with the same ID
+				if _, ok := tables[id]; !ok {
+					tableOrder = append(tableOrder, id)
+				}
+				tables[id] = table
+				body = body[1+size:]
+			}
+
+		case seg.marker == markerDRI:
+			if len(seg.data) >= 2 {
+				info.RestartInterval = int(binary.BigEndian.Uint16(seg.data))
+			}
+		}
+	}
+
+	if !sawFrame {
+		return nil
+	}
+
+	for _, id := range tableOrder {
+		info.QuantTables = append(info.QuantTables, *tables[id])
+	}
+	info.EstimatedQuality, info.QualityExact = estimateJPEGQuality(info.Components, tables)
+
+	return info
+}
+
+// nameJPEGComponents labels components from their count and IDs
+func nameJPEGComponents(components []models.JPEGComponent) {
+	var names []string
+	switch len(components) {
+	case 1:
+		names = []string{"Y"}
+	case 3:
+		names = []string{"Y", "Cb", "Cr"}
+		if components[0].ID == 'R' && components[1].ID == 'G' && components[2].ID == 'B' {
+			names = []string{"R", "G", "B"}
+		}
+	case 4:
+		names = []string{"C", "M", "Y", "K"}
+	}
+	for i := range components {
+		if i < len(names) {
+			components[i].Name = names[i]
+		} else {
+			components[i].Name = fmt.Sprintf("Component %d", components[i].ID)
+		}
+	}
+}
+
+// jpegSubsampling derives J:a:b notation from YCbCr sampling factors
+func jpegSubsampling(components []models.JPEGComponent) string {
+	if len(components) != 3 || components[0].Name != "Y" {
+		return ""
+	}
+	y, cb, cr := components[0], components[1], components[2]
+	if cb.Horizontal != cr.Horizontal || cb.Vertical != cr.Vertical ||
+		cb.Horizontal == 0 || cb.Vertical == 0 ||
+		y.Horizontal%cb.Horizontal != 0 || y.Vertical%cb.Vertical != 0 {
+		return fmt.Sprintf("YCbCr (Y %d×%d, Cb %d×%d, Cr %d×%d)",
+			y.Horizontal, y.Vertical, cb.Horizontal, cb.Vertical, cr.Horizontal, cr.Vertical)
+	}
+	return chromaSubsampling(y.Horizontal/cb.Horizontal, y.Vertical/cb.Vertical)
+}
+
+// ijgScaledTable scales an Annex K table the way libjpeg's
+// jpeg_set_quality does for the given quality
+func ijgScaledTable(base *[64]int, quality int) [64]int {
+	scale := 200 - 2*quality
+	if quality < 50 {
+		scale = 5000 / quality
+	}
+	var out [64]int
+	for i, v := range base {
+		q := (v*scale + 50) / 100
+		if q < 1 {
+			q = 1
+		} else if q > 255 {
+			q = 255
+		}
+		out[i] = q
+	}
+	return out
+}
+
+// estimateJPEGQuality finds the IJG quality whose scaled tables are closest
+// to the luminance and chrominance tables in use. exact reports a
+// coefficient-for-coefficient match, which is what a libjpeg-family encoder
+// produces; anything else suggests a custom or re-scaled table.
+func estimateJPEGQuality(components []models.JPEGComponent, tables map[int]*models.JPEGQuantTable) (quality int, exact bool) {
+	if len(components) == 0 {
+		return 0, false
+	}
+	luma, ok := tables[components[0].QuantTable]
+	if !ok {
+		return 0, false
+	}
+	var chroma *models.JPEGQuantTable
+	if len(components) >= 3 {
+		chroma = tables[components[1].QuantTable]
+	}
+
+	best := -1
+	for q := 1; q <= 100; q++ {
+		diff := tableDistance(luma.Values, ijgScaledTable(&ijgLuminance, q))
+		if chroma != nil {
+			diff += tableDistance(chroma.Values, ijgScaledTable(&ijgChrominance, q))
+		}
+		if best < 0 || diff < best {
+			best, quality = diff, q
+		}
+	}
+	return quality, best == 0
+}
+
+// tableDistance sums the absolute differences between two tables
+func tableDistance(values []int, want [64]int) int {
+	sum := 0
+	for i, v := range values {
+		d := v - want[i]
+		if d < 0 {
+			d = -d
+		}
+		sum += d
+	}
+	return sum
+}
+
+// extractJPEG fills JPEG structure details for JPEG files
+func extractJPEG(data []byte, meta *models.ImageMetadata) {
+	info := analyzeJPEG(data)
+	if info == nil {
+		return
+	}
+
+	meta.JPEG = info
+	meta.EncodingProcess = info.EncodingProcess
+	meta.BitsPerSample = fmt.Sprintf("%d", info.BitsPerSample)
+}
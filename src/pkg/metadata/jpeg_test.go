@@ -0,0 +1,65 @@
+package metadata
+
+import (
+	"bytes"
+	"image"
+	"image/jpeg"
+	"testing"
+)
+
+func TestExtractMetadataJPEGStructure(t *testing.T) {
+	dri := jpegSegment(markerDRI, []byte{0x00, 0x10})
+	meta := ExtractMetadata(buildJPEGWithSegments(t, dri), "image/jpeg", "frame.jpg")
+
+	info := meta.JPEG
+	if info == nil {
+		t.Fatal("expected JPEG section")
+	}
+	if meta.EncodingProcess != "Baseline DCT, Huffman coding" || info.Marker != "SOF0" {
+		t.Errorf("EncodingProcess = %q (%s)", meta.EncodingProcess, info.Marker)
+	}
+	if meta.BitsPerSample != "8" {
+		t.Errorf("BitsPerSample = %q", meta.BitsPerSample)
+	}
+	if info.Subsampling != "YCbCr 4:2:0" {
+		t.Errorf("Subsampling = %q", info.Subsampling)
+	}
+	if info.RestartInterval != 16 {
+		t.Errorf("RestartInterval = %d", info.RestartInterval)
+	}
+	if len(info.QuantTables) != 2 || info.QuantTables[0].Values[0] != 3 {
+		t.Errorf("QuantTables = %+v", info.QuantTables)
+	}
+	if info.EstimatedQuality != 90 || !info.QualityExact {
+		t.Errorf("EstimatedQuality = %d (exact %v), want 90", info.EstimatedQuality, info.QualityExact)
+	}
+}
+
+func TestEstimateJPEGQuality(t *testing.T) {
+	img := image.NewGray(image.Rect(0, 0, 8, 8))
+	for _, quality := range []int{10, 50, 75, 100} {
+		var buf bytes.Buffer
+		if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: quality}); err != nil {
+			t.Fatalf("jpeg.Encode: %v", err)
+		}
+		info := analyzeJPEG(buf.Bytes())
+		if info == nil || info.EstimatedQuality != quality || !info.QualityExact {
+			t.Errorf("quality %d: got %+v", quality, info)
+		}
+	}
+}
+
+func TestAnalyzeJPEGProgressive(t *testing.T) {
+	data := buildJPEGWithSegments(t)
+	for i := 2; i+1 < len(data); i++ {
+		if data[i] == 0xFF && data[i+1] == markerSOF0 {
+			data[i+1] = 0xC2
+			break
+		}
+	}
+
+	info := analyzeJPEG(data)
+	if info == nil || !info.Progressive || info.EncodingProcess != "Progressive DCT, Huffman coding" {
+		t.Errorf("info = %+v", info)
+	}
+}
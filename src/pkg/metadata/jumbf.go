@@ -0,0 +1,173 @@
+package metadata
+
+import (
+	"bytes"
+	"encoding/binary"
+	"sort"
+)
+
+// JUMBF (ISO/IEC 19566-5) uses the ISOBMFF box layout. A superbox ("jumb")
+// starts with a description box ("jumd") giving its content type and
+// label, followed by content boxes or further superboxes.
+
+// maxJUMBFDepth bounds superbox nesting; C2PA stores are four levels deep
+const maxJUMBFDepth = 16
+
+// c2paBMFFUUID is the extended type of the ISOBMFF "uuid" box that carries
+// a C2PA manifest store
+var c2paBMFFUUID = []byte{0xD8, 0xFE, 0xC3, 0xD6, 0x1B, 0x0E, 0x48, 0x3C, 0x92, 0x97, 0x58, 0x28, 0x87, 0x7E, 0xC4, 0x81}
+
+// jumbfBox is a parsed JUMBF superbox
+type jumbfBox struct {
+	typ      string // first four bytes of the content type UUID, e.g. c2pa, c2ma, c2as
+	label    string
+	payload  []byte // description and content boxes, which hashed URIs cover
+	children []*jumbfBox
+	content  []bmffBox
+}
+
+// parseJUMBF parses the payload of a superbox
+func parseJUMBF(payload []byte, depth int) *jumbfBox {
+	if depth > maxJUMBFDepth {
+		return nil
+	}
+	boxes := readBoxes(payload)
+	if len(boxes) == 0 || boxes[0].typ != "jumd" || len(boxes[0].data) < 17 {
+		return nil
+	}
+
+	desc := boxes[0].data
+	box := &jumbfBox{typ: string(desc[:4]), payload: payload}
+	// Toggles: 0x02 label present, 0x04 ID, 0x08 signature, 0x10 private box
+	if desc[16]&0x02 != 0 {
+		r := bmffReader{data: desc, pos: 17}
+		box.label = r.cstring()
+	}
+
+	for _, b := range boxes[1:] {
+		if b.typ != "jumb" {
+			box.content = append(box.content, b)
+			continue
+		}
+		if child := parseJUMBF(b.data, depth+1); child != nil {
+			box.children = append(box.children, child)
+		}
+	}
+	return box
+}
+
+// child returns the superbox directly inside b with the given label
+func (b *jumbfBox) child(label string) *jumbfBox {
+	for _, c := range b.children {
+		if c.label == label {
+			return c
+		}
+	}
+	return nil
+}
+
+// contentBox returns the payload of b's first content box of type typ
+func (b *jumbfBox) contentBox(typ string) []byte {
+	if box := findBox(b.content, typ); box != nil {
+		return box.data
+	}
+	return nil
+}
+
+// jumbfCandidates returns the JUMBF superboxes, header included, that may
+// hold a C2PA manifest store, and the name of the structure carrying them
+func jumbfCandidates(data []byte) (carrier string, boxes [][]byte) {
+	switch sniffFormat(data) {
+	case formatJPEG:
+		return "JPEG APP11", jpegJUMBF(data)
+	case formatPNG:
+		for _, c := range readPNGChunks(data) {
+			if c.typ == "caBX" {
+				boxes = append(boxes, c.data)
+			}
+		}
+		return "PNG caBX", boxes
+	case formatWebP:
+		for _, c := range readWebPChunks(data) {
+			if c.fourCC == "C2PA" {
+				boxes = append(boxes, c.data)
+			}
+		}
+		return "WebP C2PA", boxes
+	case formatJXL:
+		if !bytes.HasPrefix(data, jxlContainerSignature) {
+			return "", nil
+		}
+		for _, b := range readBoxes(data) {
+			if b.typ == "jumb" {
+				boxes = append(boxes, data[b.offset:b.offset+jumbfHeaderLen(data[b.offset:])+len(b.data)])
+			}
+		}
+		return "JPEG XL jumb", boxes
+	case formatHEIF, formatCR3:
+		for _, b := range readBoxes(data) {
+			if b.typ != "uuid" || !bytes.Equal(b.uuid, c2paBMFFUUID) {
+				continue
+			}
+			_, _, body, ok := fullBoxHeader(b.data)
+			if !ok {
+				continue
+			}
+			r := bmffReader{data: body}
+			if r.cstring() != "manifest" {
+				continue
+			}
+			r.uint(8) // offset of the first Merkle tree box
+			if !r.err {
+				boxes = append(boxes, body[r.pos:])
+			}
+		}
+		return "ISOBMFF uuid", boxes
+	}
+	return "", nil
+}
+
+// jpegJUMBF reassembles the JUMBF boxes split across APP11 segments. Each
+// segment starts with "JP", a box instance number and a sequence number;
+// segments after the first repeat the box header, which is dropped.
+func jpegJUMBF(data []byte) [][]byte {
+	type packet struct {
+		seq  uint32
+		body []byte
+	}
+	instances := make(map[uint16][]packet)
+	var order []uint16
+	for _, seg := range readJPEGSegments(data) {
+		if seg.marker != markerAPP11 || len(seg.data) < 16 || string(seg.data[:2]) != "JP" {
+			continue
+		}
+		en := binary.BigEndian.Uint16(seg.data[2:])
+		if _, seen := instances[en]; !seen {
+			order = append(order, en)
+		}
+		instances[en] = append(instances[en], packet{binary.BigEndian.Uint32(seg.data[4:]), seg.data[8:]})
+	}
+
+	var boxes [][]byte
+	for _, en := range order {
+		packets := instances[en]
+		sort.SliceStable(packets, func(i, j int) bool { return packets[i].seq < packets[j].seq })
+		box := append([]byte(nil), packets[0].body...)
+		header := jumbfHeaderLen(box)
+		for _, p := range packets[1:] {
+			if len(p.body) > header {
+				box = append(box, p.body[header:]...)
+			}
+		}
+		boxes = append(boxes, box)
+	}
+	return boxes
+}
+
+// jumbfHeaderLen is the length of the box header at the start of b
+func jumbfHeaderLen(b []byte) int {
+	if len(b) >= 4 && binary.BigEndian.Uint32(b) == 1 {
+		return 16
+	}
+	return 8
+}
@@ -0,0 +1,430 @@
+package metadata
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"strings"
+
+	"github.com/ahrdadan/image-metadata-viewer/src/internal/models"
+)
+
+// JPEG XL signatures: a bare codestream, or the ISOBMFF signature box
+var (
+	jxlCodestreamSignature = []byte{0xFF, 0x0A}
+	jxlContainerSignature  = []byte("\x00\x00\x00\x0cJXL \r\n\x87\n")
+)
+
+// errJXLPixels is returned when asked to decode JPEG XL pixel data
+var errJXLPixels = errors.New("jxl: decoding pixel data is not supported")
+
+// jxlRatios are the fixed aspect ratios of the SizeHeader, width over height
+var jxlRatios = [8][2]uint32{{0, 0}, {1, 1}, {12, 10}, {4, 3}, {3, 2}, {16, 9}, {5, 4}, {2, 1}}
+
+var jxlColorSpaces = map[int]string{0: "RGB", 1: "Grayscale", 2: "XYB", 3: "Unknown"}
+
+var jxlWhitePoints = map[int]string{1: "D65", 2: "Custom", 10: "E", 11: "DCI"}
+
+var jxlPrimaries = map[int]string{1: "sRGB", 2: "Custom", 9: "BT.2100", 11: "P3"}
+
+var jxlTransferFunctions = map[int]string{
+	1:  "BT.709",
+	2:  "Unknown",
+	8:  "Linear",
+	13: "sRGB",
+	16: "PQ",
+	17: "DCI",
+	18: "HLG",
+}
+
+var jxlRenderingIntents = map[int]string{0: "Perceptual", 1: "Relative", 2: "Saturation", 3: "Absolute"}
+
+var jxlExtraChannelTypes = map[int]string{
+	0:  "Alpha",
+	1:  "Depth",
+	2:  "Spot color",
+	3:  "Selection mask",
+	4:  "Black",
+	5:  "CFA",
+	6:  "Thermal",
+	15: "Non-optional",
+	16: "Optional",
+}
+
+// Descriptions of the JPEG XL container boxes
+var jxlBoxNames = map[string]string{
+	"JXL ": "Signature",
+	"ftyp": "File type",
+	"jxll": "Level",
+	"jxlc": "Codestream",
+	"jxlp": "Partial codestream",
+	"jxli": "Frame index",
+	"jbrd": "JPEG reconstruction data",
+	"Exif": "Exif",
+	"xml ": "XML (XMP)",
+	"jumb": "JUMBF",
+	"brob": "Brotli-compressed box",
+}
+
+func init() {
+	// Register JPEG XL so image.DecodeConfig reports the SizeHeader
+	// dimensions; pixels are never decoded
+	image.RegisterFormat("jxl", string(jxlCodestreamSignature), decodeJXL, decodeJXLConfig)
+	image.RegisterFormat("jxl", string(jxlContainerSignature), decodeJXL, decodeJXLConfig)
+}
+
+// decodeJXL satisfies image.RegisterFormat; pixel decoding is unsupported
+func decodeJXL(io.Reader) (image.Image, error) {
+	return nil, errJXLPixels
+}
+
+// decodeJXLConfig reads the dimensions from the codestream headers
+func decodeJXLConfig(r io.Reader) (image.Config, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return image.Config{}, err
+	}
+	info := parseJXL(data)
+	if info == nil || info.Width == 0 || info.Height == 0 {
+		return image.Config{}, errors.New("jxl: invalid size header")
+	}
+	return image.Config{Width: info.Width, Height: info.Height}, nil
+}
+
+// isJXL reports whether data is a JPEG XL codestream or container
+func isJXL(data []byte) bool {
+	return bytes.HasPrefix(data, jxlCodestreamSignature) || bytes.HasPrefix(data, jxlContainerSignature)
+}
+
+// jxlBitReader reads the least-significant-bit-first fields of a codestream
+type jxlBitReader struct {
+	data []byte
+	pos  int // in bits
+	err  bool
+}
+
+// u reads an n-bit unsigned integer
+func (r *jxlBitReader) u(n int) uint32 {
+	if r.err || r.pos+n > 8*len(r.data) {
+		r.err = true
+		return 0
+	}
+	var v uint32
+	for i := 0; i < n; i++ {
+		bit := r.data[(r.pos+i)/8] >> ((r.pos + i) % 8) & 1
+		v |= uint32(bit) << i
+	}
+	r.pos += n
+	return v
+}
+
+// bool reads a single-bit flag
+func (r *jxlBitReader) bool() bool {
+	return r.u(1) == 1
+}
+
+// jxlDist is one of the four choices of a U32 field: an offset plus an
+// optional number of extra bits
+type jxlDist struct {
+	offset uint32
+	bits   int
+}
+
+// u32 reads a U32 field: a 2-bit selector picks the distribution
+func (r *jxlBitReader) u32(d0, d1, d2, d3 jxlDist) uint32 {
+	d := [4]jxlDist{d0, d1, d2, d3}[r.u(2)]
+	return d.offset + r.u(d.bits)
+}
+
+// enum reads an Enum field
+func (r *jxlBitReader) enum() int {
+	return int(r.u32(jxlDist{0, 0}, jxlDist{1, 0}, jxlDist{2, 4}, jxlDist{18, 6}))
+}
+
+// size reads a SizeHeader or, with preview set, a PreviewHeader
+func (r *jxlBitReader) size(preview bool) (int, int) {
+	var dim func() uint32
+	switch {
+	case preview && r.bool():
+		dim = func() uint32 { return 8 * r.u32(jxlDist{16, 0}, jxlDist{32, 0}, jxlDist{1, 5}, jxlDist{33, 9}) }
+	case preview:
+		dim = func() uint32 { return r.u32(jxlDist{1, 6}, jxlDist{65, 8}, jxlDist{321, 10}, jxlDist{1345, 12}) }
+	case r.bool():
+		// Small image: multiples of 8 up to 256
+		dim = func() uint32 { return 8 * (r.u(5) + 1) }
+	default:
+		dim = func() uint32 { return r.u32(jxlDist{1, 9}, jxlDist{1, 13}, jxlDist{1, 18}, jxlDist{1, 30}) }
+	}
+
+	height := dim()
+	width := dim
+	if ratio := r.u(3); ratio != 0 {
+		width = func() uint32 {
+			return uint32(uint64(height) * uint64(jxlRatios[ratio][0]) / uint64(jxlRatios[ratio][1]))
+		}
+	}
+	return int(width()), int(height)
+}
+
+// bitDepth reads a BitDepth bundle
+func (r *jxlBitReader) bitDepth() (bits, exponent int, float bool) {
+	if r.bool() {
+		bits = int(r.u32(jxlDist{32, 0}, jxlDist{16, 0}, jxlDist{24, 0}, jxlDist{1, 6}))
+		return bits, int(r.u(4)) + 1, true
+	}
+	return int(r.u32(jxlDist{8, 0}, jxlDist{10, 0}, jxlDist{12, 0}, jxlDist{1, 6})), 0, false
+}
+
+// customXY skips a CustomXY bundle (two signed chromaticity coordinates)
+func (r *jxlBitReader) customXY() {
+	for i := 0; i < 2; i++ {
+		r.u32(jxlDist{0, 19}, jxlDist{524288, 19}, jxlDist{1048576, 20}, jxlDist{2097152, 21})
+	}
+}
+
+// jxlCodestream returns the codestream, or its first part when the
+// container splits it across jxlp boxes; the headers fit in the first part
+func jxlCodestream(data []byte, info *models.JXLInfo) []byte {
+	if bytes.HasPrefix(data, jxlCodestreamSignature) {
+		return data
+	}
+
+	info.Container = true
+	info.Boxes = []models.ContainerBox{}
+	var codestream []byte
+	for _, box := range readBoxes(data) {
+		typ, description := box.typ, jxlBoxNames[box.typ]
+		switch typ {
+		case "jxlc":
+			if codestream == nil {
+				codestream = box.data
+			}
+		case "jxlp":
+			if codestream == nil && len(box.data) > 4 {
+				codestream = box.data[4:]
+			}
+		case "jxll":
+			if len(box.data) > 0 {
+				info.Level = int(box.data[0])
+			}
+		case "jbrd":
+			info.JPEGReconstruction = true
+		case "brob":
+			if len(box.data) >= 4 {
+				description = fmt.Sprintf("Brotli-compressed %q", string(box.data[:4]))
+			}
+		}
+		info.Boxes = append(info.Boxes, models.ContainerBox{
+			Type:        typ,
+			Offset:      box.offset,
+			Length:      len(box.data),
+			Description: description,
+		})
+	}
+	return codestream
+}
+
+// parseJXL reads the SizeHeader and ImageMetadata bundles from the start
+// of the codestream. Nothing after the color encoding is needed, so the
+// frame headers and entropy-coded ICC profile are left alone.
+func parseJXL(data []byte) *models.JXLInfo {
+	if !isJXL(data) {
+		return nil
+	}
+	info := &models.JXLInfo{}
+	codestream := jxlCodestream(data, info)
+	if !bytes.HasPrefix(codestream, jxlCodestreamSignature) {
+		if info.Container {
+			return info
+		}
+		return nil
+	}
+
+	r := &jxlBitReader{data: codestream[2:]}
+	info.Width, info.Height = r.size(false)
+
+	// Defaults for an all_default ImageMetadata bundle
+	info.BitsPerSample, info.Orientation = 8, 1
+	info.XYBEncoded, info.ColorSpace = true, jxlColorSpaces[0]
+	info.WhitePoint, info.Primaries, info.TransferFunction = "D65", "sRGB", "sRGB"
+	info.RenderingIntent = jxlRenderingIntents[1]
+	if r.bool() || r.err {
+		return info
+	}
+
+	if r.bool() {
+		// extra_fields
+		info.Orientation = int(r.u(3)) + 1
+		if r.bool() {
+			r.size(false) // intrinsic size
+		}
+		if info.HasPreview = r.bool(); info.HasPreview {
+			r.size(true)
+		}
+		if info.Animated = r.bool(); info.Animated {
+			r.u32(jxlDist{100, 0}, jxlDist{1000, 0}, jxlDist{1, 10}, jxlDist{1, 30})
+			r.u32(jxlDist{1, 0}, jxlDist{1001, 0}, jxlDist{1, 8}, jxlDist{1, 10})
+			r.u32(jxlDist{0, 0}, jxlDist{0, 3}, jxlDist{0, 16}, jxlDist{0, 32})
+			r.bool()
+		}
+	}
+
+	info.BitsPerSample, info.ExponentBits, info.FloatSamples = r.bitDepth()
+	r.bool() // modular_16_bit_buffers
+
+	extra := int(r.u32(jxlDist{0, 0}, jxlDist{1, 0}, jxlDist{2, 4}, jxlDist{1, 12}))
+	for i := 0; i < extra && !r.err; i++ {
+		info.ExtraChannels = append(info.ExtraChannels, r.extraChannel())
+	}
+
+	info.XYBEncoded = r.bool()
+	r.colorEncoding(info)
+	if r.err {
+		// Keep the size even when a later bundle is truncated
+		info.ColorSpace, info.WhitePoint, info.Primaries, info.TransferFunction = "", "", "", ""
+	}
+	return info
+}
+
+// extraChannel reads an ExtraChannelInfo bundle and describes it
+func (r *jxlBitReader) extraChannel() string {
+	if r.bool() {
+		return "Alpha (8-bit)"
+	}
+	typ := r.enum()
+	bits, _, float := r.bitDepth()
+	r.u32(jxlDist{0, 0}, jxlDist{3, 0}, jxlDist{4, 0}, jxlDist{1, 3}) // dim_shift
+
+	nameLen := int(r.u32(jxlDist{0, 0}, jxlDist{0, 4}, jxlDist{16, 5}, jxlDist{48, 10}))
+	name := make([]byte, 0, nameLen)
+	for i := 0; i < nameLen && !r.err; i++ {
+		name = append(name, byte(r.u(8)))
+	}
+	switch typ {
+	case 0:
+		r.bool() // alpha_associated
+	case 2:
+		for i := 0; i < 4; i++ {
+			r.u(16) // spot color and solidity as F16
+		}
+	case 5:
+		r.u32(jxlDist{1, 0}, jxlDist{0, 2}, jxlDist{3, 4}, jxlDist{19, 8})
+	}
+
+	desc := fmt.Sprintf("%s (%d-bit", lookupName(jxlExtraChannelTypes, typ), bits)
+	if float {
+		desc += " float"
+	}
+	desc += ")"
+	if len(name) > 0 {
+		desc += fmt.Sprintf(" %q", name)
+	}
+	return desc
+}
+
+// colorEncoding reads a ColourEncoding bundle
+func (r *jxlBitReader) colorEncoding(info *models.JXLInfo) {
+	if r.bool() {
+		return // all_default: sRGB
+	}
+	info.EmbeddedICC = r.bool()
+	space := r.enum()
+	info.ColorSpace = lookupName(jxlColorSpaces, space)
+	if info.EmbeddedICC {
+		info.WhitePoint, info.Primaries, info.TransferFunction, info.RenderingIntent = "", "", "", ""
+		return
+	}
+
+	info.WhitePoint, info.Primaries = "", ""
+	if space != 2 {
+		wp := r.enum()
+		info.WhitePoint = lookupName(jxlWhitePoints, wp)
+		if wp == 2 {
+			r.customXY()
+		}
+	}
+	if space != 1 && space != 2 {
+		primaries := r.enum()
+		info.Primaries = lookupName(jxlPrimaries, primaries)
+		if primaries == 2 {
+			r.customXY()
+			r.customXY()
+			r.customXY()
+		}
+	}
+
+	switch {
+	case space == 2:
+		info.TransferFunction = ""
+	case r.bool():
+		info.TransferFunction = fmt.Sprintf("Gamma %.4g", float64(r.u(24))/1e7)
+	default:
+		info.TransferFunction = lookupName(jxlTransferFunctions, r.enum())
+	}
+	info.RenderingIntent = lookupName(jxlRenderingIntents, r.enum())
+}
+
+// jxlBox returns the payload of the first container box of the given type
+func jxlBox(data []byte, typ string) []byte {
+	if !bytes.HasPrefix(data, jxlContainerSignature) {
+		return nil
+	}
+	if box := findBox(readBoxes(data), typ); box != nil {
+		return box.data
+	}
+	return nil
+}
+
+// jxlExifTIFF returns the TIFF structure from the Exif box, which starts
+// with the offset of the TIFF header
+func jxlExifTIFF(data []byte) []byte {
+	exif := jxlBox(data, "Exif")
+	if len(exif) < 4 {
+		return nil
+	}
+	offset := int(binary.BigEndian.Uint32(exif))
+	if offset > len(exif)-4 {
+		return nil
+	}
+	return exif[4+offset:]
+}
+
+// extractJXL fills the JPEG XL section and the shared color fields
+func extractJXL(data []byte, meta *models.ImageMetadata) {
+	if sniffFormat(data) != formatJXL {
+		return
+	}
+	info := parseJXL(data)
+	if info == nil {
+		return
+	}
+	meta.JXL = info
+	if meta.MIMEType == "" || meta.MIMEType == "application/octet-stream" {
+		meta.MIMEType = "image/jxl"
+	}
+	if info.BitsPerSample > 0 {
+		meta.BitsPerSample = fmt.Sprintf("%d", info.BitsPerSample)
+	}
+
+	components := 3
+	if info.ColorSpace == "Grayscale" {
+		components = 1
+	}
+	meta.ColorComponents, meta.SamplesPerPixel = components, components+len(info.ExtraChannels)
+	meta.ColorMode = "RGB"
+	if components == 1 {
+		meta.ColorMode = "Grayscale"
+	}
+	for _, ch := range info.ExtraChannels {
+		if strings.HasPrefix(ch, "Alpha") {
+			meta.ColorMode += " with alpha"
+			break
+		}
+	}
+	if info.Primaries == "sRGB" && info.TransferFunction == "sRGB" {
+		meta.ColorSpace = "sRGB"
+	}
+}
@@ -0,0 +1,96 @@
+package metadata
+
+import (
+	"strings"
+	"testing"
+)
+
+// jxlBitWriter packs codestream fields least-significant bit first
+type jxlBitWriter struct {
+	data []byte
+	pos  int
+}
+
+// u writes v as an n-bit field
+func (w *jxlBitWriter) u(v uint32, n int) *jxlBitWriter {
+	for i := 0; i < n; i++ {
+		if w.pos%8 == 0 {
+			w.data = append(w.data, 0)
+		}
+		w.data[w.pos/8] |= byte(v>>i&1) << (w.pos % 8)
+		w.pos++
+	}
+	return w
+}
+
+// buildJXLCodestream writes a 1920x1080 SizeHeader and an ImageMetadata
+// bundle for an animated, 10-bit, Display P3 PQ image with one alpha channel
+func buildJXLCodestream() []byte {
+	w := &jxlBitWriter{}
+	w.u(0, 1).u(1, 2).u(1079, 13).u(5, 3) // height 1080, 16:9
+	w.u(0, 1).u(1, 1)                     // not all_default, extra_fields
+	w.u(5, 3).u(0, 1).u(0, 1)             // orientation 6, no intrinsic size or preview
+	w.u(1, 1).u(0, 2).u(0, 2).u(0, 2).u(0, 1)
+	w.u(0, 1).u(1, 2).u(1, 1) // 10-bit integer samples, modular_16_bit_buffers
+	w.u(1, 2)                 // one extra channel
+	w.u(0, 1).u(0, 2).u(0, 1).u(1, 2).u(0, 2).u(0, 2).u(0, 1)
+	w.u(0, 1)                                  // xyb_encoded
+	w.u(0, 1).u(0, 1).u(0, 2).u(1, 2)          // RGB, D65
+	w.u(2, 2).u(9, 4).u(0, 1).u(2, 2).u(14, 4) // P3, PQ
+	w.u(0, 2)                                  // perceptual
+	return append([]byte{0xFF, 0x0A}, w.data...)
+}
+
+func TestParseJXLCodestream(t *testing.T) {
+	info := parseJXL(buildJXLCodestream())
+	if info == nil {
+		t.Fatal("expected JXL info")
+	}
+	if info.Container || info.Width != 1920 || info.Height != 1080 || info.Orientation != 6 || !info.Animated {
+		t.Errorf("info = %+v", info)
+	}
+	if info.BitsPerSample != 10 || info.XYBEncoded || len(info.ExtraChannels) != 1 || info.ExtraChannels[0] != "Alpha (10-bit)" {
+		t.Errorf("bits = %d, xyb = %v, extra = %v", info.BitsPerSample, info.XYBEncoded, info.ExtraChannels)
+	}
+	if info.ColorSpace != "RGB" || info.WhitePoint != "D65" || info.Primaries != "P3" ||
+		info.TransferFunction != "PQ" || info.RenderingIntent != "Perceptual" {
+		t.Errorf("color = %s / %s / %s / %s / %s", info.ColorSpace, info.WhitePoint, info.Primaries,
+			info.TransferFunction, info.RenderingIntent)
+	}
+}
+
+func TestExtractMetadataJXLContainer(t *testing.T) {
+	tiffData := buildTIFF([]testIFD{{tags: []testTag{asciiTag(0x010F, "Canon")}}})
+	data := append([]byte{}, jxlContainerSignature...)
+	data = append(data, bmff("ftyp", []byte("jxl "), u32(0), []byte("jxl "))...)
+	data = append(data, bmff("jxll", []byte{5})...)
+	data = append(data, bmff("Exif", u32(0), tiffData)...)
+	data = append(data, bmff("xml ", []byte(testXMPPacket))...)
+	data = append(data, bmff("jxlp", u32(0x80000000), buildJXLCodestream())...)
+	data = append(data, bmff("brob", []byte("jumb"), []byte{1, 2, 3})...)
+
+	meta := ExtractMetadata(data, "application/octet-stream", "photo.jxl")
+	if meta.DecodeError != "" {
+		t.Fatalf("DecodeError = %q", meta.DecodeError)
+	}
+	if meta.Format != "jxl" || meta.Width != 1920 || meta.Height != 1080 || meta.MIMEType != "image/jxl" {
+		t.Errorf("Format = %q, size = %dx%d, MIMEType = %q", meta.Format, meta.Width, meta.Height, meta.MIMEType)
+	}
+	if meta.BitsPerSample != "10" || meta.ColorMode != "RGB with alpha" {
+		t.Errorf("BitsPerSample = %q, ColorMode = %q", meta.BitsPerSample, meta.ColorMode)
+	}
+	if meta.Camera == nil || meta.Camera.Make != "Canon" {
+		t.Errorf("Camera = %+v, want EXIF from the Exif box", meta.Camera)
+	}
+	if meta.XMP == nil {
+		t.Error("expected XMP from the xml box")
+	}
+
+	info := meta.JXL
+	if info == nil || !info.Container || info.Level != 5 || len(info.Boxes) != 7 {
+		t.Fatalf("JXL = %+v", info)
+	}
+	if last := info.Boxes[6]; !strings.Contains(last.Description, `"jumb"`) {
+		t.Errorf("brob box = %+v", last)
+	}
+}
@@ -0,0 +1,293 @@
+package metadata
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/ahrdadan/image-metadata-viewer/src/internal/models"
+	"github.com/rwcarlsen/goexif/tiff"
+)
+
+// tagMakerNote holds the manufacturer MakerNote in the Exif IFD
+const tagMakerNote uint16 = 0x927C
+
+// makerNoteIFDName is the IFD name of the main MakerNote directory
+const makerNoteIFDName = "MakerNote"
+
+// maxMakerNoteEntries bounds the entries read from one MakerNote IFD
+const maxMakerNoteEntries = 512
+
+// dtIFD is the TIFF field type of sub-IFD offsets, which goexif does not
+// decode; MakerNote entries of this type are read as LONG
+const dtIFD = 13
+
+// makerNoteSource is a MakerNote value together with the TIFF block it was
+// stored in, since some vendors count offsets from that block
+type makerNoteSource struct {
+	make   string           // camera Make from IFD0
+	data   []byte           // the MakerNote value
+	tiff   []byte           // TIFF block holding the MakerNote
+	offset int64            // position of data within tiff
+	order  binary.ByteOrder // byte order of tiff
+}
+
+// makerNoteIFD locates a MakerNote directory. Value offsets inside it are
+// relative to the start of base.
+type makerNoteIFD struct {
+	format string
+	base   []byte
+	offset int64
+	order  binary.ByteOrder
+}
+
+// makerNoteTag names a vendor tag; describe interprets its value and falls
+// back to the default formatting when nil or empty
+type makerNoteTag struct {
+	name     string
+	describe tagDescriber
+}
+
+// makerNoteDecoder decodes one manufacturer's MakerNote layout
+type makerNoteDecoder struct {
+	vendor string
+	// locate recognizes the MakerNote and returns its main IFD
+	locate func(src makerNoteSource) (makerNoteIFD, bool)
+	// tags names the tags of the main IFD
+	tags map[uint16]makerNoteTag
+	// summarize fills the cross-vendor fields and may add sub-IFD tags
+	summarize func(note *models.MakerNote, dir *tiff.Dir, loc makerNoteIFD)
+}
+
+// makerNoteDecoders holds the registered vendor decoders in the order they
+// are tried
+var makerNoteDecoders []makerNoteDecoder
+
+// registerMakerNote adds a vendor decoder to the registry
+func registerMakerNote(d makerNoteDecoder) {
+	makerNoteDecoders = append(makerNoteDecoders, d)
+}
+
+// extractMakerNote decodes the MakerNote referenced from the Exif IFD, or
+// Canon's CMT3 box in CR3 files
+func extractMakerNote(data []byte, dirs *exifDirs) *models.MakerNote {
+	if dirs == nil {
+		return nil
+	}
+	src := makerNoteSource{make: dirString(dirs.ifd0, tagMake)}
+
+	if sniffFormat(data) == formatCR3 {
+		// CMT3 is a TIFF whose IFD0 is the Canon MakerNote
+		cmt3 := findBox(cr3MetadataBoxes(data), "CMT3")
+		if cmt3 == nil {
+			return nil
+		}
+		loc, ok := embeddedTIFF(cmt3.data, "CMT3")
+		if !ok || loc.offset >= int64(len(cmt3.data)) {
+			return nil
+		}
+		src.data, src.tiff, src.offset, src.order = cmt3.data[loc.offset:], cmt3.data, loc.offset, loc.order
+	} else {
+		tag := findTag(dirs.exif, tagMakerNote)
+		if tag == nil || len(tag.Val) == 0 || tag.ValOffset == 0 {
+			return nil
+		}
+		src.data, src.tiff, src.offset, src.order = tag.Val, dirs.raw, int64(tag.ValOffset), dirs.order
+	}
+	return decodeMakerNote(src)
+}
+
+// decodeMakerNote runs the first registered decoder that recognizes src
+func decodeMakerNote(src makerNoteSource) *models.MakerNote {
+	for _, d := range makerNoteDecoders {
+		loc, ok := d.locate(src)
+		if !ok {
+			continue
+		}
+		dir := decodeMakerNoteDir(loc.base, loc.order, loc.offset)
+		if dir == nil {
+			// Some bodies write the note in the other byte order from the
+			// EXIF block around it
+			loc.order = otherByteOrder(loc.order)
+			if dir = decodeMakerNoteDir(loc.base, loc.order, loc.offset); dir == nil {
+				return nil
+			}
+		}
+
+		note := &models.MakerNote{
+			Vendor:    d.vendor,
+			Format:    loc.format,
+			ByteOrder: byteOrderName(loc.order),
+			Offset:    int(src.offset),
+			Size:      len(src.data),
+		}
+		note.Tags = appendMakerNoteTags(nil, dir, makerNoteIFDName, d.tags, loc.order)
+		if d.summarize != nil {
+			d.summarize(note, dir, loc)
+		}
+		return note
+	}
+	return nil
+}
+
+// parentIFD locates an IFD that starts skip bytes into the MakerNote and
+// whose offsets count from the enclosing TIFF block
+func parentIFD(src makerNoteSource, format string, skip int) (makerNoteIFD, bool) {
+	if len(src.data) < skip+2 || src.tiff == nil {
+		return makerNoteIFD{}, false
+	}
+	return makerNoteIFD{format: format, base: src.tiff, offset: src.offset + int64(skip), order: src.order}, true
+}
+
+// selfIFD locates an IFD that starts skip bytes into the MakerNote and
+// whose offsets count from the start of the MakerNote. The base runs to the
+// end of the TIFF block, since sub-IFDs often lie past the note's declared
+// size.
+func selfIFD(src makerNoteSource, format string, skip int, order binary.ByteOrder) (makerNoteIFD, bool) {
+	if len(src.data) < skip+2 {
+		return makerNoteIFD{}, false
+	}
+	base := src.data
+	if src.tiff != nil && src.offset < int64(len(src.tiff)) {
+		base = src.tiff[src.offset:]
+	}
+	return makerNoteIFD{format: format, base: base, offset: int64(skip), order: order}, true
+}
+
+// embeddedTIFF locates IFD0 of a complete TIFF structure, header included
+func embeddedTIFF(b []byte, format string) (makerNoteIFD, bool) {
+	order := tiffByteOrder(b)
+	if order == nil || len(b) < 8 {
+		return makerNoteIFD{}, false
+	}
+	return makerNoteIFD{format: format, base: b, offset: int64(order.Uint32(b[4:])), order: order}, true
+}
+
+// tiffByteOrder reads an "II" or "MM" byte order marker
+func tiffByteOrder(b []byte) binary.ByteOrder {
+	switch {
+	case bytes.HasPrefix(b, []byte("II")):
+		return binary.LittleEndian
+	case bytes.HasPrefix(b, []byte("MM")):
+		return binary.BigEndian
+	}
+	return nil
+}
+
+// otherByteOrder returns the opposite of order
+func otherByteOrder(order binary.ByteOrder) binary.ByteOrder {
+	if order == binary.BigEndian {
+		return binary.LittleEndian
+	}
+	return binary.BigEndian
+}
+
+// makerNoteEntry serves a single IFD entry to tiff.DecodeTag while value
+// offsets keep resolving against the whole base
+type makerNoteEntry struct {
+	*bytes.Reader
+	base *bytes.Reader
+}
+
+// ReadAt reads value data from the MakerNote base
+func (e makerNoteEntry) ReadAt(p []byte, off int64) (int, error) {
+	return e.base.ReadAt(p, off)
+}
+
+// decodeMakerNoteDir decodes the IFD at offset in base. Unlike decodeDirAt
+// it skips entries goexif cannot decode instead of failing the whole IFD,
+// and reads IFD-typed sub-directory pointers as LONG.
+func decodeMakerNoteDir(base []byte, order binary.ByteOrder, offset int64) *tiff.Dir {
+	if offset <= 0 || offset+2 > int64(len(base)) {
+		return nil
+	}
+	count := int(order.Uint16(base[offset:]))
+	if count == 0 || count > maxMakerNoteEntries || offset+2+12*int64(count) > int64(len(base)) {
+		return nil
+	}
+
+	dir := &tiff.Dir{}
+	r := bytes.NewReader(base)
+	for i := 0; i < count; i++ {
+		pos := offset + 2 + 12*int64(i)
+		entry := append([]byte{}, base[pos:pos+12]...)
+		switch typ := order.Uint16(entry[2:]); {
+		case typ == dtIFD:
+			order.PutUint16(entry[2:], uint16(tiff.DTLong))
+		case typ == 0 || typ > uint16(tiff.DTDouble):
+			continue
+		}
+		if !entryFits(entry, order, int64(len(base))) {
+			continue
+		}
+		tag, err := tiff.DecodeTag(makerNoteEntry{bytes.NewReader(entry), r}, order)
+		if err != nil {
+			continue
+		}
+		dir.Tags = append(dir.Tags, tag)
+	}
+	if len(dir.Tags) == 0 {
+		return nil
+	}
+	return dir
+}
+
+// makerNoteSubDir decodes the sub-IFD referenced by tag id in dir. The
+// pointer is either an offset or, in older notes, the sub-IFD itself
+// stored as an undefined value.
+func makerNoteSubDir(dir *tiff.Dir, id uint16, loc makerNoteIFD) *tiff.Dir {
+	tag := findTag(dir, id)
+	if tag == nil {
+		return nil
+	}
+	if tag.Type == tiff.DTUndefined {
+		return decodeMakerNoteDir(loc.base, loc.order, int64(tag.ValOffset))
+	}
+	offset, err := tag.Int64(0)
+	if err != nil {
+		return nil
+	}
+	return decodeMakerNoteDir(loc.base, loc.order, offset)
+}
+
+// appendMakerNoteTags converts every tag in dir to a models.EXIFTag, named
+// from a vendor tag table
+func appendMakerNoteTags(tags []models.EXIFTag, dir *tiff.Dir, ifd string, names map[uint16]makerNoteTag, order binary.ByteOrder) []models.EXIFTag {
+	if dir == nil {
+		return tags
+	}
+	for _, tag := range dir.Tags {
+		info, ok := names[tag.Id]
+		if !ok {
+			info.name = fmt.Sprintf("Unknown (0x%04X)", tag.Id)
+		}
+
+		value := ""
+		if info.describe != nil {
+			value = info.describe(tag, order)
+		}
+		if value == "" {
+			value = defaultTagValue(tag)
+		}
+
+		tags = append(tags, models.EXIFTag{
+			IFD:      ifd,
+			ID:       tag.Id,
+			Name:     info.name,
+			Type:     dataTypeName(tag.Type),
+			Count:    tag.Count,
+			RawValue: rawTagValue(tag),
+			Value:    value,
+		})
+	}
+	return tags
+}
+
+// dirEnum returns the name of the first integer value of tag id, or ""
+// when the tag is missing or the value is not in names
+func dirEnum(dir *tiff.Dir, id uint16, names map[int]string) string {
+	if v, ok := dirInt(dir, id); ok {
+		return names[v]
+	}
+	return ""
+}
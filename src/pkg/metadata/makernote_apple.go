@@ -0,0 +1,63 @@
+package metadata
+
+import (
+	"bytes"
+
+	"github.com/ahrdadan/image-metadata-viewer/src/internal/models"
+	"github.com/rwcarlsen/goexif/tiff"
+)
+
+// tagAppleContentIdentifier pairs a Live Photo still with its video
+const tagAppleContentIdentifier uint16 = 0x0011
+
+// appleHeader starts Apple iOS notes; a version and "MM" follow, the IFD
+// starts at byte 14 and offsets count from the start of the note
+var appleHeader = []byte("Apple iOS\x00")
+
+// appleIFDOffset is where the IFD starts after the header
+const appleIFDOffset = 14
+
+var appleTags = map[uint16]makerNoteTag{
+	0x0001: {name: "MakerNoteVersion"},
+	0x0002: {name: "AEMatrix"},
+	0x0003: {name: "RunTime"},
+	0x0004: {name: "AEStable", describe: enumDescriber(map[int]string{0: "No", 1: "Yes"})},
+	0x0005: {name: "AETarget"},
+	0x0006: {name: "AEAverage"},
+	0x0007: {name: "AFStable", describe: enumDescriber(map[int]string{0: "No", 1: "Yes"})},
+	0x0008: {name: "AccelerationVector"},
+	0x000A: {name: "HDRImageType", describe: enumDescriber(map[int]string{3: "HDR Image", 4: "Original Image"})},
+	0x000B: {name: "BurstUUID"},
+	0x000C: {name: "FocusDistanceRange"},
+	0x000F: {name: "OISMode"},
+	0x0011: {name: "ContentIdentifier"},
+	0x0014: {name: "ImageCaptureType", describe: enumDescriber(map[int]string{
+		1: "ProRAW", 2: "Portrait", 10: "Photo", 11: "Manual Focus", 12: "Scene",
+	})},
+	0x0015: {name: "ImageCaptureRequestID"},
+	0x0017: {name: "LivePhotoVideoIndex"},
+	0x001F: {name: "PhotosAppFeatureFlags"},
+}
+
+func init() {
+	registerMakerNote(makerNoteDecoder{
+		vendor: "Apple",
+		locate: func(src makerNoteSource) (makerNoteIFD, bool) {
+			if !bytes.HasPrefix(src.data, appleHeader) || len(src.data) < appleIFDOffset {
+				return makerNoteIFD{}, false
+			}
+			order := tiffByteOrder(src.data[appleIFDOffset-2:])
+			if order == nil {
+				return makerNoteIFD{}, false
+			}
+			return selfIFD(src, "Apple iOS", appleIFDOffset, order)
+		},
+		tags:      appleTags,
+		summarize: summarizeApple,
+	})
+}
+
+// summarizeApple fills the common fields from an Apple MakerNote
+func summarizeApple(note *models.MakerNote, dir *tiff.Dir, _ makerNoteIFD) {
+	note.ContentIdentifier = dirString(dir, tagAppleContentIdentifier)
+}
@@ -0,0 +1,124 @@
+package metadata
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/ahrdadan/image-metadata-viewer/src/internal/models"
+	"github.com/rwcarlsen/goexif/tiff"
+)
+
+// Canon MakerNote tags
+const (
+	canonCameraSettings       uint16 = 0x0001
+	canonFirmwareVersion      uint16 = 0x0007
+	canonSerialNumber         uint16 = 0x000C
+	canonLensModel            uint16 = 0x0095
+	canonInternalSerialNumber uint16 = 0x0096
+)
+
+// canonFocusModeIndex is the FocusMode position in CameraSettings
+const canonFocusModeIndex = 7
+
+var canonTags = map[uint16]makerNoteTag{
+	0x0001: {name: "CameraSettings"},
+	0x0002: {name: "FocalLength"},
+	0x0004: {name: "ShotInfo"},
+	0x0006: {name: "CanonImageType"},
+	0x0007: {name: "CanonFirmwareVersion"},
+	0x0008: {name: "FileNumber"},
+	0x0009: {name: "OwnerName"},
+	0x000C: {name: "SerialNumber", describe: intDescriber(canonSerial)},
+	0x000D: {name: "CameraInfo"},
+	0x0010: {name: "CanonModelID", describe: intDescriber(func(v int) string { return fmt.Sprintf("0x%08X", uint32(v)) })},
+	0x0012: {name: "AFInfo"},
+	0x0013: {name: "ThumbnailImageValidArea"},
+	0x0026: {name: "AFInfo2"},
+	0x0035: {name: "TimeInfo"},
+	0x0093: {name: "FileInfo"},
+	0x0095: {name: "LensModel"},
+	0x0096: {name: "InternalSerialNumber"},
+	0x0097: {name: "DustRemovalData"},
+	0x00A0: {name: "ProcessingInfo"},
+	0x00AA: {name: "MeasuredColor"},
+	0x00B4: {name: "ColorSpace", describe: enumDescriber(map[int]string{1: "sRGB", 2: "Adobe RGB"})},
+	0x00E0: {name: "SensorInfo"},
+	0x4001: {name: "ColorData"},
+	0x4008: {name: "PictureStyleUserDef"},
+	0x4010: {name: "CustomPictureStyleFileName"},
+	0x4019: {name: "LensInfo"},
+}
+
+var canonFocusModes = map[int]string{
+	0:   "One-shot AF",
+	1:   "AI Servo AF",
+	2:   "AI Focus AF",
+	3:   "Manual Focus",
+	4:   "Single",
+	5:   "Continuous",
+	6:   "Manual Focus",
+	16:  "Pan Focus",
+	256: "One-shot AF (Live View)",
+	257: "AI Servo AF (Live View)",
+	258: "AI Focus AF (Live View)",
+	512: "Movie Snap Focus",
+	519: "Movie Servo AF",
+}
+
+func init() {
+	registerMakerNote(makerNoteDecoder{
+		vendor:    "Canon",
+		locate:    locateCanon,
+		tags:      canonTags,
+		summarize: summarizeCanon,
+	})
+}
+
+// locateCanon finds a Canon note. It has no header and counts offsets from
+// the EXIF TIFF; the 8-byte footer repeats the note's byte order and its
+// original offset, so a note moved by editing software can still be read.
+func locateCanon(src makerNoteSource) (makerNoteIFD, bool) {
+	if !strings.HasPrefix(src.make, "Canon") {
+		return makerNoteIFD{}, false
+	}
+	loc, ok := parentIFD(src, "Canon", 0)
+	if !ok || len(src.data) < 8 {
+		return loc, ok
+	}
+
+	footer := src.data[len(src.data)-8:]
+	if !bytes.HasPrefix(footer, []byte("II*\x00")) && !bytes.HasPrefix(footer, []byte("MM\x00*")) {
+		return loc, true
+	}
+	loc.order = tiffByteOrder(footer)
+	original := int64(loc.order.Uint32(footer[4:]))
+	switch shift := original - src.offset; {
+	case shift < 0 && -shift < int64(len(src.tiff)):
+		loc.base, loc.offset = src.tiff[-shift:], original
+	case shift > 0 && shift < int64(len(src.tiff)):
+		loc.base, loc.offset = append(make([]byte, shift), src.tiff...), original
+	}
+	return loc, true
+}
+
+// summarizeCanon fills the common fields from a Canon MakerNote
+func summarizeCanon(note *models.MakerNote, dir *tiff.Dir, _ makerNoteIFD) {
+	if v, ok := dirInt(dir, canonSerialNumber); ok && v > 0 {
+		note.SerialNumber = canonSerial(v)
+	}
+	note.InternalSerialNumber = dirString(dir, canonInternalSerialNumber)
+	note.FirmwareVersion = strings.TrimPrefix(dirString(dir, canonFirmwareVersion), "Firmware Version ")
+	note.LensModel = dirString(dir, canonLensModel)
+
+	if tag := findTag(dir, canonCameraSettings); tag != nil && tag.Count > canonFocusModeIndex {
+		if v, err := tag.Int(canonFocusModeIndex); err == nil {
+			note.FocusMode = canonFocusModes[v]
+		}
+	}
+}
+
+// canonSerial formats a body serial number the way it is printed on the camera
+func canonSerial(v int) string {
+	return fmt.Sprintf("%010d", uint32(v))
+}
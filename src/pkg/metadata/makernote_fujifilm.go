@@ -0,0 +1,107 @@
+package metadata
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strconv"
+
+	"github.com/ahrdadan/image-metadata-viewer/src/internal/models"
+	"github.com/rwcarlsen/goexif/tiff"
+)
+
+// Fujifilm MakerNote tags
+const (
+	fujiInternalSerialNumber uint16 = 0x0010
+	fujiFocusMode            uint16 = 0x1021
+	fujiImageCount           uint16 = 0x1438
+)
+
+// fujiHeader starts every Fujifilm note; the IFD offset follows as a
+// little-endian LONG and all offsets count from the start of the note
+var fujiHeader = []byte("FUJIFILM")
+
+var fujiFocusModes = map[int]string{
+	0:      "Auto",
+	1:      "Manual",
+	0xFFFF: "Movie",
+}
+
+var fujiTags = map[uint16]makerNoteTag{
+	0x0000: {name: "Version"},
+	0x0010: {name: "InternalSerialNumber"},
+	0x1000: {name: "Quality"},
+	0x1001: {name: "Sharpness"},
+	0x1002: {name: "WhiteBalance", describe: enumDescriber(map[int]string{
+		0x0: "Auto", 0x1: "Auto (white priority)", 0x2: "Auto (ambiance priority)",
+		0x100: "Daylight", 0x200: "Cloudy", 0x300: "Daylight Fluorescent",
+		0x301: "Day White Fluorescent", 0x302: "White Fluorescent",
+		0x303: "Warm White Fluorescent", 0x304: "Living Room Warm White Fluorescent",
+		0x400: "Incandescent", 0x500: "Flash", 0x600: "Underwater",
+		0xF00: "Custom", 0xFF0: "Kelvin",
+	})},
+	0x1003: {name: "Saturation"},
+	0x1004: {name: "Contrast"},
+	0x1010: {name: "FujiFlashMode"},
+	0x1011: {name: "FlashExposureComp"},
+	0x1020: {name: "Macro", describe: enumDescriber(map[int]string{0: "Off", 1: "On"})},
+	0x1021: {name: "FocusMode", describe: enumDescriber(fujiFocusModes)},
+	0x1022: {name: "AFMode"},
+	0x1023: {name: "FocusPixel"},
+	0x1030: {name: "SlowSync"},
+	0x1031: {name: "PictureMode"},
+	0x1032: {name: "ExposureCount"},
+	0x1100: {name: "AutoBracketing"},
+	0x1101: {name: "SequenceNumber"},
+	0x1300: {name: "BlurWarning"},
+	0x1301: {name: "FocusWarning"},
+	0x1302: {name: "ExposureWarning"},
+	0x1400: {name: "DynamicRange"},
+	0x1401: {name: "FilmMode", describe: enumDescriber(map[int]string{
+		0x000: "F0/Standard (Provia)", 0x100: "F1/Studio Portrait",
+		0x110: "F1a/Studio Portrait Enhanced Saturation", 0x120: "F1b/Studio Portrait Smooth Skin Tone (Astia)",
+		0x130: "F1c/Studio Portrait Increased Sharpness", 0x200: "F2/Fujichrome (Velvia)",
+		0x300: "F3/Studio Portrait Ex", 0x400: "F4/Velvia", 0x500: "Pro Neg. Std",
+		0x501: "Pro Neg. Hi", 0x600: "Classic Chrome", 0x700: "Eterna",
+		0x800: "Classic Negative", 0x900: "Bleach Bypass", 0xA00: "Nostalgic Neg",
+		0xB00: "Reala ACE",
+	})},
+	0x1402: {name: "DynamicRangeSetting"},
+	0x1404: {name: "MinFocalLength"},
+	0x1405: {name: "MaxFocalLength"},
+	0x1406: {name: "MaxApertureAtMinFocal"},
+	0x1407: {name: "MaxApertureAtMaxFocal"},
+	0x1422: {name: "ImageStabilization"},
+	0x1431: {name: "Rating"},
+	0x1436: {name: "ImageGeneration"},
+	0x1438: {name: "ImageCount", describe: intDescriber(func(v int) string {
+		return strconv.Itoa(v & 0x7FFF)
+	})},
+	0x1443: {name: "DRangePriority"},
+	0x4100: {name: "FacesDetected"},
+}
+
+func init() {
+	registerMakerNote(makerNoteDecoder{
+		vendor: "Fujifilm",
+		locate: func(src makerNoteSource) (makerNoteIFD, bool) {
+			if !bytes.HasPrefix(src.data, fujiHeader) || len(src.data) < 12 {
+				return makerNoteIFD{}, false
+			}
+			offset := int(binary.LittleEndian.Uint32(src.data[8:]))
+			return selfIFD(src, "Fujifilm", offset, binary.LittleEndian)
+		},
+		tags:      fujiTags,
+		summarize: summarizeFujifilm,
+	})
+}
+
+// summarizeFujifilm fills the common fields from a Fujifilm MakerNote.
+// ImageCount counts every exposure the body has made, so it is reported as
+// the shutter count; bit 15 is a flag.
+func summarizeFujifilm(note *models.MakerNote, dir *tiff.Dir, _ makerNoteIFD) {
+	note.InternalSerialNumber = dirString(dir, fujiInternalSerialNumber)
+	note.FocusMode = dirEnum(dir, fujiFocusMode, fujiFocusModes)
+	if v, ok := dirInt(dir, fujiImageCount); ok {
+		note.ShutterCount = v & 0x7FFF
+	}
+}
@@ -0,0 +1,136 @@
+package metadata
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strconv"
+	"strings"
+
+	"github.com/ahrdadan/image-metadata-viewer/src/internal/models"
+	"github.com/rwcarlsen/goexif/tiff"
+)
+
+// Nikon MakerNote tags
+const (
+	nikonFocusMode    uint16 = 0x0007
+	nikonSerialNumber uint16 = 0x001D
+	nikonShutterCount uint16 = 0x00A7
+)
+
+// nikonType3Header starts a Nikon type 3 note, which embeds its own TIFF
+// header 10 bytes in; offsets count from that header
+var nikonType3Header = []byte("Nikon\x00\x02")
+
+var nikonTags = map[uint16]makerNoteTag{
+	0x0001: {name: "MakerNoteVersion"},
+	0x0002: {name: "ISO", describe: nikonISO},
+	0x0003: {name: "ColorMode"},
+	0x0004: {name: "Quality"},
+	0x0005: {name: "WhiteBalance"},
+	0x0006: {name: "Sharpness"},
+	0x0007: {name: "FocusMode"},
+	0x0008: {name: "FlashSetting"},
+	0x0009: {name: "FlashType"},
+	0x000B: {name: "WhiteBalanceFineTune"},
+	0x000C: {name: "WB_RBLevels"},
+	0x000D: {name: "ProgramShift"},
+	0x000E: {name: "ExposureDifference"},
+	0x0011: {name: "PreviewIFD"},
+	0x0012: {name: "FlashExposureComp"},
+	0x0013: {name: "ISOSetting", describe: nikonISO},
+	0x0016: {name: "ImageBoundary"},
+	0x0017: {name: "ExternalFlashExposureComp"},
+	0x0018: {name: "FlashExposureBracketValue"},
+	0x0019: {name: "ExposureBracketValue"},
+	0x001B: {name: "CropHiSpeed"},
+	0x001C: {name: "ExposureTuning"},
+	0x001D: {name: "SerialNumber"},
+	0x001E: {name: "ColorSpace", describe: enumDescriber(map[int]string{1: "sRGB", 2: "Adobe RGB"})},
+	0x001F: {name: "VRInfo"},
+	0x0022: {name: "ActiveD-Lighting", describe: enumDescriber(map[int]string{
+		0: "Off", 1: "Low", 3: "Normal", 5: "High", 7: "Extra High", 8: "Extra High 1",
+		9: "Extra High 2", 10: "Extra High 3", 11: "Extra High 4", 0xFFFF: "Auto",
+	})},
+	0x0023: {name: "PictureControlData"},
+	0x0024: {name: "WorldTime"},
+	0x0025: {name: "ISOInfo"},
+	0x002A: {name: "VignetteControl"},
+	0x0083: {name: "LensType", describe: intDescriber(nikonLensType)},
+	0x0084: {name: "Lens", describe: describeLensSpecification},
+	0x0087: {name: "FlashMode"},
+	0x0088: {name: "AFInfo"},
+	0x0089: {name: "ShootingMode"},
+	0x008B: {name: "LensFStops"},
+	0x008C: {name: "ContrastCurve"},
+	0x0091: {name: "ShotInfo"},
+	0x0093: {name: "NEFCompression", describe: enumDescriber(map[int]string{
+		1: "Lossy (type 1)", 2: "Uncompressed", 3: "Lossless", 4: "Lossy (type 2)",
+		5: "Striped packed 12 bits", 6: "Uncompressed (reduced to 12 bit)",
+		7: "Unpacked 12 bits", 8: "Small", 9: "Packed 12 bits", 10: "Packed 14 bits",
+		13: "High Efficiency", 14: "High Efficiency*",
+	})},
+	0x0095: {name: "NoiseReduction"},
+	0x0097: {name: "ColorBalance"},
+	0x0098: {name: "LensData"},
+	0x00A7: {name: "ShutterCount"},
+	0x00A8: {name: "FlashInfo"},
+	0x00AB: {name: "VariProgram"},
+	0x00B1: {name: "HighISONoiseReduction"},
+	0x00B7: {name: "AFInfo2"},
+	0x00B8: {name: "FileInfo"},
+	0x00BB: {name: "RetouchInfo"},
+}
+
+func init() {
+	registerMakerNote(makerNoteDecoder{
+		vendor: "Nikon",
+		locate: func(src makerNoteSource) (makerNoteIFD, bool) {
+			if bytes.HasPrefix(src.data, nikonType3Header) && len(src.data) > 10 {
+				return embeddedTIFF(src.data[10:], "Nikon type 3")
+			}
+			// Early Coolpix notes have no header
+			if strings.HasPrefix(strings.ToUpper(src.make), "NIKON") && !bytes.HasPrefix(src.data, []byte("Nikon")) {
+				return parentIFD(src, "Nikon type 2", 0)
+			}
+			return makerNoteIFD{}, false
+		},
+		tags:      nikonTags,
+		summarize: summarizeNikon,
+	})
+}
+
+// summarizeNikon fills the common fields from a Nikon MakerNote
+func summarizeNikon(note *models.MakerNote, dir *tiff.Dir, _ makerNoteIFD) {
+	note.SerialNumber = dirString(dir, nikonSerialNumber)
+	note.FocusMode = dirString(dir, nikonFocusMode)
+	if v, ok := dirInt(dir, nikonShutterCount); ok {
+		note.ShutterCount = v
+	}
+}
+
+// nikonISO reads the ISO value from the second element of the pair
+func nikonISO(tag *tiff.Tag, _ binary.ByteOrder) string {
+	if tag.Count < 2 {
+		return ""
+	}
+	v, err := tag.Int(1)
+	if err != nil || v == 0 {
+		return ""
+	}
+	return strconv.Itoa(v)
+}
+
+// nikonLensType lists the lens type flags
+func nikonLensType(v int) string {
+	flags := []string{"MF", "D", "G", "VR", "1", "FT-1", "E", "AF-P"}
+	var parts []string
+	for i, flag := range flags {
+		if v&(1<<i) != 0 {
+			parts = append(parts, flag)
+		}
+	}
+	if len(parts) == 0 {
+		return "AF"
+	}
+	return strings.Join(parts, " ")
+}
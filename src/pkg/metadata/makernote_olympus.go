@@ -0,0 +1,150 @@
+package metadata
+
+import (
+	"bytes"
+
+	"github.com/ahrdadan/image-metadata-viewer/src/internal/models"
+	"github.com/rwcarlsen/goexif/tiff"
+)
+
+// Olympus sub-IFD pointers and the tags read from them
+const (
+	olympusEquipment        uint16 = 0x2010
+	olympusCameraSettings   uint16 = 0x2020
+	olympusSerialNumber     uint16 = 0x0101
+	olympusInternalSerial   uint16 = 0x0102
+	olympusLensSerialNumber uint16 = 0x0202
+	olympusLensModel        uint16 = 0x0203
+	olympusFocusMode        uint16 = 0x0301
+)
+
+// IFD names of the Olympus sub-IFDs in the tag dump
+const (
+	olympusEquipmentIFDName      = "Equipment"
+	olympusCameraSettingsIFDName = "CameraSettings"
+)
+
+// Olympus and OM System headers. Type 1 notes count offsets from the EXIF
+// TIFF; the newer ones from the start of the note and carry a byte order
+// marker after the signature.
+var (
+	olympusType1Header    = []byte("OLYMP\x00")
+	olympusType2Header    = []byte("OLYMPUS\x00")
+	olympusOMSystemHeader = []byte("OM SYSTEM\x00\x00\x00")
+)
+
+var olympusFocusModes = map[int]string{
+	0:  "Single AF",
+	1:  "Sequential shooting AF",
+	2:  "Continuous AF",
+	3:  "Multi AF",
+	4:  "Face detect",
+	10: "MF",
+}
+
+var olympusTags = map[uint16]makerNoteTag{
+	0x0000: {name: "MakerNoteVersion"},
+	0x0040: {name: "CompressedImageSize"},
+	0x0088: {name: "PreviewImageStart"},
+	0x0089: {name: "PreviewImageLength"},
+	0x0104: {name: "BodyFirmwareVersion"},
+	0x0200: {name: "SpecialMode"},
+	0x0201: {name: "Quality"},
+	0x0202: {name: "Macro"},
+	0x0204: {name: "DigitalZoom"},
+	0x0207: {name: "CameraType"},
+	0x0208: {name: "TextInfo"},
+	0x0209: {name: "CameraID"},
+	0x0E00: {name: "PrintIM"},
+	0x2010: {name: "Equipment"},
+	0x2020: {name: "CameraSettings"},
+	0x2030: {name: "RawDevelopment"},
+	0x2031: {name: "RawDev2"},
+	0x2040: {name: "ImageProcessing"},
+	0x2050: {name: "FocusInfo"},
+	0x3000: {name: "RawInfo"},
+}
+
+var olympusEquipmentTags = map[uint16]makerNoteTag{
+	0x0000: {name: "EquipmentVersion"},
+	0x0100: {name: "CameraType2"},
+	0x0101: {name: "SerialNumber"},
+	0x0102: {name: "InternalSerialNumber"},
+	0x0103: {name: "FocalPlaneDiagonal"},
+	0x0104: {name: "BodyFirmwareVersion"},
+	0x0201: {name: "LensType"},
+	0x0202: {name: "LensSerialNumber"},
+	0x0203: {name: "LensModel"},
+	0x0204: {name: "LensFirmwareVersion"},
+	0x0205: {name: "MaxApertureAtMinFocal"},
+	0x0206: {name: "MaxApertureAtMaxFocal"},
+	0x0207: {name: "MinFocalLength"},
+	0x0208: {name: "MaxFocalLength"},
+	0x020A: {name: "MaxAperture"},
+	0x0301: {name: "Extender"},
+	0x0303: {name: "ExtenderModel"},
+	0x1000: {name: "FlashType"},
+	0x1002: {name: "FlashFirmwareVersion"},
+	0x1003: {name: "FlashSerialNumber"},
+}
+
+var olympusCameraSettingsTags = map[uint16]makerNoteTag{
+	0x0000: {name: "CameraSettingsVersion"},
+	0x0100: {name: "PreviewImageValid"},
+	0x0101: {name: "PreviewImageStart"},
+	0x0102: {name: "PreviewImageLength"},
+	0x0200: {name: "ExposureMode"},
+	0x0201: {name: "AELock"},
+	0x0202: {name: "MeteringMode"},
+	0x0300: {name: "MacroMode"},
+	0x0301: {name: "FocusMode", describe: enumDescriber(olympusFocusModes)},
+	0x0302: {name: "FocusProcess"},
+	0x0303: {name: "AFSearch"},
+	0x0304: {name: "AFAreas"},
+	0x0400: {name: "FlashMode"},
+	0x0401: {name: "FlashExposureComp"},
+	0x0500: {name: "WhiteBalance2"},
+	0x0501: {name: "WhiteBalanceTemperature"},
+	0x0507: {name: "ColorSpace"},
+	0x0520: {name: "PictureMode"},
+	0x0600: {name: "DriveMode"},
+	0x0604: {name: "ImageStabilization"},
+}
+
+func init() {
+	registerMakerNote(makerNoteDecoder{
+		vendor: "Olympus",
+		locate: func(src makerNoteSource) (makerNoteIFD, bool) {
+			switch {
+			case bytes.HasPrefix(src.data, olympusOMSystemHeader):
+				if order := tiffByteOrder(src.data[len(olympusOMSystemHeader):]); order != nil {
+					return selfIFD(src, "OM System", 16, order)
+				}
+			case bytes.HasPrefix(src.data, olympusType2Header):
+				if order := tiffByteOrder(src.data[len(olympusType2Header):]); order != nil {
+					return selfIFD(src, "Olympus type 2", 12, order)
+				}
+			case bytes.HasPrefix(src.data, olympusType1Header):
+				return parentIFD(src, "Olympus type 1", 8)
+			}
+			return makerNoteIFD{}, false
+		},
+		tags:      olympusTags,
+		summarize: summarizeOlympus,
+	})
+}
+
+// summarizeOlympus adds the Equipment and CameraSettings sub-IFDs and
+// fills the common fields from them
+func summarizeOlympus(note *models.MakerNote, dir *tiff.Dir, loc makerNoteIFD) {
+	equipment := makerNoteSubDir(dir, olympusEquipment, loc)
+	settings := makerNoteSubDir(dir, olympusCameraSettings, loc)
+	note.Tags = appendMakerNoteTags(note.Tags, equipment, olympusEquipmentIFDName, olympusEquipmentTags, loc.order)
+	note.Tags = appendMakerNoteTags(note.Tags, settings, olympusCameraSettingsIFDName, olympusCameraSettingsTags, loc.order)
+
+	note.SerialNumber = dirString(equipment, olympusSerialNumber)
+	note.InternalSerialNumber = dirString(equipment, olympusInternalSerial)
+	note.LensModel = dirString(equipment, olympusLensModel)
+	note.LensSerialNumber = dirString(equipment, olympusLensSerialNumber)
+	note.FocusMode = dirEnum(settings, olympusFocusMode, olympusFocusModes)
+}
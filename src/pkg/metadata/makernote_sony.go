@@ -0,0 +1,123 @@
+package metadata
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/ahrdadan/image-metadata-viewer/src/internal/models"
+	"github.com/rwcarlsen/goexif/tiff"
+)
+
+// Sony MakerNote tags
+const (
+	sonyFocusMode       uint16 = 0x201B
+	sonySerialNumber    uint16 = 0x2031
+	sonyFocusModeLegacy uint16 = 0xB042
+)
+
+// sonyHeaders prefix Sony notes written by compact cameras; the IFD
+// follows the 12-byte header and offsets count from the EXIF TIFF
+var sonyHeaders = [][]byte{
+	[]byte("SONY DSC \x00\x00\x00"),
+	[]byte("SONY CAM \x00\x00\x00"),
+}
+
+var sonyFocusModes = map[int]string{
+	0: "Manual",
+	2: "AF-S",
+	3: "AF-C",
+	4: "AF-A",
+	6: "DMF",
+	7: "AF-D",
+}
+
+var sonyLegacyFocusModes = map[int]string{
+	1:      "AF-S",
+	2:      "AF-C",
+	4:      "Permanent-AF",
+	0xFFFF: "n/a",
+}
+
+var sonyTags = map[uint16]makerNoteTag{
+	0x0102: {name: "Quality", describe: enumDescriber(map[int]string{
+		0: "RAW", 1: "Super Fine", 2: "Fine", 3: "Standard", 4: "Economy",
+		5: "Extra Fine", 6: "RAW + JPEG", 7: "Compressed RAW", 8: "Compressed RAW + JPEG",
+	})},
+	0x0104: {name: "FlashExposureComp"},
+	0x0105: {name: "Teleconverter"},
+	0x0112: {name: "WhiteBalanceFineTune"},
+	0x0115: {name: "WhiteBalance"},
+	0x2001: {name: "PreviewImage"},
+	0x2002: {name: "Rating"},
+	0x2004: {name: "Contrast"},
+	0x2005: {name: "Saturation"},
+	0x2006: {name: "Sharpness"},
+	0x2009: {name: "HighISONoiseReduction"},
+	0x200A: {name: "HDR"},
+	0x200B: {name: "MultiFrameNoiseReduction"},
+	0x2010: {name: "Tag2010"},
+	0x201B: {name: "FocusMode", describe: enumDescriber(sonyFocusModes)},
+	0x201C: {name: "AFAreaModeSetting"},
+	0x201E: {name: "AFPointSelected"},
+	0x2026: {name: "WBShiftAB_GM"},
+	0x2031: {name: "SerialNumber"},
+	0x9050: {name: "Tag9050"},
+	0x9400: {name: "Tag9400"},
+	0xB000: {name: "FileFormat"},
+	0xB001: {name: "SonyModelID"},
+	0xB020: {name: "CreativeStyle"},
+	0xB021: {name: "ColorTemperature"},
+	0xB023: {name: "SceneMode"},
+	0xB024: {name: "ZoneMatching"},
+	0xB025: {name: "DynamicRangeOptimizer"},
+	0xB026: {name: "ImageStabilization", describe: enumDescriber(map[int]string{0: "Off", 1: "On"})},
+	0xB027: {name: "LensType"},
+	0xB029: {name: "ColorMode"},
+	0xB02B: {name: "FullImageSize"},
+	0xB02C: {name: "PreviewImageSize"},
+	0xB040: {name: "Macro"},
+	0xB041: {name: "ExposureMode"},
+	0xB042: {name: "FocusMode", describe: enumDescriber(sonyLegacyFocusModes)},
+	0xB043: {name: "AFAreaMode"},
+	0xB044: {name: "AFIlluminator"},
+	0xB047: {name: "JPEGQuality"},
+	0xB048: {name: "FlashLevel"},
+	0xB049: {name: "ReleaseMode"},
+	0xB04A: {name: "SequenceNumber"},
+	0xB04B: {name: "Anti-Blur"},
+	0xB04E: {name: "LongExposureNoiseReduction"},
+	0xB04F: {name: "DynamicRangeOptimizer"},
+	0xB052: {name: "IntelligentAuto"},
+	0xB054: {name: "WhiteBalance2"},
+}
+
+func init() {
+	registerMakerNote(makerNoteDecoder{
+		vendor: "Sony",
+		locate: func(src makerNoteSource) (makerNoteIFD, bool) {
+			for _, header := range sonyHeaders {
+				if bytes.HasPrefix(src.data, header) {
+					return parentIFD(src, "Sony DSC", len(header))
+				}
+			}
+			// Interchangeable-lens bodies write a bare IFD
+			if strings.HasPrefix(strings.ToUpper(src.make), "SONY") && !bytes.HasPrefix(src.data, []byte("SONY")) {
+				return parentIFD(src, "Sony", 0)
+			}
+			return makerNoteIFD{}, false
+		},
+		tags:      sonyTags,
+		summarize: summarizeSony,
+	})
+}
+
+// summarizeSony fills the common fields from a Sony MakerNote. Shutter
+// count and body serial of most models live in the enciphered 0x9050 block,
+// which is not decoded.
+func summarizeSony(note *models.MakerNote, dir *tiff.Dir, _ makerNoteIFD) {
+	note.SerialNumber = dirString(dir, sonySerialNumber)
+	note.FocusMode = dirEnum(dir, sonyFocusMode, sonyFocusModes)
+	if note.FocusMode == "" {
+		note.FocusMode = dirEnum(dir, sonyFocusModeLegacy, sonyLegacyFocusModes)
+	}
+}
@@ -0,0 +1,140 @@
+package metadata
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+func longTag(id uint16, v uint32) testTag {
+	return testTag{id: id, typ: 4, count: 1, value: binary.LittleEndian.AppendUint32(nil, v)}
+}
+
+// encodeIFD encodes a single IFD in order, as if it started at position
+// start of the block its value offsets count from. Numeric values are
+// written as given, so big-endian notes should only use ASCII tags.
+func encodeIFD(order binary.AppendByteOrder, start int, tags []testTag) []byte {
+	dataPos := start + 2 + 12*len(tags) + 4
+	var out, data []byte
+	out = order.AppendUint16(out, uint16(len(tags)))
+	for _, t := range tags {
+		out = order.AppendUint16(out, t.id)
+		out = order.AppendUint16(out, t.typ)
+		out = order.AppendUint32(out, t.count)
+		if len(t.value) > 4 {
+			out = order.AppendUint32(out, uint32(dataPos+len(data)))
+			data = append(data, t.value...)
+		} else {
+			out = append(out, make([]byte, 4)...)
+			copy(out[len(out)-4:], t.value)
+		}
+	}
+	out = order.AppendUint32(out, 0)
+	return append(out, data...)
+}
+
+// buildMakerNoteJPEG wraps a MakerNote in the Exif IFD of a JPEG. The note
+// is built twice so it can know its own position in the TIFF block.
+func buildMakerNoteJPEG(t *testing.T, maker string, note func(offset int) []byte) []byte {
+	t.Helper()
+
+	build := func(body []byte) []byte {
+		return buildTIFF([]testIFD{
+			{
+				tags:     []testTag{asciiTag(tagMake, maker)},
+				pointers: map[uint16]int{tagExifIFDPointer: 1},
+			},
+			{tags: []testTag{undefinedTag(tagMakerNote, body)}},
+		})
+	}
+	placeholder := bytes.Repeat([]byte{0xA5}, len(note(0)))
+	offset := bytes.Index(build(placeholder), placeholder)
+	return buildJPEGWithSegments(t, exifSegment(build(note(offset))))
+}
+
+func TestExtractMetadataMakerNote(t *testing.T) {
+	canon := func(offset int) []byte {
+		settings := make([]byte, 2*8)
+		binary.LittleEndian.PutUint16(settings[2*canonFocusModeIndex:], 1)
+		return encodeIFD(binary.LittleEndian, offset, []testTag{
+			{id: canonCameraSettings, typ: 3, count: 8, value: settings},
+			asciiTag(canonFirmwareVersion, "Firmware Version 1.6.0"),
+			longTag(canonSerialNumber, 123456789),
+			asciiTag(canonLensModel, "RF24-105mm F4 L IS USM"),
+		})
+	}
+
+	nikon := func(int) []byte {
+		inner := []byte("II*\x00\x08\x00\x00\x00")
+		inner = append(inner, encodeIFD(binary.LittleEndian, 8, []testTag{
+			asciiTag(nikonFocusMode, "AF-C  "),
+			asciiTag(nikonSerialNumber, "3001234"),
+			{id: 0x0099, typ: 99, count: 1, value: []byte{1, 0, 0, 0}}, // unknown type, skipped
+			longTag(nikonShutterCount, 48213),
+		})...)
+		return append([]byte("Nikon\x00\x02\x11\x00\x00"), inner...)
+	}
+
+	apple := func(int) []byte {
+		note := append([]byte{}, appleHeader...)
+		note = append(note, 0, 1, 'M', 'M')
+		return append(note, encodeIFD(binary.BigEndian, appleIFDOffset, []testTag{
+			asciiTag(tagAppleContentIdentifier, "6C9B2A3E-5D1F-4F2B-9C44-1E0A7B3D2F10"),
+		})...)
+	}
+
+	tests := []struct {
+		name      string
+		maker     string
+		note      func(offset int) []byte
+		vendor    string
+		format    string
+		byteOrder string
+	}{
+		{"Canon", "Canon", canon, "Canon", "Canon", "Little-endian (Intel, II)"},
+		{"Nikon", "NIKON CORPORATION", nikon, "Nikon", "Nikon type 3", "Little-endian (Intel, II)"},
+		{"Apple", "Apple", apple, "Apple", "Apple iOS", "Big-endian (Motorola, MM)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			meta := ExtractMetadata(buildMakerNoteJPEG(t, tt.maker, tt.note), "image/jpeg", "note.jpg")
+			note := meta.MakerNote
+			if note == nil {
+				t.Fatal("expected makerNote")
+			}
+			if note.Vendor != tt.vendor || note.Format != tt.format || note.ByteOrder != tt.byteOrder {
+				t.Errorf("vendor = %q, format = %q, byte order = %q", note.Vendor, note.Format, note.ByteOrder)
+			}
+			if note.Size != len(tt.note(0)) || note.Offset == 0 || len(note.Tags) == 0 {
+				t.Errorf("offset = %d, size = %d, %d tags", note.Offset, note.Size, len(note.Tags))
+			}
+
+			switch tt.vendor {
+			case "Canon":
+				if note.SerialNumber != "0123456789" || note.FirmwareVersion != "1.6.0" ||
+					note.LensModel != "RF24-105mm F4 L IS USM" || note.FocusMode != "AI Servo AF" {
+					t.Errorf("Canon fields = %+v", note)
+				}
+			case "Nikon":
+				if note.SerialNumber != "3001234" || note.FocusMode != "AF-C" || note.ShutterCount != 48213 {
+					t.Errorf("Nikon fields = %+v", note)
+				}
+				if len(note.Tags) != 3 || note.Tags[2].Name != "ShutterCount" {
+					t.Errorf("Nikon tags = %+v", note.Tags)
+				}
+			case "Apple":
+				if note.ContentIdentifier != "6C9B2A3E-5D1F-4F2B-9C44-1E0A7B3D2F10" {
+					t.Errorf("ContentIdentifier = %q", note.ContentIdentifier)
+				}
+			}
+		})
+	}
+}
+
+func TestExtractMetadataMakerNoteUnknown(t *testing.T) {
+	data := buildMakerNoteJPEG(t, "Leica", func(int) []byte { return []byte("LEICA\x00\x00\x00\x01\x02") })
+	if meta := ExtractMetadata(data, "image/jpeg", "leica.jpg"); meta.MakerNote != nil {
+		t.Errorf("unexpected makerNote %+v", meta.MakerNote)
+	}
+}
@@ -0,0 +1,279 @@
+package metadata
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ahrdadan/image-metadata-viewer/src/internal/models"
+)
+
+// mwgFields are the properties the MWG guidelines and the IPTC photo
+// metadata mapping keep in step between EXIF, XMP and IPTC-IIM
+var mwgFields = []struct{ exif, xmp, iptc string }{
+	{"ImageDescription", "dc:description", "Caption-Abstract"},
+	{"Copyright", "dc:rights", "CopyrightNotice"},
+	{"Artist", "dc:creator", "By-line"},
+	{"Orientation", "tiff:Orientation", ""},
+	{"Rating", "xmp:Rating", ""},
+	{"", "dc:subject", "Keywords"},
+	{"", "dc:title", "ObjectName"},
+	{"", "photoshop:Headline", "Headline"},
+	{"", "photoshop:Credit", "Credit"},
+	{"", "photoshop:Source", "Source"},
+	{"", "photoshop:AuthorsPosition", "By-lineTitle"},
+	{"", "photoshop:CaptionWriter", "Writer-Editor"},
+	{"", "photoshop:Instructions", "SpecialInstructions"},
+	{"", "photoshop:TransmissionReference", "OriginalTransmissionReference"},
+	{"", "photoshop:Category", "Category"},
+	{"", "photoshop:SupplementalCategories", "SupplementalCategories"},
+	{"", "photoshop:Urgency", "Urgency"},
+	{"", "photoshop:City", "City"},
+	{"", "photoshop:State", "Province-State"},
+	{"", "photoshop:Country", "Country-PrimaryLocationName"},
+	{"", "Iptc4xmpCore:CountryCode", "Country-PrimaryLocationCode"},
+	{"", "Iptc4xmpCore:Location", "Sub-location"},
+}
+
+// mwgDates are the dates kept in step, with the EXIF tags that hold the
+// fraction of a second and the offset and the IPTC time dataset
+var mwgDates = []struct{ exif, subsec, offset, xmp, iptcDate, iptcTime string }{
+	{"DateTimeOriginal", "SubSecTimeOriginal", "OffsetTimeOriginal", "photoshop:DateCreated", "DateCreated", "TimeCreated"},
+	{"DateTimeDigitized", "SubSecTimeDigitized", "OffsetTimeDigitized", "xmp:CreateDate", "DigitalCreationDate", "DigitalCreationTime"},
+	{"DateTime", "SubSecTime", "OffsetTime", "xmp:ModifyDate", "", ""},
+}
+
+// mwgSeparator joins list values stored in a single EXIF or IIM field
+const mwgSeparator = "; "
+
+// syncMWG completes a patch so that a value set or removed in one of
+// EXIF, XMP and IPTC-IIM is written to the others as well. Values the
+// patch gives for more than one are written as given; XMP is preferred
+// as the source, then EXIF. IPTC-IIM is only written when iptc is set.
+func syncMWG(patch EditPatch, meta *models.ImageMetadata, iptc bool) EditPatch {
+	out := EditPatch{EXIF: cloneValues(patch.EXIF), XMP: cloneValues(patch.XMP), IPTC: cloneValues(patch.IPTC)}
+
+	for _, f := range mwgFields {
+		var value EditValue
+		fromEXIF := false
+		switch {
+		case has(patch.XMP, f.xmp):
+			value = patch.XMP[f.xmp]
+		case f.exif != "" && has(patch.EXIF, f.exif):
+			value, fromEXIF = patch.EXIF[f.exif], true
+		case f.iptc != "" && has(patch.IPTC, f.iptc):
+			value = patch.IPTC[f.iptc]
+		default:
+			continue
+		}
+
+		list := value
+		if fromEXIF && value != nil && xmpForms[f.xmp] != xmpSimple && xmpForms[f.xmp] != xmpAlt {
+			list = splitList(value[0])
+		}
+		if f.exif != "" && !has(patch.EXIF, f.exif) {
+			out.EXIF[f.exif] = joinList(value)
+		}
+		if !has(patch.XMP, f.xmp) {
+			out.XMP[f.xmp] = list
+		}
+		if iptc && f.iptc != "" && !has(patch.IPTC, f.iptc) {
+			if key, ok := iptcKeyByName(f.iptc); ok && iptcRepeatable[key] {
+				out.IPTC[f.iptc] = list
+			} else {
+				out.IPTC[f.iptc] = joinList(value)
+			}
+		}
+	}
+
+	for _, d := range mwgDates {
+		ts, given, fromEXIF := mwgDate(patch, meta, d.exif, d.subsec, d.offset, d.xmp, d.iptcDate, d.iptcTime)
+		if !given {
+			continue
+		}
+		remove := ts == nil
+
+		if !fromEXIF && !has(patch.EXIF, d.exif) && (remove || ts.hasTime) {
+			if remove {
+				out.EXIF[d.exif] = nil
+			} else {
+				out.EXIF[d.exif] = EditValue{ts.wallClock().Format("2006:01:02 15:04:05")}
+			}
+			if !has(patch.EXIF, d.subsec) && (remove || has(currentEXIF(meta, d.subsec), d.subsec) || ts.time.Nanosecond() > 0) {
+				out.EXIF[d.subsec] = subSecond(ts)
+			}
+			if !has(patch.EXIF, d.offset) && (remove || ts.hasZone) {
+				out.EXIF[d.offset] = zoneOffset(ts, "-07:00")
+			}
+		}
+		if !has(patch.XMP, d.xmp) {
+			if remove {
+				out.XMP[d.xmp] = nil
+			} else {
+				out.XMP[d.xmp] = EditValue{xmpDate(*ts)}
+			}
+		}
+		if iptc && d.iptcDate != "" && !has(patch.IPTC, d.iptcDate) && !has(patch.IPTC, d.iptcTime) {
+			if remove {
+				out.IPTC[d.iptcDate], out.IPTC[d.iptcTime] = nil, nil
+				continue
+			}
+			out.IPTC[d.iptcDate] = EditValue{ts.time.Format("20060102")}
+			out.IPTC[d.iptcTime] = nil
+			if ts.hasTime {
+				out.IPTC[d.iptcTime] = EditValue{ts.time.Format("150405") + zoneOffset(ts, "-0700").first()}
+			}
+		}
+	}
+	return out
+}
+
+// mwgDate reads the date a patch sets, from XMP, EXIF or IPTC-IIM in that
+// order, completing EXIF and IPTC dates with the fraction, offset or time
+// the patch or the file has. It returns nil for a removed date and
+// given=false when the patch does not set the date or it does not parse.
+func mwgDate(patch EditPatch, meta *models.ImageMetadata, exifDate, subsec, offset, xmp, iptcDate, iptcTime string) (ts *timestamp, given, fromEXIF bool) {
+	var raw string
+	switch {
+	case has(patch.XMP, xmp):
+		if patch.XMP[xmp] == nil {
+			return nil, true, false
+		}
+		raw = patch.XMP[xmp][0]
+	case has(patch.EXIF, exifDate):
+		if patch.EXIF[exifDate] == nil {
+			return nil, true, true
+		}
+		t, ok := parseTimestamp(patch.EXIF[exifDate][0])
+		if !ok {
+			return nil, false, true
+		}
+		current := currentEXIF(meta, subsec, offset)
+		if digits := patchOr(patch.EXIF, current, subsec).first(); isDigits(digits) && len(digits) <= 9 {
+			frac, _ := time.ParseDuration("0." + digits + "s")
+			t.time = t.time.Add(frac)
+		}
+		t = t.withOffset(patchOr(patch.EXIF, current, offset).first())
+		return &t, true, true
+	case iptcDate != "" && has(patch.IPTC, iptcDate):
+		if patch.IPTC[iptcDate] == nil {
+			return nil, true, false
+		}
+		raw = patch.IPTC[iptcDate][0]
+		if clock := patchOr(patch.IPTC, currentIPTC(meta, iptcTime), iptcTime).first(); clock != "" {
+			raw += "T" + clock
+		}
+	default:
+		return nil, false, false
+	}
+
+	t, ok := parseTimestamp(raw)
+	if !ok {
+		return nil, false, false
+	}
+	return &t, true, false
+}
+
+// subSecond writes the fraction of a second of ts as EXIF SubSecTime
+// digits, or removes the tag for a whole second
+func subSecond(ts *timestamp) EditValue {
+	if ts == nil || ts.time.Nanosecond() == 0 {
+		return nil
+	}
+	return EditValue{strings.TrimRight(fmt.Sprintf("%09d", ts.time.Nanosecond()), "0")}
+}
+
+// zoneOffset writes the offset of ts in layout, or nothing when it has none
+func zoneOffset(ts *timestamp, layout string) EditValue {
+	if ts == nil || !ts.hasZone {
+		return nil
+	}
+	return EditValue{ts.time.Format(layout)}
+}
+
+// xmpDate writes ts in the ISO 8601 form XMP uses, with as much precision
+// as it has
+func xmpDate(ts timestamp) string {
+	switch {
+	case !ts.hasTime:
+		return ts.time.Format("2006-01-02")
+	case !ts.hasZone:
+		return ts.time.Format("2006-01-02T15:04:05.999999999")
+	}
+	return ts.time.Format("2006-01-02T15:04:05.999999999Z07:00")
+}
+
+// currentEXIF returns the raw values of the named tags of the file
+func currentEXIF(meta *models.ImageMetadata, names ...string) map[string]EditValue {
+	out := make(map[string]EditValue)
+	if meta.EXIF == nil {
+		return out
+	}
+	for _, tag := range meta.EXIF.Tags {
+		for _, name := range names {
+			if _, seen := out[name]; tag.Name == name && !seen && tag.IFD != ifd1Name {
+				out[name] = EditValue{tag.RawValue}
+			}
+		}
+	}
+	return out
+}
+
+// currentIPTC returns the values of the named datasets of the file
+func currentIPTC(meta *models.ImageMetadata, names ...string) map[string]EditValue {
+	out := make(map[string]EditValue)
+	if meta.IPTC == nil {
+		return out
+	}
+	for _, ds := range meta.IPTC.Datasets {
+		for _, name := range names {
+			if ds.Name == name {
+				out[name] = append(out[name], ds.Value)
+			}
+		}
+	}
+	return out
+}
+
+// patchOr returns the value a patch gives for name, or else the file's
+func patchOr(patch, current map[string]EditValue, name string) EditValue {
+	if v, ok := patch[name]; ok {
+		return v
+	}
+	return current[name]
+}
+
+// has reports whether values has an entry for name, including a removal
+func has(values map[string]EditValue, name string) bool {
+	_, ok := values[name]
+	return ok
+}
+
+// cloneValues copies a patch section, so entries can be added to it
+func cloneValues(values map[string]EditValue) map[string]EditValue {
+	out := make(map[string]EditValue, len(values))
+	for k, v := range values {
+		out[k] = v
+	}
+	return out
+}
+
+// joinList writes several values to a field that holds one
+func joinList(v EditValue) EditValue {
+	if len(v) <= 1 {
+		return v
+	}
+	return EditValue{strings.Join(v, mwgSeparator)}
+}
+
+// splitList reads the values of a field that joins several, such as EXIF
+// Artist
+func splitList(s string) EditValue {
+	var out EditValue
+	for _, part := range strings.Split(s, ";") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
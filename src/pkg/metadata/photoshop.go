@@ -0,0 +1,150 @@
+package metadata
+
+import (
+	"bytes"
+	"encoding/binary"
+
+	"github.com/ahrdadan/image-metadata-viewer/src/internal/models"
+)
+
+// photoshopJPEGSignature prefixes Photoshop image resources in APP13
+var photoshopJPEGSignature = []byte("Photoshop 3.0\x00")
+
+// Photoshop image resource IDs
+const (
+	psResourceIPTC        = 0x0404
+	psResourceJPEGQuality = 0x0406
+	psResourceICCProfile  = 0x040F
+	psResourceEXIF        = 0x0422
+	psResourceXMP         = 0x0424
+	psResourceIPTCDigest  = 0x0425
+)
+
+// TIFF tags that carry IPTC or Photoshop resources
+const (
+	tagIPTCNAA   = 0x83BB
+	tagPhotoshop = 0x8649
+)
+
+// psResource is a single Photoshop image resource block
+type psResource struct {
+	id   uint16
+	name string
+	data []byte
+	raw  []byte // the whole block, as stored
+}
+
+// readPhotoshopResources walks the image resource blocks of a Photoshop
+// resource section. Each block is a 4-byte signature, a 2-byte ID, a
+// padded Pascal name and a padded, length-prefixed payload.
+func readPhotoshopResources(data []byte) []psResource {
+	var resources []psResource
+	pos := 0
+	for pos+12 <= len(data) {
+		switch string(data[pos : pos+4]) {
+		case "8BIM", "MeSa", "PHUT", "AgHg", "DCSR":
+		default:
+			return resources
+		}
+
+		start := pos
+		res := psResource{id: binary.BigEndian.Uint16(data[pos+4:])}
+		pos += 6
+
+		// Pascal string padded to an even total length
+		nameLen := int(data[pos])
+		nameEnd := pos + 1 + nameLen
+		if nameEnd > len(data) {
+			return resources
+		}
+		res.name = string(data[pos+1 : nameEnd])
+		pos += (1 + nameLen + 1) &^ 1
+
+		if pos+4 > len(data) {
+			return resources
+		}
+		size := int(binary.BigEndian.Uint32(data[pos:]))
+		pos += 4
+		if size < 0 || pos+size > len(data) {
+			return resources
+		}
+		res.data = data[pos : pos+size]
+
+		// Payloads are padded to an even size
+		pos += (size + 1) &^ 1
+		res.raw = data[start:min(pos, len(data))]
+		resources = append(resources, res)
+	}
+	return resources
+}
+
+// findPhotoshopResources returns the Photoshop resource section and any
+// standalone IPTC-IIM block found in the container. PSD files hold the
+// section directly after the header.
+func findPhotoshopResources(data []byte) (resources []byte, iptc []byte) {
+	switch sniffFormat(data) {
+	case formatJPEG:
+		// A large resource section may be split across several APP13
+		// segments, each repeating the signature
+		var buf []byte
+		for _, seg := range readJPEGSegments(data) {
+			if seg.marker == markerAPP13 && bytes.HasPrefix(seg.data, photoshopJPEGSignature) {
+				buf = append(buf, seg.data[len(photoshopJPEGSignature):]...)
+			}
+		}
+		return buf, nil
+	case formatTIFF:
+		t, err := decodeTIFF(tiffPayload(data))
+		if err != nil || len(t.Dirs) == 0 {
+			return nil, nil
+		}
+		if tag := findTag(t.Dirs[0], tagPhotoshop); tag != nil {
+			resources = tag.Val
+		}
+		if tag := findTag(t.Dirs[0], tagIPTCNAA); tag != nil {
+			iptc = tag.Val
+		}
+		return resources, iptc
+	case formatPSD:
+		if file := readPSD(data); file != nil {
+			return file.resources, nil
+		}
+	}
+	return nil, nil
+}
+
+// photoshopQuality decodes the JPEG quality resource (0x0406). Photoshop
+// stores the 0-12 quality slider offset by -4.
+func photoshopQuality(data []byte) (int, bool) {
+	if len(data) < 2 {
+		return 0, false
+	}
+	quality := int(int16(binary.BigEndian.Uint16(data))) + 4
+	if quality < 0 || quality > 12 {
+		return 0, false
+	}
+	return quality, true
+}
+
+// extractPhotoshop reads IPTC-IIM and the JPEG quality setting from
+// Photoshop image resources
+func extractPhotoshop(data []byte, meta *models.ImageMetadata) {
+	section, iptc := findPhotoshopResources(data)
+
+	for _, res := range readPhotoshopResources(section) {
+		switch res.id {
+		case psResourceIPTC:
+			if iptc == nil {
+				iptc = res.data
+			}
+		case psResourceJPEGQuality:
+			if quality, ok := photoshopQuality(res.data); ok {
+				meta.PhotoshopQuality = quality
+			}
+		}
+	}
+
+	if len(iptc) > 0 {
+		meta.IPTC = parseIPTC(iptc)
+	}
+}
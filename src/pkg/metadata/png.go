@@ -0,0 +1,332 @@
+package metadata
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/ahrdadan/image-metadata-viewer/src/internal/models"
+)
+
+// maxInflatedBytes caps decompression of zlib-compressed data, per file
+// for PNG chunks
+const maxInflatedBytes = 16 << 20
+
+// maxPNGTextValue caps the length of a text chunk value kept in PNGInfo
+const maxPNGTextValue = 1 << 20
+
+// pngChunk is a single chunk from a PNG stream
+type pngChunk struct {
+	typ      string
+	offset   int // offset of the length field
+	data     []byte
+	crc      uint32
+	crcValid bool
+}
+
+// readPNGChunks walks every chunk after the PNG signature up to IEND
+func readPNGChunks(data []byte) []pngChunk {
+	if !bytes.HasPrefix(data, pngSignature) {
+		return nil
+	}
+
+	var chunks []pngChunk
+	pos := len(pngSignature)
+	for pos+12 <= len(data) {
+		length := int(binary.BigEndian.Uint32(data[pos:]))
+		end := pos + 12 + length
+		if length < 0 || end > len(data) {
+			break
+		}
+
+		typ := data[pos+4 : pos+8]
+		body := data[pos+8 : pos+8+length]
+		crc := binary.BigEndian.Uint32(data[pos+8+length:])
+
+		chunks = append(chunks, pngChunk{
+			typ:      string(typ),
+			offset:   pos,
+			data:     body,
+			crc:      crc,
+			crcValid: crc32.ChecksumIEEE(data[pos+4:pos+8+length]) == crc,
+		})
+
+		if string(typ) == "IEND" {
+			break
+		}
+		pos = end
+	}
+
+	return chunks
+}
+
+// pngExifTIFF returns the TIFF structure of the eXIf chunk, or nil
+func pngExifTIFF(data []byte) []byte {
+	for _, chunk := range readPNGChunks(data) {
+		if chunk.typ == "eXIf" {
+			return chunk.data
+		}
+	}
+	return nil
+}
+
+// pngITXt is a decoded international text chunk
+type pngITXt struct {
+	keyword           string
+	languageTag       string
+	translatedKeyword string
+	text              string
+}
+
+// parsePNGITXt decodes an iTXt chunk body, inflating it when compressed
+func parsePNGITXt(body []byte, budget *inflateBudget) (pngITXt, bool) {
+	var out pngITXt
+
+	keyword, rest, ok := bytes.Cut(body, []byte{0})
+	if !ok || len(rest) < 2 {
+		return out, false
+	}
+	out.keyword = string(keyword)
+	compressed := rest[0] == 1
+	rest = rest[2:]
+
+	lang, rest, ok := bytes.Cut(rest, []byte{0})
+	if !ok {
+		return out, false
+	}
+	translated, text, ok := bytes.Cut(rest, []byte{0})
+	if !ok {
+		return out, false
+	}
+	out.languageTag = string(lang)
+	out.translatedKeyword = string(translated)
+
+	if compressed {
+		inflated, err := budget.inflate(text)
+		if err != nil {
+			return out, false
+		}
+		text = inflated
+	}
+	out.text = string(text)
+
+	return out, true
+}
+
+// inflateBudget is how many bytes zlib inflation may still produce for
+// one file. The zTXt, iTXt and iCCP chunks of a PNG share it, so many
+// compressed chunks cannot add up to more than maxInflatedBytes.
+type inflateBudget struct {
+	left int64
+}
+
+// newInflateBudget returns a budget of maxInflatedBytes
+func newInflateBudget() *inflateBudget {
+	return &inflateBudget{left: maxInflatedBytes}
+}
+
+// inflate decompresses zlib data, stopping where the budget runs out
+func (b *inflateBudget) inflate(data []byte) ([]byte, error) {
+	if b.left <= 0 {
+		return nil, errors.New("zlib: inflation limit for the file reached")
+	}
+	r, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	out, err := io.ReadAll(io.LimitReader(r, b.left))
+	b.left -= int64(len(out))
+	return out, err
+}
+
+// truncateText shortens s to at most max bytes, cutting at a character
+// boundary
+func truncateText(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	for max > 0 && !utf8.RuneStart(s[max]) {
+		max--
+	}
+	return s[:max] + "…"
+}
+
+// pngColorTypes maps IHDR color types to names
+var pngColorTypes = map[int]string{
+	0: "Grayscale",
+	2: "RGB",
+	3: "Indexed",
+	4: "Grayscale with alpha",
+	6: "RGB with alpha",
+}
+
+// analyzePNG decodes the header, text, color and animation chunks
+func analyzePNG(chunks []pngChunk, budget *inflateBudget) *models.PNGInfo {
+	if len(chunks) == 0 || chunks[0].typ != "IHDR" || len(chunks[0].data) < 13 {
+		return nil
+	}
+
+	ihdr := chunks[0].data
+	info := &models.PNGInfo{
+		BitDepth:  int(ihdr[8]),
+		ColorType: lookupName(pngColorTypes, int(ihdr[9])),
+		Interlace: "None",
+	}
+	if ihdr[12] == 1 {
+		info.Interlace = "Adam7"
+	}
+
+	sawIDAT := false
+	for _, chunk := range chunks {
+		info.Chunks = append(info.Chunks, models.PNGChunk{
+			Type:     chunk.typ,
+			Offset:   chunk.offset,
+			Length:   len(chunk.data),
+			CRC:      fmt.Sprintf("%08X", chunk.crc),
+			CRCValid: chunk.crcValid,
+		})
+		body := chunk.data
+
+		switch chunk.typ {
+		case "IDAT":
+			sawIDAT = true
+
+		case "tEXt":
+			if keyword, text, ok := bytes.Cut(body, []byte{0}); ok {
+				info.Text = append(info.Text, models.PNGText{
+					Chunk:   chunk.typ,
+					Keyword: decodeLatin1(keyword),
+					Value:   truncateText(decodeLatin1(text), maxPNGTextValue),
+				})
+			}
+
+		case "zTXt":
+			keyword, rest, ok := bytes.Cut(body, []byte{0})
+			if !ok || len(rest) < 1 || rest[0] != 0 {
+				continue
+			}
+			if text, err := budget.inflate(rest[1:]); err == nil {
+				info.Text = append(info.Text, models.PNGText{
+					Chunk:   chunk.typ,
+					Keyword: decodeLatin1(keyword),
+					Value:   truncateText(decodeLatin1(text), maxPNGTextValue),
+				})
+			}
+
+		case "iTXt":
+			if itxt, ok := parsePNGITXt(body, budget); ok {
+				info.Text = append(info.Text, models.PNGText{
+					Chunk:             chunk.typ,
+					Keyword:           itxt.keyword,
+					Value:             truncateText(itxt.text, maxPNGTextValue),
+					Language:          itxt.languageTag,
+					TranslatedKeyword: itxt.translatedKeyword,
+				})
+			}
+
+		case "pHYs":
+			if len(body) >= 9 {
+				info.PixelsPerUnitX = int(binary.BigEndian.Uint32(body))
+				info.PixelsPerUnitY = int(binary.BigEndian.Uint32(body[4:]))
+				info.PixelUnit = "unknown"
+				if body[8] == 1 {
+					info.PixelUnit = "meter"
+				}
+			}
+
+		case "gAMA":
+			if len(body) >= 4 {
+				info.Gamma = float64(binary.BigEndian.Uint32(body)) / 100000
+			}
+
+		case "cHRM":
+			if len(body) >= 32 {
+				v := func(i int) float64 { return float64(binary.BigEndian.Uint32(body[4*i:])) / 100000 }
+				info.Chromaticities = &models.PNGChromaticities{
+					WhiteX: v(0), WhiteY: v(1),
+					RedX: v(2), RedY: v(3),
+					GreenX: v(4), GreenY: v(5),
+					BlueX: v(6), BlueY: v(7),
+				}
+			}
+
+		case "sRGB":
+			if len(body) >= 1 {
+				info.SRGBIntent = lookupName(iccRenderingIntents, int(body[0]))
+			}
+
+		case "iCCP":
+			if name, _, ok := bytes.Cut(body, []byte{0}); ok {
+				info.ICCProfileName = decodeLatin1(name)
+			}
+
+		case "tIME":
+			if len(body) >= 7 {
+				info.LastModified = time.Date(
+					int(binary.BigEndian.Uint16(body)), time.Month(body[2]), int(body[3]),
+					int(body[4]), int(body[5]), int(body[6]), 0, time.UTC,
+				).Format(time.RFC3339)
+			}
+
+		case "acTL":
+			if len(body) >= 8 {
+				if info.Animation == nil {
+					info.Animation = &models.PNGAnimation{}
+				}
+				info.Animation.Frames = int(binary.BigEndian.Uint32(body))
+				info.Animation.Plays = int(binary.BigEndian.Uint32(body[4:]))
+			}
+
+		case "fcTL":
+			if info.Animation == nil {
+				info.Animation = &models.PNGAnimation{}
+			}
+			// A frame control before the first IDAT makes the default
+			// image the first animation frame
+			if info.Animation.FrameControls == 0 {
+				info.Animation.DefaultImageIsFrame = !sawIDAT
+			}
+			info.Animation.FrameControls++
+		}
+	}
+
+	return info
+}
+
+// decodeLatin1 converts ISO-8859-1 bytes to a UTF-8 string
+func decodeLatin1(b []byte) string {
+	var s strings.Builder
+	s.Grow(len(b))
+	for _, c := range b {
+		s.WriteRune(rune(c))
+	}
+	return s.String()
+}
+
+// extractPNG fills PNG chunk details and pHYs resolution for PNG files
+func extractPNG(data []byte, result *Result) error {
+	meta := result.Metadata
+	info := analyzePNG(readPNGChunks(data), result.budget())
+	if info == nil {
+		return nil
+	}
+
+	meta.PNG = info
+	meta.BitsPerSample = fmt.Sprintf("%d", info.BitDepth)
+	if info.PixelUnit == "meter" {
+		// Pixels per meter to dots per inch
+		meta.XResolution = int(math.Round(float64(info.PixelsPerUnitX) * 0.0254))
+		meta.YResolution = int(math.Round(float64(info.PixelsPerUnitY) * 0.0254))
+		meta.ResolutionUnit = "inches"
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package metadata
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"strings"
+	"testing"
+)
+
+func TestExtractMetadataPNGChunks(t *testing.T) {
+	var z bytes.Buffer
+	zw := zlib.NewWriter(&z)
+	zw.Write([]byte("A cat in a hat"))
+	zw.Close()
+
+	phys := binary.BigEndian.AppendUint32(nil, 2835)
+	phys = binary.BigEndian.AppendUint32(phys, 2835)
+	phys = append(phys, 1)
+
+	actl := binary.BigEndian.AppendUint32(nil, 3)
+	actl = binary.BigEndian.AppendUint32(actl, 0)
+
+	broken := buildChunk("tEXt", []byte("Comment\x00bad crc"))
+	broken[len(broken)-1] ^= 0xFF
+
+	data := buildPNGWithChunks(t,
+		buildChunk("tEXt", []byte("parameters\x00masterpiece, Steps: 20")),
+		buildChunk("zTXt", append([]byte("Description\x00\x00"), z.Bytes()...)),
+		buildChunk("pHYs", phys),
+		buildChunk("gAMA", binary.BigEndian.AppendUint32(nil, 45455)),
+		buildChunk("sRGB", []byte{0}),
+		buildChunk("acTL", actl),
+		buildChunk("fcTL", make([]byte, 26)),
+		broken,
+	)
+	meta := ExtractMetadata(data, "image/png", "chunks.png")
+
+	info := meta.PNG
+	if info == nil {
+		t.Fatal("expected PNG section")
+	}
+	if info.BitDepth != 8 || info.ColorType != "Grayscale" || info.Interlace != "None" {
+		t.Errorf("IHDR = %d %q %q", info.BitDepth, info.ColorType, info.Interlace)
+	}
+	if len(info.Text) != 3 || info.Text[0].Keyword != "parameters" ||
+		info.Text[0].Value != "masterpiece, Steps: 20" || info.Text[1].Value != "A cat in a hat" {
+		t.Errorf("Text = %+v", info.Text)
+	}
+	if meta.XResolution != 72 || meta.YResolution != 72 || meta.ResolutionUnit != "inches" {
+		t.Errorf("resolution = %d×%d %s", meta.XResolution, meta.YResolution, meta.ResolutionUnit)
+	}
+	if info.Gamma != 0.45455 || info.SRGBIntent != "Perceptual" {
+		t.Errorf("Gamma = %v, SRGBIntent = %q", info.Gamma, info.SRGBIntent)
+	}
+	if a := info.Animation; a == nil || a.Frames != 3 || a.Plays != 0 || !a.DefaultImageIsFrame {
+		t.Errorf("Animation = %+v", info.Animation)
+	}
+
+	var sawBroken bool
+	for _, chunk := range info.Chunks {
+		if !chunk.CRCValid {
+			sawBroken = true
+			if chunk.Type != "tEXt" {
+				t.Errorf("unexpected invalid CRC on %s", chunk.Type)
+			}
+		}
+	}
+	if !sawBroken {
+		t.Error("expected one chunk with an invalid CRC")
+	}
+}
+
+func TestPNGInflationBudget(t *testing.T) {
+	var z bytes.Buffer
+	zw := zlib.NewWriter(&z)
+	zw.Write(bytes.Repeat([]byte{0xE9}, 6<<20))
+	zw.Close()
+
+	var chunks [][]byte
+	for i := 0; i < 5; i++ {
+		chunks = append(chunks, buildChunk("zTXt", append([]byte("Comment\x00\x00"), z.Bytes()...)))
+	}
+	meta := ExtractMetadata(buildPNGWithChunks(t, chunks...), "image/png", "bomb.png")
+
+	// 16 MB of budget inflates two chunks and part of a third
+	if len(meta.PNG.Text) != 3 {
+		t.Fatalf("got %d text values, want 3", len(meta.PNG.Text))
+	}
+	for _, text := range meta.PNG.Text {
+		if want := strings.Repeat("é", maxPNGTextValue/2) + "…"; text.Value != want {
+			t.Errorf("value of %d bytes was not truncated to %d", len(text.Value), len(want))
+		}
+	}
+}
@@ -0,0 +1,318 @@
+package metadata
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/ahrdadan/image-metadata-viewer/src/internal/models"
+)
+
+// Risk levels of a privacy report
+const (
+	riskNone   = "none"
+	riskLow    = "low"
+	riskMedium = "medium"
+	riskHigh   = "high"
+)
+
+// privacyWeights is what a finding of each severity adds to the risk
+// score, which is capped at 100
+var privacyWeights = map[string]int{severityHigh: 40, severityMedium: 15, severityLow: 5}
+
+// privacyChecks run in order; each reports its findings
+var privacyChecks = []func(*models.ImageMetadata) []models.PrivacyFinding{
+	checkGPS,
+	checkPlaceNames,
+	checkSerialNumbers,
+	checkPeople,
+	checkWindowsAuthor,
+	checkDeviceIDs,
+	checkFilePaths,
+	checkDocumentIDs,
+	checkFaceRegions,
+	checkThumbnails,
+}
+
+// localPathPattern finds absolute file paths on a local disk or share
+var localPathPattern = regexp.MustCompile(`(?i)(?:\b[a-z]:\\|\\\\[\w.$-]+\\|file:/|(?:^|[\s"'=(])/(?:Users|home|Volumes|mnt|media)/)[^"'<>;]*`)
+
+// pathUserPattern takes the account name from a home directory path
+var pathUserPattern = regexp.MustCompile(`(?i)[\\/](?:Users|home|Documents and Settings)[\\/]([^\\/]+)`)
+
+// AuditPrivacy lists the metadata that could identify the person who made
+// an image, the people in it, where it was taken or the device that took
+// it. Like CheckConsistency it reads the metadata once extraction is
+// complete.
+func AuditPrivacy(meta *models.ImageMetadata) *models.PrivacyReport {
+	r := &models.PrivacyReport{FileName: meta.FileName, Risk: riskNone, Findings: []models.PrivacyFinding{}}
+	if meta.DecodeError != "" || meta.FetchError != "" {
+		return r
+	}
+	for _, check := range privacyChecks {
+		r.Findings = append(r.Findings, check(meta)...)
+	}
+
+	rank := map[string]int{severityHigh: 0, severityMedium: 1, severityLow: 2}
+	sort.SliceStable(r.Findings, func(i, j int) bool {
+		return rank[r.Findings[i].Severity] < rank[r.Findings[j].Severity]
+	})
+	for _, f := range r.Findings {
+		r.Score += privacyWeights[f.Severity]
+	}
+	r.Score = min(r.Score, 100)
+	switch {
+	case r.Score >= privacyWeights[severityHigh]:
+		r.Risk = riskHigh
+	case r.Score >= privacyWeights[severityMedium]:
+		r.Risk = riskMedium
+	case r.Score > 0:
+		r.Risk = riskLow
+	}
+	return r
+}
+
+// privacyFinding reports the values, or nothing when there are none
+func privacyFinding(category, severity, message string, evidence []models.SourcedValue) []models.PrivacyFinding {
+	if len(evidence) == 0 {
+		return nil
+	}
+	return []models.PrivacyFinding{{Category: category, Severity: severity, Message: message, Evidence: evidence}}
+}
+
+// exifValues returns the non-empty EXIF tags accepted by match
+func exifValues(meta *models.ImageMetadata, match func(name string) bool) []models.SourcedValue {
+	if meta.EXIF == nil {
+		return nil
+	}
+	var values []models.SourcedValue
+	for _, tag := range meta.EXIF.Tags {
+		if match(tag.Name) && strings.TrimSpace(tag.Value) != "" {
+			values = append(values, models.SourcedValue{Source: "EXIF " + tag.Name, Raw: tag.RawValue, Display: tag.Value})
+		}
+	}
+	return values
+}
+
+// makerNoteValues returns the non-empty MakerNote tags accepted by match
+func makerNoteValues(meta *models.ImageMetadata, match func(name string) bool) []models.SourcedValue {
+	if meta.MakerNote == nil {
+		return nil
+	}
+	var values []models.SourcedValue
+	for _, tag := range meta.MakerNote.Tags {
+		if match(tag.Name) && strings.TrimSpace(tag.Value) != "" {
+			values = append(values, models.SourcedValue{
+				Source:  meta.MakerNote.Vendor + " MakerNote " + tag.Name,
+				Raw:     tag.RawValue,
+				Display: tag.Value,
+			})
+		}
+	}
+	return values
+}
+
+// xmpValues returns the non-empty XMP properties whose path is accepted
+// by match
+func xmpValues(meta *models.ImageMetadata, match func(path string) bool) []models.SourcedValue {
+	if meta.XMP == nil {
+		return nil
+	}
+	var values []models.SourcedValue
+	for _, p := range meta.XMP.Properties {
+		if match(p.Path) && strings.TrimSpace(p.Value) != "" {
+			values = append(values, models.SourcedValue{Source: "XMP " + p.Path, Raw: p.Value, Display: p.Value})
+		}
+	}
+	return values
+}
+
+// iptcValues returns the non-empty IPTC datasets with the given names
+func iptcValues(meta *models.ImageMetadata, names ...string) []models.SourcedValue {
+	if meta.IPTC == nil {
+		return nil
+	}
+	var values []models.SourcedValue
+	for _, ds := range meta.IPTC.Datasets {
+		for _, name := range names {
+			if ds.Name == name && strings.TrimSpace(ds.Value) != "" {
+				values = append(values, models.SourcedValue{Source: "IPTC " + ds.Name, Raw: ds.Value, Display: ds.Value})
+			}
+		}
+	}
+	return values
+}
+
+// named matches any of names
+func named(names ...string) func(string) bool {
+	return func(name string) bool { return slices.Contains(names, name) }
+}
+
+// checkGPS reports the recorded position
+func checkGPS(meta *models.ImageMetadata) []models.PrivacyFinding {
+	evidence := exifValues(meta, named("GPSLatitude", "GPSLongitude", "GPSAltitude"))
+	evidence = append(evidence, xmpValues(meta, named("exif:GPSLatitude", "exif:GPSLongitude", "exif:GPSAltitude"))...)
+	message := "GPS coordinates pinpoint where the image was taken"
+	loc := meta.Location
+	switch {
+	case loc != nil && loc.Latitude == 0 && loc.Longitude == 0:
+		// Cameras without a fix write zeros; the tags give nothing away
+		return privacyFinding("gps", severityLow, "GPS tags are present but hold 0, 0, the placeholder written without a fix", evidence)
+	case loc != nil:
+		message = fmt.Sprintf("GPS coordinates %s pinpoint where the image was taken", loc.Coordinates)
+	}
+	return privacyFinding("gps", severityHigh, message, evidence)
+}
+
+// checkPlaceNames reports the named location fields
+func checkPlaceNames(meta *models.ImageMetadata) []models.PrivacyFinding {
+	evidence := iptcValues(meta, "Sub-location", "City", "Province-State", "Country-PrimaryLocationName")
+	evidence = append(evidence, xmpValues(meta, func(path string) bool {
+		return path == "photoshop:City" || path == "photoshop:State" || path == "Iptc4xmpCore:Location" ||
+			strings.HasPrefix(path, "Iptc4xmpExt:LocationCreated") || strings.HasPrefix(path, "Iptc4xmpExt:LocationShown")
+	})...)
+	return privacyFinding("place-name", severityLow, "Place names say where the image was taken", evidence)
+}
+
+// checkSerialNumbers reports camera, lens and accessory serial numbers
+func checkSerialNumbers(meta *models.ImageMetadata) []models.PrivacyFinding {
+	serial := func(name string) bool { return strings.HasSuffix(name, "SerialNumber") }
+	evidence := exifValues(meta, serial)
+	evidence = append(evidence, makerNoteValues(meta, serial)...)
+	evidence = append(evidence, xmpValues(meta, serial)...)
+	return privacyFinding("serial-number", severityMedium,
+		"Serial numbers tie the image to one camera or lens, and to every other image it made", evidence)
+}
+
+// checkPeople reports the names and contact details of the photographer,
+// owner and writers
+func checkPeople(meta *models.ImageMetadata) []models.PrivacyFinding {
+	evidence := exifValues(meta, named("Artist", "CameraOwnerName"))
+	evidence = append(evidence, makerNoteValues(meta, named("OwnerName"))...)
+	evidence = append(evidence, xmpValues(meta, func(path string) bool {
+		name, _, _ := strings.Cut(path, "[")
+		return name == "dc:creator" || name == "aux:OwnerName" || name == "exifEX:CameraOwnerName" ||
+			name == "photoshop:CaptionWriter" || strings.HasPrefix(path, "Iptc4xmpCore:CreatorContactInfo/")
+	})...)
+	evidence = append(evidence, iptcValues(meta, "By-line", "Writer-Editor", "Contact")...)
+	if meta.PNG != nil {
+		for _, t := range meta.PNG.Text {
+			if t.Keyword == "Author" && strings.TrimSpace(t.Value) != "" {
+				evidence = append(evidence, models.SourcedValue{Source: "PNG " + t.Chunk + " Author", Raw: t.Value, Display: t.Value})
+			}
+		}
+	}
+	return privacyFinding("person", severityMedium, "Names and contact details identify the photographer or owner", evidence)
+}
+
+// checkWindowsAuthor reports the author Windows Explorer writes to its own
+// EXIF tag, which few other tools show
+func checkWindowsAuthor(meta *models.ImageMetadata) []models.PrivacyFinding {
+	return privacyFinding("windows-author", severityMedium,
+		"The Windows XPAuthor tag names the author; most viewers and many strip tools ignore it",
+		exifValues(meta, named("XPAuthor")))
+}
+
+// checkDeviceIDs reports the identifiers cameras and phones assign
+func checkDeviceIDs(meta *models.ImageMetadata) []models.PrivacyFinding {
+	evidence := exifValues(meta, named("ImageUniqueID"))
+	evidence = append(evidence, makerNoteValues(meta, named("CameraID", "ContentIdentifier"))...)
+	evidence = append(evidence, xmpValues(meta, named("exif:ImageUniqueID", "exifEX:ImageUniqueID"))...)
+	return privacyFinding("device-id", severityMedium,
+		"Unique IDs written by the camera or phone can link this image to the device and its other images", evidence)
+}
+
+// checkFilePaths reports local paths in the XMP edit history and
+// ingredient references
+func checkFilePaths(meta *models.ImageMetadata) []models.PrivacyFinding {
+	var evidence []models.SourcedValue
+	var users []string
+	for _, v := range xmpValues(meta, func(string) bool { return true }) {
+		paths := localPathPattern.FindAllString(v.Raw, -1)
+		if len(paths) == 0 {
+			continue
+		}
+		for i, p := range paths {
+			paths[i] = strings.TrimSpace(p)
+			if m := pathUserPattern.FindStringSubmatch(paths[i]); m != nil && !slices.Contains(users, m[1]) {
+				users = append(users, m[1])
+			}
+		}
+		v.Display = strings.Join(paths, ", ")
+		evidence = append(evidence, v)
+	}
+	message := "The XMP history records paths on the editing computer"
+	if len(users) > 0 {
+		message += ", including the account name " + strings.Join(users, ", ")
+	}
+	return privacyFinding("file-path", severityMedium, message, evidence)
+}
+
+// checkDocumentIDs reports the XMP document IDs, which are shared by every
+// copy and derivative of a file
+func checkDocumentIDs(meta *models.ImageMetadata) []models.PrivacyFinding {
+	evidence := xmpValues(meta, func(path string) bool {
+		return path == "xmpMM:DocumentID" || path == "xmpMM:OriginalDocumentID" || path == "xmpMM:InstanceID" ||
+			strings.HasSuffix(path, "/stRef:documentID") || strings.HasSuffix(path, "/stRef:originalDocumentID")
+	})
+	// Photoshop lists every document ever pasted in; one value stands for all
+	if ancestors := xmpValues(meta, func(path string) bool {
+		return strings.HasPrefix(path, "photoshop:DocumentAncestors[")
+	}); len(ancestors) > 0 {
+		raw := make([]string, len(ancestors))
+		for i, a := range ancestors {
+			raw[i] = a.Raw
+		}
+		evidence = append(evidence, models.SourcedValue{
+			Source:  "XMP photoshop:DocumentAncestors",
+			Raw:     strings.Join(raw, "; "),
+			Display: fmt.Sprintf("%d ancestor documents", len(ancestors)),
+		})
+	}
+	return privacyFinding("document-id", severityLow,
+		"Document IDs link this file to the original and to other copies and versions made from it", evidence)
+}
+
+// checkFaceRegions reports the names given to people tagged in the image
+func checkFaceRegions(meta *models.ImageMetadata) []models.PrivacyFinding {
+	evidence := xmpValues(meta, func(path string) bool {
+		return strings.HasPrefix(path, "mwg-rs:Regions/") && strings.HasSuffix(path, "/mwg-rs:Name") ||
+			strings.HasPrefix(path, "MP:RegionInfo/") && strings.HasSuffix(path, "/MPReg:PersonDisplayName") ||
+			strings.HasPrefix(path, "Iptc4xmpExt:PersonInImage[") ||
+			strings.HasPrefix(path, "Iptc4xmpExt:PersonInImageWDetails[") && strings.Contains(path, "/Iptc4xmpExt:PersonName")
+	})
+	return privacyFinding("face-region", severityHigh,
+		fmt.Sprintf("Tagged regions name %d people or subjects shown in the image", len(evidence)), evidence)
+}
+
+// checkThumbnails reports embedded thumbnails, which editors do not always
+// regenerate and may still show what was cropped out
+func checkThumbnails(meta *models.ImageMetadata) []models.PrivacyFinding {
+	var findings []models.PrivacyFinding
+	for _, t := range meta.Thumbnails {
+		ev := models.SourcedValue{
+			Source:  t.Source + " thumbnail",
+			Raw:     fmt.Sprintf("%dx%d", t.Width, t.Height),
+			Display: fmt.Sprintf("%d × %d, %s", t.Width, t.Height, t.SizeHuman),
+		}
+		f := models.PrivacyFinding{
+			Category: "thumbnail",
+			Severity: severityLow,
+			Message:  fmt.Sprintf("The %s thumbnail is a separate copy of the image that editors do not always update", t.Source),
+			Evidence: []models.SourcedValue{ev},
+		}
+		cropped := meta.RAW == nil && meta.Width > 0 && meta.Height > 0 && t.Width > 0 && t.Height > 0 &&
+			!sameAspect(t.Width, t.Height, meta.Width, meta.Height) &&
+			!sameAspect(t.Width, t.Height, meta.Height, meta.Width) &&
+			!(t.Width == 160 && t.Height == 120)
+		if cropped {
+			f.Severity = severityHigh
+			f.Message = fmt.Sprintf("The %s thumbnail is a different shape from the %d × %d image and may show the original before it was cropped",
+				t.Source, meta.Width, meta.Height)
+		}
+		findings = append(findings, f)
+	}
+	return findings
+}
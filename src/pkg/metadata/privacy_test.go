@@ -0,0 +1,125 @@
+package metadata
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf16"
+
+	"github.com/ahrdadan/image-metadata-viewer/src/internal/models"
+)
+
+const testPrivacyXMP = `<x:xmpmeta xmlns:x="adobe:ns:meta/">
+ <rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">
+  <rdf:Description rdf:about=""
+    xmlns:xmpMM="http://ns.adobe.com/xap/1.0/mm/"
+    xmlns:stRef="http://ns.adobe.com/xap/1.0/sType/ResourceRef#"
+    xmlns:mwg-rs="http://www.metadataworkinggroup.com/schemas/regions/"
+    xmpMM:DocumentID="xmp.did:5c1f0b2e-8d4a-4f61-a2f0-6b3e9d7c1a20">
+   <xmpMM:DerivedFrom rdf:parseType="Resource">
+    <stRef:documentID>xmp.did:0a9b8c7d-6e5f-4a3b-9c2d-1e0f9a8b7c6d</stRef:documentID>
+    <stRef:filePath>C:\Users\jdoe\Pictures\family\IMG_0042.psd</stRef:filePath>
+   </xmpMM:DerivedFrom>
+   <mwg-rs:Regions rdf:parseType="Resource">
+    <mwg-rs:RegionList>
+     <rdf:Bag>
+      <rdf:li mwg-rs:Name="Jane Doe" mwg-rs:Type="Face"/>
+      <rdf:li mwg-rs:Name="Sam Doe" mwg-rs:Type="Face"/>
+     </rdf:Bag>
+    </mwg-rs:RegionList>
+   </mwg-rs:Regions>
+  </rdf:Description>
+ </rdf:RDF>
+</x:xmpmeta>`
+
+func TestAuditPrivacy(t *testing.T) {
+	xpAuthor := []byte{}
+	for _, u := range utf16.Encode([]rune("J. Doe\x00")) {
+		xpAuthor = append(xpAuthor, byte(u), byte(u>>8))
+	}
+	tiffData := buildTIFF([]testIFD{
+		{
+			tags: []testTag{
+				asciiTag(0x013B, "Jane Doe"),
+				{id: 0x9C9D, typ: 1, count: uint32(len(xpAuthor)), value: xpAuthor},
+			},
+			pointers: map[uint16]int{tagExifIFDPointer: 1, tagGPSIFDPointer: 2},
+		},
+		{
+			tags: []testTag{
+				asciiTag(0xA420, "4f1b2a9c8d7e6f5a4b3c2d1e0f9a8b7c"),
+				asciiTag(0xA431, "012345678901"),
+			},
+		},
+		{
+			tags: []testTag{
+				asciiTag(0x0001, "N"),
+				rationalTag(0x0002, 48, 1, 51, 1, 2400, 100),
+				asciiTag(0x0003, "E"),
+				rationalTag(0x0004, 2, 1, 17, 1, 4000, 100),
+			},
+		},
+	})
+	xmpSeg := jpegSegment(0xE1, append(append([]byte{}, xmpJPEGSignature...), testPrivacyXMP...))
+	meta := ExtractMetadata(buildJPEGWithSegments(t, exifSegment(tiffData), xmpSeg), "image/jpeg", "family.jpg")
+
+	r := AuditPrivacy(meta)
+	var got []string
+	for _, f := range r.Findings {
+		got = append(got, f.Category+":"+f.Severity)
+	}
+	want := "gps:high face-region:high serial-number:medium person:medium windows-author:medium device-id:medium file-path:medium document-id:low"
+	if strings.Join(got, " ") != want {
+		t.Errorf("findings = %v, want %s", got, want)
+	}
+	if r.Score != 100 || r.Risk != riskHigh {
+		t.Errorf("score = %d, risk = %s", r.Score, r.Risk)
+	}
+
+	for _, f := range r.Findings {
+		switch f.Category {
+		case "face-region":
+			if len(f.Evidence) != 2 || f.Evidence[0].Raw != "Jane Doe" || f.Evidence[1].Raw != "Sam Doe" {
+				t.Errorf("face-region evidence = %+v", f.Evidence)
+			}
+		case "file-path":
+			if !strings.HasSuffix(f.Message, "account name jdoe") || f.Evidence[0].Source != "XMP xmpMM:DerivedFrom/stRef:filePath" {
+				t.Errorf("file-path = %+v", f)
+			}
+		case "windows-author":
+			if f.Evidence[0].Display != "J. Doe" {
+				t.Errorf("XPAuthor = %+v", f.Evidence)
+			}
+		case "document-id":
+			if len(f.Evidence) != 2 {
+				t.Errorf("document-id evidence = %+v", f.Evidence)
+			}
+		}
+	}
+
+	clean := AuditPrivacy(ExtractMetadata(buildJPEGWithSegments(t), "image/jpeg", "clean.jpg"))
+	if clean.Score != 0 || clean.Risk != riskNone || len(clean.Findings) != 0 {
+		t.Errorf("clean report = %+v", clean)
+	}
+}
+
+func TestAuditPrivacyCroppedThumbnail(t *testing.T) {
+	data := buildPNGWithChunks(t, buildChunk("tEXt", []byte("Author\x00Jane Doe")))
+	meta := ExtractMetadata(data, "image/png", "crop.png")
+	meta.Thumbnails = []models.Thumbnail{
+		{Source: "EXIF IFD1", Width: 160, Height: 90},
+		{Source: "Photoshop", Width: 40, Height: 40},
+	}
+
+	r := AuditPrivacy(meta)
+	var got []string
+	for _, f := range r.Findings {
+		got = append(got, f.Category+":"+f.Severity)
+	}
+	// The 4 × 4 image is square, so only the 16:9 thumbnail was cropped
+	if want := "thumbnail:high person:medium thumbnail:low"; strings.Join(got, " ") != want {
+		t.Errorf("findings = %v, want %s", got, want)
+	}
+	if r.Score != 60 || r.Risk != riskHigh {
+		t.Errorf("score = %d, risk = %s", r.Score, r.Risk)
+	}
+}
@@ -0,0 +1,571 @@
+package metadata
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/ahrdadan/image-metadata-viewer/src/internal/models"
+)
+
+// psdSignature starts every PSD and PSB file
+var psdSignature = []byte("8BPS")
+
+// psdHeaderSize is the fixed file header before the color mode data
+const psdHeaderSize = 26
+
+// Section divider types of the lsct block
+const (
+	psdDividerOpenGroup   = 1
+	psdDividerClosedGroup = 2
+	psdDividerGroupEnd    = 3
+)
+
+// maxPSDLayers bounds the layer walk; documents rarely hold more than a
+// few hundred records
+const maxPSDLayers = 8192
+
+// errPSDPixels is returned when asked to decode Photoshop pixel data
+var errPSDPixels = errors.New("psd: compositing is not supported")
+
+var psdColorModes = map[int]string{
+	0: "Bitmap",
+	1: "Grayscale",
+	2: "Indexed",
+	3: "RGB",
+	4: "CMYK",
+	7: "Multichannel",
+	8: "Duotone",
+	9: "Lab",
+}
+
+// psdColorComponents is the number of color channels of each mode; any
+// further channels are alpha or spot channels
+var psdColorComponents = map[int]int{0: 1, 1: 1, 2: 1, 3: 3, 4: 4, 8: 1, 9: 3}
+
+var psdBlendModes = map[string]string{
+	"pass": "Pass through",
+	"norm": "Normal",
+	"diss": "Dissolve",
+	"dark": "Darken",
+	"mul ": "Multiply",
+	"idiv": "Color burn",
+	"lbrn": "Linear burn",
+	"dkCl": "Darker color",
+	"lite": "Lighten",
+	"scrn": "Screen",
+	"div ": "Color dodge",
+	"lddg": "Linear dodge",
+	"lgCl": "Lighter color",
+	"over": "Overlay",
+	"sLit": "Soft light",
+	"hLit": "Hard light",
+	"vLit": "Vivid light",
+	"lLit": "Linear light",
+	"pLit": "Pin light",
+	"hMix": "Hard mix",
+	"diff": "Difference",
+	"smud": "Exclusion",
+	"fsub": "Subtract",
+	"fdiv": "Divide",
+	"hue ": "Hue",
+	"sat ": "Saturation",
+	"colr": "Color",
+	"lum ": "Luminosity",
+}
+
+// psdLayerKinds maps the additional layer information that marks a
+// layer's kind
+var psdLayerKinds = map[string]string{
+	"TySh": "text",
+	"tySh": "text",
+	"SoLd": "smart object",
+	"SoLE": "smart object",
+	"PlLd": "smart object",
+	"SoCo": "fill",
+	"GdFl": "fill",
+	"PtFl": "fill",
+	"levl": "adjustment",
+	"curv": "adjustment",
+	"brit": "adjustment",
+	"blnc": "adjustment",
+	"hue ": "adjustment",
+	"hue2": "adjustment",
+	"selc": "adjustment",
+	"thrs": "adjustment",
+	"nvrt": "adjustment",
+	"post": "adjustment",
+	"mixr": "adjustment",
+	"grdm": "adjustment",
+	"phfl": "adjustment",
+	"expA": "adjustment",
+	"vibA": "adjustment",
+	"blwh": "adjustment",
+	"clrL": "adjustment",
+}
+
+// psdLongKeys are the additional information blocks whose length is
+// 8 bytes in PSB files
+var psdLongKeys = map[string]bool{
+	"LMsk": true, "Lr16": true, "Lr32": true, "Layr": true, "Mt16": true, "Mt32": true, "Mtrn": true,
+	"Alph": true, "FMsk": true, "lnk2": true, "FEid": true, "FXid": true, "PxSD": true,
+}
+
+var psResourceNames = map[int]string{
+	0x03E9: "Macintosh print info",
+	0x03ED: "Resolution info",
+	0x03EE: "Alpha channel names",
+	0x03F0: "Caption",
+	0x03F3: "Print flags",
+	0x03F5: "Color halftoning info",
+	0x03F8: "Color transfer functions",
+	0x0400: "Layer state",
+	0x0402: "Layer groups",
+	0x0404: "IPTC-NAA",
+	0x0405: "Raw image mode",
+	0x0406: "JPEG quality",
+	0x0408: "Grid and guides",
+	0x0409: "Thumbnail (legacy)",
+	0x040A: "Copyright flag",
+	0x040B: "URL",
+	0x040C: "Thumbnail",
+	0x040D: "Global angle",
+	0x040F: "ICC profile",
+	0x0410: "Watermark",
+	0x0411: "ICC untagged",
+	0x0412: "Effects visible",
+	0x0414: "Document ID seed",
+	0x0415: "Unicode alpha names",
+	0x0416: "Indexed color table count",
+	0x0417: "Transparency index",
+	0x0419: "Global altitude",
+	0x041A: "Slices",
+	0x041B: "Workflow URL",
+	0x041D: "Alpha identifiers",
+	0x041E: "URL list",
+	0x0421: "Version info",
+	0x0422: "EXIF data 1",
+	0x0423: "EXIF data 3",
+	0x0424: "XMP metadata",
+	0x0425: "Caption digest",
+	0x0426: "Print scale",
+	0x0428: "Pixel aspect ratio",
+	0x0429: "Layer comps",
+	0x042B: "Alternate spot colors",
+	0x042D: "Layer selection IDs",
+	0x042F: "Auto save format",
+	0x0430: "Layer group enabled IDs",
+	0x0432: "Measurement scale",
+	0x0433: "Timeline info",
+	0x0434: "Sheet disclosure",
+	0x0435: "Display info",
+	0x0436: "Onion skins",
+	0x0438: "Count info",
+	0x043A: "Print info",
+	0x043B: "Print style",
+	0x043C: "Mac NSPrintInfo",
+	0x043D: "Windows DEVMODE",
+	0x043E: "Auto save file path",
+	0x0BB7: "Clipping path name",
+	0x0BB8: "Origin path info",
+	0x1B58: "Image Ready variables",
+	0x1B59: "Image Ready data sets",
+	0x1F40: "Lightroom workflow",
+	0x2710: "Print flags info",
+}
+
+func init() {
+	// Register PSD and PSB so image.DecodeConfig reports the canvas size;
+	// layers are never composited
+	image.RegisterFormat("psd", "8BPS\x00\x01", decodePSD, decodePSDConfig)
+	image.RegisterFormat("psb", "8BPS\x00\x02", decodePSD, decodePSDConfig)
+}
+
+// decodePSD satisfies image.RegisterFormat; compositing is unsupported
+func decodePSD(io.Reader) (image.Image, error) {
+	return nil, errPSDPixels
+}
+
+// decodePSDConfig reads the canvas size from the file header
+func decodePSDConfig(r io.Reader) (image.Config, error) {
+	header := make([]byte, psdHeaderSize)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return image.Config{}, err
+	}
+	if !isPSD(header) {
+		return image.Config{}, errors.New("psd: invalid header")
+	}
+	return image.Config{
+		Width:  int(binary.BigEndian.Uint32(header[18:])),
+		Height: int(binary.BigEndian.Uint32(header[14:])),
+	}, nil
+}
+
+// isPSD reports whether data starts with a PSD or PSB header
+func isPSD(data []byte) bool {
+	if len(data) < psdHeaderSize || !bytes.HasPrefix(data, psdSignature) {
+		return false
+	}
+	version := binary.BigEndian.Uint16(data[4:])
+	return version == 1 || version == 2
+}
+
+// psdFile holds the sections that follow the header
+type psdFile struct {
+	large     bool // PSB: section and channel lengths are 8 bytes
+	resources []byte
+	layerMask []byte
+}
+
+// readPSD splits the file into its length-prefixed sections
+func readPSD(data []byte) *psdFile {
+	if !isPSD(data) {
+		return nil
+	}
+	file := &psdFile{large: binary.BigEndian.Uint16(data[4:]) == 2}
+	r := &psdReader{data: data, pos: psdHeaderSize, large: file.large}
+	r.bytes(int(r.uint(4))) // color mode data
+	file.resources = r.bytes(int(r.uint(4)))
+	file.layerMask = r.bytes(r.length())
+	return file
+}
+
+// psdReader reads big-endian fields, with PSB's wider lengths when large
+type psdReader struct {
+	data  []byte
+	pos   int
+	large bool
+	err   bool
+}
+
+// uint reads an n-byte unsigned integer
+func (r *psdReader) uint(n int) uint64 {
+	b := r.bytes(n)
+	var v uint64
+	for _, c := range b {
+		v = v<<8 | uint64(c)
+	}
+	return v
+}
+
+// int32 reads a signed 4-byte integer
+func (r *psdReader) int32() int {
+	return int(int32(r.uint(4)))
+}
+
+// length reads a section length: 4 bytes in PSD, 8 in PSB
+func (r *psdReader) length() int {
+	if r.large {
+		return int(r.uint(8))
+	}
+	return int(r.uint(4))
+}
+
+// bytes reads n bytes
+func (r *psdReader) bytes(n int) []byte {
+	if r.err || n < 0 || n > len(r.data)-r.pos {
+		r.err = true
+		return nil
+	}
+	b := r.data[r.pos : r.pos+n]
+	r.pos += n
+	return b
+}
+
+// psdRecord is a layer record before the group tree is built
+type psdRecord struct {
+	layer   models.PSDLayer
+	divider int
+}
+
+// parsePSD reads the header, the image resource list and the layer records
+func parsePSD(data []byte) *models.PSDInfo {
+	file := readPSD(data)
+	if file == nil {
+		return nil
+	}
+	mode := int(binary.BigEndian.Uint16(data[24:]))
+	info := &models.PSDInfo{
+		Version:   "PSD",
+		Channels:  int(binary.BigEndian.Uint16(data[12:])),
+		Height:    int(binary.BigEndian.Uint32(data[14:])),
+		Width:     int(binary.BigEndian.Uint32(data[18:])),
+		Depth:     int(binary.BigEndian.Uint16(data[22:])),
+		ColorMode: lookupName(psdColorModes, mode),
+		Resources: []models.PSDResource{},
+		Layers:    []models.PSDLayer{},
+	}
+	if file.large {
+		info.Version = "PSB"
+	}
+
+	for _, res := range readPhotoshopResources(file.resources) {
+		description := psResourceNames[int(res.id)]
+		switch {
+		case description != "":
+		case res.id >= 0x07D0 && res.id <= 0x0BB6:
+			description = "Path"
+		case res.id >= 0x0FA0 && res.id <= 0x1387:
+			description = "Plug-in resource"
+		}
+		info.Resources = append(info.Resources, models.PSDResource{
+			ID:          int(res.id),
+			Name:        res.name,
+			Description: description,
+			Size:        len(res.data),
+		})
+	}
+
+	records, mergedAlpha := readPSDLayers(file)
+	info.LayerCount, info.MergedAlpha = len(records), mergedAlpha
+	info.Layers = psdLayerTree(records)
+	return info
+}
+
+// readPSDLayers returns the layer records, top of the stack first. 16- and
+// 32-bit documents leave the layer info empty and store it in an Lr16 or
+// Lr32 block after the global layer mask.
+func readPSDLayers(file *psdFile) ([]psdRecord, bool) {
+	r := &psdReader{data: file.layerMask, large: file.large}
+	layerInfo := r.bytes(r.length())
+	if len(layerInfo) == 0 && !r.err {
+		r.bytes(int(r.uint(4))) // global layer mask
+		for _, block := range readPSDBlocks(r.data[r.pos:], file.large) {
+			if block.key == "Lr16" || block.key == "Lr32" || block.key == "Layr" {
+				layerInfo = block.data
+				break
+			}
+		}
+	}
+	if len(layerInfo) < 2 {
+		return nil, false
+	}
+
+	r = &psdReader{data: layerInfo, large: file.large}
+	count := int(int16(r.uint(2)))
+	mergedAlpha := count < 0
+	if count < 0 {
+		count = -count
+	}
+	if count > maxPSDLayers {
+		count = maxPSDLayers
+	}
+
+	records := make([]psdRecord, 0, count)
+	for i := 0; i < count && !r.err; i++ {
+		rec, ok := readPSDRecord(r)
+		if !ok {
+			break
+		}
+		records = append(records, rec)
+	}
+
+	// Records are stored bottom first
+	for i, j := 0, len(records)-1; i < j; i, j = i+1, j-1 {
+		records[i], records[j] = records[j], records[i]
+	}
+	return records, mergedAlpha
+}
+
+// readPSDRecord reads one layer record and its additional information
+func readPSDRecord(r *psdReader) (psdRecord, bool) {
+	var rec psdRecord
+	layer := &rec.layer
+	layer.Top, layer.Left, layer.Bottom, layer.Right = r.int32(), r.int32(), r.int32(), r.int32()
+
+	channels := int(r.uint(2))
+	channelInfo := 6
+	if r.large {
+		channelInfo = 10
+	}
+	r.bytes(channels * channelInfo)
+
+	if sig := string(r.bytes(4)); sig != "8BIM" && sig != "8B64" {
+		return rec, false
+	}
+	blendKey := string(r.bytes(4))
+	layer.BlendMode = psdBlendName(blendKey)
+	layer.Opacity = (int(r.uint(1))*100 + 127) / 255
+	layer.Clipped = r.uint(1) != 0
+	flags := r.uint(1)
+	layer.Visible = flags&0x02 == 0
+	r.uint(1) // filler
+	extra := r.bytes(int(r.uint(4)))
+	if r.err {
+		return rec, false
+	}
+
+	// Layer mask and blending ranges, then the Pascal name padded to 4
+	x := &psdReader{data: extra}
+	x.bytes(int(x.uint(4)))
+	x.bytes(int(x.uint(4)))
+	nameLen := int(x.uint(1))
+	layer.Name = string(x.bytes(nameLen))
+	x.bytes((4 - (1+nameLen)%4) % 4)
+	if x.err {
+		return rec, true
+	}
+
+	layer.Kind = "pixel"
+	for _, block := range readPSDBlocks(extra[x.pos:], r.large) {
+		switch block.key {
+		case "luni":
+			if name := psdUnicodeName(block.data); name != "" {
+				layer.Name = name
+			}
+		case "lsct", "lsdk":
+			if len(block.data) >= 4 {
+				rec.divider = int(binary.BigEndian.Uint32(block.data))
+			}
+			// Groups store their own blend mode here; the record holds
+			// pass through
+			if len(block.data) >= 12 && string(block.data[4:8]) == "8BIM" {
+				layer.BlendMode = psdBlendName(string(block.data[8:12]))
+			}
+		default:
+			if kind, ok := psdLayerKinds[block.key]; ok {
+				layer.Kind = kind
+			}
+		}
+	}
+	if rec.divider == psdDividerOpenGroup || rec.divider == psdDividerClosedGroup {
+		layer.Kind = "group"
+		layer.Open = rec.divider == psdDividerOpenGroup
+	}
+	return rec, true
+}
+
+// psdBlock is one additional information block
+type psdBlock struct {
+	key  string
+	data []byte
+}
+
+// readPSDBlocks walks additional information blocks: a signature, a key
+// and a length-prefixed payload padded to an even size
+func readPSDBlocks(data []byte, large bool) []psdBlock {
+	var blocks []psdBlock
+	r := &psdReader{data: data}
+	for r.pos+12 <= len(data) {
+		if sig := string(r.bytes(4)); sig != "8BIM" && sig != "8B64" {
+			break
+		}
+		key := string(r.bytes(4))
+		size := int(r.uint(4))
+		if large && psdLongKeys[key] {
+			r.pos -= 4
+			size = int(r.uint(8))
+		}
+		body := r.bytes(size)
+		if r.err {
+			break
+		}
+		blocks = append(blocks, psdBlock{key: key, data: body})
+		if size%2 == 1 && r.pos < len(data) {
+			r.pos++
+		}
+	}
+	return blocks
+}
+
+// psdUnicodeName decodes a luni block: a character count and UTF-16 text
+func psdUnicodeName(data []byte) string {
+	if len(data) < 4 {
+		return ""
+	}
+	n := int(binary.BigEndian.Uint32(data))
+	if n > (len(data)-4)/2 {
+		return ""
+	}
+	units := make([]uint16, n)
+	for i := range units {
+		units[i] = binary.BigEndian.Uint16(data[4+2*i:])
+	}
+	return strings.TrimRight(string(utf16.Decode(units)), "\x00")
+}
+
+// psdBlendName names a blend mode key
+func psdBlendName(key string) string {
+	if name, ok := psdBlendModes[key]; ok {
+		return name
+	}
+	return fmt.Sprintf("Unknown (%q)", key)
+}
+
+// psdLayerTree nests the records between a group's header and its end
+// marker under the group. The end markers themselves are dropped.
+func psdLayerTree(records []psdRecord) []models.PSDLayer {
+	i := 0
+	var build func() []models.PSDLayer
+	build = func() []models.PSDLayer {
+		layers := []models.PSDLayer{}
+		for i < len(records) {
+			rec := records[i]
+			i++
+			switch rec.divider {
+			case psdDividerGroupEnd:
+				return layers
+			case psdDividerOpenGroup, psdDividerClosedGroup:
+				group := rec.layer
+				group.Children = build()
+				layers = append(layers, group)
+			default:
+				layers = append(layers, rec.layer)
+			}
+		}
+		return layers
+	}
+
+	// An unmatched end marker closes nothing; keep reading the stack
+	layers := []models.PSDLayer{}
+	for i < len(records) {
+		layers = append(layers, build()...)
+	}
+	return layers
+}
+
+// psdResource returns the payload of the first image resource with the
+// given ID
+func psdResource(data []byte, id uint16) []byte {
+	file := readPSD(data)
+	if file == nil {
+		return nil
+	}
+	for _, res := range readPhotoshopResources(file.resources) {
+		if res.id == id {
+			return res.data
+		}
+	}
+	return nil
+}
+
+// extractPSD fills the Photoshop section and the shared color fields
+func extractPSD(data []byte, meta *models.ImageMetadata) {
+	if sniffFormat(data) != formatPSD {
+		return
+	}
+	info := parsePSD(data)
+	if info == nil {
+		return
+	}
+	meta.PSD = info
+	if meta.MIMEType == "" || meta.MIMEType == "application/octet-stream" {
+		meta.MIMEType = "image/vnd.adobe.photoshop"
+	}
+
+	mode := int(binary.BigEndian.Uint16(data[24:]))
+	components, ok := psdColorComponents[mode]
+	if !ok || components > info.Channels {
+		components = info.Channels
+	}
+	meta.ColorMode = info.ColorMode
+	meta.ColorComponents, meta.SamplesPerPixel = components, info.Channels
+	if info.Channels > 0 && info.Depth > 0 {
+		meta.BitsPerSample = strings.TrimSpace(strings.Repeat(fmt.Sprintf("%d ", info.Depth), info.Channels))
+	}
+}
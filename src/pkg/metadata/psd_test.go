@@ -0,0 +1,157 @@
+package metadata
+
+import (
+	"encoding/binary"
+	"testing"
+	"unicode/utf16"
+)
+
+// psdInfoBlock encodes an additional layer information block
+func psdInfoBlock(key string, data []byte) []byte {
+	out := append([]byte("8BIM"), key...)
+	out = binary.BigEndian.AppendUint32(out, uint32(len(data)))
+	out = append(out, data...)
+	if len(data)%2 == 1 {
+		out = append(out, 0)
+	}
+	return out
+}
+
+// psdLayerRecord encodes a layer record with one empty channel
+func psdLayerRecord(large bool, name, blend string, flags byte, blocks ...[]byte) []byte {
+	out := make([]byte, 0, 64)
+	for _, v := range []int32{10, 20, 110, 220} {
+		out = binary.BigEndian.AppendUint32(out, uint32(v))
+	}
+	out = binary.BigEndian.AppendUint16(out, 1)
+	out = append(out, 0, 0)
+	if large {
+		out = append(out, make([]byte, 8)...)
+	} else {
+		out = append(out, make([]byte, 4)...)
+	}
+	out = append(append(append(out, "8BIM"...), blend...), 204, 0, flags, 0)
+
+	extra := make([]byte, 8) // no mask, no blending ranges
+	extra = append(append(extra, byte(len(name))), name...)
+	extra = append(extra, make([]byte, (4-(1+len(name))%4)%4)...)
+	for _, block := range blocks {
+		extra = append(extra, block...)
+	}
+	out = binary.BigEndian.AppendUint32(out, uint32(len(extra)))
+	return append(out, extra...)
+}
+
+// psdLength encodes a section length, 8 bytes wide in PSB
+func psdLength(large bool, n int) []byte {
+	if large {
+		return binary.BigEndian.AppendUint64(nil, uint64(n))
+	}
+	return binary.BigEndian.AppendUint32(nil, uint32(n))
+}
+
+// buildPSD writes an RGBA document whose layers, stored bottom first, are
+// a background, a group end marker, a hidden pixel layer, a text layer
+// with a Unicode name and the group header
+func buildPSD(large bool, depth uint16, resources ...[]byte) []byte {
+	name := utf16.Encode([]rune("Título"))
+	luni := binary.BigEndian.AppendUint32(nil, uint32(len(name)))
+	for _, u := range name {
+		luni = binary.BigEndian.AppendUint16(luni, u)
+	}
+	records := [][]byte{
+		psdLayerRecord(large, "Background", "norm", 0),
+		psdLayerRecord(large, "</Layer group>", "norm", 0, psdInfoBlock("lsct", u32(psdDividerGroupEnd))),
+		psdLayerRecord(large, "Shadow", "mul ", 0x02),
+		psdLayerRecord(large, "Title", "norm", 0, psdInfoBlock("luni", luni), psdInfoBlock("TySh", []byte{0, 1})),
+		psdLayerRecord(large, "Header", "pass", 0,
+			psdInfoBlock("lsct", append(u32(psdDividerOpenGroup), "8BIMscrn"...))),
+	}
+	layerInfo := binary.BigEndian.AppendUint16(nil, uint16(0x10000-len(records))) // negative: merged alpha
+	for _, rec := range records {
+		layerInfo = append(layerInfo, rec...)
+	}
+
+	var layerMask []byte
+	if depth == 8 {
+		layerMask = append(psdLength(large, len(layerInfo)), layerInfo...)
+		layerMask = append(layerMask, u32(0)...)
+	} else {
+		// Deep documents keep the layer info in an Lr16 block
+		layerMask = append(psdLength(large, 0), u32(0)...)
+		layerMask = append(layerMask, "8BIMLr16"...)
+		layerMask = append(append(layerMask, psdLength(large, len(layerInfo))...), layerInfo...)
+	}
+
+	version := uint16(1)
+	if large {
+		version = 2
+	}
+	out := append([]byte("8BPS"), u16(version)...)
+	out = append(out, make([]byte, 6)...)
+	out = append(append(append(out, u16(4)...), u32(300)...), u32(400)...)
+	out = append(append(out, u16(depth)...), u16(3)...)
+	out = append(out, u32(0)...) // color mode data
+
+	var section []byte
+	for _, res := range resources {
+		section = append(section, res...)
+	}
+	out = append(append(out, u32(uint32(len(section)))...), section...)
+	return append(append(out, psdLength(large, len(layerMask))...), layerMask...)
+}
+
+func TestExtractMetadataPSD(t *testing.T) {
+	data := buildPSD(false, 8,
+		psResourceBlock(0x03ED, make([]byte, 16)),
+		psResourceBlock(psResourceIPTC, iimDataset(2, 5, "Harbour")),
+		psResourceBlock(psResourceXMP, []byte(testXMPPacket)),
+	)
+	meta := ExtractMetadata(data, "application/octet-stream", "poster.psd")
+	if meta.DecodeError != "" {
+		t.Fatalf("DecodeError = %q", meta.DecodeError)
+	}
+	if meta.Format != "psd" || meta.Width != 400 || meta.Height != 300 || meta.MIMEType != "image/vnd.adobe.photoshop" {
+		t.Errorf("Format = %q, size = %dx%d, MIMEType = %q", meta.Format, meta.Width, meta.Height, meta.MIMEType)
+	}
+	if meta.ColorMode != "RGB" || meta.ColorComponents != 3 || meta.BitsPerSample != "8 8 8 8" {
+		t.Errorf("ColorMode = %q, ColorComponents = %d, BitsPerSample = %q", meta.ColorMode, meta.ColorComponents, meta.BitsPerSample)
+	}
+	if meta.IPTC == nil || meta.XMP == nil {
+		t.Errorf("IPTC = %+v, XMP = %+v, want both from the image resources", meta.IPTC, meta.XMP)
+	}
+
+	info := meta.PSD
+	if info == nil || info.Version != "PSD" || len(info.Resources) != 3 || info.Resources[0].Description != "Resolution info" {
+		t.Fatalf("PSD = %+v", info)
+	}
+	if info.LayerCount != 5 || !info.MergedAlpha || len(info.Layers) != 2 {
+		t.Fatalf("LayerCount = %d, MergedAlpha = %v, layers = %+v", info.LayerCount, info.MergedAlpha, info.Layers)
+	}
+	group, background := info.Layers[0], info.Layers[1]
+	if group.Name != "Header" || group.Kind != "group" || !group.Open || group.BlendMode != "Screen" || len(group.Children) != 2 {
+		t.Errorf("group = %+v", group)
+	}
+	if background.Name != "Background" || background.Kind != "pixel" || background.Opacity != 80 || background.Right != 220 {
+		t.Errorf("background = %+v", background)
+	}
+	if len(group.Children) == 2 {
+		text, shadow := group.Children[0], group.Children[1]
+		if text.Name != "Título" || text.Kind != "text" || !text.Visible {
+			t.Errorf("text layer = %+v", text)
+		}
+		if shadow.Name != "Shadow" || shadow.Visible || shadow.BlendMode != "Multiply" {
+			t.Errorf("shadow layer = %+v", shadow)
+		}
+	}
+}
+
+func TestExtractMetadataPSB16(t *testing.T) {
+	meta := ExtractMetadata(buildPSD(true, 16), "", "poster.psb")
+	if meta.Format != "psb" || meta.PSD == nil || meta.PSD.Version != "PSB" || meta.BitsPerSample != "16 16 16 16" {
+		t.Fatalf("Format = %q, PSD = %+v, BitsPerSample = %q", meta.Format, meta.PSD, meta.BitsPerSample)
+	}
+	if len(meta.PSD.Layers) != 2 || len(meta.PSD.Layers[0].Children) != 2 {
+		t.Errorf("layers = %+v", meta.PSD.Layers)
+	}
+}
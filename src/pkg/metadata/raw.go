@@ -0,0 +1,683 @@
+package metadata
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"strings"
+
+	"github.com/ahrdadan/image-metadata-viewer/src/internal/models"
+	"github.com/rwcarlsen/goexif/exif"
+	"github.com/rwcarlsen/goexif/tiff"
+)
+
+// Camera raw formats, reported as the image format
+const (
+	rawDNG = "dng"
+	rawCR2 = "cr2"
+	rawCR3 = "cr3"
+	rawNEF = "nef"
+	rawARW = "arw"
+	rawORF = "orf"
+	rawRAF = "raf"
+)
+
+// rawMIMETypes maps raw formats to their conventional MIME types
+var rawMIMETypes = map[string]string{
+	rawDNG: "image/x-adobe-dng",
+	rawCR2: "image/x-canon-cr2",
+	rawCR3: "image/x-canon-cr3",
+	rawNEF: "image/x-nikon-nef",
+	rawARW: "image/x-sony-arw",
+	rawORF: "image/x-olympus-orf",
+	rawRAF: "image/x-fuji-raf",
+}
+
+// rafSignature starts every Fujifilm RAF file
+var rafSignature = []byte("FUJIFILMCCD-RAW ")
+
+// TIFF tags that describe the images stored in raw IFDs
+const (
+	tagNewSubfileType      uint16 = 0x00FE
+	tagImageWidth          uint16 = 0x0100
+	tagImageHeight         uint16 = 0x0101
+	tagBitsPerSample       uint16 = 0x0102
+	tagCompression         uint16 = 0x0103
+	tagPhotometric         uint16 = 0x0106
+	tagStripOffsets        uint16 = 0x0111
+	tagStripByteCounts     uint16 = 0x0117
+	tagSubIFDs             uint16 = 0x014A
+	tagJPEGOffset          uint16 = 0x0201
+	tagJPEGLength          uint16 = 0x0202
+	tagCFARepeatPatternDim uint16 = 0x828D
+	tagCFAPattern          uint16 = 0x828E
+	tagEXIFCFAPattern      uint16 = 0xA302
+	tagDNGVersion          uint16 = 0xC612
+	tagDNGBackwardVersion  uint16 = 0xC613
+	tagUniqueCameraModel   uint16 = 0xC614
+	tagDefaultCropSize     uint16 = 0xC620
+)
+
+// Photometric interpretations of color images, which raw files only use
+// for previews
+const (
+	photometricRGB   = 2
+	photometricYCbCr = 6
+)
+
+// RAF header directory tags
+const (
+	rafTagFullSize    = 0x0100
+	rafTagCroppedSize = 0x0111
+	rafTagXTrans      = 0x0131
+)
+
+// maxRawIFDs bounds the IFD chain and SubIFD walk
+const maxRawIFDs = 16
+
+// cfaColors names CFA color indexes
+const cfaColors = "RGBCMYW"
+
+// detectRAW identifies a camera raw format from the file header and, for
+// TIFF-based formats, from IFD0
+func detectRAW(data []byte) string {
+	switch sniffFormat(data) {
+	case formatRAF:
+		return rawRAF
+	case formatCR3:
+		return rawCR3
+	case formatTIFF:
+	default:
+		return ""
+	}
+
+	if isORF(data) {
+		return rawORF
+	}
+	if len(data) >= 11 && string(data[8:10]) == "CR" && data[10] == 2 {
+		return rawCR2
+	}
+
+	order, ifds := rawIFDs(data)
+	if order == nil || len(ifds) == 0 {
+		return ""
+	}
+	ifd0 := ifds[0].dir
+	if findTag(ifd0, tagDNGVersion) != nil {
+		return rawDNG
+	}
+	if findTag(ifd0, tagSubIFDs) == nil {
+		// Scanners and editors write plain TIFFs under these makes too
+		return ""
+	}
+	maker := strings.ToUpper(dirString(ifd0, tagMake))
+	switch {
+	case strings.HasPrefix(maker, "NIKON"):
+		return rawNEF
+	case strings.HasPrefix(maker, "SONY"):
+		return rawARW
+	}
+	return ""
+}
+
+// isORF reports whether data starts with an Olympus ORF header, which
+// replaces the TIFF magic number 42 with "RO" or "RS"
+func isORF(data []byte) bool {
+	if len(data) < 4 {
+		return false
+	}
+	switch string(data[:4]) {
+	case "IIRO", "IIRS", "MMOR":
+		return true
+	}
+	return false
+}
+
+// tiffPayload returns TIFF data with the ORF magic number replaced so the
+// TIFF decoder accepts it
+func tiffPayload(data []byte) []byte {
+	if !isORF(data) {
+		return data
+	}
+	out := append([]byte(nil), data...)
+	if out[0] == 'I' {
+		binary.LittleEndian.PutUint16(out[2:], 42)
+	} else {
+		binary.BigEndian.PutUint16(out[2:], 42)
+	}
+	return out
+}
+
+// rawIFD is a decoded IFD with its name in the raw file
+type rawIFD struct {
+	name string
+	dir  *tiff.Dir
+}
+
+// rawIFDs walks the IFD0 chain and the SubIFDs below each IFD. The TIFF
+// decoder stops at the first malformed directory, so the chain is followed
+// one IFD at a time.
+func rawIFDs(data []byte) (binary.ByteOrder, []rawIFD) {
+	if len(data) < 8 {
+		return nil, nil
+	}
+	var order binary.ByteOrder
+	switch string(data[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return nil, nil
+	}
+
+	var ifds []rawIFD
+	subCount := 0
+	var addSubIFDs func(dir *tiff.Dir, depth int)
+	addSubIFDs = func(dir *tiff.Dir, depth int) {
+		tag := findTag(dir, tagSubIFDs)
+		if tag == nil || depth > 1 {
+			return
+		}
+		for i := 0; i < int(tag.Count) && len(ifds) < maxRawIFDs; i++ {
+			offset, err := tag.Int64(i)
+			if err != nil {
+				return
+			}
+			if sub, _ := decodeDirAt(data, order, offset); sub != nil {
+				ifds = append(ifds, rawIFD{name: fmt.Sprintf("SubIFD%d", subCount), dir: sub})
+				subCount++
+				addSubIFDs(sub, depth+1)
+			}
+		}
+	}
+
+	offset := int64(order.Uint32(data[4:]))
+	for i := 0; offset != 0 && len(ifds) < maxRawIFDs; i++ {
+		dir, next := decodeDirAt(data, order, offset)
+		if dir == nil {
+			break
+		}
+		ifds = append(ifds, rawIFD{name: fmt.Sprintf("IFD%d", i), dir: dir})
+		addSubIFDs(dir, 0)
+		if next == offset {
+			break
+		}
+		offset = next
+	}
+	return order, ifds
+}
+
+// parseRAW describes the sensor image and embedded previews of a camera
+// raw file, or returns nil for other images
+func parseRAW(data []byte) *models.RAWInfo {
+	format := detectRAW(data)
+	var info *models.RAWInfo
+	switch format {
+	case "":
+		return nil
+	case rawCR3:
+		info = parseCR3(data)
+	case rawRAF:
+		info = parseRAF(data)
+	default:
+		info = parseTIFFRAW(data, format)
+	}
+	if info != nil {
+		info.Format = strings.ToUpper(format)
+	}
+	return info
+}
+
+// parseTIFFRAW classifies each IFD of a TIFF-based raw file as the sensor
+// image or a preview
+func parseTIFFRAW(data []byte, format string) *models.RAWInfo {
+	data = tiffPayload(data)
+	order, ifds := rawIFDs(data)
+	if len(ifds) == 0 {
+		return nil
+	}
+
+	ifd0 := ifds[0].dir
+	info := &models.RAWInfo{
+		Container:         "TIFF",
+		Camera:            cameraName(ifd0),
+		UniqueCameraModel: dirString(ifd0, tagUniqueCameraModel),
+	}
+	if tag := findTag(ifd0, tagDNGVersion); tag != nil {
+		info.DNGVersion = describeByteVersion(tag, order)
+	}
+	if tag := findTag(ifd0, tagDNGBackwardVersion); tag != nil {
+		info.DNGBackwardVersion = describeByteVersion(tag, order)
+	}
+
+	var sensor *tiff.Dir
+	for _, ifd := range ifds {
+		dir := ifd.dir
+		subfile, _ := dirInt(dir, tagNewSubfileType)
+		photometric, _ := dirInt(dir, tagPhotometric)
+		compression, _ := dirInt(dir, tagCompression)
+		width, _ := dirInt(dir, tagImageWidth)
+		height, _ := dirInt(dir, tagImageHeight)
+
+		// JPEGInterchangeFormat always points at a preview
+		if offset, ok := dirInt(dir, tagJPEGOffset); ok {
+			length, _ := dirInt(dir, tagJPEGLength)
+			info.Previews = append(info.Previews, rawPreview(data, ifd.name, offset, length, width, height, ""))
+			continue
+		}
+
+		offset, length := stripRange(dir)
+		// Reduced-resolution and color images are previews; ORF keeps its
+		// sensor data in IFD0 without marking it
+		isPreview := subfile&1 != 0 ||
+			(format != rawORF && (photometric == photometricRGB || photometric == photometricYCbCr))
+		if isPreview {
+			info.Previews = append(info.Previews, rawPreview(data, ifd.name, offset, length, width, height, lookupName(compressionNames, compression)))
+			continue
+		}
+		if subfile != 0 {
+			// Depth maps, transparency masks and other auxiliary images
+			continue
+		}
+
+		bits, _ := dirInt(dir, tagBitsPerSample)
+		if width == 0 && (compression == 6 || compression == 7) && offset+length <= len(data) {
+			// CR2 stores the sensor as a lossless JPEG without size tags; a
+			// frame line holds one sample per component
+			if jpg := analyzeJPEG(data[offset : offset+length]); jpg != nil {
+				width, height = jpg.Width*len(jpg.Components), jpg.Height
+				bits = jpg.BitsPerSample
+			}
+		}
+		if width*height <= info.SensorWidth*info.SensorHeight {
+			continue
+		}
+		sensor = dir
+		info.SensorSource = ifd.name
+		info.SensorWidth, info.SensorHeight = width, height
+		info.BitsPerSample = bits
+		info.Compression = lookupName(compressionNames, compression)
+		if photometric != 0 {
+			info.Photometric = lookupName(photometricNames, photometric)
+		}
+	}
+
+	if sensor != nil {
+		info.CFAPattern = tiffCFAPattern(sensor)
+		if tag := findTag(sensor, tagDefaultCropSize); tag != nil && tag.Count == 2 {
+			info.CropWidth, info.CropHeight = tagIntOrRational(tag, 0), tagIntOrRational(tag, 1)
+		}
+	}
+	if info.CFAPattern == "" {
+		if exifDir := rawExifDir(data, order, ifd0); exifDir != nil {
+			info.CFAPattern = exifCFAPattern(findTag(exifDir, tagEXIFCFAPattern), order)
+		}
+	}
+
+	return info
+}
+
+// cameraName joins Make and Model, which often already starts with the make
+func cameraName(dir *tiff.Dir) string {
+	maker, model := dirString(dir, tagMake), dirString(dir, tagModel)
+	if maker == "" || strings.HasPrefix(strings.ToUpper(model), strings.ToUpper(maker)) {
+		return model
+	}
+	return strings.TrimSpace(maker + " " + model)
+}
+
+// stripRange returns the byte range of the first strip of an IFD
+func stripRange(dir *tiff.Dir) (offset, length int) {
+	offset, _ = dirInt(dir, tagStripOffsets)
+	if tag := findTag(dir, tagStripByteCounts); tag != nil {
+		for i := 0; i < int(tag.Count); i++ {
+			n, err := tag.Int(i)
+			if err != nil {
+				break
+			}
+			length += n
+		}
+	}
+	return offset, length
+}
+
+// rawPreview describes a preview image. JPEG previews are measured from
+// their frame header when the IFD has no size tags.
+func rawPreview(data []byte, source string, offset, length, width, height int, format string) models.RAWPreview {
+	preview := models.RAWPreview{
+		Source: source,
+		Format: format,
+		Width:  width,
+		Height: height,
+		Offset: offset,
+		Length: length,
+	}
+	if offset < 0 || length <= 0 || offset+length > len(data) {
+		return preview
+	}
+	body := data[offset : offset+length]
+	if len(body) >= 2 && body[0] == 0xFF && body[1] == markerSOI {
+		preview.Format = "JPEG"
+		if jpg := analyzeJPEG(body); jpg != nil && jpg.Width > 0 {
+			preview.Width, preview.Height = jpg.Width, jpg.Height
+		}
+	}
+	return preview
+}
+
+// tagIntOrRational returns value i of an integer or rational tag, rounded
+func tagIntOrRational(tag *tiff.Tag, i int) int {
+	if tag.Format() == tiff.RatVal {
+		if _, d, err := tag.Rat2(i); err == nil && d != 0 {
+			return int(ratValue(tag, i) + 0.5)
+		}
+		return 0
+	}
+	v, _ := tag.Int(i)
+	return v
+}
+
+// rawExifDir decodes the Exif IFD linked from IFD0
+func rawExifDir(data []byte, order binary.ByteOrder, ifd0 *tiff.Dir) *tiff.Dir {
+	tag := findTag(ifd0, tagExifIFDPointer)
+	if tag == nil {
+		return nil
+	}
+	offset, err := tag.Int64(0)
+	if err != nil {
+		return nil
+	}
+	dir, _ := decodeDirAt(data, order, offset)
+	return dir
+}
+
+// tiffCFAPattern reads the TIFF/EP CFARepeatPatternDim and CFAPattern tags
+func tiffCFAPattern(dir *tiff.Dir) string {
+	dim, pattern := findTag(dir, tagCFARepeatPatternDim), findTag(dir, tagCFAPattern)
+	if dim == nil || pattern == nil || dim.Count != 2 {
+		return ""
+	}
+	rows, _ := dim.Int(0)
+	cols, _ := dim.Int(1)
+	return cfaPatternString(rows, cols, pattern.Val)
+}
+
+// exifCFAPattern reads the EXIF CFAPattern tag: column and row counts
+// followed by the colors. Some cameras write the counts big-endian
+// regardless of the file's byte order.
+func exifCFAPattern(tag *tiff.Tag, order binary.ByteOrder) string {
+	if tag == nil || len(tag.Val) < 4 {
+		return ""
+	}
+	colors := tag.Val[4:]
+	for _, o := range []binary.ByteOrder{order, binary.BigEndian, binary.LittleEndian} {
+		cols, rows := int(o.Uint16(tag.Val)), int(o.Uint16(tag.Val[2:]))
+		if cols*rows == len(colors) {
+			return cfaPatternString(rows, cols, colors)
+		}
+	}
+	return ""
+}
+
+// cfaPatternString spells a CFA pattern as color letters, e.g. "RGGB".
+// Patterns larger than 2×2 list each row separated by "/".
+func cfaPatternString(rows, cols int, colors []byte) string {
+	if rows <= 0 || cols <= 0 || rows*cols != len(colors) {
+		return ""
+	}
+	var sb strings.Builder
+	for i, c := range colors {
+		if i > 0 && i%cols == 0 && rows*cols > 4 {
+			sb.WriteByte('/')
+		}
+		if int(c) < len(cfaColors) {
+			sb.WriteByte(cfaColors[c])
+		} else {
+			sb.WriteByte('?')
+		}
+	}
+	return sb.String()
+}
+
+// parseRAF reads the Fujifilm RAF header, its embedded JPEG preview and
+// the sensor sizes from the header directory
+func parseRAF(data []byte) *models.RAWInfo {
+	if len(data) < 108 {
+		return nil
+	}
+	info := &models.RAWInfo{
+		Container: "RAF",
+		Camera:    iccASCII(data[28:60]),
+	}
+
+	jpegOffset := int(binary.BigEndian.Uint32(data[84:]))
+	jpegLength := int(binary.BigEndian.Uint32(data[88:]))
+	if jpegLength > 0 {
+		info.Previews = append(info.Previews, rawPreview(data, "RAF header", jpegOffset, jpegLength, 0, 0, ""))
+	}
+
+	// The directory holds big-endian records: tag, size, value
+	dir := int(binary.BigEndian.Uint32(data[92:]))
+	if dir <= 0 || dir+4 > len(data) {
+		return info
+	}
+	count := int(binary.BigEndian.Uint32(data[dir:]))
+	pos := dir + 4
+	for i := 0; i < count && pos+4 <= len(data); i++ {
+		tag := binary.BigEndian.Uint16(data[pos:])
+		size := int(binary.BigEndian.Uint16(data[pos+2:]))
+		pos += 4
+		if pos+size > len(data) {
+			break
+		}
+		value := data[pos : pos+size]
+		pos += size
+
+		switch {
+		case tag == rafTagFullSize && size == 4:
+			info.SensorHeight = int(binary.BigEndian.Uint16(value))
+			info.SensorWidth = int(binary.BigEndian.Uint16(value[2:]))
+			info.SensorSource = "RAF header"
+		case tag == rafTagCroppedSize && size == 4:
+			info.CropHeight = int(binary.BigEndian.Uint16(value))
+			info.CropWidth = int(binary.BigEndian.Uint16(value[2:]))
+		case tag == rafTagXTrans && size == 36:
+			info.CFAPattern = cfaPatternString(6, 6, value)
+		}
+	}
+	return info
+}
+
+// rafJPEG returns the embedded JPEG, which carries the EXIF block
+func rafJPEG(data []byte) []byte {
+	if len(data) < 92 {
+		return nil
+	}
+	offset := int(binary.BigEndian.Uint32(data[84:]))
+	length := int(binary.BigEndian.Uint32(data[88:]))
+	if offset <= 0 || length <= 0 || offset+length > len(data) {
+		return nil
+	}
+	return data[offset : offset+length]
+}
+
+// cr3MetadataBoxes returns the children of Canon's metadata uuid box in
+// moov: CMT1-CMT4 hold TIFF structures and THMB the thumbnail
+func cr3MetadataBoxes(data []byte) []bmffBox {
+	moov := findBox(readBoxes(data), "moov")
+	if moov == nil {
+		return nil
+	}
+	for _, box := range readBoxes(moov.data) {
+		if box.typ != "uuid" {
+			continue
+		}
+		if children := readBoxes(box.data); findBox(children, "CMT1") != nil {
+			return children
+		}
+	}
+	return nil
+}
+
+// parseCR3 reads the CRAW sample entries, thumbnail and preview of a
+// Canon CR3 file
+func parseCR3(data []byte) *models.RAWInfo {
+	top := readBoxes(data)
+	moov := findBox(top, "moov")
+	if moov == nil {
+		return nil
+	}
+	info := &models.RAWInfo{Container: "ISOBMFF", Compression: "Canon CRX"}
+
+	boxes := cr3MetadataBoxes(data)
+	if cmt1 := findBox(boxes, "CMT1"); cmt1 != nil {
+		if t, err := decodeTIFF(cmt1.data); err == nil && len(t.Dirs) > 0 {
+			info.Camera = cameraName(t.Dirs[0])
+		}
+	}
+	if thmb := findBox(boxes, "THMB"); thmb != nil {
+		info.Previews = append(info.Previews, cr3Preview(data, "THMB", thmb.data))
+	}
+
+	// Each track holds one image: a full-size JPEG, a small raw and the
+	// full raw, whose sample entry carries a CMP1 box
+	track := 0
+	for _, trak := range readBoxes(moov.data) {
+		if trak.typ != "trak" {
+			continue
+		}
+		track++
+		entry := cr3SampleEntry(trak.data)
+		if len(entry) < 28 {
+			continue
+		}
+		width := int(binary.BigEndian.Uint16(entry[24:]))
+		height := int(binary.BigEndian.Uint16(entry[26:]))
+		source := fmt.Sprintf("trak%d", track)
+		switch {
+		case bytes.Contains(entry, []byte("CMP1")):
+			if width*height > info.SensorWidth*info.SensorHeight {
+				info.SensorWidth, info.SensorHeight = width, height
+				info.SensorSource = source
+			}
+		case bytes.Contains(entry, []byte("JPEG")):
+			info.Previews = append(info.Previews, models.RAWPreview{Source: source, Format: "JPEG", Width: width, Height: height})
+		}
+	}
+
+	// The preview sits in a top-level uuid box after an 8-byte header
+	for _, box := range top {
+		if box.typ != "uuid" || len(box.data) < 8 {
+			continue
+		}
+		if prvw := findBox(readBoxes(box.data[8:]), "PRVW"); prvw != nil {
+			info.Previews = append(info.Previews, cr3Preview(data, "PRVW", prvw.data))
+		}
+	}
+
+	return info
+}
+
+// cr3SampleEntry returns the CRAW sample entry from a trak box
+func cr3SampleEntry(trak []byte) []byte {
+	box := &bmffBox{data: trak}
+	for _, typ := range []string{"mdia", "minf", "stbl", "stsd"} {
+		if box = findBox(readBoxes(box.data), typ); box == nil {
+			return nil
+		}
+	}
+	_, _, body, ok := fullBoxHeader(box.data)
+	if !ok || len(body) < 4 {
+		return nil
+	}
+	if entry := findBox(readBoxes(body[4:]), "CRAW"); entry != nil {
+		return entry.data
+	}
+	return nil
+}
+
+// cr3Preview describes the JPEG in a THMB or PRVW box, which follows a
+// short header holding its size
+func cr3Preview(data []byte, source string, body []byte) models.RAWPreview {
+	start := bytes.Index(body, []byte{0xFF, markerSOI, 0xFF})
+	if start < 0 {
+		return models.RAWPreview{Source: source, Format: "JPEG"}
+	}
+	jpg := body[start:]
+	return rawPreview(data, source, fileOffset(data, jpg), len(jpg), 0, 0, "JPEG")
+}
+
+// loadCR3Dirs adds the Exif and GPS IFDs, which CR3 stores as separate
+// TIFF structures in CMT2 and CMT4, to the IFD0 decoded from CMT1
+func loadCR3Dirs(data []byte, x *exif.Exif, dirs *exifDirs) {
+	if dirs == nil || sniffFormat(data) != formatCR3 {
+		return
+	}
+	boxes := cr3MetadataBoxes(data)
+	load := func(typ string, names map[uint16]string) (*tiff.Dir, []byte) {
+		box := findBox(boxes, typ)
+		if box == nil {
+			return nil, nil
+		}
+		t, err := decodeTIFF(box.data)
+		if err != nil || len(t.Dirs) == 0 {
+			return nil, nil
+		}
+		fields := make(map[uint16]exif.FieldName, len(names))
+		for id, name := range names {
+			fields[id] = exif.FieldName(name)
+		}
+		x.LoadTags(t.Dirs[0], fields, false)
+		return t.Dirs[0], box.data
+	}
+	if dir, raw := load("CMT2", exifTagNames); dir != nil {
+		dirs.exif, dirs.raw = dir, raw
+	}
+	if dir, _ := load("CMT4", gpsTagNames); dir != nil {
+		dirs.gps = dir
+	}
+}
+
+// decodeImageConfig reads the dimensions and format name. Camera raw
+// files are measured from their sensor image, since the TIFF decoder would
+// report the IFD0 thumbnail or fail on the raw SubIFDs. SVG has no magic
+// bytes to register with the image package, so it is sniffed here. The
+// parsed raw layout is returned so later extractors need not walk the IFDs
+// again.
+func decodeImageConfig(data []byte) (image.Config, string, *models.RAWInfo, error) {
+	raw := parseRAW(data)
+	if raw != nil && raw.SensorWidth > 0 {
+		return image.Config{Width: raw.SensorWidth, Height: raw.SensorHeight}, strings.ToLower(raw.Format), raw, nil
+	}
+	if sniffFormat(data) == formatSVG {
+		if cfg, err := decodeSVGConfig(data); err == nil {
+			return cfg, formatSVG, raw, nil
+		}
+	}
+	cfg, name, err := image.DecodeConfig(bytes.NewReader(data))
+	return cfg, name, raw, err
+}
+
+// extractRAW fills the raw section, MIME type and sensor bit depth from the
+// layout decodeImageConfig parsed
+func extractRAW(_ []byte, result *Result) error {
+	raw := result.raw
+	if raw == nil {
+		return nil
+	}
+	meta := result.Metadata
+	meta.RAW = raw
+	format := strings.ToLower(raw.Format)
+	if meta.MIMEType == "" || meta.MIMEType == "application/octet-stream" || meta.MIMEType == "image/tiff" {
+		meta.MIMEType = rawMIMETypes[format]
+	}
+	if raw.BitsPerSample > 0 {
+		meta.BitsPerSample = fmt.Sprintf("%d", raw.BitsPerSample)
+	}
+	if raw.Photometric != "" {
+		meta.ColorMode = raw.Photometric
+	}
+	return nil
+}
@@ -0,0 +1,184 @@
+package metadata
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+func byteTag(id uint16, b ...byte) testTag {
+	return testTag{id: id, typ: 1, count: uint32(len(b)), value: b}
+}
+
+func shortsTag(id uint16, vals ...uint16) testTag {
+	b := make([]byte, 2*len(vals))
+	for i, v := range vals {
+		binary.LittleEndian.PutUint16(b[2*i:], v)
+	}
+	return testTag{id: id, typ: 3, count: uint32(len(vals)), value: b}
+}
+
+func TestExtractMetadataDNG(t *testing.T) {
+	data := buildTIFF([]testIFD{
+		{
+			tags: []testTag{
+				shortTag(0x00FE, 1),
+				shortTag(0x0100, 256),
+				shortTag(0x0101, 171),
+				shortTag(0x0103, 1),
+				shortTag(0x0106, 2),
+				asciiTag(0x010F, "Canon"),
+				asciiTag(0x0110, "EOS R5"),
+				byteTag(0xC612, 1, 4, 0, 0),
+			},
+			pointers: map[uint16]int{0x014A: 1},
+		},
+		{
+			tags: []testTag{
+				shortTag(0x00FE, 0),
+				shortTag(0x0100, 6000),
+				shortTag(0x0101, 4000),
+				shortTag(0x0102, 14),
+				shortTag(0x0103, 7),
+				shortTag(0x0106, 32803),
+				shortsTag(0x828D, 2, 2),
+				byteTag(0x828E, 0, 1, 1, 2),
+				shortsTag(0xC620, 5984, 3992),
+			},
+		},
+	})
+
+	meta := ExtractMetadata(data, "application/octet-stream", "IMG_0001.dng")
+	if meta.DecodeError != "" {
+		t.Fatalf("DecodeError = %q", meta.DecodeError)
+	}
+	if meta.Format != "dng" || meta.Width != 6000 || meta.Height != 4000 || meta.MIMEType != "image/x-adobe-dng" {
+		t.Errorf("Format = %q, size = %dx%d, MIMEType = %q", meta.Format, meta.Width, meta.Height, meta.MIMEType)
+	}
+	if meta.Camera == nil || meta.Camera.Make != "Canon" {
+		t.Errorf("Camera = %+v", meta.Camera)
+	}
+
+	raw := meta.RAW
+	if raw == nil {
+		t.Fatal("expected RAW section")
+	}
+	if raw.DNGVersion != "1.4.0.0" || raw.CFAPattern != "RGGB" || raw.BitsPerSample != 14 || raw.SensorSource != "SubIFD0" {
+		t.Errorf("RAW = %+v", raw)
+	}
+	if raw.CropWidth != 5984 || raw.CropHeight != 3992 || raw.Photometric != "Color Filter Array" {
+		t.Errorf("crop = %dx%d, Photometric = %q", raw.CropWidth, raw.CropHeight, raw.Photometric)
+	}
+	if len(raw.Previews) != 1 || raw.Previews[0].Source != "IFD0" || raw.Previews[0].Width != 256 {
+		t.Errorf("Previews = %+v", raw.Previews)
+	}
+}
+
+func TestExtractMetadataRAF(t *testing.T) {
+	preview := buildJPEGWithSegments(t, exifSegment(buildTIFF([]testIFD{{tags: []testTag{asciiTag(0x010F, "FUJIFILM")}}})))
+	xtrans := []byte("GGRGGBGGBGGRBRGRBGGGBGGRGGRGGBRBGBRG")
+	for i, c := range xtrans {
+		xtrans[i] = map[byte]byte{'R': 0, 'G': 1, 'B': 2}[c]
+	}
+
+	var dir []byte
+	dir = append(dir, u32(3)...)
+	dir = append(dir, append(append(u16(0x0100), u16(4)...), append(u16(4160), u16(6240)...)...)...)
+	dir = append(dir, append(append(u16(0x0111), u16(4)...), append(u16(4000), u16(6000)...)...)...)
+	dir = append(dir, append(append(u16(0x0131), u16(36)...), xtrans...)...)
+
+	header := make([]byte, 108)
+	copy(header, rafSignature)
+	copy(header[28:], "X-T5")
+	jpegOffset := len(header) + len(dir)
+	binary.BigEndian.PutUint32(header[84:], uint32(jpegOffset))
+	binary.BigEndian.PutUint32(header[88:], uint32(len(preview)))
+	binary.BigEndian.PutUint32(header[92:], uint32(len(header)))
+	data := append(append(header, dir...), preview...)
+
+	meta := ExtractMetadata(data, "", "DSCF0001.RAF")
+	if meta.Format != "raf" || meta.Width != 6240 || meta.Height != 4160 {
+		t.Errorf("Format = %q, size = %dx%d", meta.Format, meta.Width, meta.Height)
+	}
+	if meta.Camera == nil || meta.Camera.Make != "FUJIFILM" {
+		t.Errorf("Camera = %+v, want EXIF from the embedded JPEG", meta.Camera)
+	}
+	raw := meta.RAW
+	if raw == nil {
+		t.Fatal("expected RAW section")
+	}
+	if raw.Camera != "X-T5" || raw.CropWidth != 6000 || raw.CFAPattern != "GGRGGB/GGBGGR/BRGRBG/GGBGGR/GGRGGB/RBGBRG" {
+		t.Errorf("RAW = %+v", raw)
+	}
+	if len(raw.Previews) != 1 || raw.Previews[0].Format != "JPEG" || raw.Previews[0].Width != 16 || raw.Previews[0].Offset != jpegOffset {
+		t.Errorf("Previews = %+v", raw.Previews)
+	}
+}
+
+func TestExtractMetadataCR3(t *testing.T) {
+	thumb := buildJPEGWithSegments(t)
+	cmt1 := buildTIFF([]testIFD{{tags: []testTag{asciiTag(0x010F, "Canon"), asciiTag(0x0110, "Canon EOS R6")}}})
+	cmt2 := buildTIFF([]testIFD{{tags: []testTag{rationalTag(0x829A, 1, 250)}}})
+
+	canon := bmff("uuid", make([]byte, 16),
+		bmff("CMT1", cmt1),
+		bmff("CMT2", cmt2),
+		bmff("THMB", u32(0), u16(16), u16(8), u32(uint32(len(thumb))), u32(0), thumb),
+	)
+	craw := bmff("CRAW", make([]byte, 24), u16(6888), u16(4546), make([]byte, 54), bmff("CMP1", make([]byte, 8)))
+	stsd := fullBox("stsd", 0, 0, u32(1), craw)
+	trak := bmff("trak", bmff("mdia", bmff("minf", bmff("stbl", stsd))))
+	data := append(bmff("ftyp", []byte("crx "), u32(1), []byte("crx isom")), bmff("moov", canon, trak)...)
+
+	meta := ExtractMetadata(data, "", "IMG_0001.CR3")
+	if meta.Format != "cr3" || meta.Width != 6888 || meta.Height != 4546 || meta.MIMEType != "image/x-canon-cr3" {
+		t.Errorf("Format = %q, size = %dx%d, MIMEType = %q", meta.Format, meta.Width, meta.Height, meta.MIMEType)
+	}
+	if meta.Camera == nil || meta.Camera.Make != "Canon" || meta.Camera.ExposureTime != "1/250 s" {
+		t.Errorf("Camera = %+v, want IFD0 from CMT1 and exposure from CMT2", meta.Camera)
+	}
+	raw := meta.RAW
+	if raw == nil || raw.Camera != "Canon EOS R6" || raw.SensorSource != "trak1" {
+		t.Fatalf("RAW = %+v", raw)
+	}
+	if len(raw.Previews) != 1 || raw.Previews[0].Source != "THMB" || raw.Previews[0].Width != 16 ||
+		data[raw.Previews[0].Offset] != 0xFF {
+		t.Errorf("Previews = %+v", raw.Previews)
+	}
+}
+
+// overflowingTIFF returns a TIFF block whose IFD0 holds one LONG entry with
+// a count that wraps around uint32 to 4 bytes when multiplied by the type
+// size; goexif would still allocate a value slice of the full count
+func overflowingTIFF() []byte {
+	data := make([]byte, 90)
+	copy(data, "II*\x00\x08\x00\x00\x00\x01\x00")
+	binary.LittleEndian.PutUint16(data[10:], 0x0100)
+	binary.LittleEndian.PutUint16(data[12:], 4)
+	binary.LittleEndian.PutUint32(data[14:], 0x40000001)
+	return data
+}
+
+func TestOverflowingTIFFCount(t *testing.T) {
+	data := overflowingTIFF()
+	if dir, _ := decodeDirAt(data, binary.LittleEndian, 8); dir != nil {
+		t.Error("decodeDirAt accepted an entry larger than the file")
+	}
+	if _, err := decodeTIFF(data); err == nil {
+		t.Error("decodeTIFF accepted an entry larger than the file")
+	}
+
+	// The same entry in IFD0 of a JPEG, and in an Exif IFD that IFD0 points to
+	exifIFD := buildTIFF([]testIFD{
+		{pointers: map[uint16]int{tagExifIFDPointer: 1}},
+		{tags: []testTag{{id: 0x9000, typ: 4, count: 0x40000001, value: make([]byte, 4)}}},
+	})
+	for _, block := range [][]byte{data, exifIFD} {
+		meta := ExtractMetadata(buildJPEGWithSegments(t, exifSegment(block)), "image/jpeg", "overflow.jpg")
+		if meta.DecodeError != "" || meta.Width != 16 {
+			t.Fatalf("JPEG not decoded: %q", meta.DecodeError)
+		}
+		if meta.EXIF != nil {
+			t.Errorf("EXIF decoded from an oversized entry: %+v", meta.EXIF)
+		}
+	}
+}
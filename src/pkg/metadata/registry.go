@@ -0,0 +1,186 @@
+package metadata
+
+import (
+	"fmt"
+	"image"
+	"sync"
+	"time"
+
+	"github.com/ahrdadan/image-metadata-viewer/src/internal/models"
+	"github.com/ahrdadan/image-metadata-viewer/src/internal/utils"
+)
+
+// Extractor reads one kind of metadata from the files it recognises.
+// Extractors run in registration order once the image header has been
+// decoded, so each one sees what the earlier ones contributed.
+type Extractor interface {
+	// Name identifies the extractor in ExtractorErrors
+	Name() string
+	// Sniff reports whether the extractor applies to data
+	Sniff(data []byte) bool
+	// Extract adds to result; an error is recorded and the remaining
+	// extractors still run
+	Extract(data []byte, result *Result) error
+}
+
+// Result is the metadata being assembled for one file
+type Result struct {
+	Metadata *models.ImageMetadata
+	Config   image.Config // header decoded by image.DecodeConfig or a format parser
+
+	raw       *models.RAWInfo // camera raw layout parsed with the header
+	inflation *inflateBudget
+}
+
+// budget returns the zlib inflation budget the extractors of the file share
+func (r *Result) budget() *inflateBudget {
+	if r.inflation == nil {
+		r.inflation = newInflateBudget()
+	}
+	return r.inflation
+}
+
+// Set stores a value under key in the Extensions section. Extractors
+// registered from outside this module use it for results that have no
+// field of their own.
+func (r *Result) Set(key string, value any) {
+	if r.Metadata.Extensions == nil {
+		r.Metadata.Extensions = make(map[string]any)
+	}
+	r.Metadata.Extensions[key] = value
+}
+
+// funcExtractor is an Extractor built from plain functions
+type funcExtractor struct {
+	name    string
+	sniff   func([]byte) bool
+	extract func([]byte, *Result) error
+}
+
+func (e funcExtractor) Name() string                              { return e.name }
+func (e funcExtractor) Sniff(data []byte) bool                    { return e.sniff(data) }
+func (e funcExtractor) Extract(data []byte, result *Result) error { return e.extract(data, result) }
+
+// NewExtractor builds an Extractor from a sniff and an extract function.
+// A nil sniff matches every file.
+func NewExtractor(name string, sniff func([]byte) bool, extract func([]byte, *Result) error) Extractor {
+	if sniff == nil {
+		sniff = anyFormat
+	}
+	return funcExtractor{name: name, sniff: sniff, extract: extract}
+}
+
+// Registry runs an ordered list of extractors
+type Registry struct {
+	mu         sync.RWMutex
+	extractors []Extractor
+}
+
+// NewRegistry returns a registry that runs the given extractors in order
+func NewRegistry(extractors ...Extractor) *Registry {
+	return &Registry{extractors: append([]Extractor(nil), extractors...)}
+}
+
+// DefaultRegistry holds the built-in extractors and backs ExtractMetadata
+var DefaultRegistry = NewRegistry(BuiltinExtractors()...)
+
+// Register adds an extractor to DefaultRegistry, after the built-in ones
+func Register(e Extractor) {
+	DefaultRegistry.Register(e)
+}
+
+// Register appends an extractor; it runs after those already registered
+func (r *Registry) Register(e Extractor) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.extractors = append(r.extractors, e)
+}
+
+// Extractors returns the registered extractors in the order they run
+func (r *Registry) Extractors() []Extractor {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return append([]Extractor(nil), r.extractors...)
+}
+
+// Extract decodes the image header and runs every extractor whose Sniff
+// matches. A header that cannot be decoded is reported in DecodeError and
+// stops extraction; extractor failures and panics are listed per extractor
+// in ExtractorErrors.
+func (r *Registry) Extract(data []byte, contentType, fileName string) *models.ImageMetadata {
+	meta := &models.ImageMetadata{
+		FileName:          fileName,
+		FileSize:          int64(len(data)),
+		FileSizeHuman:     utils.HumanBytes(int64(len(data))),
+		MIMEType:          contentType,
+		DeclaredMIMEType:  contentType,
+		FileTypeExtension: utils.ExtensionFromName(fileName),
+		UploadedAt:        time.Now(),
+	}
+
+	// Decode image config for basic dimensions
+	cfg, format, raw, err := decodeImageConfig(data)
+	if err != nil {
+		meta.DecodeError = err.Error()
+		return meta
+	}
+	setDimensions(meta, cfg, format)
+
+	result := &Result{Metadata: meta, Config: cfg, raw: raw}
+	for _, e := range r.Extractors() {
+		if err := runExtractor(e, data, result); err != nil {
+			meta.ExtractorErrors = append(meta.ExtractorErrors, models.ExtractorError{
+				Extractor: e.Name(),
+				Error:     err.Error(),
+			})
+		}
+	}
+	return meta
+}
+
+// runExtractor calls one extractor, turning a panic into an error so a
+// faulty extractor cannot take down the request
+func runExtractor(e Extractor, data []byte, result *Result) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	if !e.Sniff(data) {
+		return nil
+	}
+	return e.Extract(data, result)
+}
+
+// anyFormat matches every file
+func anyFormat([]byte) bool {
+	return true
+}
+
+// formatIs matches files of the given container formats
+func formatIs(formats ...string) func([]byte) bool {
+	return func(data []byte) bool {
+		format := sniffFormat(data)
+		for _, f := range formats {
+			if format == f {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// infallible adapts an extract step that reports nothing
+func infallible(extract func([]byte, *models.ImageMetadata)) func([]byte, *Result) error {
+	return func(data []byte, result *Result) error {
+		extract(data, result.Metadata)
+		return nil
+	}
+}
+
+// fallible adapts an extract step that can fail
+func fallible(extract func([]byte, *models.ImageMetadata) error) func([]byte, *Result) error {
+	return func(data []byte, result *Result) error {
+		return extract(data, result.Metadata)
+	}
+}
@@ -0,0 +1,71 @@
+package metadata
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestRegistryCustomExtractors(t *testing.T) {
+	var order []string
+	trace := func(name string) func([]byte, *Result) error {
+		return func([]byte, *Result) error {
+			order = append(order, name)
+			return nil
+		}
+	}
+
+	r := NewRegistry(BuiltinExtractors()...)
+	r.Register(NewExtractor("dam", nil, func(_ []byte, result *Result) error {
+		order = append(order, "dam")
+		if result.Metadata.XMP == nil || !strings.Contains(result.Metadata.XMP.Raw, "Harbour") {
+			return errors.New("expected the built-in XMP result")
+		}
+		result.Set("dam", map[string]string{"assetId": "A-1"})
+		return nil
+	}))
+	r.Register(NewExtractor("jpeg-only", formatIs(formatJPEG), trace("jpeg-only")))
+	r.Register(NewExtractor("broken", nil, func([]byte, *Result) error { return errors.New("bad block") }))
+	r.Register(NewExtractor("panics", nil, func([]byte, *Result) error { panic("index out of range") }))
+	r.Register(NewExtractor("last", nil, trace("last")))
+
+	itxt := append([]byte(xmpPNGKeyword+"\x00\x00\x00\x00\x00"), testXMPPacket...)
+	meta := r.Extract(buildPNGWithChunks(t, buildChunk("iTXt", itxt)), "image/png", "x.png")
+
+	if meta.DecodeError != "" || meta.Width == 0 {
+		t.Fatalf("DecodeError = %q, width = %d", meta.DecodeError, meta.Width)
+	}
+	if strings.Join(order, ",") != "dam,last" {
+		t.Errorf("ran %v, want the non-JPEG extractors in registration order", order)
+	}
+	if ext, ok := meta.Extensions["dam"].(map[string]string); !ok || ext["assetId"] != "A-1" {
+		t.Errorf("Extensions = %v", meta.Extensions)
+	}
+	if len(meta.ExtractorErrors) != 2 {
+		t.Fatalf("ExtractorErrors = %+v", meta.ExtractorErrors)
+	}
+	if e := meta.ExtractorErrors[0]; e.Extractor != "broken" || e.Error != "bad block" {
+		t.Errorf("first error = %+v", e)
+	}
+	if e := meta.ExtractorErrors[1]; e.Extractor != "panics" || !strings.HasPrefix(e.Error, "panic: ") {
+		t.Errorf("second error = %+v", e)
+	}
+
+	// The default registry is untouched
+	if len(DefaultRegistry.Extractors()) != len(BuiltinExtractors()) {
+		t.Errorf("DefaultRegistry has %d extractors", len(DefaultRegistry.Extractors()))
+	}
+}
+
+func TestExtractMetadataReportsBrokenEXIF(t *testing.T) {
+	// An APP1 Exif segment whose TIFF header is garbage
+	data := buildJPEGWithSegments(t, jpegSegment(markerAPP1, append([]byte("Exif\x00\x00"), bytes.Repeat([]byte{0xAB}, 16)...)))
+	meta := ExtractMetadata(data, "image/jpeg", "broken.jpg")
+	if meta.DecodeError != "" {
+		t.Fatalf("DecodeError = %q", meta.DecodeError)
+	}
+	if len(meta.ExtractorErrors) != 1 || meta.ExtractorErrors[0].Extractor != "exif" {
+		t.Errorf("ExtractorErrors = %+v", meta.ExtractorErrors)
+	}
+}
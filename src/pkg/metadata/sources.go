@@ -0,0 +1,411 @@
+package metadata
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ahrdadan/image-metadata-viewer/src/internal/models"
+	"github.com/ahrdadan/image-metadata-viewer/src/internal/utils"
+)
+
+// Source groups of SourcedMetadata, as named in composite values
+const (
+	groupFile      = "file"
+	groupHTTP      = "http"
+	groupEXIF      = "exif"
+	groupXMP       = "xmp"
+	groupIPTC      = "iptc"
+	groupICC       = "icc"
+	groupContainer = "container"
+)
+
+// formatMIMETypes are the MIME types of the formats image.DecodeConfig
+// reports; raw formats use rawMIMETypes
+var formatMIMETypes = map[string]string{
+	"jpeg": "image/jpeg",
+	"png":  "image/png",
+	"gif":  "image/gif",
+	"webp": "image/webp",
+	"tiff": "image/tiff",
+	"bmp":  "image/bmp",
+	"heic": "image/heic",
+	"avif": "image/avif",
+	"ico":  "image/x-icon",
+	"cur":  "image/x-win-cursor",
+	"svg":  "image/svg+xml",
+	"jxl":  "image/jxl",
+	"jp2":  "image/jp2",
+	"j2k":  "image/j2c",
+	"exr":  "image/x-exr",
+	"psd":  "image/vnd.adobe.photoshop",
+	"psb":  "image/vnd.adobe.photoshop",
+}
+
+// How candidate values of a composite field are compared
+const (
+	compareText = iota
+	compareDate // the date and time digits must match
+	compareList // the same items in any order
+	compareRaw  // the stored values must match, e.g. an enum and its name
+)
+
+// compositeRule lists where a composite field is read from, highest
+// precedence first. Descriptive fields prefer XMP, then IPTC-IIM, then
+// EXIF, since editors that update several blocks always update XMP.
+// Capture fields prefer EXIF, which the camera wrote, and the format
+// fields prefer the decoded image header over anything declared about it.
+type compositeRule struct {
+	field      string
+	compare    int
+	candidates [][2]string // group, key
+}
+
+var compositeRules = []compositeRule{
+	{"mimeType", compareText, [][2]string{{groupContainer, "mimeType"}, {groupHTTP, "Content-Type"}, {groupFile, "declaredMimeType"}}},
+	{"width", compareText, [][2]string{{groupContainer, "width"}, {groupEXIF, "PixelXDimension"}, {groupEXIF, "ImageWidth"}}},
+	{"height", compareText, [][2]string{{groupContainer, "height"}, {groupEXIF, "PixelYDimension"}, {groupEXIF, "ImageLength"}}},
+	{"orientation", compareRaw, [][2]string{{groupEXIF, "Orientation"}, {groupXMP, "tiff:Orientation"}}},
+	{"colorSpace", compareText, [][2]string{{groupICC, "description"}, {groupEXIF, "ColorSpace"}}},
+	{"make", compareText, [][2]string{{groupEXIF, "Make"}, {groupXMP, "tiff:Make"}}},
+	{"model", compareText, [][2]string{{groupEXIF, "Model"}, {groupXMP, "tiff:Model"}}},
+	{"lens", compareText, [][2]string{{groupEXIF, "LensModel"}, {groupXMP, "exifEX:LensModel"}, {groupXMP, "aux:Lens"}}},
+	{"createDate", compareDate, [][2]string{{groupEXIF, "DateTimeOriginal"}, {groupXMP, "photoshop:DateCreated"}, {groupXMP, "xmp:CreateDate"}, {groupIPTC, "DateCreated"}}},
+	{"modifyDate", compareDate, [][2]string{{groupEXIF, "DateTime"}, {groupXMP, "xmp:ModifyDate"}, {groupHTTP, "Last-Modified"}}},
+	{"software", compareText, [][2]string{{groupEXIF, "Software"}, {groupXMP, "xmp:CreatorTool"}, {groupIPTC, "OriginatingProgram"}}},
+	{"title", compareText, [][2]string{{groupXMP, "dc:title"}, {groupIPTC, "ObjectName"}}},
+	{"description", compareText, [][2]string{{groupXMP, "dc:description"}, {groupIPTC, "Caption-Abstract"}, {groupEXIF, "ImageDescription"}}},
+	{"creator", compareList, [][2]string{{groupXMP, "dc:creator"}, {groupIPTC, "By-line"}, {groupEXIF, "Artist"}}},
+	{"copyright", compareText, [][2]string{{groupXMP, "dc:rights"}, {groupIPTC, "CopyrightNotice"}, {groupEXIF, "Copyright"}}},
+	{"keywords", compareList, [][2]string{{groupXMP, "dc:subject"}, {groupIPTC, "Keywords"}}},
+	{"city", compareText, [][2]string{{groupXMP, "photoshop:City"}, {groupIPTC, "City"}}},
+	{"country", compareText, [][2]string{{groupXMP, "photoshop:Country"}, {groupIPTC, "Country-PrimaryLocationName"}}},
+	{"rating", compareText, [][2]string{{groupXMP, "xmp:Rating"}, {groupEXIF, "Rating"}}},
+}
+
+// GroupBySource rebuilds meta as values grouped by where they were read
+// from, each with its raw and display form, and resolves one composite
+// value per field
+func GroupBySource(meta *models.ImageMetadata) *models.SourcedMetadata {
+	s := &models.SourcedMetadata{
+		FileName:  meta.FileName,
+		File:      fileGroup(meta),
+		HTTP:      httpGroup(meta),
+		EXIF:      exifGroup(meta.EXIF),
+		XMP:       xmpGroup(meta.XMP),
+		IPTC:      iptcGroup(meta.IPTC),
+		ICC:       iccGroup(meta.ICCProfile),
+		Container: containerGroup(meta),
+	}
+	s.Composite = composite(s)
+	s.Consistency = meta.Consistency
+	s.AIProvenance = meta.AIProvenance
+
+	if meta.FetchError != "" {
+		s.Errors = append(s.Errors, models.ExtractorError{Extractor: "fetch", Error: meta.FetchError})
+	}
+	if meta.DecodeError != "" {
+		s.Errors = append(s.Errors, models.ExtractorError{Extractor: "decode", Error: meta.DecodeError})
+	}
+	s.Errors = append(s.Errors, meta.ExtractorErrors...)
+	return s
+}
+
+// set adds a value to g unless it is empty
+func (g valueGroup) set(key, source, raw, display string) {
+	if raw == "" && display == "" {
+		return
+	}
+	if display == "" {
+		display = raw
+	}
+	g[key] = models.SourcedValue{Source: source, Raw: raw, Display: display}
+}
+
+// valueGroup is models.ValueGroup with the set helper
+type valueGroup models.ValueGroup
+
+// group returns g, or nil when it holds no values so the section is omitted
+func (g valueGroup) group() models.ValueGroup {
+	if len(g) == 0 {
+		return nil
+	}
+	return models.ValueGroup(g)
+}
+
+// fileGroup holds what is known about the file before it is parsed
+func fileGroup(meta *models.ImageMetadata) models.ValueGroup {
+	g := valueGroup{}
+	g.set("fileName", "File name", meta.FileName, "")
+	g.set("extension", "File name", meta.FileTypeExtension, "")
+	g.set("fileSize", "File", strconv.FormatInt(meta.FileSize, 10), meta.FileSizeHuman)
+	if meta.Source != "remote" {
+		g.set("declaredMimeType", "Upload Content-Type", meta.DeclaredMIMEType, "")
+	}
+	return models.ValueGroup(g)
+}
+
+// httpGroup holds the response of a remote fetch
+func httpGroup(meta *models.ImageMetadata) models.ValueGroup {
+	if meta.Source != "remote" {
+		return nil
+	}
+	g := valueGroup{}
+	g.set("Status", "HTTP response", meta.Status, "")
+	g.set("Final-URL", "HTTP response", meta.FinalURL, "")
+	g.set("Content-Type", "HTTP header", meta.DeclaredMIMEType, "")
+	if meta.ContentLength > 0 {
+		g.set("Content-Length", "HTTP header", strconv.FormatInt(meta.ContentLength, 10), utils.HumanBytes(meta.ContentLength))
+	}
+	g.set("Last-Modified", "HTTP header", meta.LastModified, "")
+	if meta.DownloadedBytes > 0 {
+		g.set("downloadedBytes", "HTTP body", strconv.FormatInt(meta.DownloadedBytes, 10), utils.HumanBytes(meta.DownloadedBytes))
+	}
+	if meta.Truncated {
+		g.set("truncated", "HTTP body", "true", "")
+	}
+	g.set("duration", "HTTP response", meta.Duration, "")
+	return g.group()
+}
+
+// exifGroup keys tags by name. A name seen again in a later IFD, such as
+// IFD1's resolution, is keyed as IFD:Name.
+func exifGroup(data *models.EXIFData) models.ValueGroup {
+	if data == nil {
+		return nil
+	}
+	g := valueGroup{}
+	for _, tag := range data.Tags {
+		key := tag.Name
+		if _, ok := g[key]; ok {
+			key = tag.IFD + ":" + tag.Name
+		}
+		g.set(key, "EXIF "+tag.IFD, tag.RawValue, tag.Value)
+	}
+	return g.group()
+}
+
+// xmpItem is one array item or language alternative of an XMP property
+type xmpItem struct {
+	index string
+	value string
+}
+
+// xmpGroup keys properties by path. Arrays and language alternatives are
+// collected under the property itself; struct fields keep their full path.
+func xmpGroup(data *models.XMPData) models.ValueGroup {
+	if data == nil {
+		return nil
+	}
+	g := valueGroup{}
+	items := make(map[string][]xmpItem)
+	var order []string
+	prefixes := make(map[string]string)
+	for _, p := range data.Properties {
+		base, rest, found := strings.Cut(p.Path, "[")
+		index, tail, closed := strings.Cut(rest, "]")
+		if !found || !closed || tail != "" {
+			g.set(p.Path, "XMP "+p.Prefix, p.Value, "")
+			continue
+		}
+		if _, seen := items[base]; !seen {
+			order = append(order, base)
+		}
+		items[base] = append(items[base], xmpItem{index: index, value: p.Value})
+		prefixes[base] = p.Prefix
+	}
+
+	for _, base := range order {
+		list := items[base]
+		if _, err := strconv.Atoi(list[0].index); err == nil {
+			values := make([]string, len(list))
+			for i, item := range list {
+				values[i] = item.value
+			}
+			g.set(base, "XMP "+prefixes[base], strings.Join(values, "; "), strings.Join(values, ", "))
+			continue
+		}
+
+		// Language alternatives show x-default, or the first language
+		display := list[0].value
+		raw := make([]string, len(list))
+		for i, item := range list {
+			raw[i] = item.index + ": " + item.value
+			if item.index == "x-default" {
+				display = item.value
+			}
+		}
+		if len(list) == 1 {
+			raw[0] = list[0].value
+		}
+		g.set(base, "XMP "+prefixes[base], strings.Join(raw, "; "), display)
+	}
+	return g.group()
+}
+
+// iptcGroup keys datasets by name; repeated datasets such as Keywords are
+// joined in stream order
+func iptcGroup(data *models.IPTCData) models.ValueGroup {
+	if data == nil {
+		return nil
+	}
+	g := valueGroup{}
+	for _, ds := range data.Datasets {
+		source := fmt.Sprintf("IPTC %d:%d", ds.Record, ds.Dataset)
+		if prev, ok := g[ds.Name]; ok {
+			g.set(ds.Name, source, prev.Raw+"; "+ds.Value, prev.Display+", "+ds.Value)
+			continue
+		}
+		g.set(ds.Name, source, ds.Value, "")
+	}
+	return g.group()
+}
+
+// iccGroup holds the profile header fields and text tags
+func iccGroup(p *models.ICCProfile) models.ValueGroup {
+	if p == nil {
+		return nil
+	}
+	g := valueGroup{}
+	g.set("description", "ICC desc tag", p.Description, "")
+	g.set("copyright", "ICC cprt tag", p.Copyright, "")
+	for _, f := range []struct{ key, value string }{
+		{"version", p.Version},
+		{"deviceClass", p.DeviceClass},
+		{"colorSpace", p.ColorSpace},
+		{"pcs", p.PCS},
+		{"renderingIntent", p.RenderingIntent},
+		{"whitePoint", p.WhitePoint},
+		{"cmm", p.CMM},
+		{"platform", p.Platform},
+		{"manufacturer", p.Manufacturer},
+		{"model", p.Model},
+		{"creator", p.Creator},
+		{"created", p.Created},
+		{"profileID", p.ProfileID},
+	} {
+		g.set(f.key, "ICC header", f.value, "")
+	}
+	g.set("size", "ICC header", strconv.Itoa(p.Size), utils.HumanBytes(int64(p.Size)))
+	return g.group()
+}
+
+// containerGroup holds what the image header of the file format says
+func containerGroup(meta *models.ImageMetadata) models.ValueGroup {
+	if meta.Format == "" {
+		return nil
+	}
+	source := strings.ToUpper(meta.Format) + " header"
+	g := valueGroup{}
+	g.set("format", source, meta.Format, meta.FileType)
+
+	mimeType := formatMIMETypes[meta.Format]
+	if mimeType == "" {
+		mimeType = rawMIMETypes[meta.Format]
+	}
+	g.set("mimeType", source, mimeType, "")
+
+	if meta.Width > 0 && meta.Height > 0 {
+		g.set("width", source, strconv.Itoa(meta.Width), "")
+		g.set("height", source, strconv.Itoa(meta.Height), "")
+	}
+	g.set("bitsPerSample", source, meta.BitsPerSample, "")
+	g.set("colorMode", source, meta.ColorMode, "")
+	if meta.ColorComponents > 0 {
+		g.set("colorComponents", source, strconv.Itoa(meta.ColorComponents), "")
+	}
+	if meta.SamplesPerPixel > 0 {
+		g.set("samplesPerPixel", source, strconv.Itoa(meta.SamplesPerPixel), "")
+	}
+	g.set("encodingProcess", source, meta.EncodingProcess, "")
+	if meta.Animation != nil {
+		g.set("frameCount", source, strconv.Itoa(meta.Animation.FrameCount), "")
+	}
+	return g.group()
+}
+
+// composite picks one value per field by compositeRules and lists the
+// lower-precedence values that disagree with it
+func composite(s *models.SourcedMetadata) map[string]models.CompositeValue {
+	groups := map[string]models.ValueGroup{
+		groupFile:      s.File,
+		groupHTTP:      s.HTTP,
+		groupEXIF:      s.EXIF,
+		groupXMP:       s.XMP,
+		groupIPTC:      s.IPTC,
+		groupICC:       s.ICC,
+		groupContainer: s.Container,
+	}
+
+	out := make(map[string]models.CompositeValue)
+	for _, rule := range compositeRules {
+		var chosen *models.CompositeValue
+		for _, c := range rule.candidates {
+			v, ok := groups[c[0]][c[1]]
+			if !ok {
+				continue
+			}
+			if chosen == nil {
+				chosen = &models.CompositeValue{SourcedValue: v, Group: c[0], Key: c[1]}
+			} else if !sameValue(rule.compare, chosen.SourcedValue, v) {
+				chosen.Conflicts = append(chosen.Conflicts, v)
+			}
+		}
+		if chosen != nil {
+			out[rule.field] = *chosen
+		}
+	}
+	return out
+}
+
+// sameValue compares two values the way the field is compared
+func sameValue(compare int, va, vb models.SourcedValue) bool {
+	a, b := va.Display, vb.Display
+	switch compare {
+	case compareRaw:
+		return strings.TrimSpace(va.Raw) == strings.TrimSpace(vb.Raw)
+	case compareDate:
+		da, db := dateDigits(a), dateDigits(b)
+		n := min(len(da), len(db))
+		// A date without a time agrees with any time on that day
+		return n >= 8 && da[:n] == db[:n]
+	case compareList:
+		return normalizeList(a) == normalizeList(b)
+	}
+	return normalizeText(a) == normalizeText(b)
+}
+
+// dateDigits keeps the year to second digits of a date, whatever its
+// separators. HTTP dates are not numeric and never match.
+func dateDigits(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if r >= '0' && r <= '9' {
+			b.WriteRune(r)
+			if b.Len() == 14 {
+				break
+			}
+		} else if r == '+' || r == 'Z' || r == '.' {
+			if b.Len() >= 8 {
+				break
+			}
+		}
+	}
+	return b.String()
+}
+
+// normalizeText folds case and runs of white space
+func normalizeText(s string) string {
+	return strings.ToLower(strings.Join(strings.Fields(s), " "))
+}
+
+// normalizeList sorts the items of a comma- or semicolon-separated list
+func normalizeList(s string) string {
+	items := strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ';' })
+	for i, item := range items {
+		items[i] = normalizeText(item)
+	}
+	sort.Strings(items)
+	return strings.Join(items, "\x00")
+}
@@ -0,0 +1,93 @@
+package metadata
+
+import (
+	"testing"
+
+	"github.com/ahrdadan/image-metadata-viewer/src/internal/models"
+)
+
+func TestGroupBySource(t *testing.T) {
+	meta := &models.ImageMetadata{
+		FileName:         "harbour.jpg",
+		FileSize:         2048,
+		FileSizeHuman:    "2.0 KB",
+		FileType:         "JPEG",
+		DeclaredMIMEType: "image/png",
+		Source:           "upload",
+		Format:           "jpeg",
+		Width:            640,
+		Height:           480,
+		EXIF: &models.EXIFData{Tags: []models.EXIFTag{
+			{IFD: "IFD0", Name: "Software", RawValue: "Camera 1.0", Value: "Camera 1.0"},
+			{IFD: "IFD0", Name: "XResolution", RawValue: "72/1", Value: "72"},
+			{IFD: "IFD1", Name: "XResolution", RawValue: "96/1", Value: "96"},
+			{IFD: "ExifIFD", Name: "DateTimeOriginal", RawValue: "2024:05:01 10:20:30", Value: "2024:05:01 10:20:30"},
+			{IFD: "IFD0", Name: "Artist", RawValue: "Someone Else", Value: "Someone Else"},
+		}},
+		XMP: &models.XMPData{Properties: []models.XMPProperty{
+			{Prefix: "xmp", Path: "xmp:CreatorTool", Value: "Editor 2.0"},
+			{Prefix: "xmp", Path: "xmp:CreateDate", Value: "2024-05-01T10:20:30+02:00"},
+			{Prefix: "dc", Path: "dc:title[x-default]", Value: "Harbour"},
+			{Prefix: "dc", Path: "dc:title[de]", Value: "Hafen"},
+			{Prefix: "dc", Path: "dc:subject[1]", Value: "boats"},
+			{Prefix: "dc", Path: "dc:subject[2]", Value: "harbour"},
+			{Prefix: "dc", Path: "dc:creator[1]", Value: "Jane Doe"},
+			{Prefix: "xmpMM", Path: "xmpMM:History[1]/stEvt:action", Value: "saved"},
+		}},
+		IPTC: &models.IPTCData{Datasets: []models.IPTCDataset{
+			{Record: 2, Dataset: 25, Name: "Keywords", Value: "harbour"},
+			{Record: 2, Dataset: 25, Name: "Keywords", Value: "Boats"},
+			{Record: 2, Dataset: 80, Name: "By-line", Value: "Jane Doe"},
+		}},
+	}
+
+	s := GroupBySource(meta)
+
+	if got := s.EXIF["XResolution"]; got.Source != "EXIF IFD0" || got.Raw != "72/1" || got.Display != "72" {
+		t.Errorf("EXIF XResolution = %+v", got)
+	}
+	if got := s.EXIF["IFD1:XResolution"].Display; got != "96" {
+		t.Errorf("EXIF IFD1:XResolution = %q, want 96", got)
+	}
+	if got := s.XMP["dc:title"]; got.Display != "Harbour" || got.Raw != "x-default: Harbour; de: Hafen" {
+		t.Errorf("XMP dc:title = %+v", got)
+	}
+	if got := s.XMP["dc:subject"].Raw; got != "boats; harbour" {
+		t.Errorf("XMP dc:subject = %q", got)
+	}
+	if _, ok := s.XMP["xmpMM:History[1]/stEvt:action"]; !ok {
+		t.Error("XMP struct field is not kept under its full path")
+	}
+	if got := s.IPTC["Keywords"]; got.Source != "IPTC 2:25" || got.Raw != "harbour; Boats" {
+		t.Errorf("IPTC Keywords = %+v", got)
+	}
+	if s.HTTP != nil {
+		t.Errorf("HTTP group of an upload = %v, want none", s.HTTP)
+	}
+
+	tests := []struct {
+		field     string
+		group     string
+		display   string
+		conflicts int
+	}{
+		{"mimeType", groupContainer, "image/jpeg", 1},       // declared type differs
+		{"software", groupEXIF, "Camera 1.0", 1},            // XMP CreatorTool differs
+		{"createDate", groupEXIF, "2024:05:01 10:20:30", 0}, // same instant, other notation
+		{"title", groupXMP, "Harbour", 0},
+		{"keywords", groupXMP, "boats, harbour", 0}, // same items in another order
+		{"creator", groupXMP, "Jane Doe", 1},        // EXIF Artist differs
+		{"width", groupContainer, "640", 0},
+	}
+	for _, tt := range tests {
+		got, ok := s.Composite[tt.field]
+		if !ok {
+			t.Errorf("composite %s missing", tt.field)
+			continue
+		}
+		if got.Group != tt.group || got.Display != tt.display || len(got.Conflicts) != tt.conflicts {
+			t.Errorf("composite %s = %s %q with %d conflicts, want %s %q with %d",
+				tt.field, got.Group, got.Display, len(got.Conflicts), tt.group, tt.display, tt.conflicts)
+		}
+	}
+}
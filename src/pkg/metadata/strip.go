@@ -0,0 +1,402 @@
+package metadata
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"strconv"
+	"strings"
+
+	"github.com/ahrdadan/image-metadata-viewer/src/internal/models"
+)
+
+// exifJPEGSignature starts the APP1 segment holding EXIF
+var exifJPEGSignature = []byte("Exif\x00\x00")
+
+// nsDC is the Dublin Core namespace of XMP dc:rights
+const nsDC = "http://purl.org/dc/elements/1.1/"
+
+// VP8X feature flags
+const (
+	vp8xICC  = 0x20
+	vp8xEXIF = 0x08
+	vp8xXMP  = 0x04
+)
+
+// ErrStripUnsupported is returned by Strip for formats other than JPEG,
+// PNG and WebP
+var ErrStripUnsupported = errors.New("only JPEG, PNG and WebP files can be stripped")
+
+// StripKeep lists the metadata Strip leaves in a file
+type StripKeep struct {
+	ICC         bool // the ICC color profile
+	Orientation bool // the EXIF orientation
+	Copyright   bool // the copyright notice, written to EXIF and XMP
+}
+
+// ParseStripKeep reads a keep-list of icc, orientation and copyright.
+// Entries may be repeated or separated by commas.
+func ParseStripKeep(entries []string) (StripKeep, error) {
+	var keep StripKeep
+	for _, entry := range entries {
+		for _, name := range strings.Split(entry, ",") {
+			switch strings.ToLower(strings.TrimSpace(name)) {
+			case "":
+			case "icc":
+				keep.ICC = true
+			case "orientation":
+				keep.Orientation = true
+			case "copyright":
+				keep.Copyright = true
+			default:
+				return StripKeep{}, fmt.Errorf("cannot keep %q: keep must list icc, orientation or copyright", strings.TrimSpace(name))
+			}
+		}
+	}
+	return keep, nil
+}
+
+// embedded is the metadata written into a rewritten file
+type embedded struct {
+	exif      []byte // TIFF block
+	xmp       []byte // XMP packet
+	photoshop []byte // Photoshop image resources, JPEG only
+}
+
+// Strip removes EXIF, XMP, IPTC, comments, thumbnails and other metadata
+// from a JPEG, PNG or WebP file, except what keep lists, and returns the
+// cleaned file and its MIME type. The image data is copied as stored, not
+// re-encoded. Kept orientation and copyright values are rewritten into
+// minimal EXIF and XMP blocks.
+func Strip(data []byte, keep StripKeep) ([]byte, string, error) {
+	format := sniffFormat(data)
+	if format != formatJPEG && format != formatPNG && format != formatWebP {
+		return nil, "", ErrStripUnsupported
+	}
+
+	var m embedded
+	if keep.Orientation || keep.Copyright {
+		m = keptMetadata(ExtractMetadata(data, "", ""), keep)
+	}
+
+	switch format {
+	case formatJPEG:
+		out, err := stripJPEG(data, keep.ICC, m)
+		return out, "image/jpeg", err
+	case formatPNG:
+		out, err := stripPNG(data, keep.ICC, m)
+		return out, "image/png", err
+	default:
+		out, err := stripWebP(data, keep.ICC, m)
+		return out, "image/webp", err
+	}
+}
+
+// keptMetadata builds the EXIF and XMP blocks holding the orientation and
+// copyright of meta that keep asks for
+func keptMetadata(meta *models.ImageMetadata, keep StripKeep) embedded {
+	var entries []exifEntry
+	var fields []xmpField
+	if keep.Orientation {
+		if v, err := strconv.Atoi(ifd0Value(meta, "Orientation", true)); err == nil && v >= 1 && v <= 8 {
+			entries = append(entries, exifShort(tagOrientation, uint16(v)))
+		}
+	}
+	if keep.Copyright {
+		if c := copyrightNotice(meta); c != "" {
+			entries = append(entries, exifASCII(tagCopyright, c))
+			fields = append(fields, xmpField{namespace: nsDC, name: "rights", form: xmpAlt, values: []string{c}})
+		}
+	}
+
+	var m embedded
+	if len(entries) > 0 {
+		m.exif = encodeTIFF(entries)
+	}
+	if len(fields) > 0 {
+		m.xmp = encodeXMP(fields)
+	}
+	return m
+}
+
+// ifd0Value returns the raw or displayed value of an IFD0 tag, or ""
+func ifd0Value(meta *models.ImageMetadata, name string, raw bool) string {
+	if meta.EXIF == nil {
+		return ""
+	}
+	for _, tag := range meta.EXIF.Tags {
+		if tag.IFD == ifd0Name && tag.Name == name {
+			if raw {
+				return tag.RawValue
+			}
+			return tag.Value
+		}
+	}
+	return ""
+}
+
+// copyrightNotice returns the copyright from EXIF, XMP, IPTC or a PNG
+// text chunk, in that order
+func copyrightNotice(meta *models.ImageMetadata) string {
+	if c := strings.TrimSpace(ifd0Value(meta, "Copyright", false)); c != "" {
+		return c
+	}
+	if meta.XMP != nil && strings.TrimSpace(meta.XMP.Rights) != "" {
+		return strings.TrimSpace(meta.XMP.Rights)
+	}
+	if meta.IPTC != nil && strings.TrimSpace(meta.IPTC.CopyrightNotice) != "" {
+		return strings.TrimSpace(meta.IPTC.CopyrightNotice)
+	}
+	if meta.PNG != nil {
+		for _, t := range meta.PNG.Text {
+			if t.Keyword == "Copyright" && strings.TrimSpace(t.Value) != "" {
+				return strings.TrimSpace(t.Value)
+			}
+		}
+	}
+	return ""
+}
+
+// keepJPEGSegment reports whether a header segment survives stripping:
+// the tables and frame headers, JFIF, the Adobe color transform and, if
+// asked, the ICC profile
+func keepJPEGSegment(seg markerSegment, icc bool) bool {
+	switch {
+	case seg.marker == markerAPP0:
+		return bytes.HasPrefix(seg.data, []byte("JFIF\x00"))
+	case seg.marker == markerAPP2:
+		return icc && bytes.HasPrefix(seg.data, iccJPEGSignature)
+	case seg.marker == markerAPP14:
+		return bytes.HasPrefix(seg.data, []byte("Adobe"))
+	case seg.marker >= markerAPP0 && seg.marker <= markerAPP15, seg.marker == markerCOM:
+		return false
+	}
+	return true
+}
+
+// jpegSegmentBytes encodes a marker segment
+func jpegSegmentBytes(marker byte, payload []byte) []byte {
+	out := []byte{0xFF, marker}
+	out = binary.BigEndian.AppendUint16(out, uint16(len(payload)+2))
+	return append(out, payload...)
+}
+
+// jfifHeaderLen is the size of a JFIF APP0 payload without a thumbnail
+const jfifHeaderLen = 14
+
+// stripJPEG rebuilds a JPEG from the segments that survive stripping,
+// with m written right after SOI and JFIF. Whatever follows EOI, such as
+// MPF secondary images, is dropped.
+func stripJPEG(data []byte, icc bool, m embedded) ([]byte, error) {
+	segments := readJPEGSegments(data)
+	if len(segments) == 0 || segments[len(segments)-1].marker != markerSOS {
+		return nil, errors.New("JPEG has no image data")
+	}
+
+	var blocks []byte
+	if m.exif != nil {
+		blocks = append(blocks, jpegSegmentBytes(markerAPP1, append(append([]byte{}, exifJPEGSignature...), m.exif...))...)
+	}
+	if m.xmp != nil {
+		blocks = append(blocks, jpegSegmentBytes(markerAPP1, append(append([]byte{}, xmpJPEGSignature...), m.xmp...))...)
+	}
+
+	out := []byte{0xFF, markerSOI}
+	header := segments[:len(segments)-1]
+	if len(header) > 0 && header[0].marker == markerAPP0 && keepJPEGSegment(header[0], icc) {
+		// JFIF comes first; its thumbnail is dropped
+		jfif := header[0].data
+		if len(jfif) > jfifHeaderLen {
+			jfif = append(append([]byte{}, jfif[:jfifHeaderLen-2]...), 0, 0)
+		}
+		out = append(out, jpegSegmentBytes(markerAPP0, jfif)...)
+		header = header[1:]
+	}
+	out = append(out, blocks...)
+	for _, seg := range header {
+		if keepJPEGSegment(seg, icc) {
+			out = append(out, data[seg.offset:seg.offset+4+len(seg.data)]...)
+		}
+	}
+	return appendJPEGScans(out, data, segments[len(segments)-1].offset, icc), nil
+}
+
+// appendJPEGScans copies the scans from the first SOS at pos up to EOI,
+// applying keepJPEGSegment to the segments between progressive scans
+func appendJPEGScans(out, data []byte, pos int, icc bool) []byte {
+	start := pos // start of bytes not yet copied
+	for pos+1 < len(data) {
+		if data[pos] != 0xFF {
+			pos++
+			continue
+		}
+		marker := data[pos+1]
+		switch {
+		case marker == 0x00 || marker == 0xFF || marker >= 0xD0 && marker <= 0xD7:
+			// Stuffed byte, fill byte or restart marker within entropy data
+			pos++
+			if marker != 0xFF {
+				pos++
+			}
+			continue
+		case marker == markerEOI:
+			return append(out, data[start:pos+2]...)
+		}
+
+		if pos+4 > len(data) {
+			break
+		}
+		end := pos + 2 + int(binary.BigEndian.Uint16(data[pos+2:]))
+		if end > len(data) {
+			break
+		}
+		if !keepJPEGSegment(markerSegment{marker: marker, data: data[pos+4 : end]}, icc) {
+			out = append(out, data[start:pos]...)
+			start = end
+		}
+		pos = end
+	}
+	// A truncated stream is kept as far as it goes
+	return append(out, data[start:]...)
+}
+
+// pngRenderingChunks are the ancillary chunks that affect how the image
+// is displayed, including APNG frames; they survive stripping
+var pngRenderingChunks = map[string]bool{
+	"tRNS": true, "gAMA": true, "cHRM": true, "sRGB": true, "cICP": true, "mDCv": true, "cLLi": true,
+	"sBIT": true, "bKGD": true, "hIST": true, "sPLT": true, "pHYs": true,
+	"acTL": true, "fcTL": true, "fdAT": true,
+}
+
+// pngChunkBytes encodes a chunk with its CRC
+func pngChunkBytes(typ string, body []byte) []byte {
+	out := binary.BigEndian.AppendUint32(nil, uint32(len(body)))
+	out = append(out, typ...)
+	out = append(out, body...)
+	return binary.BigEndian.AppendUint32(out, crc32.ChecksumIEEE(out[4:]))
+}
+
+// pngXMPChunk stores an XMP packet in an uncompressed iTXt chunk
+func pngXMPChunk(packet []byte) []byte {
+	body := append([]byte(xmpPNGKeyword), 0, 0, 0, 0, 0)
+	return pngChunkBytes("iTXt", append(body, packet...))
+}
+
+// stripPNG rebuilds a PNG from its critical and rendering chunks, with m
+// written right after IHDR
+func stripPNG(data []byte, icc bool, m embedded) ([]byte, error) {
+	chunks := readPNGChunks(data)
+	if len(chunks) == 0 || chunks[0].typ != "IHDR" || chunks[len(chunks)-1].typ != "IEND" {
+		return nil, errors.New("PNG is truncated or does not start with IHDR")
+	}
+
+	out := append([]byte{}, pngSignature...)
+	for i, c := range chunks {
+		critical := c.typ[0] >= 'A' && c.typ[0] <= 'Z'
+		if critical || pngRenderingChunks[c.typ] || c.typ == "iCCP" && icc {
+			out = append(out, data[c.offset:c.offset+12+len(c.data)]...)
+		}
+		if i == 0 {
+			if m.exif != nil {
+				out = append(out, pngChunkBytes("eXIf", m.exif)...)
+			}
+			if m.xmp != nil {
+				out = append(out, pngXMPChunk(m.xmp)...)
+			}
+		}
+	}
+	return out, nil
+}
+
+// newVP8X builds the VP8X chunk that extends a simple WebP, whose first
+// chunk is its VP8 or VP8L bitstream
+func newVP8X(first riffChunk) ([]byte, error) {
+	width, height, alpha, ok := webpCanvas(first)
+	if !ok {
+		return nil, errors.New("WebP image header cannot be read")
+	}
+	var flags byte
+	if alpha {
+		flags = vp8xAlpha
+	}
+	vp8x := []byte{flags, 0, 0, 0}
+	vp8x = binary.LittleEndian.AppendUint32(vp8x, uint32(width-1))[:7]
+	vp8x = binary.LittleEndian.AppendUint32(vp8x, uint32(height-1))[:10]
+	return vp8x, nil
+}
+
+// appendRIFFChunk encodes a RIFF chunk, padded to an even size
+func appendRIFFChunk(out []byte, fourCC string, body []byte) []byte {
+	out = append(out, fourCC...)
+	out = binary.LittleEndian.AppendUint32(out, uint32(len(body)))
+	out = append(out, body...)
+	if len(body)%2 == 1 {
+		out = append(out, 0)
+	}
+	return out
+}
+
+// stripWebP rebuilds a WebP from its image, animation and, if asked, ICC
+// chunks, with m appended and the VP8X flags updated. A simple WebP
+// without VP8X cannot carry metadata, so m is only written to extended
+// files.
+func stripWebP(data []byte, icc bool, m embedded) ([]byte, error) {
+	chunks := readWebPChunks(data)
+	if len(chunks) == 0 {
+		return nil, errors.New("WebP has no chunks")
+	}
+
+	var body []byte
+	flagsAt := -1
+	iccKept := false
+	for _, c := range chunks {
+		switch c.fourCC {
+		case "VP8X":
+			if len(c.data) < 10 {
+				return nil, errors.New("VP8X chunk is truncated")
+			}
+			flagsAt = len(body) + 8
+		case "ICCP":
+			if !icc {
+				continue
+			}
+			iccKept = true
+		case "VP8 ", "VP8L", "ALPH", "ANIM", "ANMF":
+		default:
+			continue
+		}
+		body = appendRIFFChunk(body, c.fourCC, c.data)
+	}
+
+	if flagsAt < 0 && (m.exif != nil || m.xmp != nil) {
+		// A simple WebP needs a VP8X chunk before it can carry metadata
+		vp8x, err := newVP8X(chunks[0])
+		if err != nil {
+			return nil, err
+		}
+		body = append(appendRIFFChunk(nil, "VP8X", vp8x), body...)
+		flagsAt = 8
+	}
+	if flagsAt >= 0 {
+		flags := body[flagsAt] &^ (vp8xICC | vp8xEXIF | vp8xXMP)
+		if iccKept {
+			flags |= vp8xICC
+		}
+		if m.exif != nil {
+			flags |= vp8xEXIF
+			body = appendRIFFChunk(body, "EXIF", m.exif)
+		}
+		if m.xmp != nil {
+			flags |= vp8xXMP
+			body = appendRIFFChunk(body, "XMP ", m.xmp)
+		}
+		body[flagsAt] = flags
+	}
+
+	out := []byte("RIFF")
+	out = binary.LittleEndian.AppendUint32(out, uint32(4+len(body)))
+	out = append(out, "WEBP"...)
+	return append(out, body...), nil
+}
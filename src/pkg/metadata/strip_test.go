@@ -0,0 +1,217 @@
+package metadata
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image/jpeg"
+	"image/png"
+	"reflect"
+	"testing"
+)
+
+func TestStripJPEG(t *testing.T) {
+	tiffData := buildTIFF([]testIFD{
+		{
+			tags: []testTag{
+				shortTag(0x0112, 6),
+				asciiTag(0x013B, "Jane Doe"),
+				asciiTag(0x8298, "Copyright 2025 Jane Doe"),
+			},
+			pointers: map[uint16]int{tagGPSIFDPointer: 1},
+		},
+		{
+			tags: []testTag{asciiTag(0x0001, "N"), rationalTag(0x0002, 48, 1, 51, 1, 2400, 100)},
+		},
+	})
+	data := buildJPEGWithSegments(t,
+		exifSegment(tiffData),
+		jpegSegment(markerAPP1, append(append([]byte{}, xmpJPEGSignature...), testXMPPacket...)),
+		jpegSegment(markerAPP13, append(append([]byte{}, photoshopJPEGSignature...), "8BIM\x04\x04\x00\x00\x00\x00\x00\x00"...)),
+		jpegSegment(markerCOM, []byte("shot at home")),
+		iccSegment(1, 1, buildICCProfile()),
+	)
+	segments := readJPEGSegments(data)
+	scans := data[segments[len(segments)-1].offset:]
+	data = append(data, "trailing preview"...)
+
+	tests := []struct {
+		name        string
+		keep        StripKeep
+		icc         bool
+		tags        []string // IFD0 name=raw value
+		xmpRights   string
+		orientation string
+	}{
+		{name: "everything"},
+		{name: "icc and orientation", keep: StripKeep{ICC: true, Orientation: true}, icc: true, tags: []string{"Orientation=6"}},
+		{name: "copyright", keep: StripKeep{Copyright: true}, tags: []string{"Copyright=Copyright 2025 Jane Doe"}, xmpRights: "Copyright 2025 Jane Doe"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, contentType, err := Strip(data, tt.keep)
+			if err != nil || contentType != "image/jpeg" {
+				t.Fatalf("Strip = %q, %v", contentType, err)
+			}
+			if !bytes.HasSuffix(out, scans) {
+				t.Error("image data was not copied unchanged up to EOI")
+			}
+			if bytes.Contains(out, []byte("shot at home")) || bytes.Contains(out, []byte("Photoshop 3.0")) {
+				t.Error("comment or IPTC survived")
+			}
+			if _, err := jpeg.Decode(bytes.NewReader(out)); err != nil {
+				t.Fatalf("jpeg.Decode: %v", err)
+			}
+
+			meta := ExtractMetadata(out, "image/jpeg", "clean.jpg")
+			var tags []string
+			if meta.EXIF != nil {
+				for _, tag := range meta.EXIF.Tags {
+					tags = append(tags, tag.Name+"="+tag.RawValue)
+				}
+			}
+			if !reflect.DeepEqual(tags, tt.tags) {
+				t.Errorf("EXIF tags = %v, want %v", tags, tt.tags)
+			}
+			if (meta.ICCProfile != nil) != tt.icc {
+				t.Errorf("ICCProfile = %v, want kept %v", meta.ICCProfile != nil, tt.icc)
+			}
+			rights := ""
+			if meta.XMP != nil {
+				rights = meta.XMP.Rights
+			}
+			if rights != tt.xmpRights || meta.Location != nil || meta.IPTC != nil {
+				t.Errorf("XMP rights = %q, location = %v, IPTC = %v", rights, meta.Location, meta.IPTC)
+			}
+		})
+	}
+}
+
+func TestStripPNG(t *testing.T) {
+	orientation := buildTIFF([]testIFD{{tags: []testTag{shortTag(0x0112, 8), asciiTag(0x013B, "Jane Doe")}}})
+	data := buildPNGWithChunks(t,
+		buildChunk("eXIf", orientation),
+		buildChunk("tEXt", []byte("Author\x00Jane Doe")),
+		buildChunk("iTXt", append([]byte(xmpPNGKeyword+"\x00\x00\x00\x00\x00"), testXMPPacket...)),
+		buildChunk("tIME", []byte{0x07, 0xE9, 10, 31, 12, 0, 0}),
+		buildChunk("pHYs", []byte{0, 0, 0x0B, 0x13, 0, 0, 0x0B, 0x13, 1}),
+	)
+
+	out, _, err := Strip(data, StripKeep{Orientation: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var types []string
+	for _, c := range readPNGChunks(out) {
+		types = append(types, c.typ)
+		if !c.crcValid {
+			t.Errorf("%s CRC is invalid", c.typ)
+		}
+	}
+	if want := []string{"IHDR", "eXIf", "pHYs", "IDAT", "IEND"}; !reflect.DeepEqual(types, want) {
+		t.Errorf("chunks = %v, want %v", types, want)
+	}
+	if meta := ExtractMetadata(out, "image/png", "clean.png"); meta.Orientation == "" || len(meta.EXIF.Tags) != 1 {
+		t.Errorf("Orientation = %q, EXIF = %+v", meta.Orientation, meta.EXIF)
+	}
+	if _, err := png.Decode(bytes.NewReader(out)); err != nil {
+		t.Errorf("png.Decode: %v", err)
+	}
+}
+
+func TestStripWebP(t *testing.T) {
+	vp8x := []byte{vp8xICC | vp8xEXIF | vp8xXMP, 0, 0, 0, 15, 0, 0, 7, 0, 0}
+	var body []byte
+	body = appendRIFFChunk(body, "VP8X", vp8x)
+	body = appendRIFFChunk(body, "ICCP", buildICCProfile())
+	body = appendRIFFChunk(body, "VP8L", []byte{0x2F, 0x0F, 0xC0, 0x01, 0x00})
+	body = appendRIFFChunk(body, "EXIF", buildTIFF([]testIFD{{tags: []testTag{asciiTag(0x013B, "Jane Doe")}}}))
+	body = appendRIFFChunk(body, "XMP ", []byte(testXMPPacket))
+	body = appendRIFFChunk(body, "LOCA", []byte("home"))
+	data := append(append(append([]byte("RIFF"), 0, 0, 0, 0), "WEBP"...), body...)
+	binary.LittleEndian.PutUint32(data[4:], uint32(len(data)-8))
+
+	out, contentType, err := Strip(data, StripKeep{ICC: true})
+	if err != nil || contentType != "image/webp" {
+		t.Fatalf("Strip = %q, %v", contentType, err)
+	}
+	var fourCCs []string
+	for _, c := range readWebPChunks(out) {
+		fourCCs = append(fourCCs, c.fourCC)
+	}
+	if want := []string{"VP8X", "ICCP", "VP8L"}; !reflect.DeepEqual(fourCCs, want) {
+		t.Errorf("chunks = %v, want %v", fourCCs, want)
+	}
+	if out[20] != vp8xICC || int(binary.LittleEndian.Uint32(out[4:])) != len(out)-8 {
+		t.Errorf("VP8X flags = %#x, RIFF size = %d for %d bytes", out[20], binary.LittleEndian.Uint32(out[4:]), len(out))
+	}
+}
+
+func TestStripSimpleWebPKeepsMetadata(t *testing.T) {
+	// A 16x8 lossless image without VP8X, followed by an EXIF chunk
+	var body []byte
+	body = appendRIFFChunk(body, "VP8L", []byte{0x2F, 0x0F, 0xC0, 0x01, 0x00})
+	body = appendRIFFChunk(body, "EXIF", buildTIFF([]testIFD{{tags: []testTag{shortTag(0x0112, 6), asciiTag(0x8298, "Jane Doe")}}}))
+	data := append(append(append([]byte("RIFF"), 0, 0, 0, 0), "WEBP"...), body...)
+	binary.LittleEndian.PutUint32(data[4:], uint32(len(data)-8))
+
+	out, _, err := Strip(data, StripKeep{Orientation: true, Copyright: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fourCCs []string
+	for _, c := range readWebPChunks(out) {
+		fourCCs = append(fourCCs, c.fourCC)
+	}
+	if len(fourCCs) < 3 || fourCCs[0] != "VP8X" || fourCCs[1] != "VP8L" || fourCCs[2] != "EXIF" {
+		t.Fatalf("chunks = %v", fourCCs)
+	}
+	if vp8x := out[20:30]; vp8x[0]&vp8xEXIF == 0 || !bytes.Equal(vp8x[4:], []byte{15, 0, 0, 7, 0, 0}) {
+		t.Errorf("VP8X = % x", vp8x)
+	}
+	meta := ExtractMetadata(out, "image/webp", "clean.webp")
+	var copyright string
+	for _, tag := range meta.EXIF.Tags {
+		if tag.Name == "Copyright" {
+			copyright = tag.Value
+		}
+	}
+	if meta.Orientation == "" || copyright != "Jane Doe" {
+		t.Errorf("Orientation = %q, Copyright = %q", meta.Orientation, copyright)
+	}
+}
+
+func TestExtractMetadataOversizedEXIFChunk(t *testing.T) {
+	var body []byte
+	body = appendRIFFChunk(body, "VP8X", []byte{vp8xEXIF, 0, 0, 0, 15, 0, 0, 7, 0, 0})
+	body = appendRIFFChunk(body, "VP8L", []byte{0x2F, 0x0F, 0xC0, 0x01, 0x00})
+	body = appendRIFFChunk(body, "EXIF", overflowingTIFF())
+	webp := append(append(append([]byte("RIFF"), 0, 0, 0, 0), "WEBP"...), body...)
+	binary.LittleEndian.PutUint32(webp[4:], uint32(len(webp)-8))
+
+	files := map[string][]byte{
+		"overflow.png":  buildPNGWithChunks(t, buildChunk("eXIf", overflowingTIFF())),
+		"overflow.webp": webp,
+	}
+	for name, data := range files {
+		meta := ExtractMetadata(data, "application/octet-stream", name)
+		if meta.DecodeError != "" || meta.Width == 0 {
+			t.Fatalf("%s not decoded: %q", name, meta.DecodeError)
+		}
+		if meta.EXIF != nil {
+			t.Errorf("%s: EXIF decoded from an oversized entry", name)
+		}
+	}
+}
+
+func TestParseStripKeep(t *testing.T) {
+	keep, err := ParseStripKeep([]string{"icc, Orientation", "copyright", ""})
+	if err != nil || keep != (StripKeep{ICC: true, Orientation: true, Copyright: true}) {
+		t.Errorf("ParseStripKeep = %+v, %v", keep, err)
+	}
+	if _, err := ParseStripKeep([]string{"gps"}); err == nil {
+		t.Error("expected an unknown entry to fail")
+	}
+	if _, _, err := Strip([]byte("GIF89a"), StripKeep{}); err != ErrStripUnsupported {
+		t.Errorf("Strip(GIF) error = %v", err)
+	}
+}
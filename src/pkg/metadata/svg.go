@@ -0,0 +1,387 @@
+package metadata
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"image"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/ahrdadan/image-metadata-viewer/src/internal/models"
+)
+
+// svgSniffLength is how far into the file the <svg> root is looked for
+const svgSniffLength = 64 << 10
+
+// maxSVGFindingValue truncates attribute values quoted in findings
+const maxSVGFindingValue = 120
+
+// Finding severities
+const (
+	svgSeverityHigh   = "high"
+	svgSeverityMedium = "medium"
+	svgSeverityLow    = "low"
+)
+
+// errNotSVG is returned when an XML document has a root other than <svg>
+var errNotSVG = errors.New("svg: root element is not <svg>")
+
+// svgUnits converts SVG length units to CSS pixels
+var svgUnits = map[string]float64{
+	"":   1,
+	"px": 1,
+	"pt": 4.0 / 3,
+	"pc": 16,
+	"mm": 96 / 25.4,
+	"cm": 96 / 2.54,
+	"in": 96,
+}
+
+// svgEmbeddingElements can load and run another document inside the SVG
+var svgEmbeddingElements = map[string]bool{
+	"iframe": true,
+	"embed":  true,
+	"object": true,
+	"frame":  true,
+}
+
+// svgCSSURL matches url() references and @import rules in style content
+var svgCSSURL = regexp.MustCompile(`(?i)url\(\s*['"]?([^'")\s]+)|@import\s+(?:url\(\s*)?['"]?([^'")\s;]+)`)
+
+// isSVG reports whether data looks like an XML document with an <svg> root
+// near its start. parseSVG makes the final decision.
+func isSVG(data []byte) bool {
+	data = bytes.TrimPrefix(data, []byte("\xEF\xBB\xBF"))
+	data = bytes.TrimLeft(data, " \t\r\n")
+	if !bytes.HasPrefix(data, []byte("<")) {
+		return false
+	}
+	if len(data) > svgSniffLength {
+		data = data[:svgSniffLength]
+	}
+	return bytes.Contains(data, []byte("<svg")) || bytes.Contains(data, []byte(":svg"))
+}
+
+// svgParser collects document facts and findings while tokenizing
+type svgParser struct {
+	dec      *xml.Decoder
+	data     []byte
+	info     *models.SVGInfo
+	stack    []string
+	findings []models.SVGFinding
+	css      strings.Builder
+
+	// Text collection for the element currently open, if any
+	textElement string
+	text        strings.Builder
+	metaStart   int64
+}
+
+// parseSVG tokenizes an SVG document without resolving external entities
+// or rendering anything. It returns nil when the root is not <svg>.
+func parseSVG(data []byte) *models.SVGInfo {
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	dec.Strict = false
+	dec.Entity = xml.HTMLEntity
+	dec.CharsetReader = func(_ string, r io.Reader) (io.Reader, error) {
+		// Structure is all that matters, so bytes pass through unconverted
+		return r, nil
+	}
+
+	p := &svgParser{dec: dec, data: data}
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			break
+		}
+		if !p.token(tok) {
+			return nil
+		}
+	}
+	if p.info == nil {
+		return nil
+	}
+	p.info.Findings = p.findings
+	p.info.Safe = len(p.findings) == 0
+	return p.info
+}
+
+// token handles one XML token and reports false when the document turns
+// out not to be SVG
+func (p *svgParser) token(tok xml.Token) bool {
+	switch t := tok.(type) {
+	case xml.Directive:
+		p.directive(t)
+	case xml.StartElement:
+		if p.info == nil {
+			if t.Name.Local != "svg" {
+				return false
+			}
+			p.info = &models.SVGInfo{}
+			p.root(t)
+		}
+		p.stack = append(p.stack, t.Name.Local)
+		p.element(t)
+	case xml.EndElement:
+		if len(p.stack) == 0 {
+			return true
+		}
+		if p.textElement != "" && len(p.stack) == 2 && t.Name.Local == p.textElement {
+			p.endText()
+		}
+		if t.Name.Local == "style" {
+			p.checkCSS("style", "", p.css.String())
+			p.css.Reset()
+		}
+		p.stack = p.stack[:len(p.stack)-1]
+	case xml.CharData:
+		if p.textElement != "" {
+			p.text.Write(t)
+		}
+		if p.current() == "style" {
+			p.css.Write(t)
+		}
+	}
+	return true
+}
+
+// root reads the outermost <svg> attributes
+func (p *svgParser) root(t xml.StartElement) {
+	for _, a := range t.Attr {
+		if a.Name.Space != "" {
+			continue
+		}
+		switch a.Name.Local {
+		case "version":
+			p.info.Version = a.Value
+		case "width":
+			p.info.Width = strings.TrimSpace(a.Value)
+		case "height":
+			p.info.Height = strings.TrimSpace(a.Value)
+		case "viewBox":
+			p.info.ViewBox = strings.Join(strings.Fields(strings.ReplaceAll(a.Value, ",", " ")), " ")
+		}
+	}
+}
+
+// element counts an element, starts text collection for the root's title,
+// desc and metadata children, and checks the element and its attributes
+func (p *svgParser) element(t xml.StartElement) {
+	p.info.Elements++
+	name := t.Name.Local
+
+	if len(p.stack) == 2 && p.textElement == "" {
+		switch {
+		case name == "title" && p.info.Title == "",
+			name == "desc" && p.info.Description == "",
+			name == "metadata" && p.info.Metadata == "":
+			p.textElement = name
+			p.text.Reset()
+			p.metaStart = p.dec.InputOffset()
+		}
+	}
+
+	switch {
+	case name == "script" || name == "handler":
+		p.add(svgSeverityHigh, "script", name, "", "")
+	case name == "foreignObject":
+		p.add(svgSeverityMedium, "foreign-object", name, "", "")
+	case svgEmbeddingElements[strings.ToLower(name)]:
+		p.add(svgSeverityHigh, "embedded-content", name, "", "")
+	}
+
+	for _, a := range t.Attr {
+		p.attribute(name, a)
+	}
+}
+
+// attribute checks one attribute for handlers, script URLs and external
+// references, and records namespace declarations
+func (p *svgParser) attribute(element string, a xml.Attr) {
+	local := strings.ToLower(a.Name.Local)
+	value := strings.TrimSpace(a.Value)
+	lower := strings.ToLower(value)
+
+	if a.Name.Space == "xmlns" || (a.Name.Space == "" && local == "xmlns") {
+		p.addNamespace(value)
+		return
+	}
+
+	switch {
+	case strings.HasPrefix(local, "on"):
+		p.add(svgSeverityHigh, "event-handler", element, a.Name.Local, value)
+		return
+	case strings.HasPrefix(lower, "javascript:") || strings.HasPrefix(lower, "vbscript:"):
+		p.add(svgSeverityHigh, "javascript-url", element, a.Name.Local, value)
+		return
+	case local == "style":
+		p.checkCSS(element, a.Name.Local, value)
+		return
+	case local != "href" && local != "src":
+		return
+	}
+
+	switch {
+	case value == "" || strings.HasPrefix(value, "#"):
+		// Same-document reference
+	case strings.HasPrefix(lower, "data:"):
+		if !strings.HasPrefix(lower, "data:image/") || strings.HasPrefix(lower, "data:image/svg") {
+			p.add(svgSeverityMedium, "embedded-content", element, a.Name.Local, value)
+		}
+	case element == "a":
+		// A link is only followed when clicked
+		p.add(svgSeverityLow, "external-reference", element, a.Name.Local, value)
+	default:
+		p.add(svgSeverityMedium, "external-reference", element, a.Name.Local, value)
+	}
+}
+
+// checkCSS looks for external url() references and @import rules
+func (p *svgParser) checkCSS(element, attribute, css string) {
+	for _, m := range svgCSSURL.FindAllStringSubmatch(css, -1) {
+		ref := m[1]
+		if ref == "" {
+			ref = m[2]
+		}
+		lower := strings.ToLower(ref)
+		switch {
+		case strings.HasPrefix(ref, "#"), strings.HasPrefix(lower, "data:image/"):
+		case strings.HasPrefix(lower, "javascript:"):
+			p.add(svgSeverityHigh, "javascript-url", element, attribute, ref)
+		default:
+			p.add(svgSeverityMedium, "external-reference", element, attribute, ref)
+		}
+	}
+}
+
+// directive flags entity declarations in the DOCTYPE. They are never
+// expanded here, but other XML parsers may fetch or recursively expand them.
+func (p *svgParser) directive(d xml.Directive) {
+	if !bytes.Contains(d, []byte("<!ENTITY")) {
+		return
+	}
+	severity := svgSeverityMedium
+	if bytes.Contains(d, []byte("SYSTEM")) || bytes.Contains(d, []byte("PUBLIC \"")) {
+		severity = svgSeverityHigh
+	}
+	p.add(severity, "entity-declaration", "!DOCTYPE", "", string(d))
+}
+
+// endText stores the collected title, desc or metadata content
+func (p *svgParser) endText() {
+	text := strings.TrimSpace(p.text.String())
+	switch p.textElement {
+	case "title":
+		p.info.Title = text
+	case "desc":
+		p.info.Description = text
+	case "metadata":
+		// Keep markup intact by slicing the source up to the closing tag
+		end := p.dec.InputOffset()
+		if closing := bytes.LastIndex(p.data[:end], []byte("</")); closing >= int(p.metaStart) {
+			p.info.Metadata = strings.TrimSpace(string(p.data[p.metaStart:closing]))
+		}
+	}
+	p.textElement = ""
+	p.text.Reset()
+}
+
+// add records a finding at the decoder's current line
+func (p *svgParser) add(severity, kind, element, attribute, value string) {
+	if len(value) > maxSVGFindingValue {
+		value = value[:maxSVGFindingValue] + "…"
+	}
+	line, _ := p.dec.InputPos()
+	p.findings = append(p.findings, models.SVGFinding{
+		Severity:  severity,
+		Kind:      kind,
+		Element:   element,
+		Attribute: attribute,
+		Value:     value,
+		Line:      line,
+	})
+}
+
+// addNamespace records a declared namespace once
+func (p *svgParser) addNamespace(uri string) {
+	for _, ns := range p.info.Namespaces {
+		if ns == uri {
+			return
+		}
+	}
+	p.info.Namespaces = append(p.info.Namespaces, uri)
+}
+
+// current returns the innermost open element
+func (p *svgParser) current() string {
+	if len(p.stack) == 0 {
+		return ""
+	}
+	return p.stack[len(p.stack)-1]
+}
+
+// decodeSVGConfig reports the intrinsic size in CSS pixels from width and
+// height, falling back to the viewBox for missing or relative lengths
+func decodeSVGConfig(data []byte) (image.Config, error) {
+	info := parseSVG(data)
+	if info == nil {
+		return image.Config{}, errNotSVG
+	}
+
+	width, wok := svgLength(info.Width)
+	height, hok := svgLength(info.Height)
+	if vb := strings.Fields(info.ViewBox); len(vb) == 4 {
+		vbWidth, _ := strconv.ParseFloat(vb[2], 64)
+		vbHeight, _ := strconv.ParseFloat(vb[3], 64)
+		switch {
+		case !wok && !hok:
+			width, height = vbWidth, vbHeight
+		case !wok && vbHeight > 0:
+			width = height * vbWidth / vbHeight
+		case !hok && vbWidth > 0:
+			height = width * vbHeight / vbWidth
+		}
+	}
+	return image.Config{Width: int(math.Round(width)), Height: int(math.Round(height))}, nil
+}
+
+// svgLength converts an absolute SVG length to CSS pixels
+func svgLength(s string) (float64, bool) {
+	s = strings.TrimSpace(s)
+	i := strings.IndexFunc(s, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.' && r != '-' && r != '+' && r != 'e' && r != 'E'
+	})
+	number, unit := s, ""
+	if i >= 0 {
+		number, unit = s[:i], strings.ToLower(strings.TrimSpace(s[i:]))
+	}
+	v, err := strconv.ParseFloat(number, 64)
+	scale, ok := svgUnits[unit]
+	if err != nil || !ok || v <= 0 {
+		return 0, false
+	}
+	return v * scale, true
+}
+
+// extractSVG fills the SVG section and MIME type. XML and text content
+// types from servers are replaced, since browsers need image/svg+xml.
+func extractSVG(data []byte, meta *models.ImageMetadata) {
+	if sniffFormat(data) != formatSVG {
+		return
+	}
+	info := parseSVG(data)
+	if info == nil {
+		return
+	}
+	meta.SVG = info
+
+	mediaType, _, _ := strings.Cut(meta.MIMEType, ";")
+	switch strings.TrimSpace(mediaType) {
+	case "", "application/octet-stream", "text/xml", "application/xml", "text/plain", "text/html":
+		meta.MIMEType = "image/svg+xml"
+	}
+}
@@ -0,0 +1,80 @@
+package metadata
+
+import "testing"
+
+const testSVG = `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE svg [ <!ENTITY ext SYSTEM "file:///etc/passwd"> ]>
+<svg xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink"
+     version="1.1" width="2in" height="100%" viewBox="0 0 200 100" onload="init()">
+  <title>Company &amp; mark</title>
+  <desc>Test drawing</desc>
+  <metadata><dc:title xmlns:dc="http://purl.org/dc/elements/1.1/">Mark</dc:title></metadata>
+  <style>@import url("https://fonts.example.com/a.css"); .a { fill: url(#grad) }</style>
+  <script>document.cookie</script>
+  <a xlink:href="javascript:alert(1)"><rect width="1" height="1"/></a>
+  <a href="https://example.com/"><text>home</text></a>
+  <image href="https://tracker.example.com/p.png"/>
+  <image href="data:image/png;base64,AAAA"/>
+  <use href="#shape"/>
+  <foreignObject><p xmlns="http://www.w3.org/1999/xhtml">hi</p></foreignObject>
+</svg>`
+
+func TestExtractMetadataSVG(t *testing.T) {
+	meta := ExtractMetadata([]byte(testSVG), "text/xml; charset=utf-8", "logo.svg")
+	if meta.DecodeError != "" {
+		t.Fatalf("DecodeError = %q", meta.DecodeError)
+	}
+	// 2in is 192px; the relative height follows the 2:1 viewBox
+	if meta.Format != "svg" || meta.Width != 192 || meta.Height != 96 || meta.MIMEType != "image/svg+xml" {
+		t.Errorf("Format = %q, size = %dx%d, MIMEType = %q", meta.Format, meta.Width, meta.Height, meta.MIMEType)
+	}
+
+	info := meta.SVG
+	if info == nil {
+		t.Fatal("expected SVG section")
+	}
+	if info.Title != "Company & mark" || info.Description != "Test drawing" || info.ViewBox != "0 0 200 100" {
+		t.Errorf("title = %q, desc = %q, viewBox = %q", info.Title, info.Description, info.ViewBox)
+	}
+	if info.Metadata != `<dc:title xmlns:dc="http://purl.org/dc/elements/1.1/">Mark</dc:title>` {
+		t.Errorf("metadata = %q", info.Metadata)
+	}
+	if info.Safe {
+		t.Error("expected unsafe document")
+	}
+
+	want := []struct{ severity, kind, element string }{
+		{"high", "entity-declaration", "!DOCTYPE"},
+		{"high", "event-handler", "svg"},
+		{"medium", "external-reference", "style"},
+		{"high", "script", "script"},
+		{"high", "javascript-url", "a"},
+		{"low", "external-reference", "a"},
+		{"medium", "external-reference", "image"},
+		{"medium", "foreign-object", "foreignObject"},
+	}
+	if len(info.Findings) != len(want) {
+		t.Fatalf("findings = %+v", info.Findings)
+	}
+	for i, w := range want {
+		f := info.Findings[i]
+		if f.Severity != w.severity || f.Kind != w.kind || f.Element != w.element || f.Line == 0 {
+			t.Errorf("finding %d = %+v, want %+v", i, f, w)
+		}
+	}
+}
+
+func TestExtractMetadataSVGSafe(t *testing.T) {
+	data := []byte(`<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 24 24"><path d="M0 0h24v24z"/></svg>`)
+	meta := ExtractMetadata(data, "", "icon.svg")
+	if meta.SVG == nil || !meta.SVG.Safe || meta.Width != 24 || meta.Height != 24 {
+		t.Errorf("svg = %+v, size = %dx%d", meta.SVG, meta.Width, meta.Height)
+	}
+}
+
+func TestExtractMetadataXMLNotSVG(t *testing.T) {
+	data := []byte(`<?xml version="1.0"?><feed><entry>an <svg> mention</entry></feed>`)
+	if meta := ExtractMetadata(data, "", "feed.xml"); meta.SVG != nil || meta.DecodeError == "" {
+		t.Errorf("svg = %+v, DecodeError = %q", meta.SVG, meta.DecodeError)
+	}
+}
@@ -0,0 +1,194 @@
+package metadata
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/png"
+
+	"github.com/ahrdadan/image-metadata-viewer/src/internal/models"
+	"github.com/ahrdadan/image-metadata-viewer/src/internal/utils"
+)
+
+// Photoshop thumbnail resources; 0x0409 is the pre-5.0 BGR variant
+const (
+	psResourceThumbnailOld = 0x0409
+	psResourceThumbnail    = 0x040C
+)
+
+// psThumbnailHeader is the size of the header before the JFIF data
+const psThumbnailHeader = 28
+
+// thumbnail is an embedded preview together with its bytes
+type thumbnail struct {
+	info models.Thumbnail
+	data []byte
+}
+
+// collectThumbnails finds every embedded thumbnail and preview: the EXIF
+// IFD1 thumbnail, Photoshop's resource thumbnail, raw previews and HEIF
+// thumbnail items. raw is the parsed camera raw layout, or nil.
+func collectThumbnails(data []byte, raw *models.RAWInfo) []thumbnail {
+	var thumbs []thumbnail
+	add := func(source, format string, width, height int, body []byte) {
+		if len(body) == 0 {
+			return
+		}
+		if bytes.HasPrefix(body, []byte{0xFF, markerSOI}) {
+			format = "JPEG"
+			if jpg := analyzeJPEG(body); jpg != nil && jpg.Width > 0 {
+				width, height = jpg.Width, jpg.Height
+			}
+		}
+		thumbs = append(thumbs, thumbnail{
+			info: models.Thumbnail{
+				Index:     len(thumbs),
+				Source:    source,
+				Format:    format,
+				MIMEType:  thumbnailMIMEType(format, width, height, len(body)),
+				Width:     width,
+				Height:    height,
+				Size:      len(body),
+				SizeHuman: utils.HumanBytes(int64(len(body))),
+				Offset:    fileOffset(data, body),
+			},
+			data: body,
+		})
+	}
+
+	// Raw previews already include the thumbnail IFDs
+	if raw != nil {
+		for _, p := range raw.Previews {
+			if p.Offset > 0 && p.Length > 0 && p.Offset+p.Length <= len(data) {
+				add(p.Source, p.Format, p.Width, p.Height, data[p.Offset:p.Offset+p.Length])
+			}
+		}
+	} else if block := exifTIFFBlock(data); block != nil {
+		_, ifds := rawIFDs(block)
+		for _, ifd := range ifds {
+			if ifd.name != ifd1Name {
+				continue
+			}
+			offset, ok := dirInt(ifd.dir, tagJPEGOffset)
+			length, _ := dirInt(ifd.dir, tagJPEGLength)
+			if ok && offset > 0 && length > 0 && offset+length <= len(block) {
+				add("EXIF IFD1", "JPEG", 0, 0, block[offset:offset+length])
+			}
+		}
+	}
+
+	section, _ := findPhotoshopResources(data)
+	for _, res := range readPhotoshopResources(section) {
+		if res.id != psResourceThumbnail && res.id != psResourceThumbnailOld {
+			continue
+		}
+		// Format 1 is JFIF; raw RGB thumbnails are not written in practice
+		if len(res.data) > psThumbnailHeader && binary.BigEndian.Uint32(res.data) == 1 {
+			width := int(binary.BigEndian.Uint32(res.data[4:]))
+			height := int(binary.BigEndian.Uint32(res.data[8:]))
+			add("Photoshop", "JPEG", width, height, res.data[psThumbnailHeader:])
+		}
+	}
+
+	if sniffFormat(data) == formatHEIF {
+		if file := parseHEIF(data); file != nil {
+			for _, item := range file.referencing("thmb", file.primary) {
+				props := &models.HEIFInfo{}
+				file.applyProps(props, item.id)
+				format := heifCodecs[item.typ]
+				if format == "" {
+					format = item.typ
+				}
+				add("HEIF thmb", format, props.Width, props.Height, file.itemData(item))
+			}
+		}
+	}
+
+	return thumbs
+}
+
+// exifTIFFBlock returns the TIFF structure holding EXIF, in place within
+// data, for JPEG, TIFF, PNG, WebP and HEIF files
+func exifTIFFBlock(data []byte) []byte {
+	switch sniffFormat(data) {
+	case formatJPEG:
+		for _, seg := range readJPEGSegments(data) {
+			if seg.marker == markerAPP1 && bytes.HasPrefix(seg.data, []byte("Exif\x00\x00")) {
+				return seg.data[6:]
+			}
+		}
+	case formatTIFF:
+		return data
+	case formatPNG:
+		return pngExifTIFF(data)
+	case formatWebP:
+		return webpExifTIFF(data)
+	case formatHEIF:
+		if file := parseHEIF(data); file != nil {
+			return file.exifTIFF()
+		}
+	}
+	return nil
+}
+
+// fileOffset returns where part is stored in data. Most parts are slices
+// of data, so the capacity difference gives the offset; parts that were
+// copied out, such as Photoshop resources spread over several segments,
+// are searched for and give -1 when not stored contiguously.
+func fileOffset(data, part []byte) int {
+	if len(part) == 0 {
+		return -1
+	}
+	if off := cap(data) - cap(part); off >= 0 && off+len(part) <= len(data) && &data[off] == &part[0] {
+		return off
+	}
+	return bytes.Index(data, part)
+}
+
+// thumbnailMIMEType returns the type a thumbnail is served as. Uncompressed
+// 8-bit RGB previews are converted to PNG.
+func thumbnailMIMEType(format string, width, height, size int) string {
+	switch {
+	case format == "JPEG":
+		return "image/jpeg"
+	case format == "Uncompressed" && width > 0 && width*height*3 == size:
+		return "image/png"
+	}
+	return ""
+}
+
+// extractThumbnails lists the embedded thumbnails and previews
+func extractThumbnails(data []byte, result *Result) error {
+	for _, thumb := range collectThumbnails(data, result.raw) {
+		result.Metadata.Thumbnails = append(result.Metadata.Thumbnails, thumb.info)
+	}
+	return nil
+}
+
+// ExtractThumbnail returns the bytes and content type of the embedded
+// thumbnail at index, as listed in ImageMetadata.Thumbnails
+func ExtractThumbnail(data []byte, index int) ([]byte, string, error) {
+	thumbs := collectThumbnails(data, parseRAW(data))
+	if index < 0 || index >= len(thumbs) {
+		return nil, "", fmt.Errorf("thumbnail %d not found", index)
+	}
+	thumb := thumbs[index]
+
+	switch thumb.info.MIMEType {
+	case "":
+		return thumb.data, "application/octet-stream", nil
+	case "image/png":
+		img := image.NewRGBA(image.Rect(0, 0, thumb.info.Width, thumb.info.Height))
+		for i := 0; i < thumb.info.Width*thumb.info.Height; i++ {
+			p := thumb.data[3*i:]
+			img.Pix[4*i], img.Pix[4*i+1], img.Pix[4*i+2], img.Pix[4*i+3] = p[0], p[1], p[2], 0xFF
+		}
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, img); err != nil {
+			return nil, "", err
+		}
+		return buf.Bytes(), "image/png", nil
+	}
+	return thumb.data, thumb.info.MIMEType, nil
+}
@@ -0,0 +1,93 @@
+package metadata
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/jpeg"
+	"testing"
+)
+
+// encodeTestJPEG encodes a flat grey JPEG of the given size
+func encodeTestJPEG(t *testing.T, width, height int) []byte {
+	t.Helper()
+
+	img := image.NewGray(image.Rect(0, 0, width, height))
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, nil); err != nil {
+		t.Fatalf("jpeg.Encode: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// withIFD1Thumbnail links an IFD1 after the single IFD in tiffData that
+// points at thumb, which is appended after it
+func withIFD1Thumbnail(tiffData []byte, thumb []byte) []byte {
+	out := append([]byte{}, tiffData...)
+	count := int(binary.LittleEndian.Uint16(out[8:]))
+	binary.LittleEndian.PutUint32(out[8+2+12*count:], uint32(len(out)))
+
+	thumbOffset := len(out) + 2 + 2*12 + 4
+	out = binary.LittleEndian.AppendUint16(out, 2)
+	for _, tag := range [][2]uint32{
+		{uint32(tagJPEGOffset), uint32(thumbOffset)},
+		{uint32(tagJPEGLength), uint32(len(thumb))},
+	} {
+		out = binary.LittleEndian.AppendUint16(out, uint16(tag[0]))
+		out = binary.LittleEndian.AppendUint16(out, 4)
+		out = binary.LittleEndian.AppendUint32(out, 1)
+		out = binary.LittleEndian.AppendUint32(out, tag[1])
+	}
+	out = binary.LittleEndian.AppendUint32(out, 0)
+	return append(out, thumb...)
+}
+
+func TestExtractMetadataThumbnails(t *testing.T) {
+	exifThumb := encodeTestJPEG(t, 8, 4)
+	tiffData := withIFD1Thumbnail(buildTIFF([]testIFD{
+		{tags: []testTag{asciiTag(0x010F, "Canon")}},
+	}), exifThumb)
+
+	psThumb := encodeTestJPEG(t, 6, 6)
+	header := make([]byte, psThumbnailHeader)
+	binary.BigEndian.PutUint32(header, 1)
+	binary.BigEndian.PutUint32(header[4:], 6)
+	binary.BigEndian.PutUint32(header[8:], 6)
+
+	data := buildJPEGWithSegments(t,
+		exifSegment(tiffData),
+		photoshopSegment(psResourceBlock(psResourceThumbnail, append(header, psThumb...))),
+	)
+
+	meta := ExtractMetadata(data, "image/jpeg", "thumbs.jpg")
+	if len(meta.Thumbnails) != 2 {
+		t.Fatalf("Thumbnails = %+v, want 2 entries", meta.Thumbnails)
+	}
+
+	exif := meta.Thumbnails[0]
+	if exif.Source != "EXIF IFD1" || exif.Format != "JPEG" || exif.MIMEType != "image/jpeg" {
+		t.Errorf("EXIF thumbnail = %+v", exif)
+	}
+	if exif.Width != 8 || exif.Height != 4 || exif.Size != len(exifThumb) {
+		t.Errorf("EXIF thumbnail size = %dx%d %d bytes", exif.Width, exif.Height, exif.Size)
+	}
+	if exif.Offset < 0 || !bytes.Equal(data[exif.Offset:exif.Offset+exif.Size], exifThumb) {
+		t.Errorf("EXIF thumbnail offset = %d", exif.Offset)
+	}
+
+	ps := meta.Thumbnails[1]
+	if ps.Index != 1 || ps.Source != "Photoshop" || ps.Width != 6 || ps.Height != 6 {
+		t.Errorf("Photoshop thumbnail = %+v", ps)
+	}
+
+	body, contentType, err := ExtractThumbnail(data, 1)
+	if err != nil {
+		t.Fatalf("ExtractThumbnail: %v", err)
+	}
+	if contentType != "image/jpeg" || !bytes.Equal(body, psThumb) {
+		t.Errorf("ExtractThumbnail = %s, %d bytes", contentType, len(body))
+	}
+	if _, _, err := ExtractThumbnail(data, 2); err == nil {
+		t.Error("ExtractThumbnail(2) succeeded, want error")
+	}
+}
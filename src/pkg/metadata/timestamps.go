@@ -0,0 +1,241 @@
+package metadata
+
+import (
+	"math"
+	"strings"
+	"time"
+
+	"github.com/ahrdadan/image-metadata-viewer/src/internal/models"
+)
+
+// Where the offset of a normalized timestamp came from, besides the name
+// of the EXIF OffsetTime tag that supplied it
+const (
+	offsetRecorded = "recorded" // part of the value itself
+	offsetGPS      = "gps"      // inferred from GPS time and position
+	offsetUnknown  = "unknown"
+)
+
+// exifDateTags pairs each EXIF date with its sub-second and offset tags
+var exifDateTags = []struct{ date, subsec, offset string }{
+	{"DateTimeOriginal", "SubSecTimeOriginal", "OffsetTimeOriginal"},
+	{"DateTimeDigitized", "SubSecTimeDigitized", "OffsetTimeDigitized"},
+	{"DateTime", "SubSecTime", "OffsetTime"},
+}
+
+// iptcDateDatasets pairs each IPTC date dataset with its time dataset
+var iptcDateDatasets = []struct{ date, time string }{
+	{"DateCreated", "TimeCreated"},
+	{"DigitalCreationDate", "DigitalCreationTime"},
+	{"ReleaseDate", "ReleaseTime"},
+	{"ExpirationDate", "ExpirationTime"},
+	{"DateSent", "TimeSent"},
+}
+
+// captureSources are the timestamps that give the capture time, best first
+var captureSources = []string{
+	"EXIF DateTimeOriginal",
+	"XMP photoshop:DateCreated",
+	"XMP exif:DateTimeOriginal",
+	"XMP xmp:CreateDate",
+	"IPTC DateCreated",
+	"EXIF DateTimeDigitized",
+}
+
+// normalized is a timestamp being collected, with where it came from
+type normalized struct {
+	source       string
+	raw          string
+	ts           timestamp
+	offsetSource string
+}
+
+// extractTimestamps normalizes every EXIF, XMP and IPTC date to RFC 3339.
+// Dates without an offset take one from another EXIF OffsetTime tag or,
+// failing that, from the GPS clock; the rest are marked as unknown.
+func extractTimestamps(_ []byte, meta *models.ImageMetadata) {
+	exifTags := make(map[string]string)
+	if meta.EXIF != nil {
+		for _, tag := range meta.EXIF.Tags {
+			if _, seen := exifTags[tag.Name]; !seen {
+				exifTags[tag.Name] = strings.TrimSpace(tag.RawValue)
+			}
+		}
+	}
+
+	var dates []normalized
+	exifOffset, exifOffsetTag := "", ""
+	for _, t := range exifDateTags {
+		raw, ok := exifTags[t.date]
+		if !ok {
+			continue
+		}
+		value := raw
+		if sub := exifTags[t.subsec]; sub != "" && isDigits(sub) {
+			value += "." + sub
+		}
+		ts, ok := parseTimestamp(value)
+		if !ok {
+			continue
+		}
+		n := normalized{source: "EXIF " + t.date, raw: raw, ts: ts}
+		if offset := exifTags[t.offset]; offset != "" {
+			n.ts = ts.withOffset(offset)
+			if n.ts.hasZone {
+				n.offsetSource = t.offset
+				if exifOffset == "" {
+					exifOffset, exifOffsetTag = offset, t.offset
+				}
+			}
+		}
+		dates = append(dates, n)
+	}
+
+	gps, hasGPS := gpsTimestamp(exifTags["GPSDateStamp"], exifTags["GPSTimeStamp"])
+	if hasGPS {
+		dates = append(dates, normalized{
+			source:       "EXIF GPSDateStamp",
+			raw:          exifTags["GPSDateStamp"] + " " + exifTags["GPSTimeStamp"],
+			ts:           timestamp{time: gps, hasTime: true, hasZone: true},
+			offsetSource: offsetRecorded,
+		})
+	}
+
+	if meta.XMP != nil {
+		for _, p := range meta.XMP.Properties {
+			if !isXMPDateProperty(p.Path) {
+				continue
+			}
+			if ts, ok := parseTimestamp(p.Value); ok {
+				dates = append(dates, normalized{source: "XMP " + p.Path, raw: p.Value, ts: ts})
+			}
+		}
+	}
+
+	if meta.IPTC != nil {
+		datasets := make(map[string]string)
+		for _, ds := range meta.IPTC.Datasets {
+			if _, seen := datasets[ds.Name]; !seen {
+				datasets[ds.Name] = strings.TrimSpace(ds.Value)
+			}
+		}
+		for _, d := range iptcDateDatasets {
+			raw, ok := datasets[d.date]
+			if !ok {
+				continue
+			}
+			if t := datasets[d.time]; t != "" {
+				raw += "T" + t
+			}
+			if ts, ok := parseTimestamp(raw); ok {
+				dates = append(dates, normalized{source: "IPTC " + d.date, raw: raw, ts: ts})
+			}
+		}
+	}
+
+	// The GPS clock gives the offset of the camera's clock when both read
+	// the same moment
+	gpsOffset, hasGPSOffset := time.Duration(0), false
+	if hasGPS && meta.Location != nil {
+		for _, n := range dates {
+			if strings.HasPrefix(n.source, "EXIF DateTime") && n.ts.hasTime && !n.ts.hasZone {
+				gpsOffset, hasGPSOffset = inferOffset(n.ts, gps, meta.Location.Longitude)
+				break
+			}
+		}
+	}
+
+	for i := range dates {
+		n := &dates[i]
+		switch {
+		case n.offsetSource != "":
+		case n.ts.hasZone:
+			n.offsetSource = offsetRecorded
+		case !n.ts.hasTime:
+			n.offsetSource = offsetUnknown
+		case strings.HasPrefix(n.source, "EXIF ") && exifOffset != "":
+			n.ts = n.ts.withOffset(exifOffset)
+			n.offsetSource = exifOffsetTag
+		case hasGPSOffset:
+			n.ts = n.ts.inZone(gpsOffset)
+			n.offsetSource = offsetGPS
+		default:
+			n.offsetSource = offsetUnknown
+		}
+	}
+
+	meta.Timestamps = nil
+	for _, n := range dates {
+		meta.Timestamps = append(meta.Timestamps, n.timestamp())
+	}
+	for _, source := range captureSources {
+		for i := range meta.Timestamps {
+			if meta.Timestamps[i].Source == source {
+				capture := meta.Timestamps[i]
+				meta.CaptureTime = &capture
+				return
+			}
+		}
+	}
+}
+
+// timestamp renders n for the API
+func (n normalized) timestamp() models.Timestamp {
+	out := models.Timestamp{Source: n.source, Raw: n.raw, OffsetSource: n.offsetSource}
+	switch {
+	case !n.ts.hasTime:
+		out.Value = n.ts.time.Format(time.DateOnly)
+		out.DateOnly = true
+	case n.ts.hasZone:
+		out.Value = n.ts.time.Format(time.RFC3339Nano)
+		out.UTC = n.ts.time.UTC().Format(time.RFC3339Nano)
+		out.Offset = n.ts.time.Format("-07:00")
+	default:
+		out.Value = n.ts.time.Format("2006-01-02T15:04:05.999999999")
+	}
+	return out
+}
+
+// isXMPDateProperty reports whether an XMP property holds a date:
+// xmp:CreateDate, photoshop:DateCreated, exif:DateTimeOriginal,
+// exif:GPSTimeStamp and the stEvt:when of history entries
+func isXMPDateProperty(path string) bool {
+	name := path
+	if i := strings.LastIndexAny(name, "/:"); i >= 0 {
+		name = name[i+1:]
+	}
+	name, _, _ = strings.Cut(name, "[")
+	return strings.Contains(name, "Date") || name == "when" || name == "GPSTimeStamp"
+}
+
+// inferOffset derives the offset of a local camera time from the GPS time
+// of the same shot. The offset is rounded to a quarter hour and rejected
+// if the clocks were more than two minutes apart after rounding, or if it
+// is more than three hours from the solar offset of the longitude, which
+// catches stale GPS fixes while allowing for daylight saving time and
+// wide zones such as China's.
+func inferOffset(local timestamp, gps time.Time, longitude float64) (time.Duration, bool) {
+	diff := local.wallClock().Sub(gps)
+	offset := diff.Round(15 * time.Minute)
+	if (diff - offset).Abs() > 2*time.Minute {
+		return 0, false
+	}
+	if offset < -12*time.Hour || offset > 14*time.Hour {
+		return 0, false
+	}
+	solar := time.Duration(longitude / 15 * float64(time.Hour))
+	if math.Abs(float64(offset-solar)) > float64(3*time.Hour) {
+		return 0, false
+	}
+	return offset, true
+}
+
+// isDigits reports whether s is a non-empty run of ASCII digits
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
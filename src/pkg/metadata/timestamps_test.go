@@ -0,0 +1,112 @@
+package metadata
+
+import (
+	"testing"
+
+	"github.com/ahrdadan/image-metadata-viewer/src/internal/models"
+)
+
+func TestExtractTimestamps(t *testing.T) {
+	tags := func(pairs ...string) *models.EXIFData {
+		data := &models.EXIFData{}
+		for i := 0; i < len(pairs); i += 2 {
+			data.Tags = append(data.Tags, models.EXIFTag{Name: pairs[i], RawValue: pairs[i+1]})
+		}
+		return data
+	}
+
+	tests := []struct {
+		name    string
+		meta    models.ImageMetadata
+		want    map[string]string // source → value/offsetSource
+		capture string
+	}{
+		{
+			name: "offset tags and subseconds",
+			meta: models.ImageMetadata{
+				EXIF: tags(
+					"DateTimeOriginal", "2024:05:01 10:20:30",
+					"SubSecTimeOriginal", "045",
+					"DateTime", "2024:05:02 08:00:00",
+					"OffsetTime", "-04:00",
+				),
+				XMP: &models.XMPData{Properties: []models.XMPProperty{
+					{Path: "xmp:CreateDate", Value: "2024-05-01T16:20:30+02:00"},
+					{Path: "xmpMM:History[1]/stEvt:when", Value: "2024-05-03T09:00:00Z"},
+					{Path: "dc:title[x-default]", Value: "2024-05-01"},
+				}},
+				IPTC: &models.IPTCData{Datasets: []models.IPTCDataset{
+					{Name: "DateCreated", Value: "20240501"},
+				}},
+			},
+			want: map[string]string{
+				"EXIF DateTimeOriginal":           "2024-05-01T10:20:30.045-04:00 OffsetTime",
+				"EXIF DateTime":                   "2024-05-02T08:00:00-04:00 OffsetTime",
+				"XMP xmp:CreateDate":              "2024-05-01T16:20:30+02:00 recorded",
+				"XMP xmpMM:History[1]/stEvt:when": "2024-05-03T09:00:00Z recorded",
+				"IPTC DateCreated":                "2024-05-01 unknown",
+			},
+			capture: "EXIF DateTimeOriginal",
+		},
+		{
+			name: "offset from GPS",
+			meta: models.ImageMetadata{
+				EXIF: tags(
+					"DateTimeOriginal", "2024:05:01 10:20:30",
+					"GPSDateStamp", "2024:05:01",
+					"GPSTimeStamp", "8/1 20/1 29/1",
+				),
+				Location: &models.Location{Latitude: 48.85, Longitude: 2.35},
+				IPTC: &models.IPTCData{Datasets: []models.IPTCDataset{
+					{Name: "DateCreated", Value: "20240501"},
+					{Name: "TimeCreated", Value: "102030"},
+				}},
+			},
+			want: map[string]string{
+				"EXIF DateTimeOriginal": "2024-05-01T10:20:30+02:00 gps",
+				"EXIF GPSDateStamp":     "2024-05-01T08:20:29Z recorded",
+				"IPTC DateCreated":      "2024-05-01T10:20:30+02:00 gps",
+			},
+			capture: "EXIF DateTimeOriginal",
+		},
+		{
+			name: "stale GPS fix",
+			meta: models.ImageMetadata{
+				EXIF: tags(
+					"DateTimeOriginal", "2024:05:01 10:20:30",
+					"GPSDateStamp", "2024:05:01",
+					"GPSTimeStamp", "1/1 5/1 0/1",
+				),
+				Location: &models.Location{Latitude: 48.85, Longitude: 2.35},
+			},
+			want: map[string]string{
+				"EXIF DateTimeOriginal": "2024-05-01T10:20:30 unknown",
+				"EXIF GPSDateStamp":     "2024-05-01T01:05:00Z recorded",
+			},
+			capture: "EXIF DateTimeOriginal",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			meta := tt.meta
+			extractTimestamps(nil, &meta)
+
+			got := make(map[string]string)
+			for _, ts := range meta.Timestamps {
+				got[ts.Source] = ts.Value + " " + ts.OffsetSource
+			}
+			if len(got) != len(tt.want) {
+				t.Errorf("timestamps = %v, want %v", got, tt.want)
+			}
+			for source, want := range tt.want {
+				if got[source] != want {
+					t.Errorf("%s = %q, want %q", source, got[source], want)
+				}
+			}
+			if meta.CaptureTime == nil || meta.CaptureTime.Source != tt.capture {
+				t.Errorf("capture time = %+v, want %s", meta.CaptureTime, tt.capture)
+			}
+		})
+	}
+}
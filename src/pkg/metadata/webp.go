@@ -0,0 +1,57 @@
+package metadata
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+// riffChunk is a single chunk from a RIFF (WebP) container
+type riffChunk struct {
+	fourCC string
+	offset int // offset of the FourCC
+	data   []byte
+}
+
+// readWebPChunks walks the top-level chunks of a RIFF/WEBP file
+func readWebPChunks(data []byte) []riffChunk {
+	if len(data) < 12 || string(data[:4]) != "RIFF" || string(data[8:12]) != "WEBP" {
+		return nil
+	}
+
+	end := len(data)
+	if riffSize := int(binary.LittleEndian.Uint32(data[4:])) + 8; riffSize < end {
+		end = riffSize
+	}
+
+	var chunks []riffChunk
+	pos := 12
+	for pos+8 <= end {
+		size := int(binary.LittleEndian.Uint32(data[pos+4:]))
+		bodyEnd := pos + 8 + size
+		if size < 0 || bodyEnd > end {
+			break
+		}
+
+		chunks = append(chunks, riffChunk{
+			fourCC: string(data[pos : pos+4]),
+			offset: pos,
+			data:   data[pos+8 : bodyEnd],
+		})
+
+		// Chunks are padded to an even size
+		pos = bodyEnd + size%2
+	}
+
+	return chunks
+}
+
+// webpExifTIFF returns the TIFF structure of the EXIF chunk, or nil. Some
+// writers keep the JPEG "Exif" header in front of it.
+func webpExifTIFF(data []byte) []byte {
+	for _, chunk := range readWebPChunks(data) {
+		if chunk.fourCC == "EXIF" {
+			return bytes.TrimPrefix(chunk.data, exifJPEGSignature)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,561 @@
+package metadata
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/xml"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ahrdadan/image-metadata-viewer/src/internal/models"
+)
+
+// XMP container signatures
+var (
+	xmpJPEGSignature    = []byte("http://ns.adobe.com/xap/1.0/\x00")
+	xmpExtJPEGSignature = []byte("http://ns.adobe.com/xmp/extension/\x00")
+)
+
+const (
+	xmpPNGKeyword = "XML:com.adobe.xmp"
+	xmpGIFAppID   = "XMP DataXMP"
+	tagXMP        = 0x02BC
+)
+
+// Namespace URIs used by the XMP parser
+const (
+	nsRDF = "http://www.w3.org/1999/02/22-rdf-syntax-ns#"
+	nsXML = "http://www.w3.org/XML/1998/namespace"
+)
+
+// xmpPrefixes maps well-known namespace URIs to their conventional prefix
+var xmpPrefixes = map[string]string{
+	"http://purl.org/dc/elements/1.1/":                     "dc",
+	"http://ns.adobe.com/xap/1.0/":                         "xmp",
+	"http://ns.adobe.com/xap/1.0/mm/":                      "xmpMM",
+	"http://ns.adobe.com/xap/1.0/rights/":                  "xmpRights",
+	"http://ns.adobe.com/photoshop/1.0/":                   "photoshop",
+	"http://ns.adobe.com/camera-raw-settings/1.0/":         "crs",
+	"http://iptc.org/std/Iptc4xmpCore/1.0/xmlns/":          "Iptc4xmpCore",
+	"http://iptc.org/std/Iptc4xmpExt/2008-02-29/":          "Iptc4xmpExt",
+	"http://ns.adobe.com/tiff/1.0/":                        "tiff",
+	"http://ns.adobe.com/exif/1.0/":                        "exif",
+	"http://cipa.jp/exif/1.0/":                             "exifEX",
+	"http://ns.adobe.com/exif/1.0/aux/":                    "aux",
+	"http://ns.adobe.com/xap/1.0/sType/ResourceEvent#":     "stEvt",
+	"http://ns.adobe.com/xap/1.0/sType/ResourceRef#":       "stRef",
+	"http://ns.adobe.com/xap/1.0/sType/Dimensions#":        "stDim",
+	"http://ns.adobe.com/xmp/sType/Area#":                  "stArea",
+	"http://ns.adobe.com/pdf/1.3/":                         "pdf",
+	"http://ns.useplus.org/ldf/xmp/1.0/":                   "plus",
+	"http://www.metadataworkinggroup.com/schemas/regions/": "mwg-rs",
+	"http://ns.google.com/photos/1.0/panorama/":            "GPano",
+	"http://ns.adobe.com/xmp/note/":                        "xmpNote",
+	"http://ns.microsoft.com/photo/1.2/":                   "MP",
+	"http://ns.microsoft.com/photo/1.2/t/RegionInfo#":      "MPRI",
+	"http://ns.microsoft.com/photo/1.2/t/Region#":          "MPReg",
+}
+
+// findXMPPacket locates the raw XMP packet in a supported container,
+// inflating a compressed PNG packet within budget
+func findXMPPacket(data []byte, budget *inflateBudget) []byte {
+	switch sniffFormat(data) {
+	case formatJPEG:
+		return findJPEGXMP(data)
+	case formatPNG:
+		for _, chunk := range readPNGChunks(data) {
+			if chunk.typ != "iTXt" {
+				continue
+			}
+			if itxt, ok := parsePNGITXt(chunk.data, budget); ok && itxt.keyword == xmpPNGKeyword {
+				return []byte(itxt.text)
+			}
+		}
+	case formatWebP:
+		for _, chunk := range readWebPChunks(data) {
+			if chunk.fourCC == "XMP " {
+				return chunk.data
+			}
+		}
+	case formatGIF:
+		_, blocks := readGIFBlocks(data)
+		for _, block := range blocks {
+			if block.appID == xmpGIFAppID {
+				return trimGIFXMP(block.raw)
+			}
+		}
+	case formatTIFF:
+		t, err := decodeTIFF(tiffPayload(data))
+		if err != nil || len(t.Dirs) == 0 {
+			return nil
+		}
+		if tag := findTag(t.Dirs[0], tagXMP); tag != nil {
+			return tag.Val
+		}
+	case formatHEIF:
+		if file := parseHEIF(data); file != nil {
+			return file.xmpPacket()
+		}
+	case formatJXL:
+		return jxlBox(data, "xml ")
+	case formatJP2:
+		return jp2XMPPacket(data)
+	case formatPSD:
+		return psdResource(data, psResourceXMP)
+	case formatSVG:
+		// Illustrator writes a complete packet inside <metadata>
+		if start := bytes.Index(data, []byte("<x:xmpmeta")); start >= 0 {
+			if end := bytes.Index(data[start:], []byte("</x:xmpmeta>")); end >= 0 {
+				return data[start : start+end+len("</x:xmpmeta>")]
+			}
+		}
+	}
+	return nil
+}
+
+// findJPEGXMP returns the standard XMP packet from APP1. Extended XMP
+// chunks are reassembled and returned after the main packet.
+func findJPEGXMP(data []byte) []byte {
+	var main []byte
+	extended := make(map[string][]byte)
+	var guids []string
+
+	for _, seg := range readJPEGSegments(data) {
+		if seg.marker != markerAPP1 {
+			continue
+		}
+		switch {
+		case bytes.HasPrefix(seg.data, xmpJPEGSignature):
+			if main == nil {
+				main = seg.data[len(xmpJPEGSignature):]
+			}
+		case bytes.HasPrefix(seg.data, xmpExtJPEGSignature):
+			// GUID (32) + full length (4) + offset (4) + chunk
+			body := seg.data[len(xmpExtJPEGSignature):]
+			if len(body) < 40 {
+				continue
+			}
+			guid := string(body[:32])
+			total := int(binary.BigEndian.Uint32(body[32:]))
+			offset := int(binary.BigEndian.Uint32(body[36:]))
+			chunk := body[40:]
+			if total <= 0 || total > maxInflatedBytes || offset+len(chunk) > total {
+				continue
+			}
+			buf, ok := extended[guid]
+			if !ok {
+				buf = make([]byte, total)
+				extended[guid] = buf
+				guids = append(guids, guid)
+			}
+			copy(buf[offset:], chunk)
+		}
+	}
+
+	if main == nil {
+		return nil
+	}
+	packet := append([]byte{}, main...)
+	for _, guid := range guids {
+		packet = append(packet, '\n')
+		packet = append(packet, extended[guid]...)
+	}
+	return packet
+}
+
+// trimGIFXMP strips the sub-block "magic trailer" that follows XMP in GIF
+func trimGIFXMP(raw []byte) []byte {
+	if end := bytes.Index(raw, []byte("<?xpacket end=")); end >= 0 {
+		if closing := bytes.Index(raw[end:], []byte("?>")); closing >= 0 {
+			return raw[:end+closing+2]
+		}
+	}
+	if trailer := bytes.Index(raw, []byte{0x01, 0xFF, 0xFE, 0xFD}); trailer >= 0 {
+		return raw[:trailer]
+	}
+	return raw
+}
+
+// maxXMPDepth bounds the element nesting kept from an XMP packet, and with
+// it the recursion of the walk functions
+const maxXMPDepth = 64
+
+// xmpNode is an element of the parsed RDF/XML tree
+type xmpNode struct {
+	name     xml.Name
+	attrs    []xml.Attr
+	children []*xmpNode
+	text     strings.Builder
+}
+
+// attr returns the value of the attribute with the given namespace and name
+func (n *xmpNode) attr(space, local string) (string, bool) {
+	for _, a := range n.attrs {
+		if a.Name.Space == space && a.Name.Local == local {
+			return a.Value, true
+		}
+	}
+	return "", false
+}
+
+// xmpParser flattens RDF/XML into namespaced properties
+type xmpParser struct {
+	prefixes map[string]string // namespace URI -> prefix declared in the packet
+	props    []models.XMPProperty
+}
+
+// parseXMP parses one or more concatenated XMP packets into an XMPData
+func parseXMP(packet []byte) *models.XMPData {
+	p := &xmpParser{prefixes: make(map[string]string)}
+
+	// Extended XMP is appended as a second document, so keep decoding
+	// until every root element has been read.
+	dec := xml.NewDecoder(bytes.NewReader(packet))
+	dec.Strict = false
+	for {
+		root, err := p.readTree(dec)
+		if root != nil {
+			p.walkRoot(root)
+		}
+		if err != nil {
+			break
+		}
+	}
+
+	if len(p.props) == 0 {
+		return nil
+	}
+
+	data := &models.XMPData{
+		Properties: p.props,
+		Raw:        strings.TrimSpace(string(packet)),
+	}
+	p.fillCurated(data)
+	return data
+}
+
+// readTree reads tokens until the next top-level element is complete.
+// Elements nested deeper than maxXMPDepth are skipped.
+func (p *xmpParser) readTree(dec *xml.Decoder) (*xmpNode, error) {
+	var stack []*xmpNode
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			if len(stack) > 0 {
+				return stack[0], err
+			}
+			return nil, err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if len(stack) == maxXMPDepth {
+				if err := dec.Skip(); err != nil {
+					return stack[0], err
+				}
+				continue
+			}
+			for _, a := range t.Attr {
+				if a.Name.Space == "xmlns" {
+					if _, ok := p.prefixes[a.Value]; !ok {
+						p.prefixes[a.Value] = a.Name.Local
+					}
+				}
+			}
+			node := &xmpNode{name: t.Name, attrs: t.Copy().Attr}
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.children = append(parent.children, node)
+			}
+			stack = append(stack, node)
+		case xml.EndElement:
+			if len(stack) == 0 {
+				continue
+			}
+			node := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if len(stack) == 0 {
+				return node, nil
+			}
+		case xml.CharData:
+			if len(stack) > 0 {
+				stack[len(stack)-1].text.Write(t)
+			}
+		}
+	}
+}
+
+// walkRoot finds rdf:Description elements and emits their properties
+func (p *xmpParser) walkRoot(node *xmpNode) {
+	if node.name.Space == nsRDF && node.name.Local == "Description" {
+		p.walkDescription(node, "")
+		return
+	}
+	for _, child := range node.children {
+		p.walkRoot(child)
+	}
+}
+
+// walkDescription emits attribute and element properties of a Description
+func (p *xmpParser) walkDescription(node *xmpNode, path string) {
+	for _, a := range node.attrs {
+		if isRDFSyntaxAttr(a.Name) {
+			continue
+		}
+		p.emit(a.Name.Space, joinPath(path, p.qname(a.Name)), a.Value)
+	}
+	for _, child := range node.children {
+		p.walkProperty(child, joinPath(path, p.qname(child.name)), child.name.Space)
+	}
+}
+
+// walkProperty emits a property, expanding arrays into indexed items
+func (p *xmpParser) walkProperty(node *xmpNode, path, space string) {
+	for _, child := range node.children {
+		if child.name.Space != nsRDF {
+			continue
+		}
+		switch child.name.Local {
+		case "Bag", "Seq", "Alt":
+			index := 0
+			for _, li := range child.children {
+				if li.name.Space != nsRDF || li.name.Local != "li" {
+					continue
+				}
+				index++
+				itemPath := path + "[" + strconv.Itoa(index) + "]"
+				if lang, ok := li.attr(nsXML, "lang"); ok && child.name.Local == "Alt" {
+					itemPath = path + "[" + lang + "]"
+				}
+				p.walkValue(li, itemPath, space)
+			}
+			return
+		}
+	}
+	p.walkValue(node, path, space)
+}
+
+// walkValue emits a simple value or expands a struct value
+func (p *xmpParser) walkValue(node *xmpNode, path, space string) {
+	if res, ok := node.attr(nsRDF, "resource"); ok {
+		p.emit(space, path, res)
+		return
+	}
+
+	parseType, _ := node.attr(nsRDF, "parseType")
+	isStruct := parseType == "Resource"
+	for _, a := range node.attrs {
+		if !isRDFSyntaxAttr(a.Name) {
+			isStruct = true
+			break
+		}
+	}
+
+	for _, child := range node.children {
+		if child.name.Space == nsRDF && child.name.Local == "Description" {
+			p.walkDescription(child, path)
+			return
+		}
+		if child.name.Space != nsRDF {
+			isStruct = true
+		}
+	}
+
+	if isStruct {
+		p.walkDescription(node, path)
+		return
+	}
+
+	p.emit(space, path, strings.TrimSpace(node.text.String()))
+}
+
+// emit records a flattened property
+func (p *xmpParser) emit(space, path, value string) {
+	p.props = append(p.props, models.XMPProperty{
+		Namespace: space,
+		Prefix:    p.prefix(space),
+		Path:      path,
+		Value:     value,
+	})
+}
+
+// prefix returns the conventional prefix for a namespace URI
+func (p *xmpParser) prefix(space string) string {
+	if prefix, ok := xmpPrefixes[space]; ok {
+		return prefix
+	}
+	if prefix, ok := p.prefixes[space]; ok {
+		return prefix
+	}
+	return space
+}
+
+// qname returns prefix:local for an element or attribute name
+func (p *xmpParser) qname(name xml.Name) string {
+	if name.Space == "" {
+		return name.Local
+	}
+	return p.prefix(name.Space) + ":" + name.Local
+}
+
+// isRDFSyntaxAttr reports attributes that belong to RDF/XML syntax
+// rather than to the metadata itself
+func isRDFSyntaxAttr(name xml.Name) bool {
+	switch name.Space {
+	case nsRDF, nsXML, "xmlns":
+		return true
+	case "":
+		return name.Local == "xmlns"
+	}
+	return false
+}
+
+// joinPath appends a struct field to a property path
+func joinPath(path, field string) string {
+	if path == "" {
+		return field
+	}
+	return path + "/" + field
+}
+
+// fillCurated copies well-known properties into the typed XMPData fields
+func (p *xmpParser) fillCurated(data *models.XMPData) {
+	values := make(map[string]string, len(p.props))
+	for _, prop := range p.props {
+		if _, ok := values[prop.Path]; !ok {
+			values[prop.Path] = prop.Value
+		}
+	}
+
+	data.CreatorTool = values["xmp:CreatorTool"]
+	data.CreateDate = values["xmp:CreateDate"]
+	data.ModifyDate = values["xmp:ModifyDate"]
+	data.MetadataDate = values["xmp:MetadataDate"]
+	data.Rating = values["xmp:Rating"]
+	data.Label = values["xmp:Label"]
+
+	data.Title = altValue(values, "dc:title")
+	data.Description = altValue(values, "dc:description")
+	data.Rights = altValue(values, "dc:rights")
+	data.Creator = listValues(p.props, "dc:creator")
+	data.Subject = listValues(p.props, "dc:subject")
+
+	data.DocumentID = values["xmpMM:DocumentID"]
+	data.InstanceID = values["xmpMM:InstanceID"]
+	data.OriginalDocumentID = values["xmpMM:OriginalDocumentID"]
+	data.History = historyEvents(p.props)
+
+	data.Headline = values["photoshop:Headline"]
+	data.Credit = values["photoshop:Credit"]
+	data.Source = values["photoshop:Source"]
+	data.City = values["photoshop:City"]
+	data.State = values["photoshop:State"]
+	data.Country = values["photoshop:Country"]
+	data.DateCreated = values["photoshop:DateCreated"]
+
+	data.Location = values["Iptc4xmpCore:Location"]
+	data.CountryCode = values["Iptc4xmpCore:CountryCode"]
+
+	data.CameraRawVersion = values["crs:Version"]
+
+	data.Namespaces = namespaceSummary(p.props)
+}
+
+// altValue returns the x-default (or first) entry of a language alternative
+func altValue(values map[string]string, path string) string {
+	if v, ok := values[path+"[x-default]"]; ok {
+		return v
+	}
+	if v, ok := values[path+"[1]"]; ok {
+		return v
+	}
+	return values[path]
+}
+
+// listValues returns the items of a Bag or Seq property
+func listValues(props []models.XMPProperty, path string) []string {
+	var out []string
+	prefix := path + "["
+	for _, prop := range props {
+		if strings.HasPrefix(prop.Path, prefix) && !strings.Contains(prop.Path[len(prefix):], "/") {
+			out = append(out, prop.Value)
+		}
+	}
+	return out
+}
+
+// historyEvents groups xmpMM:History[n]/stEvt:* properties into events
+func historyEvents(props []models.XMPProperty) []models.XMPHistoryEvent {
+	const prefix = "xmpMM:History["
+	var events []models.XMPHistoryEvent
+	index := make(map[string]int)
+
+	for _, prop := range props {
+		if !strings.HasPrefix(prop.Path, prefix) {
+			continue
+		}
+		item, field, ok := strings.Cut(prop.Path[len(prefix):], "]/")
+		if !ok {
+			continue
+		}
+		i, seen := index[item]
+		if !seen {
+			i = len(events)
+			index[item] = i
+			events = append(events, models.XMPHistoryEvent{})
+		}
+		switch field {
+		case "stEvt:action":
+			events[i].Action = prop.Value
+		case "stEvt:when":
+			events[i].When = prop.Value
+		case "stEvt:softwareAgent":
+			events[i].SoftwareAgent = prop.Value
+		case "stEvt:instanceID":
+			events[i].InstanceID = prop.Value
+		case "stEvt:changed":
+			events[i].Changed = prop.Value
+		case "stEvt:parameters":
+			events[i].Parameters = prop.Value
+		}
+	}
+	return events
+}
+
+// namespaceSummary counts properties per namespace prefix
+func namespaceSummary(props []models.XMPProperty) []models.XMPNamespace {
+	counts := make(map[string]*models.XMPNamespace)
+	for _, prop := range props {
+		ns, ok := counts[prop.Namespace]
+		if !ok {
+			ns = &models.XMPNamespace{Prefix: prop.Prefix, URI: prop.Namespace}
+			counts[prop.Namespace] = ns
+		}
+		ns.Properties++
+	}
+
+	out := make([]models.XMPNamespace, 0, len(counts))
+	for _, ns := range counts {
+		out = append(out, *ns)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Prefix < out[j].Prefix })
+	return out
+}
+
+// extractXMP finds and parses the XMP packet and fills the top-level fields
+func extractXMP(data []byte, result *Result) error {
+	meta := result.Metadata
+	packet := findXMPPacket(data, result.budget())
+	if len(packet) == 0 {
+		return nil
+	}
+
+	meta.XMP = parseXMP(packet)
+	if meta.XMP == nil {
+		return nil
+	}
+	meta.CreatorTool = meta.XMP.CreatorTool
+	meta.MetadataDate = meta.XMP.MetadataDate
+	return nil
+}
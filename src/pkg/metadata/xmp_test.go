@@ -0,0 +1,126 @@
+package metadata
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/png"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testXMPPacket = `<?xpacket begin="` + "\xEF\xBB\xBF" + `" id="W5M0MpCehiHzreSzNTczkc9d"?>
+<x:xmpmeta xmlns:x="adobe:ns:meta/">
+ <rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">
+  <rdf:Description rdf:about=""
+    xmlns:xmp="http://ns.adobe.com/xap/1.0/"
+    xmlns:dc="http://purl.org/dc/elements/1.1/"
+    xmlns:xmpMM="http://ns.adobe.com/xap/1.0/mm/"
+    xmlns:stEvt="http://ns.adobe.com/xap/1.0/sType/ResourceEvent#"
+    xmlns:ps="http://ns.adobe.com/photoshop/1.0/"
+    xmp:CreatorTool="Adobe Photoshop 26.0 (Windows)"
+    xmp:MetadataDate="2025-10-31T21:41:50+07:00"
+    ps:City="Jakarta">
+   <dc:title><rdf:Alt><rdf:li xml:lang="x-default">Harbour at dusk</rdf:li></rdf:Alt></dc:title>
+   <dc:subject><rdf:Bag><rdf:li>harbour</rdf:li><rdf:li>boats</rdf:li></rdf:Bag></dc:subject>
+   <xmpMM:History>
+    <rdf:Seq>
+     <rdf:li stEvt:action="created" stEvt:softwareAgent="Adobe Photoshop 26.0"/>
+     <rdf:li rdf:parseType="Resource">
+      <stEvt:action>saved</stEvt:action>
+      <stEvt:when>2025-10-31T21:41:50+07:00</stEvt:when>
+     </rdf:li>
+    </rdf:Seq>
+   </xmpMM:History>
+  </rdf:Description>
+ </rdf:RDF>
+</x:xmpmeta>
+<?xpacket end="w"?>`
+
+// buildPNGWithChunks encodes a small PNG and inserts chunks after IHDR
+func buildPNGWithChunks(t *testing.T, chunks ...[]byte) []byte {
+	t.Helper()
+
+	var enc bytes.Buffer
+	if err := png.Encode(&enc, image.NewGray(image.Rect(0, 0, 4, 4))); err != nil {
+		t.Fatalf("png.Encode: %v", err)
+	}
+	raw := enc.Bytes()
+
+	// signature (8) + IHDR chunk (12 + 13)
+	ihdrEnd := 8 + 25
+	out := append([]byte{}, raw[:ihdrEnd]...)
+	for _, chunk := range chunks {
+		out = append(out, chunk...)
+	}
+	return append(out, raw[ihdrEnd:]...)
+}
+
+// buildChunk wraps body in a PNG chunk with a valid CRC
+func buildChunk(typ string, body []byte) []byte {
+	out := make([]byte, 4, 12+len(body))
+	binary.BigEndian.PutUint32(out, uint32(len(body)))
+	out = append(out, typ...)
+	out = append(out, body...)
+	crc := make([]byte, 4)
+	binary.BigEndian.PutUint32(crc, crc32.ChecksumIEEE(out[4:]))
+	return append(out, crc...)
+}
+
+func TestExtractMetadataXMPFromJPEG(t *testing.T) {
+	seg := jpegSegment(0xE1, append(append([]byte{}, xmpJPEGSignature...), testXMPPacket...))
+	meta := ExtractMetadata(buildJPEGWithSegments(t, seg), "image/jpeg", "xmp.jpg")
+
+	x := meta.XMP
+	if x == nil {
+		t.Fatal("expected XMP section")
+	}
+	if meta.CreatorTool != "Adobe Photoshop 26.0 (Windows)" {
+		t.Errorf("CreatorTool = %q", meta.CreatorTool)
+	}
+	if meta.MetadataDate != "2025-10-31T21:41:50+07:00" {
+		t.Errorf("MetadataDate = %q", meta.MetadataDate)
+	}
+	if x.Title != "Harbour at dusk" {
+		t.Errorf("Title = %q", x.Title)
+	}
+	if !reflect.DeepEqual(x.Subject, []string{"harbour", "boats"}) {
+		t.Errorf("Subject = %v", x.Subject)
+	}
+	if x.City != "Jakarta" {
+		t.Errorf("City = %q, want the photoshop namespace to resolve despite the ps prefix", x.City)
+	}
+	if len(x.History) != 2 || x.History[0].Action != "created" || x.History[1].When != "2025-10-31T21:41:50+07:00" {
+		t.Errorf("History = %+v", x.History)
+	}
+}
+
+func TestExtractMetadataXMPFromPNG(t *testing.T) {
+	body := append([]byte(xmpPNGKeyword), 0, 0, 0, 0, 0)
+	body = append(body, testXMPPacket...)
+	data := buildPNGWithChunks(t, buildChunk("iTXt", body))
+
+	meta := ExtractMetadata(data, "image/png", "xmp.png")
+	if meta.XMP == nil || meta.XMP.CreatorTool != "Adobe Photoshop 26.0 (Windows)" {
+		t.Fatalf("XMP = %+v", meta.XMP)
+	}
+}
+
+func TestParseXMPNestingLimit(t *testing.T) {
+	deep := strings.Repeat("<dc:x>", 100000) + strings.Repeat("</dc:x>", 100000)
+	packet := `<x:xmpmeta xmlns:x="adobe:ns:meta/"><rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">` +
+		`<rdf:Description xmlns:dc="http://purl.org/dc/elements/1.1/" dc:format="image/jpeg">` + deep +
+		`</rdf:Description></rdf:RDF></x:xmpmeta>`
+
+	data := parseXMP([]byte(packet))
+	if data == nil || data.Properties[0].Path != "dc:format" {
+		t.Fatalf("parseXMP = %+v", data)
+	}
+	for _, prop := range data.Properties {
+		if depth := strings.Count(prop.Path, "/"); depth >= maxXMPDepth {
+			t.Errorf("property %d levels deep was kept", depth)
+		}
+	}
+}
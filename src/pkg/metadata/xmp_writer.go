@@ -0,0 +1,360 @@
+package metadata
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// XMP array forms
+const (
+	xmpSimple = ""
+	xmpAlt    = "Alt" // language alternatives; one x-default value is written
+	xmpBag    = "Bag"
+	xmpSeq    = "Seq"
+)
+
+// xmpField is a top-level property to write into a new XMP packet
+type xmpField struct {
+	namespace string
+	name      string // local name
+	form      string // xmpSimple, xmpAlt, xmpBag or xmpSeq
+	values    []string
+}
+
+// writeXMPProperty writes a property element on its own line
+func writeXMPProperty(b *bytes.Buffer, tag, rdf string, f xmpField) {
+	b.WriteString("   <" + tag + ">")
+	switch f.form {
+	case xmpSimple:
+		xml.EscapeText(b, []byte(f.values[0]))
+	case xmpAlt:
+		b.WriteString("<" + rdf + ":Alt><" + rdf + ":li xml:lang=\"x-default\">")
+		xml.EscapeText(b, []byte(f.values[0]))
+		b.WriteString("</" + rdf + ":li></" + rdf + ":Alt>")
+	default:
+		b.WriteString("<" + rdf + ":" + f.form + ">")
+		for _, v := range f.values {
+			b.WriteString("<" + rdf + ":li>")
+			xml.EscapeText(b, []byte(v))
+			b.WriteString("</" + rdf + ":li>")
+		}
+		b.WriteString("</" + rdf + ":" + f.form + ">")
+	}
+	b.WriteString("</" + tag + ">\n")
+}
+
+// encodeXMP writes fields as a standalone XMP packet, using the
+// conventional prefix of each namespace
+func encodeXMP(fields []xmpField) []byte {
+	var namespaces []string
+	seen := map[string]bool{}
+	for _, f := range fields {
+		if !seen[f.namespace] {
+			seen[f.namespace] = true
+			namespaces = append(namespaces, f.namespace)
+		}
+	}
+	sort.Strings(namespaces)
+
+	var b bytes.Buffer
+	b.WriteString("<?xpacket begin=\"\xEF\xBB\xBF\" id=\"W5M0MpCehiHzreSzNTczkc9d\"?>\n")
+	b.WriteString("<x:xmpmeta xmlns:x=\"adobe:ns:meta/\">\n")
+	b.WriteString(" <rdf:RDF xmlns:rdf=\"" + nsRDF + "\">\n")
+	b.WriteString("  <rdf:Description rdf:about=\"\"")
+	for _, ns := range namespaces {
+		b.WriteString("\n    xmlns:" + xmpPrefixes[ns] + "=\"")
+		xml.EscapeText(&b, []byte(ns))
+		b.WriteString("\"")
+	}
+	b.WriteString(">\n")
+
+	for _, f := range fields {
+		writeXMPProperty(&b, xmpPrefixes[f.namespace]+":"+f.name, "rdf", f)
+	}
+
+	b.WriteString("  </rdf:Description>\n </rdf:RDF>\n</x:xmpmeta>\n<?xpacket end=\"w\"?>")
+	return b.Bytes()
+}
+
+// xmpTag is a top-level rdf:Description start tag found in a packet
+type xmpTag struct {
+	start, end  int      // byte range of the start tag
+	name        xml.Name // as written, with the prefix in Space
+	attrs       []xml.Attr
+	selfClosing bool
+}
+
+// xmpElement is a property element of a top-level rdf:Description
+type xmpElement struct {
+	namespace  string
+	name       string
+	start, end int
+	form       string // the array form it holds, if any
+}
+
+// xmpLayout locates the top-level properties of a packet
+type xmpLayout struct {
+	prefixes     map[string]string // prefix -> namespace URI
+	descriptions []xmpTag
+	elements     []xmpElement
+}
+
+// scanXMP reads a packet without resolving namespaces, so that the byte
+// range and prefix of every top-level property are known
+func scanXMP(packet []byte) (*xmpLayout, error) {
+	l := &xmpLayout{prefixes: map[string]string{"xml": nsXML}}
+	type frame struct {
+		namespace, name string
+		description     bool
+		element         int // index in l.elements of a property, or -1
+	}
+	var stack []frame
+
+	dec := xml.NewDecoder(bytes.NewReader(packet))
+	dec.Strict = false
+	for {
+		start := int(dec.InputOffset())
+		tok, err := dec.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("XMP packet is not well-formed: %v", err)
+		}
+		end := int(dec.InputOffset())
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			for _, a := range t.Attr {
+				if _, ok := l.prefixes[a.Name.Local]; a.Name.Space == "xmlns" && !ok {
+					l.prefixes[a.Name.Local] = a.Value
+				}
+			}
+			f := frame{namespace: l.prefixes[t.Name.Space], name: t.Name.Local, element: -1}
+			parent := frame{element: -1}
+			if len(stack) > 0 {
+				parent = stack[len(stack)-1]
+			}
+			switch {
+			case f.namespace == nsRDF && f.name == "Description" && parent.namespace == nsRDF && parent.name == "RDF":
+				f.description = true
+				l.descriptions = append(l.descriptions, xmpTag{
+					start:       start,
+					end:         end,
+					name:        t.Name,
+					attrs:       t.Copy().Attr,
+					selfClosing: bytes.HasSuffix(packet[start:end], []byte("/>")),
+				})
+			case parent.description:
+				f.element = len(l.elements)
+				l.elements = append(l.elements, xmpElement{namespace: f.namespace, name: f.name, start: start, end: end})
+			case parent.element >= 0 && f.namespace == nsRDF && (f.name == xmpBag || f.name == xmpSeq || f.name == xmpAlt):
+				l.elements[parent.element].form = f.name
+			}
+			stack = append(stack, f)
+		case xml.EndElement:
+			if len(stack) == 0 {
+				return nil, errors.New("XMP packet is not well-formed: unexpected end tag")
+			}
+			if f := stack[len(stack)-1]; f.element >= 0 {
+				l.elements[f.element].end = end
+			}
+			stack = stack[:len(stack)-1]
+		}
+	}
+	return l, nil
+}
+
+// prefixFor returns the prefix the packet declares for a namespace
+func (l *xmpLayout) prefixFor(namespace string) (string, bool) {
+	var found []string
+	for prefix, uri := range l.prefixes {
+		if uri == namespace {
+			found = append(found, prefix)
+		}
+	}
+	if len(found) == 0 {
+		return "", false
+	}
+	sort.Strings(found)
+	return found[0], true
+}
+
+// arrayForm picks the form of a property that is not a known array: the
+// one it already has, or a bag when several values are given
+func arrayForm(f xmpField, existing string) string {
+	switch {
+	case f.form != xmpSimple:
+		return f.form
+	case existing != "":
+		return existing
+	case len(f.values) > 1:
+		return xmpBag
+	}
+	return xmpSimple
+}
+
+// patchXMP sets or, for fields without values, removes top-level
+// properties of a packet and leaves the rest of it as written. New values
+// go to the first rdf:Description. Without a packet a new one is written.
+func patchXMP(packet []byte, fields []xmpField) ([]byte, error) {
+	if len(bytes.TrimSpace(packet)) == 0 {
+		var set []xmpField
+		for _, f := range fields {
+			if f.values != nil {
+				f.form = arrayForm(f, "")
+				set = append(set, f)
+			}
+		}
+		return encodeXMP(set), nil
+	}
+
+	l, err := scanXMP(packet)
+	if err != nil {
+		return nil, err
+	}
+	if len(l.descriptions) == 0 {
+		return nil, errors.New("XMP packet has no rdf:Description")
+	}
+
+	type splice struct {
+		start, end int
+		text       string
+	}
+	var splices []splice
+	existing := make(map[string]string)
+	edited := make(map[string]bool)
+	for _, f := range fields {
+		edited[f.namespace+" "+f.name] = true
+	}
+	for _, el := range l.elements {
+		if key := el.namespace + " " + el.name; edited[key] {
+			existing[key] = el.form
+			splices = append(splices, splice{start: lineStart(packet, el.start), end: el.end})
+		}
+	}
+
+	// Properties written as attributes are dropped from their start tags
+	tags := make([]string, len(l.descriptions))
+	for i, d := range l.descriptions {
+		tags[i] = string(packet[d.start:d.end])
+		attrs := d.attrs[:0:0]
+		for _, a := range d.attrs {
+			if a.Name.Space != "" && edited[l.prefixes[a.Name.Space]+" "+a.Name.Local] {
+				continue
+			}
+			attrs = append(attrs, a)
+		}
+		if len(attrs) < len(d.attrs) {
+			tags[i] = renderStartTag(d.name, attrs, d.selfClosing)
+		}
+	}
+
+	rdf, _ := l.prefixFor(nsRDF)
+	var added bytes.Buffer
+	for _, f := range fields {
+		if f.values == nil {
+			continue
+		}
+		prefix, ok := l.prefixFor(f.namespace)
+		if !ok {
+			prefix = xmpPrefixes[f.namespace]
+			for l.prefixes[prefix] != "" {
+				prefix += "1"
+			}
+			l.prefixes[prefix] = f.namespace
+			tags[0] = addNamespace(tags[0], prefix, f.namespace)
+		}
+		f.form = arrayForm(f, existing[f.namespace+" "+f.name])
+		writeXMPProperty(&added, prefix+":"+f.name, rdf, f)
+	}
+
+	first := l.descriptions[0]
+	if added.Len() > 0 {
+		props := "\n" + strings.TrimSuffix(added.String(), "\n")
+		if strings.HasSuffix(tags[0], "/>") {
+			name := first.name.Local
+			if first.name.Space != "" {
+				name = first.name.Space + ":" + name
+			}
+			tags[0] = strings.TrimSuffix(tags[0], "/>") + ">" + props + "\n  </" + name + ">"
+		} else {
+			tags[0] += props
+		}
+	}
+	for i, d := range l.descriptions {
+		if tags[i] != string(packet[d.start:d.end]) {
+			splices = append(splices, splice{start: d.start, end: d.end, text: tags[i]})
+		}
+	}
+
+	sort.Slice(splices, func(i, j int) bool { return splices[i].start > splices[j].start })
+	out := append([]byte{}, packet...)
+	for _, s := range splices {
+		out = append(out[:s.start], append([]byte(s.text), out[s.end:]...)...)
+	}
+	return out, nil
+}
+
+// lineStart extends the start of an element over the indentation and line
+// break before it, so that removing it leaves no blank line
+func lineStart(packet []byte, start int) int {
+	s := start
+	for s > 0 && (packet[s-1] == ' ' || packet[s-1] == '\t') {
+		s--
+	}
+	if s > 0 && packet[s-1] == '\n' {
+		s--
+		if s > 0 && packet[s-1] == '\r' {
+			s--
+		}
+		return s
+	}
+	return start
+}
+
+// renderStartTag writes a start tag with one attribute per line
+func renderStartTag(name xml.Name, attrs []xml.Attr, selfClosing bool) string {
+	var b bytes.Buffer
+	b.WriteString("<" + qualifiedName(name))
+	for i, a := range attrs {
+		if i == 0 {
+			b.WriteString(" ")
+		} else {
+			b.WriteString("\n    ")
+		}
+		b.WriteString(qualifiedName(a.Name) + "=\"")
+		xml.EscapeText(&b, []byte(a.Value))
+		b.WriteString("\"")
+	}
+	if selfClosing {
+		b.WriteString("/>")
+	} else {
+		b.WriteString(">")
+	}
+	return b.String()
+}
+
+// addNamespace declares a prefix at the end of a start tag
+func addNamespace(tag, prefix, namespace string) string {
+	closing := ">"
+	if strings.HasSuffix(tag, "/>") {
+		closing = "/>"
+	}
+	var b bytes.Buffer
+	xml.EscapeText(&b, []byte(namespace))
+	return strings.TrimSuffix(tag, closing) + "\n    xmlns:" + prefix + "=\"" + b.String() + "\"" + closing
+}
+
+// qualifiedName joins a raw token name with its prefix
+func qualifiedName(name xml.Name) string {
+	if name.Space == "" {
+		return name.Local
+	}
+	return name.Space + ":" + name.Local
+}